@@ -0,0 +1,121 @@
+package sxgo
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// GetCityAddr is GetCity for an address already parsed, e.g. by a log
+// pipeline. IPv4 addresses (and IPv4-mapped IPv6 ones) are resolved without
+// formatting and parsing a string, unless middleware, post-processors,
+// synthetic locations, the lookup audit or recent lookups are in use, as
+// they see the address as a string; other addresses and instances take the
+// GetCity path.
+func (s *SxGeo) GetCityAddr(addr netip.Addr, opts ...CallOption) (*LocationInfo, error) {
+	addr = addr.Unmap()
+	if info, ok := s.cityAddr(addr, depthCity); ok {
+		if info = s.applyCallOptions(info, opts, depthCity); info == nil {
+			return nil, s.missing(addr.String(), true)
+		}
+		return info, nil
+	}
+	return s.GetCity(addr.String(), opts...)
+}
+
+// GetCityFullAddr is GetCityFull for an address already parsed, as for
+// GetCityAddr.
+func (s *SxGeo) GetCityFullAddr(addr netip.Addr, opts ...CallOption) (*LocationInfo, error) {
+	addr = addr.Unmap()
+	if info, ok := s.cityAddr(addr, depthFull); ok {
+		if info = s.applyCallOptions(info, opts, depthFull); info == nil {
+			return nil, s.missing(addr.String(), true)
+		}
+		return info, nil
+	}
+	return s.GetCityFull(addr.String(), opts...)
+}
+
+// GetCountryAddr is GetCountry for an address already parsed, as for
+// GetCityAddr; only synthetic locations, the lookup audit and recent lookups
+// make it take the GetCountry path. See also CountryOf.
+func (s *SxGeo) GetCountryAddr(addr netip.Addr) (string, error) {
+	addr = addr.Unmap()
+	iso, err := s.countryAddr(addr)
+	if iso == "" && err == nil {
+		err = s.missing(addr.String(), false)
+	}
+	return iso, err
+}
+
+// countryAddr is GetCountryAddr reporting misses as "" with a nil error
+// whatever the options, as country does.
+// Internal function.
+func (s *SxGeo) countryAddr(addr netip.Addr) (string, error) {
+	if addr.Is4() && len(s.synthetic) == 0 && s.audit == nil && s.recent == nil {
+		db := s.acquire()
+		defer db.unpin()
+		num, err := s.lookupNum(db, addrToUint32(addr))
+		if errors.Is(err, ErrReservedRange) {
+			return "", nil
+		}
+		if err == nil {
+			id, _, err := s.resolveNum(db, num)
+			if err == nil {
+				return getISO(id), nil
+			}
+		}
+		// Errors are rare; the GetCountry path reports them as usual
+	}
+	return s.country(addr.String())
+}
+
+// GetCityIP is GetCityAddr for a net.IP.
+func (s *SxGeo) GetCityIP(ip net.IP, opts ...CallOption) (*LocationInfo, error) {
+	if addr, ok := netip.AddrFromSlice(ip); ok {
+		return s.GetCityAddr(addr, opts...)
+	}
+	return s.GetCity(ip.String(), opts...)
+}
+
+// GetCityFullIP is GetCityFullAddr for a net.IP.
+func (s *SxGeo) GetCityFullIP(ip net.IP, opts ...CallOption) (*LocationInfo, error) {
+	if addr, ok := netip.AddrFromSlice(ip); ok {
+		return s.GetCityFullAddr(addr, opts...)
+	}
+	return s.GetCityFull(ip.String(), opts...)
+}
+
+// GetCountryIP is GetCountryAddr for a net.IP.
+func (s *SxGeo) GetCountryIP(ip net.IP) (string, error) {
+	if addr, ok := netip.AddrFromSlice(ip); ok {
+		return s.GetCountryAddr(addr)
+	}
+	return s.GetCountry(ip.String())
+}
+
+// cityAddr resolves an IPv4 address down to depth as lookupDepth does,
+// reporting false if the lookup must take the string path instead: for other
+// addresses, on instances with features seeing the address as a string, and
+// on errors, which that path then reports.
+// Internal function.
+func (s *SxGeo) cityAddr(addr netip.Addr, depth recordDepth) (*LocationInfo, bool) {
+	if !addr.Is4() || len(s.middleware) > 0 || len(s.postProcessors) > 0 ||
+		len(s.synthetic) > 0 || s.audit != nil || s.recent != nil {
+		return nil, false
+	}
+	db := s.acquire()
+	defer db.unpin()
+	if !db.seekIDs() {
+		return nil, true // Not a city database
+	}
+	num := addrToUint32(addr)
+	info, err := s.cityAt(db, "", num, depth)
+	if err != nil {
+		return nil, false
+	}
+	if info != nil {
+		s.postProcess("", info) // No post-processors see the empty address
+	}
+	return info, true
+}
@@ -0,0 +1,165 @@
+package sxgo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// maxReportExamples bounds the example lists in a RangeReport.
+const maxReportExamples = 10
+
+// IPRange is an inclusive range of IPv4 addresses.
+type IPRange struct {
+	First netip.Addr `json:"first"` // First address in the range.
+	Last  netip.Addr `json:"last"`  // Last address in the range (inclusive).
+}
+
+// Size returns the number of addresses in the range.
+func (r IPRange) Size() uint64 {
+	return uint64(addrToUint32(r.Last)-addrToUint32(r.First)) + 1
+}
+
+// String returns the range as "first-last".
+func (r IPRange) String() string {
+	return r.First.String() + "-" + r.Last.String()
+}
+
+// BlockIssue identifies a suspicious DB block.
+type BlockIssue struct {
+	Block uint32     `json:"block"` // Index of the block in the block table.
+	Start netip.Addr `json:"start"` // First address of the block.
+	Prev  netip.Addr `json:"prev"`  // First address of the preceding block in the same window.
+}
+
+// RangeReport is the result of AnalyzeRanges.
+type RangeReport struct {
+	Blocks       uint32    `json:"blocks"`        // Blocks in the table.
+	Unindexed    uint32    `json:"unindexed"`     // Blocks outside every first-byte window (unreachable).
+	Gaps         int       `json:"gaps"`          // Uncovered stretches of non-reserved address space.
+	GapAddresses uint64    `json:"gap_addresses"` // Total addresses in gaps.
+	LargestGaps  []IPRange `json:"largest_gaps"`  // Largest gaps, largest first.
+
+	Duplicates        int          `json:"duplicates"`         // Blocks starting at the same address as their predecessor.
+	DuplicateExamples []BlockIssue `json:"duplicate_examples"` // First duplicates found.
+	Overlaps          int          `json:"overlaps"`           // Blocks starting below their predecessor (out of order).
+	OverlapExamples   []BlockIssue `json:"overlap_examples"`   // First overlaps found.
+	Redundant         int          `json:"redundant"`          // Blocks with the same ID as their predecessor (mergeable).
+}
+
+// AnalyzeRanges checks the block table for quality issues: gaps (address space
+// no block covers, or covered by ID 0), duplicate and out-of-order blocks,
+// adjacent blocks that could be merged, and blocks no first-byte window reaches.
+// Reserved first bytes (0, 10, 127 and beyond the byte index) and ranges
+// configured with WithReservedRanges are not gaps.
+// It is intended as a QA tool for custom-built databases and reads the whole table.
+func (s *SxGeo) AnalyzeRanges() (*RangeReport, error) {
+	db := s.acquire()
+	defer db.unpin()
+	rep := &RangeReport{Blocks: db.header.dbItems}
+
+	// Pass 1: per-window block order checks.
+	var indexed uint32
+	for b := uint32(1); b < uint32(db.header.byteIndexLen); b++ {
+		minBlock, maxBlock := db.byteIndexAt(b-1), db.byteIndexAt(b)
+		if minBlock >= maxBlock {
+			continue
+		}
+		data, err := s.blockData(db, minBlock, maxBlock)
+		if err != nil {
+			return nil, fmt.Errorf("sxgo: failed to analyze ranges: %w", err)
+		}
+		n := len(data) / int(db.blockSize)
+		indexed += uint32(n)
+		for i := 1; i < n; i++ {
+			prev, cur := blockSuffix(data, i-1, db.blockSize), blockSuffix(data, i, db.blockSize)
+			issue := BlockIssue{
+				Block: minBlock + uint32(i),
+				Start: uint32ToAddr(b<<24 | cur),
+				Prev:  uint32ToAddr(b<<24 | prev),
+			}
+			switch {
+			case cur == prev:
+				rep.Duplicates++
+				if len(rep.DuplicateExamples) < maxReportExamples {
+					rep.DuplicateExamples = append(rep.DuplicateExamples, issue)
+				}
+			case cur < prev:
+				rep.Overlaps++
+				if len(rep.OverlapExamples) < maxReportExamples {
+					rep.OverlapExamples = append(rep.OverlapExamples, issue)
+				}
+			default:
+				prevID, err := db.blockID(data, i-1)
+				if err != nil {
+					return nil, fmt.Errorf("sxgo: failed to analyze ranges: %w", err)
+				}
+				curID, err := db.blockID(data, i)
+				if err != nil {
+					return nil, fmt.Errorf("sxgo: failed to analyze ranges: %w", err)
+				}
+				if prevID == curID {
+					rep.Redundant++
+				}
+			}
+		}
+	}
+	if indexed < rep.Blocks {
+		rep.Unindexed = rep.Blocks - indexed
+	}
+
+	// Pass 2: gaps, as lookups see them. Adjacent uncovered ranges form one gap.
+	var gap *IPRange
+	flush := func() {
+		if gap == nil {
+			return
+		}
+		rep.Gaps++
+		rep.GapAddresses += gap.Size()
+		rep.LargestGaps = append(rep.LargestGaps, *gap)
+		sort.SliceStable(rep.LargestGaps, func(i, j int) bool {
+			return rep.LargestGaps[i].Size() > rep.LargestGaps[j].Size()
+		})
+		if len(rep.LargestGaps) > maxReportExamples {
+			rep.LargestGaps = rep.LargestGaps[:maxReportExamples]
+		}
+		gap = nil
+	}
+	err := s.walkRanges(db, 0, 0xFFFFFFFF, func(r ipRange) error {
+		if r.id != 0 || r.reserved {
+			flush()
+			return nil
+		}
+		if gap == nil {
+			gap = &IPRange{First: uint32ToAddr(r.first)}
+		}
+		gap.Last = uint32ToAddr(r.last)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to analyze ranges: %w", err)
+	}
+	flush()
+	return rep, nil
+}
+
+// uint32ToAddr converts a big-endian IPv4 integer to netip.Addr.
+// Internal function.
+func uint32ToAddr(ip uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], ip)
+	return netip.AddrFrom4(b)
+}
+
+// addrToUint32 converts an IPv4 (or IPv4-mapped) netip.Addr to its integer form.
+// Other addresses yield 0.
+// Internal function.
+func addrToUint32(addr netip.Addr) uint32 {
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return 0
+	}
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,168 @@
+package sxgo
+
+import (
+	"crypto/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LookupAuditRecord is the audit record of one lookup (see WithLookupAudit).
+// It identifies the client address only by a keyed hash.
+type LookupAuditRecord struct {
+	Time   time.Time `json:"time"`
+	IPHash string    `json:"ip_hash"`       // Keyed hash of the address (see LookupAudit.Hasher).
+	Method string    `json:"method"`        // "GetCity", "GetCityRegion", "GetCityFull" or "GetCountryID".
+	Tag    string    `json:"tag,omitempty"` // Caller tag (see WithCallerTag).
+
+	// Result summary; all empty when the address was not found.
+	Country   string    `json:"country,omitempty"` // ISO code.
+	CityID    uint32    `json:"city_id,omitempty"`
+	Precision Precision `json:"precision,omitempty"`
+	Special   Special   `json:"special,omitempty"`
+	Error     string    `json:"error,omitempty"` // Error returned to the caller, if any, without the address.
+}
+
+// LookupAudit configures the lookup audit log (see WithLookupAudit).
+type LookupAudit struct {
+	// Sink receives the records. It runs synchronously in the lookup and must
+	// be safe for concurrent use.
+	Sink func(LookupAuditRecord)
+	// Hasher hashes the addresses. If nil, an HMAC-SHA256 hasher keyed with
+	// Key is used, or the instance hasher (see WithIPHasher) if Key is empty.
+	// Records hashed with the same key can be correlated; without it,
+	// addresses cannot be recovered by hashing candidates.
+	Hasher *IPHasher
+	// Key keys the default HMAC-SHA256 hasher. Without Key, Hasher and an
+	// instance hasher, a random key is generated for the instance: hashes
+	// then differ across restarts and instances, and records cannot be
+	// correlated between them.
+	Key []byte
+	// Rate is the share of addresses audited (0..1), decided by the address
+	// hash so an address is audited consistently; 0 audits every lookup.
+	Rate float64
+	// MaxPerSecond caps the records emitted per second; 0 means no cap.
+	MaxPerSecond int
+}
+
+// auditState is a configured lookup audit log and its rate limiter.
+// This struct is internal.
+type auditState struct {
+	LookupAudit
+	keyed  *IPHasher    // HMAC-SHA256 hasher keyed with Key, or a random key
+	window atomic.Int64 // Unix second counted by count
+	count  atomic.Int64
+}
+
+// WithLookupAudit calls a.Sink with a LookupAuditRecord for GetCity,
+// GetCityRegion, GetCityFull and GetCountryID lookups (GetCountry included),
+// for environments that must log geolocation decisions. Records describe the
+// result returned to the caller, after middleware and call options. a.Rate
+// and a.MaxPerSecond bound the volume. A nil Sink disables auditing.
+func WithLookupAudit(a LookupAudit) Option {
+	return func(s *SxGeo) {
+		s.audit = nil
+		if a.Sink == nil {
+			return
+		}
+		key := append([]byte(nil), a.Key...)
+		if len(key) == 0 {
+			// An empty key would let anyone hash every IPv4 address and
+			// reverse the records
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				panic("sxgo: cannot generate a lookup audit key: " + err.Error())
+			}
+		}
+		keyed := &IPHasher{alg: HashHMACSHA256, cur: ipHashKey{key: key}}
+		s.audit = &auditState{LookupAudit: a, keyed: keyed}
+	}
+}
+
+// WithCallerTag sets the caller tag recorded in lookup audit records (see
+// WithLookupAudit), e.g. the name of the calling service or endpoint.
+func WithCallerTag(tag string) CallOption {
+	return func(c *callConfig) {
+		c.tag = tag
+	}
+}
+
+// auditLookup records the result of a lookup of ip for RecentLookups and
+// emits its audit record, if auditing is enabled and the lookup is sampled.
+// opts are the call options of the lookup.
+// Internal function.
+func (s *SxGeo) auditLookup(method, ip string, info *LocationInfo, err error, opts []CallOption) {
+	if s.recent != nil && err == nil {
+		s.recent.add(info)
+	}
+	a := s.audit
+	if a == nil {
+		return
+	}
+	h := a.Hasher
+	switch {
+	case h != nil:
+	case len(a.Key) == 0 && s.hasher != nil:
+		h = s.hasher
+	default:
+		h = a.keyed
+	}
+	if a.Rate > 0 && a.Rate < 1 && float64(h.Sum64(ip)>>11) >= a.Rate*(1<<53) {
+		return
+	}
+	now := time.Now()
+	if !a.allow(now.Unix()) {
+		return
+	}
+
+	rec := LookupAuditRecord{Time: now, IPHash: h.Hash(ip), Method: method}
+	var cfg callConfig
+	for _, o := range s.callDefaults {
+		o(&cfg)
+	}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	rec.Tag = cfg.tag
+	if info != nil {
+		if info.Country != nil {
+			rec.Country = info.Country.ISO
+		}
+		if info.City != nil {
+			rec.CityID = info.City.ID
+		}
+		rec.Precision, rec.Special = info.Precision, info.Special
+	}
+	if err != nil { // Errors quote the address; keep it out of the record
+		rec.Error = strings.ReplaceAll(err.Error(), ip, "<ip>")
+	}
+	a.Sink(rec)
+}
+
+// auditCountry is auditLookup for GetCountryID, which returns only an ID.
+// Internal function.
+func (s *SxGeo) auditCountry(ip string, id uint32, err error) {
+	if s.audit == nil && s.recent == nil {
+		return
+	}
+	var info *LocationInfo
+	if id != 0 {
+		info = &LocationInfo{Country: &Country{ID: uint8(id), ISO: getISO(id)}}
+		info.setPrecision()
+	}
+	s.auditLookup("GetCountryID", ip, info, err, nil)
+}
+
+// allow reports whether another record may be emitted in second now.
+// Internal function.
+func (a *auditState) allow(now int64) bool {
+	if a.MaxPerSecond <= 0 {
+		return true
+	}
+	if w := a.window.Load(); w != now && a.window.CompareAndSwap(w, now) {
+		a.count.Store(0)
+	}
+	return a.count.Add(1) <= int64(a.MaxPerSecond)
+}
@@ -0,0 +1,50 @@
+package sxgo_test
+
+import (
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// auditHash returns the IPHash of the audit record of a lookup of ip by an
+// instance with the audit configuration a.
+func auditHash(t *testing.T, path string, a sxgo.LookupAudit, ip string) string {
+	t.Helper()
+	var rec sxgo.LookupAuditRecord
+	a.Sink = func(r sxgo.LookupAuditRecord) { rec = r }
+	geo, err := sxgo.New(path, sxgo.ModeMemory, sxgo.WithLookupAudit(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	if _, err := geo.GetCityFull(ip); err != nil {
+		t.Fatal(err)
+	}
+	if rec.IPHash == "" || rec.Method != "GetCityFull" {
+		t.Fatalf("audit record = %+v", rec)
+	}
+	return rec.IPHash
+}
+
+func TestLookupAuditKey(t *testing.T) {
+	path := miniCityPath(t)
+	const ip = "46.0.128.1"
+
+	// Without a key, hashes must not be reproducible by anyone else
+	a, b := auditHash(t, path, sxgo.LookupAudit{}, ip), auditHash(t, path, sxgo.LookupAudit{Key: []byte{}}, ip)
+	if a == b {
+		t.Errorf("keyless instances hash %s alike: %s", ip, a)
+	}
+
+	key := []byte("audit key")
+	h, err := sxgo.NewIPHasher(sxgo.HashHMACSHA256, "", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := auditHash(t, path, sxgo.LookupAudit{Key: key}, ip), h.Hash(ip); got != want {
+		t.Errorf("IPHash with Key = %s, want %s", got, want)
+	}
+	if got, want := auditHash(t, path, sxgo.LookupAudit{Hasher: h}, ip), h.Hash(ip); got != want {
+		t.Errorf("IPHash with Hasher = %s, want %s", got, want)
+	}
+}
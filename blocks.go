@@ -0,0 +1,333 @@
+package sxgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errRangeFound stops a walkRanges call once the wanted ranges are found.
+var errRangeFound = errors.New("range found")
+
+// ErrCorruptIndex is returned (wrapped in an *IndexError) by New when the
+// byte index or main index is out of order, which would make lookups search
+// the wrong blocks.
+var ErrCorruptIndex = errors.New("sxgo: corrupt index")
+
+// IndexError reports the first out-of-order entry of an index.
+type IndexError struct {
+	Index    string // "byte" or "main".
+	Position int    // Offending entry.
+	Value    uint32 // Its value.
+	Previous uint32 // The value of the entry before it, which is greater.
+}
+
+// Error describes the offending entry.
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("%s index entry %d (%d) is below entry %d (%d)",
+		e.Index, e.Position, e.Value, e.Position-1, e.Previous)
+}
+
+// Unwrap returns ErrCorruptIndex.
+func (e *IndexError) Unwrap() error {
+	return ErrCorruptIndex
+}
+
+// ipRange is a contiguous IPv4 range resolving to a single ID (seek for City DBs).
+// id 0 means the range is not covered. block is the DB block the range comes from,
+// or -1 for address space no block covers (reserved ranges, empty byte windows).
+// reserved marks reserved first bytes and ranges configured with WithReservedRanges.
+// This struct is internal.
+type ipRange struct {
+	first, last uint32
+	id          uint32
+	block       int64
+	reserved    bool
+}
+
+// isReservedByte reports whether lookups for addresses with first byte b are
+// short-circuited as reserved (see getNum).
+// Internal function.
+func (db *dbState) isReservedByte(b uint32) bool {
+	return b == 0 || b == 10 || b == 127 || b >= uint32(db.header.byteIndexLen)
+}
+
+// byteIndexAt returns entry i of the first-byte index in any mode.
+// Internal function.
+func (db *dbState) byteIndexAt(i uint32) uint32 {
+	if db.byteIndexArr != nil {
+		return db.byteIndexArr[i]
+	}
+	return binary.BigEndian.Uint32(db.byteIndexStr[i*4 : i*4+4])
+}
+
+// validateIndexes checks that the byte index is non-decreasing and the main
+// index is sorted, returning an *IndexError for the first entry that is not.
+// Internal function.
+func (db *dbState) validateIndexes() error {
+	for _, idx := range []struct {
+		name string
+		n    uint32
+		at   func(uint32) uint32
+	}{
+		{"byte", uint32(db.header.byteIndexLen), db.byteIndexAt},
+		{"main", uint32(db.header.mainIndexLen), db.mainIndexAt},
+	} {
+		for i := uint32(1); i < idx.n; i++ {
+			if prev, v := idx.at(i-1), idx.at(i); v < prev {
+				return &IndexError{Index: idx.name, Position: int(i), Value: v, Previous: prev}
+			}
+		}
+	}
+	return nil
+}
+
+// mainIndexAt returns entry i of the main index in any mode.
+// Internal function.
+func (db *dbState) mainIndexAt(i uint32) uint32 {
+	if db.mainIndexArr != nil {
+		return db.mainIndexArr[i]
+	}
+	return binary.BigEndian.Uint32(db.mainIndexStr[i*4 : i*4+4])
+}
+
+// blockData returns the raw bytes of DB blocks [from, to).
+// In ModeMemory the returned slice aliases the loaded data and must not be modified.
+// Internal function.
+func (s *SxGeo) blockData(db *dbState, from, to uint32) ([]byte, error) {
+	return s.blockDataInto(db, from, to, nil)
+}
+
+// blockDataInto is blockData reading into buf in ModeFile if it is large
+// enough, so that the result aliases buf.
+// Internal function.
+func (s *SxGeo) blockDataInto(db *dbState, from, to uint32, buf []byte) ([]byte, error) {
+	if to > db.header.dbItems {
+		to = db.header.dbItems
+	}
+	if from >= to {
+		return nil, nil
+	}
+	start := int64(from) * int64(db.blockSize)
+	end := int64(to) * int64(db.blockSize)
+	if s.memoryMode {
+		if end > int64(len(db.dbData)) {
+			return nil, fmt.Errorf("blocks [%d, %d) exceed loaded DB data (%d bytes)", from, to, len(db.dbData))
+		}
+		return db.dbData[start:end], nil
+	}
+	if int64(len(buf)) >= end-start {
+		buf = buf[:end-start]
+	} else {
+		buf = make([]byte, end-start)
+	}
+	n, err := s.readAt(db, buf, db.dbBegin+start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read blocks [%d, %d): %w", from, to, err)
+	}
+	return buf[:n-n%int(db.blockSize)], nil
+}
+
+// blockSuffix returns the low 3 bytes of the first IP of block i within data.
+// Internal function.
+func blockSuffix(data []byte, i int, blockSize uint32) uint32 {
+	off := i * int(blockSize)
+	return uint32(data[off])<<16 | uint32(data[off+1])<<8 | uint32(data[off+2])
+}
+
+// blockID decodes the ID of block i within data.
+// Internal function.
+func (db *dbState) blockID(data []byte, i int) (uint32, error) {
+	off := i*int(db.blockSize) + dbBlockLenOffset
+	return db.decodeID(data[off : off+int(db.header.idLen)])
+}
+
+// walkRanges calls fn for consecutive ranges partitioning [lo, hi], as lookups
+// resolve them: within a first-byte window a block covers addresses from its
+// start up to the next block's start; addresses below the window's first block
+// belong to the block preceding the window. Reserved first bytes, ranges
+// configured with WithReservedRanges and empty windows yield uncovered ranges.
+// Ranges are clipped to [lo, hi].
+// Blocks out of order within a window (a corrupt table) are skipped.
+// Returning an error from fn stops the walk with that error.
+// Internal function.
+func (s *SxGeo) walkRanges(db *dbState, lo, hi uint32, fn func(r ipRange) error) error {
+	if lo > hi {
+		return nil
+	}
+	emit := func(r ipRange) error {
+		if r.last < lo || r.first > hi || r.first > r.last {
+			return nil
+		}
+		r.first = max(r.first, lo)
+		r.last = min(r.last, hi)
+		if r.reserved {
+			return fn(r)
+		}
+		return s.splitReserved(r, fn)
+	}
+
+	for b := lo >> 24; b <= hi>>24; b++ {
+		byteLo, byteHi := b<<24, b<<24|0xFFFFFF
+		if db.isReservedByte(b) {
+			if err := emit(ipRange{first: byteLo, last: byteHi, block: -1, reserved: true}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		minBlock, maxBlock := db.byteIndexAt(b-1), db.byteIndexAt(b)
+		if minBlock >= maxBlock {
+			if err := emit(ipRange{first: byteLo, last: byteHi, block: -1}); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := s.blockData(db, minBlock, maxBlock)
+		if err != nil {
+			return err
+		}
+		n := len(data) / int(db.blockSize)
+		if n == 0 {
+			return fmt.Errorf("blocks [%d, %d) could not be read", minBlock, maxBlock)
+		}
+
+		// Addresses below the first block belong to the block before the window.
+		if first := blockSuffix(data, 0, db.blockSize); first > 0 {
+			r := ipRange{first: byteLo, last: byteLo | (first - 1), block: -1}
+			if minBlock > 0 {
+				prev, err := s.blockData(db, minBlock-1, minBlock)
+				if err != nil {
+					return err
+				}
+				if len(prev) > 0 {
+					if r.id, err = db.blockID(prev, 0); err != nil {
+						return err
+					}
+					r.block = int64(minBlock - 1)
+				}
+			}
+			if err := emit(r); err != nil {
+				return err
+			}
+		}
+
+		// Skip blocks ending before lo; a linear scan is cheap next to the read.
+		next := byteLo | blockSuffix(data, 0, db.blockSize) // First address not yet emitted
+		for i := 0; i < n && next <= hi; i++ {
+			end := byteHi
+			if i+1 < n {
+				end = (byteLo | blockSuffix(data, i+1, db.blockSize)) - 1
+			}
+			if end < next { // Out of order or duplicate start; superseded by a later block
+				continue
+			}
+			if end >= lo {
+				id, err := db.blockID(data, i)
+				if err != nil {
+					return err
+				}
+				if err := emit(ipRange{first: next, last: end, id: id, block: int64(minBlock) + int64(i)}); err != nil {
+					return err
+				}
+			}
+			next = end + 1
+		}
+	}
+	return nil
+}
+
+// rangeOf returns the range ipNum resolves to and whether it comes from a
+// patch (see ApplyPatch). Database ranges are clipped to ipNum's first-byte
+// window; patch ranges are returned whole, with block -1. Reserved ranges take
+// precedence over patches, as in lookups.
+// Internal function.
+func (s *SxGeo) rangeOf(db *dbState, ipNum uint32) (r ipRange, patched bool, err error) {
+	if !db.isReservedByte(ipNum>>24) && !s.isReservedSpecial(ipNum) {
+		if p, ok := s.overlayRange(ipNum); ok {
+			return ipRange{first: p.first, last: p.last, id: p.id, block: -1}, true, nil
+		}
+	}
+	err = s.walkRanges(db, ipNum&^0xFFFFFF, ipNum|0xFFFFFF, func(cur ipRange) error {
+		if cur.first <= ipNum && ipNum <= cur.last {
+			r = cur
+			return errRangeFound
+		}
+		return nil
+	})
+	if errors.Is(err, errRangeFound) {
+		return r, false, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("no range covers %s", uint32ToAddr(ipNum))
+	}
+	return ipRange{}, false, err
+}
+
+// WalkRanges calls fn for every range of the block table, in address order,
+// with the ID lookups resolve its addresses to (see LookupID): consecutive
+// ranges partition the whole IPv4 space, and ranges without a location
+// (reserved first bytes, ranges configured with WithReservedRanges, empty
+// windows and blocks with ID 0) have ID 0. Ranges are split at first-byte
+// boundaries and not merged when adjacent blocks share an ID. Patches
+// (ApplyPatch), special and synthetic ranges are not included. It reads the
+// whole table, so in ModeFile prefer ModeMemory for exports. Returning an
+// error from fn stops the walk with that error.
+func (s *SxGeo) WalkRanges(fn func(r IPRange, id uint32) error) error {
+	var fnErr error
+	db := s.acquire()
+	defer db.unpin()
+	err := s.walkRanges(db, 0, 0xFFFFFFFF, func(r ipRange) error {
+		fnErr = fn(IPRange{First: uint32ToAddr(r.first), Last: uint32ToAddr(r.last)}, r.id)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		return fmt.Errorf("sxgo: failed to walk ranges: %w", err)
+	}
+	return err
+}
+
+// NumBlocks returns the number of blocks in the block table, the sorted table
+// of ranges lookups search (see BlockAt).
+func (s *SxGeo) NumBlocks() uint32 {
+	return s.db().header.dbItems
+}
+
+// BlockAt returns block i of the block table: the low 3 bytes of the first
+// address of its range and its ID (see LookupID for what IDs stand for).
+// Blocks are sorted by address within the window of their first byte (see
+// BlockWindow); a range ends where the next block of the window starts. It
+// lets other engines extract the raw table; in ModeFile every call reads the
+// file, so extract whole tables in ModeMemory.
+func (s *SxGeo) BlockAt(i uint32) (suffix, id uint32, err error) {
+	db := s.acquire()
+	defer db.unpin()
+	if i >= db.header.dbItems {
+		return 0, 0, fmt.Errorf("sxgo: block %d out of range (%d blocks)", i, db.header.dbItems)
+	}
+	data, err := s.blockData(db, i, i+1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sxgo: failed to read block %d: %w", i, err)
+	}
+	if len(data) == 0 {
+		return 0, 0, fmt.Errorf("sxgo: failed to read block %d: truncated database", i)
+	}
+	if id, err = db.blockID(data, 0); err != nil {
+		return 0, 0, fmt.Errorf("sxgo: failed to decode block %d: %w", i, err)
+	}
+	return blockSuffix(data, 0, db.blockSize), id, nil
+}
+
+// BlockWindow returns the blocks [from, to) holding the ranges of addresses
+// with first byte b, as the byte index gives them. Addresses of the window
+// below its first block belong to the block before it. from == to for first
+// bytes without blocks, including 0 and those past the byte index.
+func (s *SxGeo) BlockWindow(b uint8) (from, to uint32) {
+	db := s.db()
+	if b == 0 || uint32(b) >= uint32(db.header.byteIndexLen) {
+		return 0, 0
+	}
+	from, to = db.byteIndexAt(uint32(b)-1), min(db.byteIndexAt(uint32(b)), db.header.dbItems)
+	return min(from, to), to
+}
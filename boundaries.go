@@ -0,0 +1,217 @@
+package sxgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// boundaryISOProperties are the feature properties LoadCountryBoundaries
+// looks for country codes in, by default. Natural Earth sets ISO_A2 to "-99"
+// for some countries and has the usable code in ISO_A2_EH.
+var boundaryISOProperties = []string{"ISO3166-1-Alpha-2", "ISO_A2_EH", "ISO_A2", "iso_a2", "iso"}
+
+// CountryBoundaries holds country polygons loaded from GeoJSON, to check that
+// coordinates lie in the country a database reports for them.
+type CountryBoundaries struct {
+	// MarginKm treats points within this distance of a country's border as
+	// inside, which absorbs the error of simplified boundaries along coasts
+	// and borders. 0 checks the polygons exactly.
+	MarginKm float64
+
+	polygons map[string][]boundaryPolygon // ISO code -> polygons
+}
+
+// boundaryPolygon is an outer ring with its holes and bounding box, as
+// longitude/latitude pairs.
+// This struct is internal.
+type boundaryPolygon struct {
+	rings                          [][][2]float64
+	minLon, minLat, maxLon, maxLat float64
+}
+
+// LoadCountryBoundaries reads a GeoJSON FeatureCollection (or a single
+// Feature) of Polygon and MultiPolygon country boundaries. isoProperty names
+// the feature property holding the ISO 3166-1 alpha-2 code; "" tries the
+// usual ones (ISO3166-1-Alpha-2, ISO_A2_EH, ISO_A2, iso_a2, iso). Features
+// without a two-letter code or with other geometries are skipped.
+func LoadCountryBoundaries(r io.Reader, isoProperty string) (*CountryBoundaries, error) {
+	type feature struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   *struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	}
+	var doc struct {
+		feature
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("sxgo: invalid boundaries GeoJSON: %w", err)
+	}
+	features := doc.Features
+	if doc.Type == "Feature" {
+		features = []feature{doc.feature}
+	}
+
+	props := boundaryISOProperties
+	if isoProperty != "" {
+		props = []string{isoProperty}
+	}
+	b := &CountryBoundaries{polygons: make(map[string][]boundaryPolygon)}
+	for i, f := range features {
+		iso := ""
+		for _, p := range props {
+			if v, ok := f.Properties[p].(string); ok && len(v) == 2 && getIDByISO(strings.ToUpper(v)) > 0 {
+				iso = strings.ToUpper(v)
+				break
+			}
+		}
+		if iso == "" || f.Geometry == nil {
+			continue
+		}
+		var polygons [][][][2]float64
+		var err error
+		switch f.Geometry.Type {
+		case "Polygon":
+			var p [][][2]float64
+			err = json.Unmarshal(f.Geometry.Coordinates, &p)
+			polygons = [][][][2]float64{p}
+		case "MultiPolygon":
+			err = json.Unmarshal(f.Geometry.Coordinates, &polygons)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sxgo: invalid boundaries GeoJSON: feature %d (%s): %w", i, iso, err)
+		}
+		for _, rings := range polygons {
+			if len(rings) > 0 && len(rings[0]) >= 3 {
+				b.polygons[iso] = append(b.polygons[iso], newBoundaryPolygon(rings))
+			}
+		}
+	}
+	if len(b.polygons) == 0 {
+		return nil, errors.New("sxgo: boundaries GeoJSON has no country polygons")
+	}
+	return b, nil
+}
+
+// newBoundaryPolygon computes the bounding box of rings.
+// Internal function.
+func newBoundaryPolygon(rings [][][2]float64) boundaryPolygon {
+	p := boundaryPolygon{rings: rings, minLon: 180, minLat: 90, maxLon: -180, maxLat: -90}
+	for _, pt := range rings[0] {
+		p.minLon, p.maxLon = math.Min(p.minLon, pt[0]), math.Max(p.maxLon, pt[0])
+		p.minLat, p.maxLat = math.Min(p.minLat, pt[1]), math.Max(p.maxLat, pt[1])
+	}
+	return p
+}
+
+// Contains reports whether the point lies in (or within MarginKm of) a
+// polygon of the country with ISO code iso. known is false if the
+// boundaries have no polygon for the country.
+func (b *CountryBoundaries) Contains(iso string, lat, lon float64) (inside, known bool) {
+	polygons, known := b.polygons[strings.ToUpper(iso)]
+	if !known {
+		return false, false
+	}
+	for i := range polygons {
+		if polygons[i].contains(lat, lon) {
+			return true, true
+		}
+	}
+	if b.MarginKm > 0 {
+		for i := range polygons {
+			if polygons[i].distanceKm(lat, lon) <= b.MarginKm {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// contains tests the point against the rings with the even-odd rule, so
+// holes are excluded.
+// Internal function.
+func (p *boundaryPolygon) contains(lat, lon float64) bool {
+	if lon < p.minLon || lon > p.maxLon || lat < p.minLat || lat > p.maxLat {
+		return false
+	}
+	inside := false
+	for _, ring := range p.rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, c := ring[i], ring[j]
+			if (a[1] > lat) != (c[1] > lat) && lon < (c[0]-a[0])*(lat-a[1])/(c[1]-a[1])+a[0] {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// distanceKm returns the approximate distance from the point to the nearest
+// edge of the polygon, on a plane tangent at the point.
+// Internal function.
+func (p *boundaryPolygon) distanceKm(lat, lon float64) float64 {
+	const kmPerDegree = 111.195 // Mean Earth radius times pi/180
+	scale := math.Cos(lat * math.Pi / 180)
+	best := math.Inf(1)
+	for _, ring := range p.rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			ax, ay := (ring[j][0]-lon)*scale, ring[j][1]-lat
+			bx, by := (ring[i][0]-lon)*scale, ring[i][1]-lat
+			dx, dy := bx-ax, by-ay
+			t := 0.0
+			if l := dx*dx + dy*dy; l > 0 {
+				t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+			}
+			best = math.Min(best, math.Hypot(ax+t*dx, ay+t*dy))
+		}
+	}
+	return best * kmPerDegree
+}
+
+// WithBoundaryCheck annotates results whose city coordinates lie outside the
+// reported country's polygon in b with "boundary": "outside", a data-quality
+// signal for bad records in custom databases. Results without city
+// coordinates, or for countries b has no polygon for, are not checked. The
+// check runs as a post-processor (see WithPostProcessor).
+func WithBoundaryCheck(b *CountryBoundaries) Option {
+	return WithPostProcessor(func(_ string, info *LocationInfo) {
+		if info.City == nil || info.Country == nil || !hasCoords(info.City.Lat, info.City.Lon) {
+			return
+		}
+		if inside, known := b.Contains(info.Country.ISO, info.City.Lat, info.City.Lon); known && !inside {
+			info.Annotate("boundary", "outside")
+		}
+	})
+}
+
+// CitiesOutsideBoundaries returns the city records whose coordinates lie
+// outside the polygon of their country in b, in storage order. Cities without
+// coordinates, or of countries b has no polygon for, are skipped. It reads the
+// whole cities block and is intended as a QA tool.
+func (s *SxGeo) CitiesOutsideBoundaries(b *CountryBoundaries) ([]CityRecord, error) {
+	db := s.acquire()
+	defer db.unpin()
+	cities, err := s.cityRecords(db)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to check city boundaries: %w", err)
+	}
+	var outside []CityRecord
+	for _, c := range cities {
+		if !hasCoords(c.Lat, c.Lon) {
+			continue
+		}
+		if inside, known := b.Contains(getISO(uint32(c.CountryID)), c.Lat, c.Lon); known && !inside {
+			outside = append(outside, c)
+		}
+	}
+	return outside, nil
+}
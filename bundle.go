@@ -0,0 +1,229 @@
+package sxgo
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleExt is the file extension of database bundles. New opens files with
+// this extension as bundles (see WriteBundle).
+const BundleExt = ".sxb"
+
+// bundleManifestName is the name of the manifest entry in a bundle.
+const bundleManifestName = "manifest.json"
+
+// ErrBundleChecksum is returned (wrapped) when the database in a bundle does
+// not match the digest recorded for it.
+var ErrBundleChecksum = errors.New("sxgo: bundle checksum mismatch")
+
+// Manifest describes the database in a bundle: what it is, where it came
+// from and how to verify it.
+type Manifest struct {
+	Database    string    `json:"database"`              // Name of the .dat entry.
+	SHA256      string    `json:"sha256"`                // Hex SHA-256 digest of the .dat entry.
+	Size        int64     `json:"size"`                  // Size of the .dat entry in bytes.
+	Source      string    `json:"source,omitempty"`      // Where the database was obtained, e.g. a download URL.
+	Edition     string    `json:"edition,omitempty"`     // Database edition, e.g. "SxGeoCity".
+	License     string    `json:"license,omitempty"`     // License terms of the database.
+	Attribution string    `json:"attribution,omitempty"` // Notice the license requires services to show.
+	Created     time.Time `json:"created"`               // When the bundle was written.
+}
+
+// WriteBundle writes a bundle of dbFile to w: a tar archive holding the
+// database, its SHA-256 digest in sha256sum format and a JSON manifest. The
+// Database, SHA256, Size and (if zero) Created fields of m are filled in;
+// the others are kept as given. The database entry is named after dbFile
+// with a .dat extension, whatever its own. dbFile must be a valid database.
+func WriteBundle(w io.Writer, dbFile string, m Manifest) error {
+	geo, err := New(dbFile, ModeFile)
+	if err != nil {
+		return err
+	}
+	geo.Close()
+
+	f, err := os.Open(dbFile)
+	if err != nil {
+		return fmt.Errorf("sxgo: failed to open db file %q: %w", dbFile, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("sxgo: failed to read db file %q: %w", dbFile, err)
+	}
+	base := filepath.Base(dbFile)
+	m.Database = strings.TrimSuffix(base, filepath.Ext(base)) + ".dat" // As readBundle finds it
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	m.Size = size
+	if m.Created.IsZero() {
+		m.Created = time.Now().UTC()
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("sxgo: failed to encode bundle manifest: %w", err)
+	}
+	sum := []byte(m.SHA256 + "  " + m.Database + "\n")
+
+	tw := tar.NewWriter(w)
+	entry := func(name string, size int64) error {
+		return tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o644,
+			Size:     size,
+			ModTime:  m.Created,
+			Format:   tar.FormatPAX,
+		})
+	}
+	if err := entry(bundleManifestName, int64(len(manifest))); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if err := entry(m.Database+".sha256", int64(len(sum))); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(sum); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if err := entry(m.Database, size); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if _, err := io.Copy(tw, io.NewSectionReader(f, 0, size)); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("sxgo: failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// VerifyBundle checks that the database in the bundle at path matches the
+// digest and size in its manifest and digest file, and returns the manifest.
+// A mismatch is reported as ErrBundleChecksum.
+func VerifyBundle(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to open bundle %q: %w", path, err)
+	}
+	defer f.Close()
+	b, err := readBundle(f)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: invalid bundle %q: %w", path, err)
+	}
+	return b.manifest, nil
+}
+
+// Manifest returns the manifest of the bundle the database was opened from,
+// or nil if it was opened from a plain .dat file.
+func (s *SxGeo) Manifest() *Manifest {
+	db := s.db()
+	if db.bundle == nil {
+		return nil
+	}
+	m := *db.bundle
+	return &m
+}
+
+// bundleLayout is a verified bundle and the location of its database entry.
+// This struct is internal.
+type bundleLayout struct {
+	manifest *Manifest
+	offset   int64 // Offset of the .dat entry data in the bundle file
+	size     int64
+}
+
+// readBundle reads the entries of the bundle in f, hashing the database entry
+// as it goes, and checks the database against the manifest and digest file.
+// Internal function.
+func readBundle(f *os.File) (*bundleLayout, error) {
+	cr := &countingReader{r: f}
+	tr := tar.NewReader(cr)
+	var b bundleLayout
+	var sum, sidecar string
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		lower := strings.ToLower(name) // Extensions match in any case
+		switch {
+		case name == bundleManifestName:
+			data, err := io.ReadAll(io.LimitReader(tr, 1<<20))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			b.manifest = new(Manifest)
+			if err := json.Unmarshal(data, b.manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest: %w", err)
+			}
+		case strings.HasSuffix(lower, ".dat.sha256"):
+			data, err := io.ReadAll(io.LimitReader(tr, 4096))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read digest file: %w", err)
+			}
+			if fields := strings.Fields(string(data)); len(fields) > 0 {
+				sidecar = strings.ToLower(fields[0])
+			}
+		case strings.HasSuffix(lower, ".dat"):
+			if found {
+				return nil, errors.New("more than one database entry")
+			}
+			found = true
+			b.offset, b.size = cr.n, hdr.Size
+			h := sha256.New()
+			if _, err := io.Copy(h, tr); err != nil {
+				return nil, fmt.Errorf("failed to read database entry: %w", err)
+			}
+			sum = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+
+	switch {
+	case b.manifest == nil:
+		return nil, errors.New("no " + bundleManifestName + " entry")
+	case !found:
+		return nil, errors.New("no .dat entry")
+	case !strings.EqualFold(b.manifest.SHA256, sum) || b.manifest.Size != b.size:
+		return nil, fmt.Errorf("%w: database %s has SHA-256 %s and %d bytes, manifest records %s and %d bytes",
+			ErrBundleChecksum, b.manifest.Database, sum, b.size, b.manifest.SHA256, b.manifest.Size)
+	case sidecar != "" && sidecar != sum:
+		return nil, fmt.Errorf("%w: database %s has SHA-256 %s, digest file records %s",
+			ErrBundleChecksum, b.manifest.Database, sum, sidecar)
+	}
+	return &b, nil
+}
+
+// countingReader counts the bytes read through it. tar.Reader does not read
+// ahead, so the count after Next is the offset of the entry data.
+// This struct is internal.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlying reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,87 @@
+package sxgo_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// writeBundle bundles miniCity, stored as name, into a bundle file.
+func writeBundle(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	db := filepath.Join(dir, name)
+	if err := os.WriteFile(db, miniCity, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := sxgo.WriteBundle(&buf, db, sxgo.Manifest{Edition: "SxGeoCity"}); err != nil {
+		t.Fatalf("WriteBundle(%s): %v", name, err)
+	}
+	path := filepath.Join(dir, "db"+sxgo.BundleExt)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	for _, tt := range []struct{ name, entry string }{
+		{"SxGeoCity.dat", "SxGeoCity.dat"},
+		{"SxGeoCity.DAT", "SxGeoCity.dat"},
+		{"city.bin", "city.dat"},
+		{"city", "city.dat"},
+	} {
+		path := writeBundle(t, tt.name)
+		m, err := sxgo.VerifyBundle(path)
+		if err != nil {
+			t.Errorf("VerifyBundle of %s: %v", tt.name, err)
+			continue
+		}
+		if m.Database != tt.entry || m.Edition != "SxGeoCity" || m.Size != int64(len(miniCity)) {
+			t.Errorf("manifest of %s = %+v", tt.name, m)
+		}
+		for _, mode := range modes {
+			geo, err := sxgo.New(path, mode.mode)
+			if err != nil {
+				t.Errorf("New(%s bundle, %s): %v", tt.name, mode.name, err)
+				continue
+			}
+			info, err := geo.GetCityFull("46.0.128.1")
+			if got := placeOf(info); err != nil || got != moscow {
+				t.Errorf("%s bundle, %s: GetCityFull = %+v, %v; want %+v", tt.name, mode.name, got, err, moscow)
+			}
+			if m := geo.Manifest(); m == nil || m.Database != tt.entry {
+				t.Errorf("%s bundle, %s: Manifest() = %+v", tt.name, mode.name, m)
+			}
+			geo.Close()
+		}
+	}
+}
+
+func TestBundleChecksumMismatch(t *testing.T) {
+	path := writeBundle(t, "SxGeoCity.dat")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte in the middle of the database entry
+	i := bytes.Index(data, miniCity)
+	if i < 0 {
+		t.Fatal("database entry not found in bundle")
+	}
+	data[i+len(miniCity)/2] ^= 0xFF
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sxgo.VerifyBundle(path); !errors.Is(err, sxgo.ErrBundleChecksum) {
+		t.Errorf("VerifyBundle = %v, want ErrBundleChecksum", err)
+	}
+	if _, err := sxgo.New(path, sxgo.ModeMemory); !errors.Is(err, sxgo.ErrBundleChecksum) {
+		t.Errorf("New = %v, want ErrBundleChecksum", err)
+	}
+}
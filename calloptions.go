@@ -0,0 +1,127 @@
+package sxgo
+
+// CallOption adjusts the result of a single GetCity or GetCityFull call.
+// Call options are applied after the middleware chain, on a copy of the
+// result, on top of the instance defaults set with WithDefaultCallOptions.
+type CallOption func(*callConfig)
+
+// Projection selects parts of a result to leave out (see WithProjection).
+// Values can be combined with |.
+type Projection uint
+
+const (
+	// FullProjection keeps the whole result.
+	FullProjection Projection = 0
+	// NoCoords clears the latitude and longitude of the city, region and country.
+	NoCoords Projection = 1 << (iota - 1)
+	// NoRegion drops the region.
+	NoRegion
+	// NoAnnotations drops annotations added by post-processors.
+	NoAnnotations
+)
+
+// callConfig is the effective set of call options for one lookup.
+// This struct is internal.
+type callConfig struct {
+	lang         string
+	projection   Projection
+	tag          string // WithCallerTag; does not change the result
+	placeholders *Placeholders
+}
+
+// WithLang keeps names in a single language: "ru" or "en". The other
+// language's names are cleared. Any other value (including "") keeps both.
+func WithLang(lang string) CallOption {
+	return func(c *callConfig) {
+		c.lang = lang
+	}
+}
+
+// WithProjection leaves the parts selected by p out of the result.
+// It replaces the projection of the instance defaults rather than adding to it.
+func WithProjection(p Projection) CallOption {
+	return func(c *callConfig) {
+		c.projection = p
+	}
+}
+
+// WithDefaultCallOptions sets call options applied to every GetCity and
+// GetCityFull call; options passed to a call are applied after them.
+func WithDefaultCallOptions(opts ...CallOption) Option {
+	return func(s *SxGeo) {
+		for _, o := range opts {
+			if o != nil {
+				s.callDefaults = append(s.callDefaults, o)
+			}
+		}
+	}
+}
+
+// applyCallOptions returns info, a result of a lookup down to depth, adjusted
+// by the instance defaults and opts. info is copied first, as it may be
+// shared (e.g. by a caching middleware).
+// Internal function.
+func (s *SxGeo) applyCallOptions(info *LocationInfo, opts []CallOption, depth recordDepth) *LocationInfo {
+	if info == nil || len(s.callDefaults)+len(opts) == 0 {
+		return info
+	}
+	var cfg callConfig
+	for _, o := range s.callDefaults {
+		o(&cfg)
+	}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.lang == "" && cfg.projection == FullProjection && cfg.placeholders == nil {
+		return info
+	}
+
+	info = info.clone()
+	if cfg.projection&NoRegion != 0 {
+		info.Region = nil
+		depth = depthCity
+	}
+	if cfg.placeholders != nil {
+		fillPlaceholders(info, cfg.placeholders, depth)
+	}
+	if cfg.projection&NoAnnotations != 0 {
+		info.Annotations = nil
+	}
+	if cfg.projection&NoCoords != 0 {
+		if info.City != nil {
+			info.City.Lat, info.City.Lon = 0, 0
+		}
+		if info.Region != nil {
+			info.Region.Lat, info.Region.Lon = 0, 0
+		}
+		if info.Country != nil {
+			info.Country.Lat, info.Country.Lon = 0, 0
+		}
+		info.Accuracy = ""
+	}
+	switch cfg.lang {
+	case "ru":
+		if info.City != nil {
+			info.City.NameEN = ""
+		}
+		if info.Region != nil {
+			info.Region.NameEN = ""
+		}
+		if info.Country != nil {
+			info.Country.NameEN = ""
+		}
+	case "en":
+		if info.City != nil {
+			info.City.NameRU = ""
+		}
+		if info.Region != nil {
+			info.Region.NameRU = ""
+		}
+		if info.Country != nil {
+			info.Country.NameRU = ""
+		}
+	}
+	return info
+}
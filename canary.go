@@ -0,0 +1,85 @@
+package sxgo
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// SplitStats counts the lookups a Splitter served from each database.
+type SplitStats struct {
+	Current uint64 `json:"current"`
+	Next    uint64 `json:"next"`
+}
+
+// Splitter serves a share of lookups from a new database version and the rest
+// from the current one, for gradual rollouts of database updates. Addresses
+// are assigned by the range hash of the current database (see InSample), so
+// every address of a range goes to the same database, assignments are stable
+// across processes, and raising the rate only moves ranges to the new
+// version. It is safe for concurrent use.
+type Splitter struct {
+	current, next *SxGeo
+	rate          atomic.Uint64 // math.Float64bits of the share served by next
+
+	servedCurrent, servedNext atomic.Uint64
+}
+
+// NewSplitter returns a Splitter serving the given share (0..1, e.g. 0.05 for
+// 5%) of ranges, and so roughly of lookups, from next and the rest from current.
+func NewSplitter(current, next *SxGeo, rate float64) *Splitter {
+	sp := &Splitter{current: current, next: next}
+	sp.SetRate(rate)
+	return sp
+}
+
+// SetRate changes the share of lookups served by the new database.
+func (sp *Splitter) SetRate(rate float64) {
+	sp.rate.Store(math.Float64bits(rate))
+}
+
+// Rate returns the share of lookups served by the new database.
+func (sp *Splitter) Rate() float64 {
+	return math.Float64frombits(sp.rate.Load())
+}
+
+// Route returns the database serving ip. Addresses the current database
+// cannot hash (e.g. invalid ones) go to it, so its error is reported.
+func (sp *Splitter) Route(ip string) *SxGeo {
+	if sp.toNext(ip) {
+		return sp.next
+	}
+	return sp.current
+}
+
+// GetCity returns GetCity(ip, opts...) of the database serving ip.
+func (sp *Splitter) GetCity(ip string, opts ...CallOption) (*LocationInfo, error) {
+	return sp.route(ip).GetCity(ip, opts...)
+}
+
+// GetCityFull returns GetCityFull(ip, opts...) of the database serving ip.
+func (sp *Splitter) GetCityFull(ip string, opts ...CallOption) (*LocationInfo, error) {
+	return sp.route(ip).GetCityFull(ip, opts...)
+}
+
+// Stats returns how many lookups each database served.
+func (sp *Splitter) Stats() SplitStats {
+	return SplitStats{Current: sp.servedCurrent.Load(), Next: sp.servedNext.Load()}
+}
+
+// route is Route counting the lookup.
+// Internal function.
+func (sp *Splitter) route(ip string) *SxGeo {
+	if sp.toNext(ip) {
+		sp.servedNext.Add(1)
+		return sp.next
+	}
+	sp.servedCurrent.Add(1)
+	return sp.current
+}
+
+// toNext reports whether the new database serves ip.
+// Internal function.
+func (sp *Splitter) toNext(ip string) bool {
+	in, err := sp.current.InSample(ip, sp.Rate())
+	return err == nil && in
+}
@@ -0,0 +1,85 @@
+package sxgo
+
+import "strings"
+
+// Capabilities describes what a database can answer, derived from its header
+// and pack formats, so generic consumers can adapt without probing lookups.
+type Capabilities struct {
+	HasCities         bool `json:"has_cities"`          // City records are present.
+	HasRegions        bool `json:"has_regions"`         // Region records are present.
+	HasCountryRecords bool `json:"has_country_records"` // Country records (names, coordinates) are present.
+	HasCoordinates    bool `json:"has_coordinates"`     // City or country records carry lat/lon.
+	HasRussianNames   bool `json:"has_russian_names"`   // Records carry name_ru.
+	HasEnglishNames   bool `json:"has_english_names"`   // Records carry name_en.
+	HasMaxFields      bool `json:"has_max_fields"`      // City Max database, or records carry non-standard fields.
+}
+
+// standardFields lists the fields of the regular (non-Max) record formats,
+// indexed like packFormats: country, region, city.
+var standardFields = [3]map[string]bool{
+	{"id": true, "iso": true, "lat": true, "lon": true, "name_ru": true, "name_en": true},
+	{"country_seek": true, "id": true, "name_ru": true, "name_en": true, "iso": true},
+	{"region_seek": true, "country_id": true, "id": true, "lat": true, "lon": true, "name_ru": true, "name_en": true},
+}
+
+// Capabilities reports what the loaded database can answer. Country records
+// are usually stored at the start of the cities block, so HasCities requires
+// the block to extend past them.
+func (s *SxGeo) Capabilities() Capabilities {
+	return s.db().layout
+}
+
+// capabilities computes Capabilities from the header and pack formats, once
+// the database is loaded.
+// Internal function.
+func (db *dbState) capabilities() Capabilities {
+	fields := [3]map[string]bool{}
+	for i := range fields {
+		fields[i] = make(map[string]bool)
+		if i < len(db.packFormats) {
+			for _, name := range formatFields(db.packFormats[i]) {
+				fields[i][name] = true
+			}
+		}
+	}
+
+	c := Capabilities{
+		HasCities:         db.header.maxCity > 0 && db.header.citySize > db.cityRecordsStart() && len(fields[2]) > 0,
+		HasRegions:        db.header.maxRegion > 0 && db.header.regionSize > 0 && len(fields[1]) > 0,
+		HasCountryRecords: db.header.maxCountry > 0 && db.header.countrySize > 0 && len(fields[0]) > 0,
+		HasMaxFields:      DBType(db.header.dbType).IsMax(),
+	}
+	c.HasCoordinates = (c.HasCities && fields[2]["lat"] && fields[2]["lon"]) ||
+		(c.HasCountryRecords && fields[0]["lat"] && fields[0]["lon"])
+
+	present := [3]bool{c.HasCountryRecords, c.HasRegions, c.HasCities}
+	for i, fs := range fields {
+		if !present[i] {
+			continue
+		}
+		c.HasRussianNames = c.HasRussianNames || fs["name_ru"]
+		c.HasEnglishNames = c.HasEnglishNames || fs["name_en"]
+		for name := range fs {
+			if !standardFields[i][name] {
+				c.HasMaxFields = true
+			}
+		}
+	}
+	return c
+}
+
+// formatFields returns the field names of a pack format string, in order.
+// Internal function.
+func formatFields(format string) []string {
+	if format == "" {
+		return nil
+	}
+	parts := strings.Split(format, "/")
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if _, name, ok := strings.Cut(part, ":"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package sxgo
+
+import (
+	"math"
+
+	"github.com/idanyas/sxgo/countries"
+)
+
+// Accuracy tells which level of the location a result's coordinates describe.
+type Accuracy string
+
+const (
+	// AccuracyCity means the coordinates are those of the city.
+	AccuracyCity Accuracy = "city"
+	// AccuracyRegion means the coordinates are a region centroid computed
+	// from the region's cities (see WithRegionCentroids).
+	AccuracyRegion Accuracy = "region"
+	// AccuracyCountry means the coordinates are those of the country.
+	AccuracyCountry Accuracy = "country"
+)
+
+// WithRegionCentroids makes GetCityFull fill Region.Lat/Lon with the centroid
+// of the region's city coordinates when the city has no coordinates of its
+// own (e.g. ranges known only to region level), and set LocationInfo.Accuracy
+// on every result. Centroids are computed on first need by reading all city
+// records once, then cached.
+func WithRegionCentroids() Option {
+	return func(s *SxGeo) {
+		s.centroids = true
+	}
+}
+
+// WithCountryCentroids fills Country.Lat/Lon from an embedded table of
+// country centroids (see countries.Centroid) when the database gives the
+// country no coordinates, as country records of non-Max City databases often
+// do, and sets Country.Approximate. Results then always carry plottable
+// coordinates for known countries. The table is empty in sxgo_minimal builds.
+func WithCountryCentroids() Option {
+	return func(s *SxGeo) {
+		s.countryCoords = true
+	}
+}
+
+// Coordinates returns the most precise coordinates in the result and their
+// accuracy: the city's, then the region centroid (see WithRegionCentroids),
+// then the country's. acc is "" if the result has no coordinates.
+func (l *LocationInfo) Coordinates() (lat, lon float64, acc Accuracy) {
+	switch {
+	case l.City != nil && hasCoords(l.City.Lat, l.City.Lon):
+		return l.City.Lat, l.City.Lon, AccuracyCity
+	case l.Region != nil && hasCoords(l.Region.Lat, l.Region.Lon):
+		return l.Region.Lat, l.Region.Lon, AccuracyRegion
+	case l.Country != nil && hasCoords(l.Country.Lat, l.Country.Lon):
+		return l.Country.Lat, l.Country.Lon, AccuracyCountry
+	}
+	return 0, 0, ""
+}
+
+// attachCentroid fills in the country centroid of info if its country lacks
+// coordinates (with WithCountryCentroids), and the region centroid if its
+// city lacks coordinates and sets info.Accuracy (with WithRegionCentroids);
+// info was read from db.
+// Internal function.
+func (s *SxGeo) attachCentroid(db *dbState, info *LocationInfo) {
+	if c := info.Country; s.countryCoords && c != nil && !hasCoords(c.Lat, c.Lon) {
+		if lat, lon, ok := countries.Centroid(c.ISO); ok {
+			c.Lat, c.Lon, c.Approximate = lat, lon, true
+		}
+	}
+	if !s.centroids {
+		return
+	}
+	if info.Region != nil && info.City != nil && !hasCoords(info.City.Lat, info.City.Lon) {
+		if c, ok := s.regionCentroids(db)[info.City.regionSeek]; ok {
+			info.Region.Lat, info.Region.Lon = c[0], c[1]
+		}
+	}
+	_, _, info.Accuracy = info.Coordinates()
+}
+
+// regionCentroids returns the centroid of each region's city coordinates in
+// db, keyed by region seek, building it on first use. Points are averaged as
+// unit vectors so regions spanning the antimeridian come out right. If the
+// cities cannot be read, the map is empty.
+// Internal function.
+func (s *SxGeo) regionCentroids(db *dbState) map[uint32][2]float64 {
+	t := db.tables
+	t.centroidsOnce.Do(func() {
+		sums := make(map[uint32][3]float64)
+		_ = s.walkCities(db, 0, func(_ uint32, rec map[string]interface{}) error {
+			regionSeek := getUint32(rec, "region_seek")
+			lat, lon := getFloat(rec, "lat"), getFloat(rec, "lon")
+			if regionSeek == 0 || !hasCoords(lat, lon) {
+				return nil
+			}
+			rlat, rlon := lat*math.Pi/180, lon*math.Pi/180
+			v := sums[regionSeek]
+			v[0] += math.Cos(rlat) * math.Cos(rlon)
+			v[1] += math.Cos(rlat) * math.Sin(rlon)
+			v[2] += math.Sin(rlat)
+			sums[regionSeek] = v
+			return nil
+		})
+
+		t.centroids = make(map[uint32][2]float64, len(sums))
+		for seek, v := range sums {
+			lat := math.Atan2(v[2], math.Hypot(v[0], v[1])) * 180 / math.Pi
+			lon := math.Atan2(v[1], v[0]) * 180 / math.Pi
+			// Round to the 5 decimals city coordinates are stored with
+			t.centroids[seek] = [2]float64{math.Round(lat*1e5) / 1e5, math.Round(lon*1e5) / 1e5}
+		}
+	})
+	return t.centroids
+}
+
+// hasCoords reports whether lat/lon are set; the database stores 0,0 for
+// missing coordinates.
+// Internal function.
+func hasCoords(lat, lon float64) bool {
+	return lat != 0 || lon != 0
+}
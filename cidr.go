@@ -0,0 +1,253 @@
+package sxgo
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// CIDRSummary describes what an IPv4 prefix geolocates to, by address count.
+type CIDRSummary struct {
+	Prefix    netip.Prefix      `json:"prefix"`           // The prefix described.
+	Ranges    int               `json:"ranges"`           // Number of database ranges intersecting the prefix.
+	Addresses uint64            `json:"addresses"`        // Total addresses in the prefix.
+	Unknown   uint64            `json:"unknown"`          // Addresses not resolving to any country.
+	Countries map[string]uint64 `json:"countries"`        // ISO country code -> addresses.
+	Cities    map[uint32]uint64 `json:"cities,omitempty"` // City ID -> addresses (City DBs only, ID 0 excluded).
+}
+
+// DescribeCIDR walks all database ranges intersecting prefix and returns the
+// distribution of countries (and cities, for City DBs) they resolve to,
+// weighted by the number of addresses each range covers within the prefix.
+// Only IPv4 prefixes are supported. The raw database contents are described:
+// synthetic locations, middleware and post-processors are not applied.
+func (s *SxGeo) DescribeCIDR(prefix netip.Prefix) (*CIDRSummary, error) {
+	db := s.acquire()
+	defer db.unpin()
+	lo, hi, err := prefixBounds(prefix)
+	if err != nil {
+		return nil, err
+	}
+	sum := newCIDRSummary(prefix.Masked())
+	resolve := s.numResolver(db)
+	err = s.walkRanges(db, lo, hi, func(r ipRange) error {
+		return sum.add(r, resolve)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to describe %s: %w", prefix, err)
+	}
+	return sum, nil
+}
+
+// prefixBounds returns the first and last IPv4 address of prefix as integers.
+// Internal function.
+func prefixBounds(prefix netip.Prefix) (lo, hi uint32, err error) {
+	if !prefix.IsValid() {
+		return 0, 0, errors.New("sxgo: invalid prefix")
+	}
+	addr := prefix.Addr().Unmap()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4In6() {
+		bits -= 96
+	}
+	if !addr.Is4() || bits < 0 {
+		return 0, 0, fmt.Errorf("sxgo: only IPv4 prefixes are supported, got %s", prefix)
+	}
+	a := addr.As4()
+	lo = binary.BigEndian.Uint32(a[:])
+	mask := uint32(0)
+	if bits > 0 {
+		mask = ^uint32(0) << (32 - bits)
+	}
+	lo &= mask
+	return lo, lo | ^mask, nil
+}
+
+// newCIDRSummary returns an empty summary for prefix.
+// Internal function.
+func newCIDRSummary(prefix netip.Prefix) *CIDRSummary {
+	return &CIDRSummary{
+		Prefix:    prefix,
+		Countries: make(map[string]uint64),
+	}
+}
+
+// add accounts for range r (already clipped to the prefix).
+// Internal function.
+func (c *CIDRSummary) add(r ipRange, resolve func(uint32) (uint32, uint32, error)) error {
+	size := uint64(r.last-r.first) + 1
+	c.Addresses += size
+	if r.block >= 0 {
+		c.Ranges++
+	}
+	countryID, cityID, err := resolve(r.id)
+	if err != nil {
+		return err
+	}
+	iso := getISO(countryID)
+	if iso == "" {
+		c.Unknown += size
+		return nil
+	}
+	c.Countries[iso] += size
+	if cityID != 0 {
+		if c.Cities == nil {
+			c.Cities = make(map[uint32]uint64)
+		}
+		c.Cities[cityID] += size
+	}
+	return nil
+}
+
+// numResolver returns resolveNum memoized per seek, as walks see the same
+// records over and over. The returned function is not safe for concurrent use.
+// Internal function.
+func (s *SxGeo) numResolver(db *dbState) func(uint32) (uint32, uint32, error) {
+	type ids struct{ country, city uint32 }
+	memo := make(map[uint32]ids)
+	return func(num uint32) (uint32, uint32, error) {
+		if v, ok := memo[num]; ok {
+			return v.country, v.city, nil
+		}
+		country, city, err := s.resolveNum(db, num)
+		if err != nil {
+			return 0, 0, err
+		}
+		memo[num] = ids{country, city}
+		return country, city, nil
+	}
+}
+
+// Dominant returns the ISO code covering the most addresses of the prefix and
+// its share of the prefix (0..1). Ties are broken by ISO code order.
+// Returns ("", 0) if no address resolves to a country.
+func (c *CIDRSummary) Dominant() (iso string, share float64) {
+	var best uint64
+	for code, n := range c.Countries {
+		if n > best || (n == best && code < iso) {
+			iso, best = code, n
+		}
+	}
+	if c.Addresses == 0 {
+		return iso, 0
+	}
+	return iso, float64(best) / float64(c.Addresses)
+}
+
+// DominantLocation returns the location covering the most addresses of
+// prefix, with city, region and country as GetCityFull reports them, and its
+// share of the prefix (0..1). It suits truncated or anonymized addresses,
+// e.g. with the last octet zeroed: pass the /24 they stand for. Ties go to
+// the location met first. Only IPv4 prefixes are supported; synthetic
+// locations, middleware and post-processors are not applied.
+// Returns (nil, 0, nil) if no address of the prefix is located.
+func (s *SxGeo) DominantLocation(prefix netip.Prefix) (*LocationInfo, float64, error) {
+	db := s.acquire()
+	defer db.unpin()
+	lo, hi, err := prefixBounds(prefix)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.countryOnly && db.seekIDs() {
+		return nil, 0, fmt.Errorf("sxgo: failed to find dominant location of %s: %w", prefix, ErrCountryOnly)
+	}
+	sizes := make(map[uint32]uint64)
+	var best uint32
+	err = s.walkRanges(db, lo, hi, func(r ipRange) error {
+		if r.id == 0 {
+			return nil
+		}
+		sizes[r.id] += uint64(r.last-r.first) + 1
+		if sizes[r.id] > sizes[best] {
+			best = r.id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("sxgo: failed to find dominant location of %s: %w", prefix, err)
+	}
+	if best == 0 {
+		return nil, 0, nil
+	}
+	share := float64(sizes[best]) / (float64(hi-lo) + 1)
+
+	if !db.seekIDs() {
+		info := &LocationInfo{Country: &Country{ID: uint8(best), ISO: getISO(best)}}
+		info.setPrecision()
+		return info, share, nil
+	}
+	info, err := s.parseCityDepth(db, best, depthFull)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sxgo: failed to find dominant location of %s (seek %d): %w", prefix, best, err)
+	}
+	s.attachCentroid(db, info)
+	s.attachDistrict(info)
+	return info, share, nil
+}
+
+// MatchCIDRs summarizes every prefix like DescribeCIDR, but in a single linear
+// pass: prefixes are sorted and intersected with the (sorted) database ranges,
+// so each part of the block table is read once however many prefixes overlap it.
+// Results are returned in input order; use CIDRSummary.Dominant for the dominant
+// country of each. Only IPv4 prefixes are supported.
+func (s *SxGeo) MatchCIDRs(prefixes []netip.Prefix) ([]*CIDRSummary, error) {
+	db := s.acquire()
+	defer db.unpin()
+	type bounds struct{ lo, hi uint32 }
+	b := make([]bounds, len(prefixes))
+	sums := make([]*CIDRSummary, len(prefixes))
+	order := make([]int, len(prefixes))
+	for i, p := range prefixes {
+		lo, hi, err := prefixBounds(p)
+		if err != nil {
+			return nil, err
+		}
+		b[i] = bounds{lo, hi}
+		sums[i] = newCIDRSummary(p.Masked())
+		order[i] = i
+	}
+	slices.SortFunc(order, func(x, y int) int {
+		return cmp.Compare(b[x].lo, b[y].lo)
+	})
+
+	resolve := s.numResolver(db)
+	next := 0        // Next prefix (in order) not yet active
+	var active []int // Prefixes intersecting the current range
+	visit := func(r ipRange) error {
+		for next < len(order) && b[order[next]].lo <= r.last {
+			active = append(active, order[next])
+			next++
+		}
+		kept := active[:0]
+		for _, i := range active {
+			clipped := r
+			clipped.first = max(r.first, b[i].lo)
+			clipped.last = min(r.last, b[i].hi)
+			if clipped.first <= clipped.last {
+				if err := sums[i].add(clipped, resolve); err != nil {
+					return err
+				}
+			}
+			if b[i].hi > r.last {
+				kept = append(kept, i)
+			}
+		}
+		active = kept
+		return nil
+	}
+
+	// Walk the union of the prefixes, one contiguous stretch at a time.
+	for k := 0; k < len(order); {
+		lo, hi := b[order[k]].lo, b[order[k]].hi
+		for k++; k < len(order) && (b[order[k]].lo <= hi || b[order[k]].lo == hi+1); k++ {
+			hi = max(hi, b[order[k]].hi)
+		}
+		if err := s.walkRanges(db, lo, hi, visit); err != nil {
+			return nil, fmt.Errorf("sxgo: failed to match prefixes: %w", err)
+		}
+	}
+	return sums, nil
+}
@@ -0,0 +1,41 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errPageFull stops the city walk of CitiesPage once the page is full.
+var errPageFull = errors.New("page full")
+
+// CitiesPage returns up to limit city records in storage order, starting at
+// the record at seek from (0 for the first one), and the seek of the record
+// following them, to pass as from for the next page; next is 0 after the last
+// page. It reads only the part of the cities block it returns, so external
+// indexers and sitemap generators can ingest the full city list page by page
+// without holding it in memory. from must be 0, a next value, or the Seek of
+// a CityRecord of the same database. limit < 1 means 1000.
+func (s *SxGeo) CitiesPage(from uint32, limit int) (cities []CityRecord, next uint32, err error) {
+	db := s.acquire()
+	defer db.unpin()
+	if limit < 1 {
+		limit = 1000
+	}
+	regionIDs := make(map[uint32]uint32)
+	err = s.walkCities(db, from, func(seek uint32, rec map[string]interface{}) error {
+		if len(cities) == limit {
+			next = seek
+			return errPageFull
+		}
+		c, err := s.cityRecord(db, seek, rec, regionIDs)
+		if err != nil {
+			return err
+		}
+		cities = append(cities, c)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPageFull) {
+		return nil, 0, fmt.Errorf("sxgo: failed to read cities page at seek %d: %w", from, err)
+	}
+	return cities, next, nil
+}
@@ -0,0 +1,382 @@
+// Command sxgo inspects Sypex Geo databases from the command line:
+//
+//	sxgo lookup -db SxGeoCity.dat 8.8.8.8 77.88.8.8
+//	sxgo info -db SxGeoCity.dat
+//	sxgo dump -db SxGeoCity.dat -format csv -o ranges.csv
+//	sxgo serve -db SxGeoCity.dat -addr :8080 -reload 1h
+//
+// lookup prints one JSON object per address (read from standard input, one
+// per line, when none are given), info prints the database metadata of
+// SxGeo.About as JSON, and dump writes every located range of the database
+// with its location as CSV or JSON lines. serve answers lookups over HTTP
+// (see package geohttp), reloading the database on SIGHUP and, with -reload,
+// when the file is replaced; -format adds template renderings of its answers.
+// SIGINT and SIGTERM stop it once requests in flight are answered.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/geohttp"
+)
+
+// commands are the subcommands, by name.
+var commands = map[string]func(args []string) error{
+	"lookup": lookup,
+	"info":   info,
+	"dump":   dump,
+	"serve":  serve,
+}
+
+// errUsage reports invalid arguments; the flag set has printed the usage.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: sxgo lookup|info|dump|serve [flags] [args]\n\nRun sxgo <command> -h for the flags of a command.")
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "sxgo:", err)
+		os.Exit(1)
+	}
+}
+
+// dbFlags adds the flags selecting the database to fs and returns a function
+// opening it once fs is parsed.
+func dbFlags(fs *flag.FlagSet, defaultMode string) func(opts ...sxgo.Option) (*sxgo.SxGeo, error) {
+	dbFile := fs.String("db", "SxGeoCity.dat", "Sypex Geo database (.dat or bundle)")
+	mode := fs.String("mode", defaultMode, "how to read the database: file, memory or mmap")
+	return func(opts ...sxgo.Option) (*sxgo.SxGeo, error) {
+		m, ok := map[string]uint{"file": sxgo.ModeFile, "memory": sxgo.ModeMemory, "mmap": sxgo.ModeMMap}[*mode]
+		if !ok {
+			return nil, fmt.Errorf("unknown mode %q (want file, memory or mmap)", *mode)
+		}
+		return sxgo.New(*dbFile, m, opts...)
+	}
+}
+
+// parse parses args into fs, reporting errUsage on invalid flags.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// lookupResult is the output of lookup for one address.
+type lookupResult struct {
+	IP string `json:"ip"`
+	*sxgo.LocationInfo
+	CountryISO string `json:"country_iso,omitempty"` // Country databases only.
+	Error      string `json:"error,omitempty"`
+}
+
+func lookup(args []string) error {
+	fs := flag.NewFlagSet("lookup", flag.ContinueOnError)
+	open := dbFlags(fs, "file")
+	withRange := fs.Bool("range", false, "include the matched address range")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sxgo lookup [flags] [ip ...]")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	var opts []sxgo.Option
+	if *withRange {
+		opts = append(opts, sxgo.WithMatchedRange())
+	}
+	geo, err := open(opts...)
+	if err != nil {
+		return err
+	}
+	defer geo.Close()
+
+	ips := fs.Args()
+	if len(ips) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if ip := strings.TrimSpace(sc.Text()); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("failed to read addresses: %w", err)
+		}
+	}
+
+	city := geo.Info().Type.IsCity()
+	out := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	failed := 0
+	for _, ip := range ips {
+		res := lookupResult{IP: ip}
+		if city {
+			res.LocationInfo, err = geo.GetCityFull(ip)
+		} else {
+			res.CountryISO, err = geo.GetCountry(ip)
+		}
+		if err != nil {
+			res.Error = err.Error()
+			failed++
+		}
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lookups failed", failed, len(ips))
+	}
+	return nil
+}
+
+func info(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	open := dbFlags(fs, "file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sxgo info [flags]")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	geo, err := open()
+	if err != nil {
+		return err
+	}
+	defer geo.Close()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(geo.About())
+}
+
+// dumpCityHeader and dumpCountryHeader are the CSV headers of dump for City
+// and Country databases.
+var (
+	dumpCityHeader = []string{
+		"first", "last", "country_iso", "country_name_en", "region_iso", "region_name_en", "region_name_ru",
+		"city_id", "city_name_en", "city_name_ru", "lat", "lon",
+	}
+	dumpCountryHeader = []string{"first", "last", "country_iso"}
+)
+
+// dumpRecord is a JSON line of dump.
+type dumpRecord struct {
+	First netip.Addr `json:"first"`
+	Last  netip.Addr `json:"last"`
+	*sxgo.LocationInfo
+	CountryISO string `json:"country_iso,omitempty"` // Country databases only.
+}
+
+func dump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	open := dbFlags(fs, "memory")
+	format := fs.String("format", "csv", "output format: csv or jsonl")
+	outFile := fs.String("o", "", "output file (default: standard output)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sxgo dump [flags]")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "jsonl" {
+		return fmt.Errorf("unknown format %q (want csv or jsonl)", *format)
+	}
+	geo, err := open(sxgo.WithRecordCache(64 << 20))
+	if err != nil {
+		return err
+	}
+	defer geo.Close()
+
+	var w io.Writer = os.Stdout
+	if *outFile != "" {
+		f, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	out := bufio.NewWriterSize(w, 1<<16)
+	city := geo.Info().Type.IsCity()
+	var cw *csv.Writer
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if *format == "csv" {
+		cw = csv.NewWriter(out)
+		header := dumpCountryHeader
+		if city {
+			header = dumpCityHeader
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	err = geo.WalkRanges(func(r sxgo.IPRange, id uint32) error {
+		if id == 0 {
+			return nil // No location
+		}
+		rec := dumpRecord{First: r.First, Last: r.Last}
+		var err error
+		if city {
+			rec.LocationInfo, err = geo.GetCityFull(r.First.String())
+		} else {
+			rec.CountryISO, err = geo.GetCountry(r.First.String())
+		}
+		if err != nil {
+			return err
+		}
+		if rec.LocationInfo == nil && rec.CountryISO == "" {
+			return nil
+		}
+		if cw == nil {
+			return enc.Encode(rec)
+		}
+		if !city {
+			return cw.Write([]string{r.First.String(), r.Last.String(), rec.CountryISO})
+		}
+		return cw.Write(cityRow(rec))
+	})
+	if err != nil {
+		return err
+	}
+	if cw != nil {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
+
+// cityRow returns the CSV row of rec, in dumpCityHeader order.
+func cityRow(rec dumpRecord) []string {
+	row := make([]string, len(dumpCityHeader))
+	row[0], row[1] = rec.First.String(), rec.Last.String()
+	if c := rec.Country; c != nil {
+		row[2], row[3] = c.ISO, c.NameEN
+	}
+	if r := rec.Region; r != nil {
+		row[4], row[5], row[6] = r.ISO, r.NameEN, r.NameRU
+	}
+	if c := rec.City; c != nil {
+		row[7], row[8], row[9] = strconv.FormatUint(uint64(c.ID), 10), c.NameEN, c.NameRU
+		row[10] = strconv.FormatFloat(c.Lat, 'f', -1, 64)
+		row[11] = strconv.FormatFloat(c.Lon, 'f', -1, 64)
+	}
+	return row
+}
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	open := dbFlags(fs, "mmap")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	reload := fs.Duration("reload", 0, "how often to check the database file for updates (0: only on SIGHUP)")
+	maxBatch := fs.Int("max-batch", geohttp.DefaultMaxBatch, "addresses per /batch request")
+	cacheMaxAge := fs.Duration("cache-max-age", 0, "how long proxies may cache /city and /country answers (0: no caching headers)")
+	trafficWindow := fs.Duration("traffic-window", 0, "sliding window of the per-country and per-city counts served at /traffic (0: off)")
+	formats := make(map[string]geohttp.Format)
+	fs.Func("format", "`name=file` of a text/template rendering /city and /country answers for ?format=name; its Content-Type follows the file extension (repeatable)", func(v string) error {
+		name, file, ok := strings.Cut(v, "=")
+		if !ok || name == "" || file == "" {
+			return errors.New("want name=file")
+		}
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			return err
+		}
+		formats[name] = geohttp.Format{ContentType: mime.TypeByExtension(filepath.Ext(file)), Template: tmpl}
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sxgo serve [flags]")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	geo, err := open(sxgo.WithAutoReload(*reload, func(e sxgo.FileEvent) {
+		if e.Err != nil {
+			log.Printf("reload of %s failed: %v", e.Path, e.Err)
+			return
+		}
+		log.Printf("reloaded %s", e.Path)
+	}))
+	if err != nil {
+		return err
+	}
+	defer geo.Close()
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           &geohttp.Handler{Geo: geo, MaxBatch: *maxBatch, CacheMaxAge: *cacheMaxAge, TrafficWindow: *trafficWindow, Formats: formats},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			if err := geo.Reload(); err != nil {
+				log.Printf("reload failed: %v", err)
+				continue
+			}
+			log.Printf("reloaded database created %s", geo.Info().Created.Format(time.RFC3339))
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Printf("serving %s on %s", geo.Info().Type, *addr)
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
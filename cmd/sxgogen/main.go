@@ -0,0 +1,51 @@
+// Command sxgogen generates typed Go structs and decoders for the record
+// formats of a Sypex Geo database (see sxgo.GenerateDecoders), e.g.
+//
+//	//go:generate go run github.com/idanyas/sxgo/cmd/sxgogen -db SxGeoCityMax.dat -pkg geo -o records_gen.go
+//
+// The generated DecodeCityRecord, DecodeRegionRecord and DecodeCountryRecord
+// functions read the bytes returned by SxGeo.RawCityAt, RawRegionAt and
+// RawCountryAt.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/idanyas/sxgo"
+)
+
+func main() {
+	dbFile := flag.String("db", "", "Sypex Geo database (.dat or bundle) to read the pack formats of")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file (default: $GOPACKAGE)")
+	out := flag.String("o", "", "output file (default: standard output)")
+	flag.Parse()
+	if *dbFile == "" || *pkg == "" {
+		fmt.Fprintln(os.Stderr, "usage: sxgogen -db file.dat -pkg name [-o out.go]")
+		os.Exit(2)
+	}
+
+	geo, err := sxgo.New(*dbFile, sxgo.ModeFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sxgogen:", err)
+		os.Exit(1)
+	}
+	formats := geo.Info().PackFormats
+	geo.Close()
+
+	var src bytes.Buffer
+	if err := sxgo.GenerateDecoders(&src, *pkg, formats); err != nil {
+		fmt.Fprintln(os.Stderr, "sxgogen:", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(src.Bytes())
+		return
+	}
+	if err := os.WriteFile(*out, src.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "sxgogen:", err)
+		os.Exit(1)
+	}
+}
@@ -11,10 +11,17 @@ const (
 	// The file handle is closed after loading.
 	ModeMemory uint = 1
 
-	// ModeBatch can be combined with ModeMemory (e.g., ModeMemory | ModeBatch).
-	// It pre-parses index data into arrays for potentially faster lookups,
-	// especially when performing many lookups sequentially.
+	// ModeBatch used to pre-parse index data into arrays for faster lookups.
+	// Indexes are now parsed in every mode, so it has no effect; it is kept
+	// so existing combinations such as ModeMemory | ModeBatch keep compiling.
 	ModeBatch uint = 2
+
+	// ModeMMap instructs the reader to memory-map the DB file read-only and
+	// read it in place: lookups run at near-ModeMemory speed without a copy
+	// of the database on the heap, and processes mapping the same file share
+	// its pages in the page cache. The file handle is closed after mapping.
+	// On platforms without mmap the reader falls back to ModeFile.
+	ModeMMap uint = 4
 )
 
 // Internal constants
@@ -23,3 +30,72 @@ const (
 	dbHeaderLen      = 40    // Length of the database header
 	dbBlockLenOffset = 3     // Offset of ID within a DB block (after 3 IP bytes)
 )
+
+// DBType identifies the kind of Sypex Geo database (the header type byte).
+type DBType uint8
+
+// Database types as defined by Sypex Geo.
+const (
+	DBTypeUnknown   DBType = 0 // Not set or unrecognized.
+	DBTypeCountry   DBType = 1 // SxGeo Country.
+	DBTypeCityRU    DBType = 2 // SxGeo City, Russian names only.
+	DBTypeCityEN    DBType = 3 // SxGeo City, English names only.
+	DBTypeCity      DBType = 4 // SxGeo City.
+	DBTypeCityMaxRU DBType = 5 // SxGeo City Max, Russian names only.
+	DBTypeCityMaxEN DBType = 6 // SxGeo City Max, English names only.
+	DBTypeCityMax   DBType = 7 // SxGeo City Max.
+)
+
+// String returns the database type name, e.g. "SxGeo City", or "unknown".
+func (t DBType) String() string {
+	switch t {
+	case DBTypeCountry:
+		return "SxGeo Country"
+	case DBTypeCityRU:
+		return "SxGeo City RU"
+	case DBTypeCityEN:
+		return "SxGeo City EN"
+	case DBTypeCity:
+		return "SxGeo City"
+	case DBTypeCityMaxRU:
+		return "SxGeo City Max RU"
+	case DBTypeCityMaxEN:
+		return "SxGeo City Max EN"
+	case DBTypeCityMax:
+		return "SxGeo City Max"
+	}
+	return "unknown"
+}
+
+// IsCity reports whether the type is one of the City (or City Max) databases.
+func (t DBType) IsCity() bool {
+	return t >= DBTypeCityRU && t <= DBTypeCityMax
+}
+
+// IsMax reports whether the type is one of the City Max databases.
+func (t DBType) IsMax() bool {
+	return t >= DBTypeCityMaxRU && t <= DBTypeCityMax
+}
+
+// Charset identifies the character set of strings in the database (the header charset byte).
+type Charset uint8
+
+// Character sets as defined by Sypex Geo.
+const (
+	CharsetUTF8   Charset = 0
+	CharsetLatin1 Charset = 1
+	CharsetCP1251 Charset = 2
+)
+
+// String returns the charset name ("utf-8", "latin1", "cp1251") or "unknown".
+func (c Charset) String() string {
+	switch c {
+	case CharsetUTF8:
+		return "utf-8"
+	case CharsetLatin1:
+		return "latin1"
+	case CharsetCP1251:
+		return "cp1251"
+	}
+	return "unknown"
+}
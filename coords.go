@@ -0,0 +1,115 @@
+package sxgo
+
+import (
+	"fmt"
+	"math"
+)
+
+// WGS84 ellipsoid parameters, as used by the database coordinates.
+const (
+	wgs84A = 6378137.0         // Semi-major axis in meters
+	wgs84F = 1 / 298.257223563 // Flattening
+	utmK0  = 0.9996            // UTM scale factor on the central meridian
+	maxLat = 85.0511287798066  // Latitude bound of Web Mercator (square world)
+	utmMin = -80.0             // Southern limit of UTM
+	utmMax = 84.0              // Northern limit of UTM
+)
+
+// WebMercator converts WGS84 coordinates in degrees to Web Mercator
+// (EPSG:3857) meters, as used by tile-based map renderers. Latitudes beyond
+// ±85.0511° are clamped to the edge of the projection.
+func WebMercator(lat, lon float64) (x, y float64) {
+	lat = math.Max(-maxLat, math.Min(maxLat, lat))
+	x = wgs84A * lon * math.Pi / 180
+	y = wgs84A * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	return x, y
+}
+
+// UTM is a position in the Universal Transverse Mercator system.
+type UTM struct {
+	Zone     int     `json:"zone"`     // Longitude zone, 1 to 60.
+	North    bool    `json:"north"`    // Northern hemisphere.
+	Easting  float64 `json:"easting"`  // Meters, with the 500 km false easting.
+	Northing float64 `json:"northing"` // Meters, with the 10000 km false northing in the south.
+}
+
+// String formats the position as "33N 500000 4649776", rounded to meters.
+func (u UTM) String() string {
+	hemisphere := "S"
+	if u.North {
+		hemisphere = "N"
+	}
+	return fmt.Sprintf("%d%s %.0f %.0f", u.Zone, hemisphere, u.Easting, u.Northing)
+}
+
+// ToUTM converts WGS84 coordinates in degrees to UTM in the standard zone of
+// the position, including the Norway and Svalbard exceptions. ok is false
+// outside the latitudes UTM covers (80°S to 84°N).
+func ToUTM(lat, lon float64) (u UTM, ok bool) {
+	if lat < utmMin || lat > utmMax || math.IsNaN(lon) {
+		return UTM{}, false
+	}
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	lon -= 180
+	u.Zone = utmZone(lat, lon)
+	u.North = lat >= 0
+
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+	phi := lat * math.Pi / 180
+	sin, cos, tan := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	n := wgs84A / math.Sqrt(1-e2*sin*sin)
+	t := tan * tan
+	c := ep2 * cos * cos
+	a := cos * (lon - float64(u.Zone*6-183)) * math.Pi / 180
+	e4, e6 := e2*e2, e2*e2*e2
+	m := wgs84A * ((1-e2/4-3*e4/64-5*e6/256)*phi -
+		(3*e2/8+3*e4/32+45*e6/1024)*math.Sin(2*phi) +
+		(15*e4/256+45*e6/1024)*math.Sin(4*phi) -
+		(35*e6/3072)*math.Sin(6*phi))
+
+	u.Easting = utmK0*n*(a+(1-t+c)*math.Pow(a, 3)/6+
+		(5-18*t+t*t+72*c-58*ep2)*math.Pow(a, 5)/120) + 500000
+	u.Northing = utmK0 * (m + n*tan*(a*a/2+(5-t+9*c+4*c*c)*math.Pow(a, 4)/24+
+		(61-58*t+t*t+600*c-330*ep2)*math.Pow(a, 6)/720))
+	if !u.North {
+		u.Northing += 10000000
+	}
+	return u, true
+}
+
+// utmZone returns the UTM zone of a position, lon in [-180, 180).
+// Internal function.
+func utmZone(lat, lon float64) int {
+	switch {
+	case lat >= 56 && lat < 64 && lon >= 3 && lon < 12:
+		return 32 // Southwestern Norway
+	case lat >= 72 && lon >= 0 && lon < 42:
+		return []int{31, 33, 35, 37}[int(lon+3)/12] // Svalbard
+	}
+	return int(lon+180)/6 + 1
+}
+
+// WebMercator returns the best coordinates of the result (see Coordinates)
+// in Web Mercator meters; ok is false if the result has none.
+func (l *LocationInfo) WebMercator() (x, y float64, ok bool) {
+	lat, lon, acc := l.Coordinates()
+	if acc == "" {
+		return 0, 0, false
+	}
+	x, y = WebMercator(lat, lon)
+	return x, y, true
+}
+
+// UTM returns the best coordinates of the result (see Coordinates) in UTM;
+// ok is false if the result has none or they are outside UTM.
+func (l *LocationInfo) UTM() (UTM, bool) {
+	lat, lon, acc := l.Coordinates()
+	if acc == "" {
+		return UTM{}, false
+	}
+	return ToUTM(lat, lon)
+}
@@ -0,0 +1,166 @@
+// Package countries maps between the country IDs used by Sypex Geo databases,
+// ISO 3166-1 alpha-2 codes, English names and flag emoji, and provides
+// per-country locale, currency, calling code and centroid data.
+//
+// It needs no database and can be used on its own; the sxgo package uses it
+// for the same mappings.
+//
+// With the sxgo_minimal build tag only the ID and ISO code mapping is
+// included: names, locales, currencies, calling codes and centroids are
+// empty.
+package countries
+
+import "strings"
+
+// Country describes one entry of the Sypex Geo country table.
+type Country struct {
+	ID          uint8    `json:"id"`                     // Country ID in Sypex Geo databases.
+	ISO         string   `json:"iso"`                    // ISO 3166-1 alpha-2 code (or a Sypex Geo pseudo-code like "A1").
+	Name        string   `json:"name"`                   // English short name.
+	Emoji       string   `json:"emoji,omitempty"`        // Flag emoji; "" for pseudo-codes.
+	Locales     []string `json:"locales,omitempty"`      // BCP 47 locale tags, most widely used first.
+	Currencies  []string `json:"currencies,omitempty"`   // ISO 4217 currency codes, primary first.
+	CallingCode string   `json:"calling_code,omitempty"` // Country calling code (ITU-T E.164), e.g. "49".
+}
+
+// Lookup indexes, built once from the tables.
+var (
+	isoToID     = make(map[string]uint8, len(id2iso))
+	nameToISO   = make(map[string]string, len(names))
+	callingCode = make(map[string]string)
+)
+
+func init() {
+	for id, iso := range id2iso {
+		if iso != "" {
+			isoToID[iso] = uint8(id)
+		}
+	}
+	for iso, name := range names {
+		nameToISO[strings.ToLower(name)] = iso
+	}
+	for code, isos := range callingCodes {
+		for _, iso := range isos {
+			callingCode[iso] = code
+		}
+	}
+}
+
+// ISO returns the code of country ID id, or "" for ID 0.
+func ISO(id uint8) string {
+	if int(id) < len(id2iso) {
+		return id2iso[id]
+	}
+	return ""
+}
+
+// ID returns the country ID of an ISO code (case-insensitive), or 0 if the
+// code is unknown.
+func ID(iso string) uint8 {
+	if id, ok := isoToID[iso]; ok {
+		return id
+	}
+	return isoToID[strings.ToUpper(iso)]
+}
+
+// Name returns the English name of an ISO code (case-insensitive), or "".
+func Name(iso string) string {
+	return names[canonicalISO(iso)]
+}
+
+// Emoji returns the flag emoji of an ISO code (case-insensitive): the pair of
+// regional indicator symbols for its letters. Returns "" for unknown codes
+// and Sypex Geo pseudo-codes without a flag (AP, A1, A2, O1).
+func Emoji(iso string) string {
+	iso = canonicalISO(iso)
+	if len(iso) != 2 || iso == "AP" || iso[0] < 'A' || iso[0] > 'Z' || iso[1] < 'A' || iso[1] > 'Z' {
+		return ""
+	}
+	return string([]rune{regionalIndicatorA + rune(iso[0]-'A'), regionalIndicatorA + rune(iso[1]-'A')})
+}
+
+// regionalIndicatorA is the regional indicator symbol letter A.
+const regionalIndicatorA = 0x1F1E6
+
+// ByID returns the country with ID id.
+func ByID(id uint8) (Country, bool) {
+	return ByISO(ISO(id))
+}
+
+// ByISO returns the country with an ISO code (case-insensitive).
+func ByISO(iso string) (Country, bool) {
+	iso = canonicalISO(iso)
+	id, ok := isoToID[iso]
+	if !ok {
+		return Country{}, false
+	}
+	c := Country{
+		ID:          id,
+		ISO:         iso,
+		Name:        names[iso],
+		Emoji:       Emoji(iso),
+		CallingCode: callingCode[iso],
+	}
+	if d, ok := countryLocales[iso]; ok {
+		c.Locales = strings.Fields(d[0])
+		c.Currencies = strings.Fields(d[1])
+	}
+	return c, true
+}
+
+// ByName returns the country with an English name (case-insensitive) as
+// returned by Name.
+func ByName(name string) (Country, bool) {
+	return ByISO(nameToISO[strings.ToLower(strings.TrimSpace(name))])
+}
+
+// ByEmoji returns the country of a flag emoji.
+func ByEmoji(flag string) (Country, bool) {
+	r := []rune(flag)
+	if len(r) != 2 {
+		return Country{}, false
+	}
+	var iso [2]byte
+	for i, c := range r {
+		if c < regionalIndicatorA || c > regionalIndicatorA+25 {
+			return Country{}, false
+		}
+		iso[i] = byte('A' + c - regionalIndicatorA)
+	}
+	return ByISO(string(iso[:]))
+}
+
+// ByCallingCode returns the ISO codes of the countries using a country
+// calling code, e.g. "49" or "1" (the North American Numbering Plan).
+// The result is a fresh slice; it is nil for unknown codes.
+func ByCallingCode(code string) []string {
+	return append([]string(nil), callingCodes[code]...)
+}
+
+// Centroid returns the approximate geographic center of a country with an ISO
+// code (case-insensitive), as latitude and longitude in degrees. ok is false
+// for unknown codes and pseudo-codes.
+func Centroid(iso string) (lat, lon float64, ok bool) {
+	c, ok := centroids[canonicalISO(iso)]
+	return c[0], c[1], ok
+}
+
+// All returns every country of the table, ordered by ID.
+func All() []Country {
+	all := make([]Country, 0, len(id2iso))
+	for id := range id2iso {
+		if c, ok := ByID(uint8(id)); ok {
+			all = append(all, c)
+		}
+	}
+	return all
+}
+
+// canonicalISO returns iso as spelled in the table.
+// Internal function.
+func canonicalISO(iso string) string {
+	if _, ok := isoToID[iso]; ok {
+		return iso
+	}
+	return strings.ToUpper(iso)
+}
@@ -0,0 +1,332 @@
+//go:build !sxgo_minimal
+
+package countries
+
+// names maps the codes of id2iso to English short names.
+var names = map[string]string{
+	"AP": "Asia/Pacific Region", "EU": "Europe", "AD": "Andorra",
+	"AE": "United Arab Emirates", "AF": "Afghanistan", "AG": "Antigua and Barbuda",
+	"AI": "Anguilla", "AL": "Albania", "AM": "Armenia",
+	"CW": "Curaçao", "AO": "Angola", "AQ": "Antarctica",
+	"AR": "Argentina", "AS": "American Samoa", "AT": "Austria",
+	"AU": "Australia", "AW": "Aruba", "AZ": "Azerbaijan",
+	"BA": "Bosnia and Herzegovina", "BB": "Barbados", "BD": "Bangladesh",
+	"BE": "Belgium", "BF": "Burkina Faso", "BG": "Bulgaria",
+	"BH": "Bahrain", "BI": "Burundi", "BJ": "Benin",
+	"BM": "Bermuda", "BN": "Brunei", "BO": "Bolivia",
+	"BR": "Brazil", "BS": "Bahamas", "BT": "Bhutan",
+	"BV": "Bouvet Island", "BW": "Botswana", "BY": "Belarus",
+	"BZ": "Belize", "CA": "Canada", "CC": "Cocos (Keeling) Islands",
+	"CD": "Democratic Republic of the Congo", "CF": "Central African Republic", "CG": "Republic of the Congo",
+	"CH": "Switzerland", "CI": "Côte d'Ivoire", "CK": "Cook Islands",
+	"CL": "Chile", "CM": "Cameroon", "CN": "China",
+	"CO": "Colombia", "CR": "Costa Rica", "CU": "Cuba",
+	"CV": "Cape Verde", "CX": "Christmas Island", "CY": "Cyprus",
+	"CZ": "Czechia", "DE": "Germany", "DJ": "Djibouti",
+	"DK": "Denmark", "DM": "Dominica", "DO": "Dominican Republic",
+	"DZ": "Algeria", "EC": "Ecuador", "EE": "Estonia",
+	"EG": "Egypt", "EH": "Western Sahara", "ER": "Eritrea",
+	"ES": "Spain", "ET": "Ethiopia", "FI": "Finland",
+	"FJ": "Fiji", "FK": "Falkland Islands", "FM": "Micronesia",
+	"FO": "Faroe Islands", "FR": "France", "SX": "Sint Maarten",
+	"GA": "Gabon", "GB": "United Kingdom", "GD": "Grenada",
+	"GE": "Georgia", "GF": "French Guiana", "GH": "Ghana",
+	"GI": "Gibraltar", "GL": "Greenland", "GM": "Gambia",
+	"GN": "Guinea", "GP": "Guadeloupe", "GQ": "Equatorial Guinea",
+	"GR": "Greece", "GS": "South Georgia and the South Sandwich Islands", "GT": "Guatemala",
+	"GU": "Guam", "GW": "Guinea-Bissau", "GY": "Guyana",
+	"HK": "Hong Kong", "HM": "Heard Island and McDonald Islands", "HN": "Honduras",
+	"HR": "Croatia", "HT": "Haiti", "HU": "Hungary",
+	"ID": "Indonesia", "IE": "Ireland", "IL": "Israel",
+	"IN": "India", "IO": "British Indian Ocean Territory", "IQ": "Iraq",
+	"IR": "Iran", "IS": "Iceland", "IT": "Italy",
+	"JM": "Jamaica", "JO": "Jordan", "JP": "Japan",
+	"KE": "Kenya", "KG": "Kyrgyzstan", "KH": "Cambodia",
+	"KI": "Kiribati", "KM": "Comoros", "KN": "Saint Kitts and Nevis",
+	"KP": "North Korea", "KR": "South Korea", "KW": "Kuwait",
+	"KY": "Cayman Islands", "KZ": "Kazakhstan", "LA": "Laos",
+	"LB": "Lebanon", "LC": "Saint Lucia", "LI": "Liechtenstein",
+	"LK": "Sri Lanka", "LR": "Liberia", "LS": "Lesotho",
+	"LT": "Lithuania", "LU": "Luxembourg", "LV": "Latvia",
+	"LY": "Libya", "MA": "Morocco", "MC": "Monaco",
+	"MD": "Moldova", "MG": "Madagascar", "MH": "Marshall Islands",
+	"MK": "North Macedonia", "ML": "Mali", "MM": "Myanmar",
+	"MN": "Mongolia", "MO": "Macao", "MP": "Northern Mariana Islands",
+	"MQ": "Martinique", "MR": "Mauritania", "MS": "Montserrat",
+	"MT": "Malta", "MU": "Mauritius", "MV": "Maldives",
+	"MW": "Malawi", "MX": "Mexico", "MY": "Malaysia",
+	"MZ": "Mozambique", "NA": "Namibia", "NC": "New Caledonia",
+	"NE": "Niger", "NF": "Norfolk Island", "NG": "Nigeria",
+	"NI": "Nicaragua", "NL": "Netherlands", "NO": "Norway",
+	"NP": "Nepal", "NR": "Nauru", "NU": "Niue",
+	"NZ": "New Zealand", "OM": "Oman", "PA": "Panama",
+	"PE": "Peru", "PF": "French Polynesia", "PG": "Papua New Guinea",
+	"PH": "Philippines", "PK": "Pakistan", "PL": "Poland",
+	"PM": "Saint Pierre and Miquelon", "PN": "Pitcairn Islands", "PR": "Puerto Rico",
+	"PS": "Palestine", "PT": "Portugal", "PW": "Palau",
+	"PY": "Paraguay", "QA": "Qatar", "RE": "Réunion",
+	"RO": "Romania", "RU": "Russia", "RW": "Rwanda",
+	"SA": "Saudi Arabia", "SB": "Solomon Islands", "SC": "Seychelles",
+	"SD": "Sudan", "SE": "Sweden", "SG": "Singapore",
+	"SH": "Saint Helena", "SI": "Slovenia", "SJ": "Svalbard and Jan Mayen",
+	"SK": "Slovakia", "SL": "Sierra Leone", "SM": "San Marino",
+	"SN": "Senegal", "SO": "Somalia", "SR": "Suriname",
+	"ST": "São Tomé and Príncipe", "SV": "El Salvador", "SY": "Syria",
+	"SZ": "Eswatini", "TC": "Turks and Caicos Islands", "TD": "Chad",
+	"TF": "French Southern Territories", "TG": "Togo", "TH": "Thailand",
+	"TJ": "Tajikistan", "TK": "Tokelau", "TM": "Turkmenistan",
+	"TN": "Tunisia", "TO": "Tonga", "TL": "Timor-Leste",
+	"TR": "Turkey", "TT": "Trinidad and Tobago", "TV": "Tuvalu",
+	"TW": "Taiwan", "TZ": "Tanzania", "UA": "Ukraine",
+	"UG": "Uganda", "UM": "U.S. Minor Outlying Islands", "US": "United States",
+	"UY": "Uruguay", "UZ": "Uzbekistan", "VA": "Vatican City",
+	"VC": "Saint Vincent and the Grenadines", "VE": "Venezuela", "VG": "British Virgin Islands",
+	"VI": "U.S. Virgin Islands", "VN": "Vietnam", "VU": "Vanuatu",
+	"WF": "Wallis and Futuna", "WS": "Samoa", "YE": "Yemen",
+	"YT": "Mayotte", "RS": "Serbia", "ZA": "South Africa",
+	"ZM": "Zambia", "ME": "Montenegro", "ZW": "Zimbabwe",
+	"A1": "Anonymous Proxy", "A2": "Satellite Provider", "O1": "Other",
+	"AX": "Åland Islands", "GG": "Guernsey", "IM": "Isle of Man",
+	"JE": "Jersey", "BL": "Saint Barthélemy", "MF": "Saint Martin",
+	"BQ": "Caribbean Netherlands", "SS": "South Sudan",
+	"Unknown": "Unknown",
+}
+
+// countryLocales maps ISO country codes to space-separated BCP 47 locales and
+// ISO 4217 currencies.
+var countryLocales = map[string][2]string{
+	"AD": {"ca-AD es-AD fr-AD", "EUR"},
+	"AE": {"ar-AE en-AE", "AED"},
+	"AF": {"fa-AF ps-AF", "AFN"},
+	"AL": {"sq-AL", "ALL"},
+	"AM": {"hy-AM ru-AM", "AMD"},
+	"AO": {"pt-AO", "AOA"},
+	"AR": {"es-AR", "ARS"},
+	"AT": {"de-AT", "EUR"},
+	"AU": {"en-AU", "AUD"},
+	"AZ": {"az-AZ ru-AZ", "AZN"},
+	"BA": {"bs-BA hr-BA sr-BA", "BAM"},
+	"BD": {"bn-BD", "BDT"},
+	"BE": {"nl-BE fr-BE de-BE", "EUR"},
+	"BG": {"bg-BG", "BGN"},
+	"BH": {"ar-BH", "BHD"},
+	"BO": {"es-BO", "BOB"},
+	"BR": {"pt-BR", "BRL"},
+	"BY": {"be-BY ru-BY", "BYN"},
+	"CA": {"en-CA fr-CA", "CAD"},
+	"CD": {"fr-CD", "CDF"},
+	"CH": {"de-CH fr-CH it-CH", "CHF"},
+	"CI": {"fr-CI", "XOF"},
+	"CL": {"es-CL", "CLP"},
+	"CM": {"fr-CM en-CM", "XAF"},
+	"CN": {"zh-CN", "CNY"},
+	"CO": {"es-CO", "COP"},
+	"CR": {"es-CR", "CRC"},
+	"CU": {"es-CU", "CUP"},
+	"CY": {"el-CY tr-CY", "EUR"},
+	"CZ": {"cs-CZ", "CZK"},
+	"DE": {"de-DE", "EUR"},
+	"DK": {"da-DK", "DKK"},
+	"DO": {"es-DO", "DOP"},
+	"DZ": {"ar-DZ fr-DZ", "DZD"},
+	"EC": {"es-EC", "USD"},
+	"EE": {"et-EE ru-EE", "EUR"},
+	"EG": {"ar-EG", "EGP"},
+	"ES": {"es-ES ca-ES", "EUR"},
+	"ET": {"am-ET", "ETB"},
+	"FI": {"fi-FI sv-FI", "EUR"},
+	"FR": {"fr-FR", "EUR"},
+	"GB": {"en-GB", "GBP"},
+	"GE": {"ka-GE ru-GE", "GEL"},
+	"GH": {"en-GH", "GHS"},
+	"GR": {"el-GR", "EUR"},
+	"GT": {"es-GT", "GTQ"},
+	"HK": {"zh-HK en-HK", "HKD"},
+	"HN": {"es-HN", "HNL"},
+	"HR": {"hr-HR", "EUR"},
+	"HU": {"hu-HU", "HUF"},
+	"ID": {"id-ID", "IDR"},
+	"IE": {"en-IE ga-IE", "EUR"},
+	"IL": {"he-IL ar-IL", "ILS"},
+	"IN": {"hi-IN en-IN", "INR"},
+	"IQ": {"ar-IQ", "IQD"},
+	"IR": {"fa-IR", "IRR"},
+	"IS": {"is-IS", "ISK"},
+	"IT": {"it-IT", "EUR"},
+	"JM": {"en-JM", "JMD"},
+	"JO": {"ar-JO", "JOD"},
+	"JP": {"ja-JP", "JPY"},
+	"KE": {"sw-KE en-KE", "KES"},
+	"KG": {"ky-KG ru-KG", "KGS"},
+	"KH": {"km-KH", "KHR"},
+	"KR": {"ko-KR", "KRW"},
+	"KW": {"ar-KW", "KWD"},
+	"KZ": {"kk-KZ ru-KZ", "KZT"},
+	"LA": {"lo-LA", "LAK"},
+	"LB": {"ar-LB fr-LB", "LBP"},
+	"LI": {"de-LI", "CHF"},
+	"LK": {"si-LK ta-LK", "LKR"},
+	"LT": {"lt-LT", "EUR"},
+	"LU": {"lb-LU fr-LU de-LU", "EUR"},
+	"LV": {"lv-LV ru-LV", "EUR"},
+	"LY": {"ar-LY", "LYD"},
+	"MA": {"ar-MA fr-MA", "MAD"},
+	"MC": {"fr-MC", "EUR"},
+	"MD": {"ro-MD ru-MD", "MDL"},
+	"ME": {"sr-ME", "EUR"},
+	"MK": {"mk-MK", "MKD"},
+	"MM": {"my-MM", "MMK"},
+	"MN": {"mn-MN", "MNT"},
+	"MO": {"zh-MO pt-MO", "MOP"},
+	"MT": {"mt-MT en-MT", "EUR"},
+	"MX": {"es-MX", "MXN"},
+	"MY": {"ms-MY en-MY", "MYR"},
+	"NG": {"en-NG", "NGN"},
+	"NI": {"es-NI", "NIO"},
+	"NL": {"nl-NL", "EUR"},
+	"NO": {"nb-NO", "NOK"},
+	"NP": {"ne-NP", "NPR"},
+	"NZ": {"en-NZ", "NZD"},
+	"OM": {"ar-OM", "OMR"},
+	"PA": {"es-PA", "PAB USD"},
+	"PE": {"es-PE", "PEN"},
+	"PH": {"en-PH fil-PH", "PHP"},
+	"PK": {"ur-PK en-PK", "PKR"},
+	"PL": {"pl-PL", "PLN"},
+	"PR": {"es-PR en-PR", "USD"},
+	"PT": {"pt-PT", "EUR"},
+	"PY": {"es-PY", "PYG"},
+	"QA": {"ar-QA", "QAR"},
+	"RO": {"ro-RO", "RON"},
+	"RS": {"sr-RS", "RSD"},
+	"RU": {"ru-RU", "RUB"},
+	"SA": {"ar-SA", "SAR"},
+	"SE": {"sv-SE", "SEK"},
+	"SG": {"en-SG zh-SG", "SGD"},
+	"SI": {"sl-SI", "EUR"},
+	"SK": {"sk-SK", "EUR"},
+	"SM": {"it-SM", "EUR"},
+	"SN": {"fr-SN", "XOF"},
+	"SV": {"es-SV", "USD"},
+	"SY": {"ar-SY", "SYP"},
+	"TH": {"th-TH", "THB"},
+	"TJ": {"tg-TJ ru-TJ", "TJS"},
+	"TM": {"tk-TM ru-TM", "TMT"},
+	"TN": {"ar-TN fr-TN", "TND"},
+	"TR": {"tr-TR", "TRY"},
+	"TW": {"zh-TW", "TWD"},
+	"TZ": {"sw-TZ en-TZ", "TZS"},
+	"UA": {"uk-UA ru-UA", "UAH"},
+	"UG": {"en-UG sw-UG", "UGX"},
+	"US": {"en-US es-US", "USD"},
+	"UY": {"es-UY", "UYU"},
+	"UZ": {"uz-UZ ru-UZ", "UZS"},
+	"VA": {"it-VA", "EUR"},
+	"VE": {"es-VE", "VES"},
+	"VN": {"vi-VN", "VND"},
+	"YE": {"ar-YE", "YER"},
+	"ZA": {"en-ZA af-ZA zu-ZA", "ZAR"},
+	"ZM": {"en-ZM", "ZMW"},
+	"ZW": {"en-ZW", "ZWL USD"},
+}
+
+// callingCodes maps country calling codes (ITU-T E.164) to the ISO codes of
+// the countries using them.
+var callingCodes = map[string][]string{
+	"1": {"US", "CA", "AG", "AI", "AS", "BB", "BM", "BS", "DM", "DO", "GD", "GU", "JM", "KN",
+		"KY", "LC", "MP", "MS", "PR", "SX", "TC", "TT", "VC", "VG", "VI"},
+	"7":  {"RU", "KZ"},
+	"20": {"EG"}, "27": {"ZA"}, "30": {"GR"}, "31": {"NL"}, "32": {"BE"}, "33": {"FR"},
+	"34": {"ES"}, "36": {"HU"}, "39": {"IT", "VA"}, "40": {"RO"}, "41": {"CH"}, "43": {"AT"},
+	"44": {"GB", "GG", "IM", "JE"}, "45": {"DK"}, "46": {"SE"}, "47": {"NO", "SJ"}, "48": {"PL"},
+	"49": {"DE"}, "51": {"PE"}, "52": {"MX"}, "53": {"CU"}, "54": {"AR"}, "55": {"BR"},
+	"56": {"CL"}, "57": {"CO"}, "58": {"VE"}, "60": {"MY"}, "61": {"AU", "CX", "CC"},
+	"62": {"ID"}, "63": {"PH"}, "64": {"NZ"}, "65": {"SG"}, "66": {"TH"}, "81": {"JP"},
+	"82": {"KR"}, "84": {"VN"}, "86": {"CN"}, "90": {"TR"}, "91": {"IN"}, "92": {"PK"},
+	"93": {"AF"}, "94": {"LK"}, "95": {"MM"}, "98": {"IR"},
+	"211": {"SS"}, "212": {"MA", "EH"}, "213": {"DZ"}, "216": {"TN"}, "218": {"LY"},
+	"220": {"GM"}, "221": {"SN"}, "222": {"MR"}, "223": {"ML"}, "224": {"GN"}, "225": {"CI"},
+	"226": {"BF"}, "227": {"NE"}, "228": {"TG"}, "229": {"BJ"}, "230": {"MU"}, "231": {"LR"},
+	"232": {"SL"}, "233": {"GH"}, "234": {"NG"}, "235": {"TD"}, "236": {"CF"}, "237": {"CM"},
+	"238": {"CV"}, "239": {"ST"}, "240": {"GQ"}, "241": {"GA"}, "242": {"CG"}, "243": {"CD"},
+	"244": {"AO"}, "245": {"GW"}, "246": {"IO"}, "248": {"SC"}, "249": {"SD"}, "250": {"RW"},
+	"251": {"ET"}, "252": {"SO"}, "253": {"DJ"}, "254": {"KE"}, "255": {"TZ"}, "256": {"UG"},
+	"257": {"BI"}, "258": {"MZ"}, "260": {"ZM"}, "261": {"MG"}, "262": {"RE", "YT"}, "263": {"ZW"},
+	"264": {"NA"}, "265": {"MW"}, "266": {"LS"}, "267": {"BW"}, "268": {"SZ"}, "269": {"KM"},
+	"290": {"SH"}, "291": {"ER"}, "297": {"AW"}, "298": {"FO"}, "299": {"GL"},
+	"350": {"GI"}, "351": {"PT"}, "352": {"LU"}, "353": {"IE"}, "354": {"IS"}, "355": {"AL"},
+	"356": {"MT"}, "357": {"CY"}, "358": {"FI", "AX"}, "359": {"BG"}, "370": {"LT"}, "371": {"LV"},
+	"372": {"EE"}, "373": {"MD"}, "374": {"AM"}, "375": {"BY"}, "376": {"AD"}, "377": {"MC"},
+	"378": {"SM"}, "380": {"UA"}, "381": {"RS"}, "382": {"ME"}, "383": {"XK"}, "385": {"HR"},
+	"386": {"SI"}, "387": {"BA"}, "389": {"MK"}, "420": {"CZ"}, "421": {"SK"}, "423": {"LI"},
+	"500": {"FK"}, "501": {"BZ"}, "502": {"GT"}, "503": {"SV"}, "504": {"HN"}, "505": {"NI"},
+	"506": {"CR"}, "507": {"PA"}, "508": {"PM"}, "509": {"HT"}, "590": {"GP", "BL", "MF"},
+	"591": {"BO"}, "592": {"GY"}, "593": {"EC"}, "594": {"GF"}, "595": {"PY"}, "596": {"MQ"},
+	"597": {"SR"}, "598": {"UY"}, "599": {"CW", "BQ"},
+	"670": {"TL"}, "672": {"NF"}, "673": {"BN"}, "674": {"NR"}, "675": {"PG"}, "676": {"TO"},
+	"677": {"SB"}, "678": {"VU"}, "679": {"FJ"}, "680": {"PW"}, "681": {"WF"}, "682": {"CK"},
+	"683": {"NU"}, "685": {"WS"}, "686": {"KI"}, "687": {"NC"}, "688": {"TV"}, "689": {"PF"},
+	"690": {"TK"}, "691": {"FM"}, "692": {"MH"},
+	"850": {"KP"}, "852": {"HK"}, "853": {"MO"}, "855": {"KH"}, "856": {"LA"}, "880": {"BD"},
+	"886": {"TW"},
+	"960": {"MV"}, "961": {"LB"}, "962": {"JO"}, "963": {"SY"}, "964": {"IQ"}, "965": {"KW"},
+	"966": {"SA"}, "967": {"YE"}, "968": {"OM"}, "970": {"PS"}, "971": {"AE"}, "972": {"IL"},
+	"973": {"BH"}, "974": {"QA"}, "975": {"BT"}, "976": {"MN"}, "977": {"NP"}, "992": {"TJ"},
+	"993": {"TM"}, "994": {"AZ"}, "995": {"GE"}, "996": {"KG"}, "998": {"UZ"},
+}
+
+// centroids maps ISO country codes to approximate geographic centers, as
+// latitude and longitude rounded like the country records of Sypex Geo
+// databases.
+var centroids = map[string][2]float64{
+	"AD": {42.5, 1.5}, "AE": {24, 54}, "AF": {33, 65}, "AG": {17.05, -61.8}, "AI": {18.25, -63.17},
+	"AL": {41, 20}, "AM": {40, 45}, "AO": {-12.5, 18.5}, "AQ": {-90, 0}, "AR": {-34, -64},
+	"AS": {-14.33, -170}, "AT": {47.33, 13.33}, "AU": {-27, 133}, "AW": {12.5, -69.97}, "AX": {60.12, 19.9},
+	"AZ": {40.5, 47.5}, "BA": {44, 18}, "BB": {13.17, -59.53}, "BD": {24, 90}, "BE": {50.83, 4},
+	"BF": {13, -2}, "BG": {43, 25}, "BH": {26, 50.55}, "BI": {-3.5, 30}, "BJ": {9.5, 2.25},
+	"BL": {17.9, -62.83}, "BM": {32.33, -64.75}, "BN": {4.5, 114.67}, "BO": {-17, -65}, "BQ": {12.18, -68.25},
+	"BR": {-10, -55}, "BS": {24.25, -76}, "BT": {27.5, 90.5}, "BV": {-54.43, 3.4}, "BW": {-22, 24},
+	"BY": {53, 28}, "BZ": {17.25, -88.75}, "CA": {60, -95}, "CC": {-12.5, 96.83}, "CD": {0, 25},
+	"CF": {7, 21}, "CG": {-1, 15}, "CH": {47, 8}, "CI": {8, -5}, "CK": {-21.23, -159.77},
+	"CL": {-30, -71}, "CM": {6, 12}, "CN": {35, 105}, "CO": {4, -72}, "CR": {10, -84},
+	"CU": {21.5, -80}, "CV": {16, -24}, "CW": {12.17, -69}, "CX": {-10.5, 105.67}, "CY": {35, 33},
+	"CZ": {49.75, 15.5}, "DE": {51, 9}, "DJ": {11.5, 43}, "DK": {56, 10}, "DM": {15.42, -61.33},
+	"DO": {19, -70.67}, "DZ": {28, 3}, "EC": {-2, -77.5}, "EE": {59, 26}, "EG": {27, 30},
+	"EH": {24.5, -13}, "ER": {15, 39}, "ES": {40, -4}, "ET": {8, 38}, "FI": {64, 26},
+	"FJ": {-18, 175}, "FK": {-51.75, -59}, "FM": {6.92, 158.25}, "FO": {62, -7}, "FR": {46, 2},
+	"GA": {-1, 11.75}, "GB": {54, -2}, "GD": {12.12, -61.67}, "GE": {42, 43.5}, "GF": {4, -53},
+	"GG": {49.5, -2.56}, "GH": {8, -2}, "GI": {36.18, -5.37}, "GL": {72, -40}, "GM": {13.47, -16.57},
+	"GN": {11, -10}, "GP": {16.25, -61.58}, "GQ": {2, 10}, "GR": {39, 22}, "GS": {-54.5, -37},
+	"GT": {15.5, -90.25}, "GU": {13.47, 144.78}, "GW": {12, -15}, "GY": {5, -59}, "HK": {22.25, 114.17},
+	"HM": {-53.1, 72.52}, "HN": {15, -86.5}, "HR": {45.17, 15.5}, "HT": {19, -72.42}, "HU": {47, 20},
+	"ID": {-5, 120}, "IE": {53, -8}, "IL": {31.5, 34.75}, "IM": {54.23, -4.55}, "IN": {20, 77},
+	"IO": {-6, 71.5}, "IQ": {33, 44}, "IR": {32, 53}, "IS": {65, -18}, "IT": {42.83, 12.83},
+	"JE": {49.21, -2.13}, "JM": {18.25, -77.5}, "JO": {31, 36}, "JP": {36, 138}, "KE": {1, 38},
+	"KG": {41, 75}, "KH": {13, 105}, "KI": {1.42, 173}, "KM": {-12.17, 44.25}, "KN": {17.33, -62.75},
+	"KP": {40, 127}, "KR": {37, 127.5}, "KW": {29.34, 47.66}, "KY": {19.5, -80.5}, "KZ": {48, 68},
+	"LA": {18, 105}, "LB": {33.83, 35.83}, "LC": {13.88, -61.13}, "LI": {47.17, 9.53}, "LK": {7, 81},
+	"LR": {6.5, -9.5}, "LS": {-29.5, 28.5}, "LT": {56, 24}, "LU": {49.75, 6.17}, "LV": {57, 25},
+	"LY": {25, 17}, "MA": {32, -5}, "MC": {43.73, 7.4}, "MD": {47, 29}, "ME": {42.5, 19.3},
+	"MF": {18.08, -63.95}, "MG": {-20, 47}, "MH": {9, 168}, "MK": {41.83, 22}, "ML": {17, -4},
+	"MM": {22, 98}, "MN": {46, 105}, "MO": {22.17, 113.55}, "MP": {15.2, 145.75}, "MQ": {14.67, -61},
+	"MR": {20, -12}, "MS": {16.75, -62.2}, "MT": {35.83, 14.58}, "MU": {-20.28, 57.55}, "MV": {3.25, 73},
+	"MW": {-13.5, 34}, "MX": {23, -102}, "MY": {2.5, 112.5}, "MZ": {-18.25, 35}, "NA": {-22, 17},
+	"NC": {-21.5, 165.5}, "NE": {16, 8}, "NF": {-29.03, 167.95}, "NG": {10, 8}, "NI": {13, -85},
+	"NL": {52.5, 5.75}, "NO": {62, 10}, "NP": {28, 84}, "NR": {-0.53, 166.92}, "NU": {-19.03, -169.87},
+	"NZ": {-41, 174}, "OM": {21, 57}, "PA": {9, -80}, "PE": {-10, -76}, "PF": {-15, -140},
+	"PG": {-6, 147}, "PH": {13, 122}, "PK": {30, 70}, "PL": {52, 20}, "PM": {46.83, -56.33},
+	"PN": {-24.7, -127.4}, "PR": {18.25, -66.5}, "PS": {32, 35.25}, "PT": {39.5, -8}, "PW": {7.5, 134.5},
+	"PY": {-23, -58}, "QA": {25.5, 51.25}, "RE": {-21.1, 55.6}, "RO": {46, 25}, "RS": {44, 21},
+	"RU": {60, 100}, "RW": {-2, 30}, "SA": {25, 45}, "SB": {-8, 159}, "SC": {-4.58, 55.67},
+	"SD": {15, 30}, "SE": {62, 15}, "SG": {1.37, 103.8}, "SH": {-15.93, -5.7}, "SI": {46, 15},
+	"SJ": {78, 20}, "SK": {48.67, 19.5}, "SL": {8.5, -11.5}, "SM": {43.77, 12.42}, "SN": {14, -14},
+	"SO": {10, 49}, "SR": {4, -56}, "SS": {7, 30}, "ST": {1, 7}, "SV": {13.83, -88.92},
+	"SX": {18.03, -63.05}, "SY": {35, 38}, "SZ": {-26.5, 31.5}, "TC": {21.75, -71.58}, "TD": {15, 19},
+	"TF": {-43, 67}, "TG": {8, 1.17}, "TH": {15, 100}, "TJ": {39, 71}, "TK": {-9, -172},
+	"TL": {-8.83, 125.75}, "TM": {40, 60}, "TN": {34, 9}, "TO": {-20, -175}, "TR": {39, 35},
+	"TT": {11, -61}, "TV": {-8, 178}, "TW": {23.5, 121}, "TZ": {-6, 35}, "UA": {49, 32},
+	"UG": {1, 32}, "UM": {19.28, 166.6}, "US": {38, -97}, "UY": {-33, -56}, "UZ": {41, 64},
+	"VA": {41.9, 12.45}, "VC": {13.25, -61.2}, "VE": {8, -66}, "VG": {18.5, -64.5}, "VI": {18.34, -64.93},
+	"VN": {16, 106}, "VU": {-16, 167}, "WF": {-13.3, -176.2}, "WS": {-13.58, -172.33}, "YE": {15, 48},
+	"YT": {-12.83, 45.17}, "ZA": {-29, 24}, "ZM": {-15, 30}, "ZW": {-20, 30},
+}
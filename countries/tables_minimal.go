@@ -0,0 +1,12 @@
+//go:build sxgo_minimal
+
+package countries
+
+// The sxgo_minimal build leaves out the enrichment tables; only the ID to
+// ISO code mapping of id2iso remains.
+var (
+	names          map[string]string
+	countryLocales map[string][2]string
+	callingCodes   map[string][]string
+	centroids      map[string][2]float64
+)
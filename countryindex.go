@@ -0,0 +1,80 @@
+package sxgo
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// countryIndex maps the whole IPv4 space to country IDs as one flat table of
+// ranges, merged where adjacent ranges share the country.
+// This struct is internal.
+type countryIndex struct {
+	starts []uint32 // First address of each range, ascending; starts[0] is 0
+	ids    []uint8  // Country ID of each range; 0 if unknown or reserved
+}
+
+// WithCountryIndex makes New resolve the country of every range once and
+// keep the result as a flat table, which CountryOf then searches with a
+// single binary search and no further reads. It suits Country databases
+// (the table is built from the block table alone) and country-only
+// instances (see WithCountryOnly); with a City database New reads every city
+// record once. The table takes about 5 bytes per range of distinct country.
+func WithCountryIndex() Option {
+	return func(s *SxGeo) {
+		s.countryIndexed = true
+	}
+}
+
+// CountryOf returns the ISO 3166-1 alpha-2 code of the country of addr, as
+// GetCountry does, or "" if unknown. With WithCountryIndex it answers IPv4
+// addresses from the country table without allocating, unless patches (see
+// ApplyPatch), synthetic locations, the lookup audit or recent lookups are in
+// use; lookup statistics then do not count it. Other addresses and instances
+// take the GetCountryAddr path.
+func (s *SxGeo) CountryOf(addr netip.Addr) (string, error) {
+	addr = addr.Unmap()
+	idx := s.db().countryIndex
+	if idx == nil || !addr.Is4() || s.overlay.Load() != nil || len(s.synthetic) > 0 || s.audit != nil || s.recent != nil {
+		return s.countryAddr(addr)
+	}
+	num := addrToUint32(addr)
+	if s.isReservedSpecial(num) {
+		return "", nil
+	}
+	return getISO(uint32(idx.lookup(num))), nil
+}
+
+// lookup returns the country ID of num.
+// Internal function.
+func (idx *countryIndex) lookup(num uint32) uint8 {
+	i, found := slices.BinarySearch(idx.starts, num)
+	if !found {
+		i--
+	}
+	return idx.ids[i]
+}
+
+// buildCountryIndex builds the country table of db (see WithCountryIndex).
+// Internal function.
+func (s *SxGeo) buildCountryIndex(db *dbState) error {
+	idx := &countryIndex{}
+	resolve := s.numResolver(db)
+	err := s.walkRanges(db, 0, 0xFFFFFFFF, func(r ipRange) error {
+		country, _, err := resolve(r.id)
+		if err != nil {
+			return err
+		}
+		if n := len(idx.ids); n > 0 && idx.ids[n-1] == uint8(country) {
+			return nil // Same country as the previous range
+		}
+		idx.starts = append(idx.starts, r.first)
+		idx.ids = append(idx.ids, uint8(country))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	idx.starts, idx.ids = slices.Clip(idx.starts), slices.Clip(idx.ids)
+	db.countryIndex = idx
+	return nil
+}
@@ -0,0 +1,71 @@
+package sxgo_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/sxgeotest"
+)
+
+func TestCountryOf(t *testing.T) {
+	path := miniCityPath(t)
+	ref, err := sxgo.New(path, sxgo.ModeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ref.Close()
+	for _, tt := range []struct {
+		name     string
+		opts     []sxgo.Option
+		reserved bool // Special-purpose ranges are not found
+	}{
+		{"index", []sxgo.Option{sxgo.WithCountryIndex()}, false},
+		{"country-only", []sxgo.Option{sxgo.WithCountryIndex(), sxgo.WithCountryOnly()}, false},
+		{"reserved-ranges", []sxgo.Option{sxgo.WithCountryIndex(), sxgo.WithReservedRanges()}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			geo, err := sxgo.New(path, sxgo.ModeMemory, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer geo.Close()
+			for _, ip := range sxgeotest.Addresses() {
+				want, err := ref.GetCountry(ip)
+				if err != nil {
+					t.Fatalf("GetCountry(%s): %v", ip, err)
+				}
+				if tt.reserved && sxgo.ClassifyIP(ip) != "" {
+					want = ""
+				}
+				got, err := geo.CountryOf(netip.MustParseAddr(ip))
+				if err != nil {
+					t.Fatalf("CountryOf(%s): %v", ip, err)
+				}
+				if got != want {
+					t.Errorf("CountryOf(%s) = %q, want %q", ip, got, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCountryOf(b *testing.B) {
+	geo, err := sxgo.New(miniCityPath(b), sxgo.ModeMemory, sxgo.WithCountryIndex())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer geo.Close()
+	ips := sxgeotest.Addresses()
+	addrs := make([]netip.Addr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = netip.MustParseAddr(ip)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := geo.CountryOf(addrs[i%len(addrs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCountryOnly is returned (wrapped) by city-level lookups on an instance
+// created with WithCountryOnly.
+var ErrCountryOnly = errors.New("sxgo: instance is country-only")
+
+// WithCountryOnly declares that the instance answers country questions only
+// (GetCountry, GetCountryID and the features built on them). With a City
+// database in ModeMemory, New then loads the DB blocks and the country of
+// each record instead of the regions and cities blocks, which saves most of
+// the memory such files need. City-level lookups (GetCity, GetCityFull,
+// GetCityRegion, GetLazy and planners) fail with ErrCountryOnly in every
+// mode, as do, in ModeMemory, other features that decode region or city
+// records. Ranges installed by ApplyPatch whose records New did not see
+// fail likewise. Country databases are unaffected.
+func WithCountryOnly() Option {
+	return func(s *SxGeo) {
+		s.countryOnly = true
+	}
+}
+
+// buildCountryTable resolves the country of every record the loaded DB blocks
+// refer to, reading the records from the still open file, and computes the
+// fingerprint while the whole database is at hand.
+// Internal function.
+func (s *SxGeo) buildCountryTable(db *dbState) error {
+	fdb := *db // Reads the records from the file
+	fdb.fileReads = true
+	if _, err := s.fingerprintOf(&fdb); err != nil {
+		return err
+	}
+	table := make(map[uint32]uint8)
+	for i := 0; i < int(db.header.dbItems); i++ {
+		id, err := db.blockID(db.dbData, i)
+		if err != nil {
+			return fmt.Errorf("failed to decode block %d: %w", i, err)
+		}
+		if _, ok := table[id]; ok || id == 0 {
+			continue
+		}
+		countryID, _, err := s.resolveNum(&fdb, id)
+		if err != nil {
+			return err
+		}
+		table[id] = uint8(countryID)
+	}
+	db.countryTable = table
+	return nil
+}
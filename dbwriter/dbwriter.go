@@ -0,0 +1,444 @@
+// Package dbwriter encodes Sypex Geo databases in the v2.2 format sxgo reads:
+// custom databases, and the mini City database and round-trip tests of sxgo.
+// The header fields (format version, type, charset, index sizes, ID length)
+// and the record pack formats are configurable, defaulting to those of the
+// official databases. Strings are written as given, in the charset the
+// header declares.
+package dbwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/idanyas/sxgo"
+)
+
+// Record formats of the country, region and city records of the official
+// City databases, used when the Database leaves its formats empty.
+const (
+	CountryFormat = "T:id/c2:iso/n2:lat/n2:lon/b:name_ru/b:name_en"
+	RegionFormat  = "S:country_seek/M:id/b:name_ru/b:name_en/c7:iso"
+	CityFormat    = "M:region_seek/T:country_id/M:id/N5:lat/N5:lon/b:name_ru/b:name_en"
+)
+
+// Header type and charset bytes (see sxgo.DBType and sxgo.Charset).
+const (
+	TypeCountry = 1
+	TypeCity    = 4
+
+	CharsetUTF8   = 0
+	CharsetCP1251 = 2
+)
+
+// DefaultVersion is the format version written when Database.Version is
+// zero: 2.2, that of the official databases.
+const DefaultVersion = 22
+
+// DefaultByteIndexLen is the first-byte index length used when
+// Database.ByteIndexLen is zero: first bytes 224 and up (multicast and
+// reserved space) have no blocks.
+const DefaultByteIndexLen = 224
+
+// DefaultRangeBlocks is the blocks per main index entry used when
+// Database.RangeBlocks is zero.
+const DefaultRangeBlocks = 8
+
+// Country is a country record of a City database, or the country a block of a
+// Country database stores the ID of.
+type Country struct {
+	ID             uint8
+	ISO            string
+	Lat, Lon       float64 // Stored with 2 decimals by CountryFormat.
+	NameRU, NameEN string
+	Extra          map[string]any // Fields of a custom format beyond these.
+}
+
+// Region is a region record.
+type Region struct {
+	ID             uint32
+	Country        *Country // Record the region points to; nil for none.
+	ISO            string   // ISO 3166-2 code, up to 7 bytes by RegionFormat.
+	NameRU, NameEN string
+	Extra          map[string]any // Fields of a custom format beyond these.
+}
+
+// City is a city record.
+type City struct {
+	ID             uint32
+	Region         *Region // nil for cities without a region.
+	CountryID      uint8
+	Lat, Lon       float64 // Stored with 5 decimals by CityFormat.
+	NameRU, NameEN string
+	Extra          map[string]any // Fields of a custom format beyond these.
+}
+
+// Block starts a range of addresses: it covers Start up to the start of the
+// next block of the same first byte, and the addresses of that first byte
+// below its first block belong to the block before them, as sxgo resolves
+// them.
+type Block struct {
+	Start   uint32
+	City    *City    // City the range resolves to, or
+	Country *Country // the country, if City is nil; both nil for ID 0 (not found).
+}
+
+// Database describes the contents of a database. In City databases, blocks
+// store seeks of records and seek 0 means none, so the country records and
+// the regions block each begin with an empty record that nothing points to.
+//
+// Records are written in the pack formats of the database, field by field
+// by name: "id", "iso", "lat", "lon", "name_ru" and "name_en" of every
+// record, "country_seek" of regions and "region_seek" and "country_id" of
+// cities come from the record, other names from its Extra map (numbers for
+// numeric fields, strings for c and b fields), and fields without a value
+// are written as zero. sxgo reads the standard fields by these names, so
+// custom formats may reorder, narrow, widen or drop them and add others.
+type Database struct {
+	Version      uint8     // Header format version; DefaultVersion if zero.
+	Type         uint8     // TypeCountry or a City type.
+	Charset      uint8     // Header charset byte; strings are written as given.
+	Created      time.Time // Header timestamp.
+	ByteIndexLen uint8     // DefaultByteIndexLen if zero.
+	RangeBlocks  uint16    // DefaultRangeBlocks if zero.
+	IDLen        uint8     // Bytes per block ID, 1 to 4; 1 for Country and 3 for City databases if zero.
+
+	// Pack formats of the records of City databases; CountryFormat,
+	// RegionFormat and CityFormat if empty. Country databases have none.
+	CountryFormat, RegionFormat, CityFormat string
+
+	Countries []*Country // Records of City databases, in storage order.
+	Regions   []*Region  // In storage order.
+	Cities    []*City    // In storage order.
+	Blocks    []Block    // Sorted by Start, without duplicates.
+}
+
+// Encode returns the database file. It fails for invalid pack formats,
+// values that do not fit their fields, unsorted blocks, blocks past the
+// first-byte index and records referenced but not listed.
+func (d *Database) Encode() ([]byte, error) {
+	version := d.Version
+	if version == 0 {
+		version = DefaultVersion
+	}
+	byteIndexLen := uint32(d.ByteIndexLen)
+	if byteIndexLen == 0 {
+		byteIndexLen = DefaultByteIndexLen
+	}
+	rangeBlocks := uint32(d.RangeBlocks)
+	if rangeBlocks == 0 {
+		rangeBlocks = DefaultRangeBlocks
+	}
+	if len(d.Blocks) == 0 {
+		return nil, errors.New("dbwriter: no blocks")
+	}
+	city := d.Type != TypeCountry
+	idLen := int(d.IDLen)
+	switch {
+	case idLen > 4:
+		return nil, fmt.Errorf("dbwriter: ID length %d is not 1 to 4", idLen)
+	case idLen == 0 && city:
+		idLen = 3
+	case idLen == 0:
+		idLen = 1
+	}
+	formats := [3]string{orDefault(d.CountryFormat, CountryFormat), orDefault(d.RegionFormat, RegionFormat), orDefault(d.CityFormat, CityFormat)}
+	if city {
+		for _, f := range formats {
+			if err := sxgo.ValidatePackFormat(f); err != nil {
+				return nil, fmt.Errorf("dbwriter: %w", err)
+			}
+		}
+	}
+
+	// Records, and the seeks blocks and records point to
+	var countries, regions, cities []byte
+	countrySeek := make(map[*Country]uint32)
+	regionSeek := make(map[*Region]uint32)
+	citySeek := make(map[*City]uint32)
+	var maxCountry, maxRegion, maxCity int
+	if city {
+		var err error
+		if countries, err = appendRecord(nil, formats[0], nil); err != nil { // Seek 0
+			return nil, err
+		}
+		for _, c := range d.Countries {
+			countrySeek[c] = uint32(len(countries))
+			n := len(countries)
+			if countries, err = appendRecord(countries, formats[0], countryFields(c)); err != nil {
+				return nil, fmt.Errorf("dbwriter: country %d: %w", c.ID, err)
+			}
+			maxCountry = max(maxCountry, len(countries)-n)
+		}
+		if regions, err = appendRecord(nil, formats[1], nil); err != nil {
+			return nil, err
+		}
+		for _, r := range d.Regions {
+			seek, ok := countrySeek[r.Country]
+			if !ok && r.Country != nil {
+				return nil, fmt.Errorf("dbwriter: region %d points to an unlisted country", r.ID)
+			}
+			regionSeek[r] = uint32(len(regions))
+			n := len(regions)
+			if regions, err = appendRecord(regions, formats[1], regionFields(r, seek)); err != nil {
+				return nil, fmt.Errorf("dbwriter: region %d: %w", r.ID, err)
+			}
+			maxRegion = max(maxRegion, len(regions)-n)
+		}
+		cities = bytes.Clone(countries) // City records follow the country records
+		for _, c := range d.Cities {
+			seek, ok := regionSeek[c.Region]
+			if !ok && c.Region != nil {
+				return nil, fmt.Errorf("dbwriter: city %d points to an unlisted region", c.ID)
+			}
+			citySeek[c] = uint32(len(cities))
+			n := len(cities)
+			if cities, err = appendRecord(cities, formats[2], cityFields(c, seek)); err != nil {
+				return nil, fmt.Errorf("dbwriter: city %d: %w", c.ID, err)
+			}
+			maxCity = max(maxCity, len(cities)-n)
+		}
+	}
+
+	// Blocks and the indexes over them
+	blocks := make([]byte, 0, len(d.Blocks)*(3+idLen))
+	byteIndex := make([]uint32, byteIndexLen)
+	for i, b := range d.Blocks {
+		if i > 0 && b.Start <= d.Blocks[i-1].Start {
+			return nil, fmt.Errorf("dbwriter: block %d (%d) does not follow block %d (%d)", i, b.Start, i-1, d.Blocks[i-1].Start)
+		}
+		if b.Start>>24 >= byteIndexLen {
+			return nil, fmt.Errorf("dbwriter: block %d starts past the first-byte index", i)
+		}
+		var id uint32
+		var ok bool
+		switch {
+		case b.City != nil && city:
+			id, ok = citySeek[b.City]
+		case b.City != nil:
+			return nil, fmt.Errorf("dbwriter: block %d locates a city in a Country database", i)
+		case b.Country != nil && city:
+			id, ok = countrySeek[b.Country]
+		case b.Country != nil:
+			id, ok = uint32(b.Country.ID), true
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("dbwriter: block %d points to an unlisted record", i)
+		}
+		if uint64(id)>>(8*idLen) != 0 {
+			return nil, fmt.Errorf("dbwriter: block %d ID %d does not fit %d bytes", i, id, idLen)
+		}
+		var idBytes [4]byte
+		binary.BigEndian.PutUint32(idBytes[:], id)
+		blocks = append(blocks, byte(b.Start>>16), byte(b.Start>>8), byte(b.Start))
+		blocks = append(blocks, idBytes[4-idLen:]...)
+		for f := b.Start >> 24; f < byteIndexLen; f++ {
+			byteIndex[f]++ // Entry f counts the blocks of first bytes up to f
+		}
+	}
+	// Entry p of the main index is the start of the last block of partition p
+	var mainIndex []uint32
+	for p := uint32(0); (p+1)*rangeBlocks <= uint32(len(d.Blocks)); p++ {
+		mainIndex = append(mainIndex, d.Blocks[(p+1)*rangeBlocks-1].Start)
+	}
+	if len(mainIndex) == 0 {
+		mainIndex = []uint32{d.Blocks[len(d.Blocks)-1].Start}
+	}
+
+	var pack []byte
+	if city {
+		pack = []byte(strings.Join(formats[:], "\x00"))
+	}
+	// Header fields are 16 bits wide; seeks are checked by their fields
+	for _, v := range []int{len(mainIndex), len(pack), maxCountry, maxRegion, maxCity} {
+		if v > math.MaxUint16 {
+			return nil, errors.New("dbwriter: database too large")
+		}
+	}
+
+	out := []byte("SxG")
+	out = append(out, version)
+	out = binary.BigEndian.AppendUint32(out, uint32(d.Created.Unix()))
+	out = append(out, d.Type, d.Charset, byte(byteIndexLen))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(mainIndex)))
+	out = binary.BigEndian.AppendUint16(out, uint16(rangeBlocks))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(d.Blocks)))
+	out = append(out, byte(idLen))
+	out = binary.BigEndian.AppendUint16(out, uint16(maxRegion))
+	out = binary.BigEndian.AppendUint16(out, uint16(maxCity))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(regions)))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(cities)))
+	out = binary.BigEndian.AppendUint16(out, uint16(maxCountry))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(countries)))
+	out = binary.BigEndian.AppendUint16(out, uint16(len(pack)))
+	out = append(out, pack...)
+	for _, v := range byteIndex {
+		out = binary.BigEndian.AppendUint32(out, v)
+	}
+	for _, v := range mainIndex {
+		out = binary.BigEndian.AppendUint32(out, v)
+	}
+	out = append(out, blocks...)
+	out = append(out, regions...)
+	return append(out, cities...), nil
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// countryFields returns the field values of c by name.
+func countryFields(c *Country) map[string]any {
+	return withExtra(c.Extra, map[string]any{
+		"id": c.ID, "iso": c.ISO, "lat": c.Lat, "lon": c.Lon, "name_ru": c.NameRU, "name_en": c.NameEN,
+	})
+}
+
+// regionFields returns the field values of r, pointing to the country at
+// seek, by name.
+func regionFields(r *Region, countrySeek uint32) map[string]any {
+	return withExtra(r.Extra, map[string]any{
+		"country_seek": countrySeek, "id": r.ID, "iso": r.ISO, "name_ru": r.NameRU, "name_en": r.NameEN,
+	})
+}
+
+// cityFields returns the field values of c, pointing to the region at seek,
+// by name.
+func cityFields(c *City, regionSeek uint32) map[string]any {
+	return withExtra(c.Extra, map[string]any{
+		"region_seek": regionSeek, "country_id": c.CountryID, "id": c.ID,
+		"lat": c.Lat, "lon": c.Lon, "name_ru": c.NameRU, "name_en": c.NameEN,
+	})
+}
+
+// withExtra adds the extra values to fields, which take precedence.
+func withExtra(extra, fields map[string]any) map[string]any {
+	for name, v := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = v
+		}
+	}
+	return fields
+}
+
+// appendRecord appends the record of the given field values in format, a
+// valid pack format. Missing values are written as zero.
+func appendRecord(b []byte, format string, values map[string]any) ([]byte, error) {
+	for _, part := range strings.Split(format, "/") {
+		code, name, _ := strings.Cut(part, ":")
+		var err error
+		if b, err = appendField(b, code, values[name]); err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+	return b, nil
+}
+
+// integerRanges are the bounds of the integer type codes.
+var integerRanges = map[byte][2]int64{
+	sxgo.PackInt8: {math.MinInt8, math.MaxInt8}, sxgo.PackUint8: {0, math.MaxUint8},
+	sxgo.PackInt16: {math.MinInt16, math.MaxInt16}, sxgo.PackUint16: {0, math.MaxUint16},
+	sxgo.PackInt24: {-1 << 23, 1<<23 - 1}, sxgo.PackUint24: {0, 1<<24 - 1},
+	sxgo.PackInt32: {math.MinInt32, math.MaxInt32}, sxgo.PackUint32: {0, math.MaxUint32},
+	sxgo.PackDecimal16: {math.MinInt16, math.MaxInt16}, sxgo.PackDecimal32: {math.MinInt32, math.MaxInt32},
+}
+
+// integerSizes are the little-endian byte lengths of the integer type codes.
+var integerSizes = map[byte]int{
+	sxgo.PackInt8: 1, sxgo.PackUint8: 1, sxgo.PackInt16: 2, sxgo.PackUint16: 2,
+	sxgo.PackInt24: 3, sxgo.PackUint24: 3, sxgo.PackInt32: 4, sxgo.PackUint32: 4,
+	sxgo.PackDecimal16: 2, sxgo.PackDecimal32: 4,
+}
+
+// appendField appends v in the type code (with its suffix) code.
+func appendField(b []byte, code string, v any) ([]byte, error) {
+	c, suffix := code[0], code[1:]
+	switch c {
+	case sxgo.PackFixedString, sxgo.PackString:
+		s, ok := v.(string)
+		if !ok && v != nil {
+			return nil, fmt.Errorf("%T is not a string", v)
+		}
+		if c == sxgo.PackString {
+			if strings.IndexByte(s, 0) >= 0 {
+				return nil, errors.New("string contains a null byte")
+			}
+			return append(append(b, s...), 0), nil
+		}
+		n, _ := strconv.Atoi(suffix)
+		return appendFixed(b, s, n), nil
+	}
+
+	f, ok := number(v)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a number", v)
+	}
+	switch c {
+	case sxgo.PackFloat32:
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f))), nil
+	case sxgo.PackFloat64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+	case sxgo.PackDecimal16, sxgo.PackDecimal32:
+		scale, _ := strconv.Atoi(suffix) // Scale 0 if empty
+		f *= math.Pow10(scale)
+	}
+	i := int64(math.Round(f))
+	if r := integerRanges[c]; i < r[0] || i > r[1] {
+		return nil, fmt.Errorf("%v does not fit %q", v, code)
+	}
+	for n := range integerSizes[c] {
+		b = append(b, byte(uint64(i)>>(8*n)))
+	}
+	return b, nil
+}
+
+// number converts a numeric field value; nil is zero.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case nil:
+		return 0, true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// appendFixed appends s null-padded (or cut) to n bytes.
+func appendFixed(b []byte, s string, n int) []byte {
+	f := make([]byte, n)
+	copy(f, s)
+	return append(b, f...)
+}
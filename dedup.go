@@ -0,0 +1,359 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nearDuplicateKm is the distance within which cities of one country with the
+// same normalized name are reported as near-duplicates.
+const nearDuplicateKm = 25
+
+// CityRecord is a city record as stored in the database.
+type CityRecord struct {
+	ID        uint32  `json:"id"`
+	Seek      uint32  `json:"seek"` // Offset in the cities block, as returned by LookupID.
+	CountryID uint8   `json:"country_id"`
+	RegionID  uint32  `json:"region_id"` // 0 if the city has no region.
+	NameRU    string  `json:"name_ru,omitempty"`
+	NameEN    string  `json:"name_en,omitempty"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+}
+
+// CityGroup is a set of city records taken to be the same place.
+// Records are sorted by ID; the first one is the canonical record.
+type CityGroup struct {
+	Records       []CityRecord `json:"records"`
+	MaxDistanceKm float64      `json:"max_distance_km"` // Largest distance between two records.
+}
+
+// DedupReport is the result of FindDuplicateCities.
+type DedupReport struct {
+	Cities int `json:"cities"` // City records examined.
+
+	// Duplicates are cities with identical names in the same region.
+	Duplicates []CityGroup `json:"duplicates"`
+	// NearDuplicates are cities of one country whose names match after
+	// normalization (case, punctuation, ё/е) and which share a region or lie
+	// within 25 km of each other, when they are not all exact duplicates.
+	NearDuplicates []CityGroup `json:"near_duplicates"`
+
+	// Merge maps the ID of every non-canonical city in a group to the ID of
+	// the group's canonical record.
+	Merge map[uint32]uint32 `json:"merge"`
+}
+
+// FindDuplicateCities scans all city records for duplicates and near-duplicates
+// and returns a report with an ID merge mapping. It reads the whole cities
+// block and is intended as a QA tool; apply the mapping to lookups with
+// DedupReport.Middleware.
+func (s *SxGeo) FindDuplicateCities() (*DedupReport, error) {
+	db := s.acquire()
+	defer db.unpin()
+	cities, err := s.cityRecords(db)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to find duplicate cities: %w", err)
+	}
+
+	rep := &DedupReport{
+		Cities: len(cities),
+		Merge:  make(map[uint32]uint32),
+	}
+
+	// Exact duplicates: same region and names
+	type exactKey struct {
+		region         uint32
+		nameRU, nameEN string
+	}
+	exact := make(map[exactKey][]int)
+	exactOf := make([]exactKey, len(cities))
+	for i, c := range cities {
+		k := exactKey{c.RegionID, c.NameRU, c.NameEN}
+		exact[k] = append(exact[k], i)
+		exactOf[i] = k
+	}
+	for _, idx := range exact {
+		if g, ok := newCityGroup(cities, idx); ok {
+			rep.Duplicates = append(rep.Duplicates, g)
+			rep.addMerge(g)
+		}
+	}
+
+	// Near duplicates: union cities of a country sharing a normalized name
+	// that are in the same region or close enough
+	type nearKey struct {
+		country uint8
+		name    string
+	}
+	buckets := make(map[nearKey][]int)
+	for i, c := range cities {
+		if name := normalizeCityName(c); name != "" {
+			k := nearKey{c.CountryID, name}
+			buckets[k] = append(buckets[k], i)
+		}
+	}
+	parent := make([]int, len(cities))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, idx := range buckets {
+		for a := 0; a < len(idx); a++ {
+			for b := a + 1; b < len(idx); b++ {
+				ca, cb := cities[idx[a]], cities[idx[b]]
+				if ca.RegionID == cb.RegionID || distanceKm(ca.Lat, ca.Lon, cb.Lat, cb.Lon) <= nearDuplicateKm {
+					parent[find(idx[a])] = find(idx[b])
+				}
+			}
+		}
+	}
+	components := make(map[int][]int)
+	for i := range cities {
+		components[find(i)] = append(components[find(i)], i)
+	}
+	for _, idx := range components {
+		distinct := make(map[exactKey]bool)
+		for _, i := range idx {
+			distinct[exactOf[i]] = true
+		}
+		if len(distinct) < 2 {
+			continue // Only exact duplicates, reported above
+		}
+		if g, ok := newCityGroup(cities, idx); ok {
+			rep.NearDuplicates = append(rep.NearDuplicates, g)
+			rep.addMerge(g) // Near groups contain their exact groups and win
+		}
+	}
+
+	sortCityGroups(rep.Duplicates)
+	sortCityGroups(rep.NearDuplicates)
+	return rep, nil
+}
+
+// Middleware returns a LookupMiddleware replacing merged cities (see Merge)
+// in lookup results by their canonical record: ID, names and coordinates.
+// It works on reports decoded from JSON as well.
+func (r *DedupReport) Middleware() LookupMiddleware {
+	canonical := make(map[uint32]CityRecord)
+	for _, groups := range [][]CityGroup{r.Duplicates, r.NearDuplicates} {
+		for _, g := range groups {
+			if len(g.Records) > 0 {
+				canonical[g.Records[0].ID] = g.Records[0]
+			}
+		}
+	}
+	return func(next LookupFunc) LookupFunc {
+		return func(ip string) (*LocationInfo, error) {
+			info, err := next(ip)
+			if err != nil || info == nil || info.City == nil {
+				return info, err
+			}
+			to, ok := r.Merge[info.City.ID]
+			if !ok {
+				return info, nil
+			}
+			c, ok := canonical[to]
+			if !ok {
+				return info, nil
+			}
+			info = info.clone() // The result may be shared, e.g. by a cache
+			info.City.ID = c.ID
+			info.City.NameRU, info.City.NameEN = c.NameRU, c.NameEN
+			info.City.Lat, info.City.Lon = c.Lat, c.Lon
+			return info, nil
+		}
+	}
+}
+
+// addMerge maps the group's records to its canonical record.
+// Internal function.
+func (r *DedupReport) addMerge(g CityGroup) {
+	canon := g.Records[0]
+	for _, c := range g.Records[1:] {
+		if c.ID != canon.ID {
+			r.Merge[c.ID] = canon.ID
+		}
+	}
+}
+
+// newCityGroup builds a group from cities[idx], sorted by ID. It reports false
+// unless the group holds at least two distinct city IDs.
+// Internal function.
+func newCityGroup(cities []CityRecord, idx []int) (CityGroup, bool) {
+	if len(idx) < 2 {
+		return CityGroup{}, false
+	}
+	g := CityGroup{Records: make([]CityRecord, 0, len(idx))}
+	ids := make(map[uint32]bool)
+	for _, i := range idx {
+		g.Records = append(g.Records, cities[i])
+		ids[cities[i].ID] = true
+	}
+	if len(ids) < 2 {
+		return CityGroup{}, false
+	}
+	sort.Slice(g.Records, func(a, b int) bool {
+		if g.Records[a].ID != g.Records[b].ID {
+			return g.Records[a].ID < g.Records[b].ID
+		}
+		return g.Records[a].Seek < g.Records[b].Seek
+	})
+	for a := range g.Records {
+		for b := a + 1; b < len(g.Records); b++ {
+			ra, rb := g.Records[a], g.Records[b]
+			g.MaxDistanceKm = math.Max(g.MaxDistanceKm, distanceKm(ra.Lat, ra.Lon, rb.Lat, rb.Lon))
+		}
+	}
+	return g, true
+}
+
+// sortCityGroups orders groups by canonical ID for stable reports.
+// Internal function.
+func sortCityGroups(groups []CityGroup) {
+	sort.Slice(groups, func(a, b int) bool {
+		return groups[a].Records[0].ID < groups[b].Records[0].ID
+	})
+}
+
+// normalizeCityName returns the English (or else Russian) name lowercased,
+// with ё folded to е and everything but letters and digits removed.
+// Internal function.
+func normalizeCityName(c CityRecord) string {
+	name := c.NameEN
+	if name == "" {
+		name = c.NameRU
+	}
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		switch {
+		case r == 'ё':
+			return 'е'
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// distanceKm returns the great-circle distance between two points in kilometres.
+// Internal function.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// cityRecords returns every city record of the cities block, in storage order.
+// Internal function.
+func (s *SxGeo) cityRecords(db *dbState) ([]CityRecord, error) {
+	var cities []CityRecord
+	regionIDs := make(map[uint32]uint32) // region seek -> region ID
+	err := s.walkCities(db, 0, func(seek uint32, rec map[string]interface{}) error {
+		c, err := s.cityRecord(db, seek, rec, regionIDs)
+		if err != nil {
+			return err
+		}
+		cities = append(cities, c)
+		return nil
+	})
+	return cities, err
+}
+
+// cityRecord builds the CityRecord of the city at seek with unpacked fields
+// rec, looking region IDs up in regionIDs (region seek -> ID) and adding
+// those it reads.
+// Internal function.
+func (s *SxGeo) cityRecord(db *dbState, seek uint32, rec map[string]interface{}, regionIDs map[uint32]uint32) (CityRecord, error) {
+	regionSeek := getUint32(rec, "region_seek")
+	regionID, ok := regionIDs[regionSeek]
+	if !ok && regionSeek > 0 {
+		region, err := s.readData(db, regionSeek, db.header.maxRegion, 1)
+		if err != nil {
+			return CityRecord{}, fmt.Errorf("failed to read region at seek %d: %w", regionSeek, err)
+		}
+		regionID = getUint32(region, "id")
+		regionIDs[regionSeek] = regionID
+	}
+	return CityRecord{
+		ID:        getUint32(rec, "id"),
+		Seek:      seek,
+		CountryID: getUint8(rec, "country_id"),
+		RegionID:  regionID,
+		NameRU:    getString(rec, "name_ru"),
+		NameEN:    getString(rec, "name_en"),
+		Lat:       getFloat(rec, "lat"),
+		Lon:       getFloat(rec, "lon"),
+	}, nil
+}
+
+// cityChunkSize is how much of the cities block walkCities reads at a time in
+// ModeFile.
+const cityChunkSize = 64 << 10
+
+// walkCities calls fn for every city record of the cities block in storage
+// order, from seek from on, with its seek and unpacked fields. Country
+// records at the start of the block are skipped. In ModeFile the block is
+// read a chunk at a time. Returning an error from fn stops the walk.
+// Internal function.
+func (s *SxGeo) walkCities(db *dbState, from uint32, fn func(seek uint32, rec map[string]interface{}) error) error {
+	if !db.layout.HasCities {
+		return errors.New("not a City database")
+	}
+	if s.memoryMode && db.citiesData == nil && s.countryOnly {
+		return ErrCountryOnly // New did not load the cities block
+	}
+
+	size := db.header.citySize
+	maxCity := uint32(db.header.maxCity)
+	var chunk []byte // Cities block bytes [chunkOff, chunkOff+len(chunk))
+	var chunkOff uint32
+	if s.memoryMode {
+		chunk, size = db.citiesData, min(size, uint32(len(db.citiesData)))
+	}
+	for off := max(from, db.cityRecordsStart()); off < size; {
+		end := min(off+maxCity, size)
+		if !s.memoryMode && (off < chunkOff || end > chunkOff+uint32(len(chunk))) {
+			n := min(max(cityChunkSize, maxCity), size-off)
+			if cap(chunk) < int(n) {
+				chunk = make([]byte, n)
+			}
+			read, err := s.readAt(db, chunk[:n], db.citiesBegin+int64(off))
+			if err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("failed to read cities block at seek %d: %w", off, err)
+			}
+			chunk, chunkOff = chunk[:read], off
+			if uint32(read) < n {
+				size = off + uint32(read) // The file ends early
+				end = min(end, size)
+			}
+		}
+		rec, n, err := unpackLen(db.packFormats[2], chunk[off-chunkOff:end-chunkOff])
+		if err != nil {
+			return fmt.Errorf("failed to unpack city at seek %d: %w", off, err)
+		}
+		if n == 0 {
+			break
+		}
+		if err := fn(off, rec); err != nil {
+			return err
+		}
+		off += uint32(n)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sxgo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Environment variables read by Default.
+const (
+	EnvDBPath = "SXGO_DB_PATH" // Path of the database file.
+	EnvMode   = "SXGO_MODE"    // "file" (default), "memory", "mmap", or a numeric mode such as "3".
+)
+
+// defaultInstance is the process-wide instance managed by Default and MustLoad.
+var defaultInstance struct {
+	once sync.Once
+	geo  *SxGeo
+	err  error
+}
+
+// Default returns the process-wide instance, opening it on first use from the
+// database at $SXGO_DB_PATH in the mode named by $SXGO_MODE. It is safe for
+// concurrent use; the first call of Default or MustLoad decides the instance,
+// and an error opening it is returned by every later call as well.
+// The instance is never closed. It suits scripts and small services; larger
+// programs should call New and pass the instance around.
+func Default() (*SxGeo, error) {
+	defaultInstance.once.Do(func() {
+		path := os.Getenv(EnvDBPath)
+		if path == "" {
+			defaultInstance.err = fmt.Errorf("sxgo: %s is not set", EnvDBPath)
+			return
+		}
+		mode, err := parseMode(os.Getenv(EnvMode))
+		if err != nil {
+			defaultInstance.err = err
+			return
+		}
+		defaultInstance.geo, defaultInstance.err = New(path, mode)
+	})
+	return defaultInstance.geo, defaultInstance.err
+}
+
+// MustLoad opens path in mode as the process-wide instance returned by
+// Default, and panics if it cannot be opened. If the instance already exists,
+// it is returned as is and path and mode are ignored.
+func MustLoad(path string, mode uint) *SxGeo {
+	defaultInstance.once.Do(func() {
+		defaultInstance.geo, defaultInstance.err = New(path, mode)
+	})
+	if defaultInstance.err != nil {
+		panic(defaultInstance.err)
+	}
+	return defaultInstance.geo
+}
+
+// parseMode parses the value of EnvMode. An empty value means ModeFile.
+// Internal function.
+func parseMode(v string) (uint, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "file":
+		return ModeFile, nil
+	case "memory":
+		return ModeMemory, nil
+	case "mmap":
+		return ModeMMap, nil
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf(`sxgo: invalid %s %q: want "file", "memory", "mmap" or a number`, EnvMode, v)
+	}
+	return uint(n), nil
+}
@@ -0,0 +1,55 @@
+package sxgo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Values of LocationInfo.DerivedFrom.
+const (
+	Derived6to4   = "6to4"   // IPv4 taken from a 2002::/16 address (RFC 3056)
+	DerivedTeredo = "teredo" // IPv4 taken from a 2001::/32 address (RFC 4380)
+)
+
+// WithIPv6Derivation enables lookups of 6to4 (2002::/16) and Teredo (2001::/32)
+// IPv6 addresses by extracting the embedded IPv4 address. City results for such
+// addresses have LocationInfo.DerivedFrom set. Other IPv6 addresses remain invalid.
+// IPv4-mapped addresses (::ffff:a.b.c.d) are always accepted.
+func WithIPv6Derivation() Option {
+	return func(s *SxGeo) {
+		s.deriveIPv6 = true
+	}
+}
+
+// parseIP converts ipStr to its uint32 form, deriving an IPv4 address from
+// 6to4/Teredo addresses when enabled. derived names the mechanism used, if any.
+// Internal function.
+func (s *SxGeo) parseIP(ipStr string) (ipNum uint32, derived string, err error) {
+	if ipNum, ok := ip2long(ipStr); ok {
+		return ipNum, "", nil
+	}
+	if s.deriveIPv6 {
+		if ipNum, derived, ok := deriveIPv4(ipStr); ok {
+			return ipNum, derived, nil
+		}
+	}
+	return 0, "", fmt.Errorf("%w: %q", ErrInvalidIP, ipStr)
+}
+
+// deriveIPv4 extracts the IPv4 address embedded in a 6to4 or Teredo address.
+// Internal function.
+func deriveIPv4(ipStr string) (uint32, string, bool) {
+	addr, err := netip.ParseAddr(ipStr)
+	if err != nil || !addr.Is6() {
+		return 0, "", false
+	}
+	b := addr.As16()
+	switch {
+	case b[0] == 0x20 && b[1] == 0x02: // 2002:WWXX:YYZZ::/48
+		return binary.BigEndian.Uint32(b[2:6]), Derived6to4, true
+	case b[0] == 0x20 && b[1] == 0x01 && b[2] == 0 && b[3] == 0: // Client address is stored inverted
+		return ^binary.BigEndian.Uint32(b[12:16]), DerivedTeredo, true
+	}
+	return 0, "", false
+}
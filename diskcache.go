@@ -0,0 +1,341 @@
+package sxgo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DiskCacheStats describes the on-disk result cache (see WithDiskCache).
+type DiskCacheStats struct {
+	Path     string `json:"path"`
+	Entries  int    `json:"entries"`   // Ranges cached.
+	Bytes    int64  `json:"bytes"`     // Size of the cache file.
+	MaxBytes int64  `json:"max_bytes"` // Configured bound.
+	Hits     uint64 `json:"hits"`      // Lookups answered from the cache.
+	Misses   uint64 `json:"misses"`    // Lookups that searched the database.
+	Resets   uint64 `json:"resets"`    // Times the cache was emptied to stay within MaxBytes.
+}
+
+// diskCacheMagic starts every cache file; a SHA-256 of the database header
+// and pack formats follows, so a cache is only reused with the database it
+// was filled from. Files of other versions start over.
+const diskCacheMagic = "SXGOCACHE2\n"
+
+// diskEntryHeaderLen is the size of an entry header: first and last address,
+// depth and payload length.
+const diskEntryHeaderLen = 4 + 4 + 1 + 4
+
+// diskSpan is a cached range and the location of its entry in the file.
+// This struct is internal.
+type diskSpan struct {
+	first, last uint32
+	off         int64 // Payload offset
+	n           uint32
+}
+
+// diskRecord is the stored form of a parsed result, including the unexported
+// seeks later steps (centroids, remapping) rely on. It is gob-encoded, which
+// keeps names in single-byte charsets such as cp1251 byte for byte.
+// This struct is internal.
+type diskRecord struct {
+	City        *City
+	Region      *Region
+	Country     *Country
+	RegionSeek  uint32
+	CountryID   uint8
+	CountrySeek uint32
+}
+
+// diskCache is a file of ranges and their parsed results, indexed in memory.
+// This struct is internal.
+type diskCache struct {
+	path     string
+	maxBytes int64
+
+	mu     sync.RWMutex
+	gen    uint64 // dbState.gen of the database results are cached for
+	f      *os.File
+	size   int64
+	spans  [depthFull + 1][]diskSpan // Per recordDepth, sorted and disjoint
+	hits   uint64
+	misses uint64
+	resets uint64
+}
+
+// WithDiskCache keeps lookup results in the file at path, keyed by the
+// database range they come from, so repeated lookups in ModeFile skip the
+// block search and record reads, also after a restart. Only the range index
+// is held in memory. The file is tied to the database it was filled from and
+// starts over when another one is loaded, or when it would grow past maxBytes.
+// New fails if the file cannot be opened. Patched ranges (ApplyPatch) are
+// never cached.
+func WithDiskCache(path string, maxBytes int64) Option {
+	return func(s *SxGeo) {
+		if path == "" || maxBytes <= 0 {
+			s.disk = nil
+			return
+		}
+		s.disk = &diskCache{path: path, maxBytes: maxBytes}
+	}
+}
+
+// openDiskCache opens the cache file configured by WithDiskCache and loads its
+// index once New has read the header.
+// Internal function.
+func (s *SxGeo) openDiskCache() error {
+	db := s.db()
+	c := s.disk
+	f, err := os.OpenFile(c.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	c.f = f
+	sum := sha256.Sum256(db.rawHead)
+	if err := c.load(sum[:]); err != nil {
+		f.Close()
+		return err
+	}
+	return nil
+}
+
+// load reads the index of a cache file with the given identity, or resets the
+// file if it belongs to another database, is not a cache file or exceeds
+// maxBytes. A truncated last entry (e.g. after a crash) is dropped.
+// Internal function.
+func (c *diskCache) load(id []byte) error {
+	head := append([]byte(diskCacheMagic), id...)
+	r := bufio.NewReader(io.NewSectionReader(c.f, 0, 1<<62))
+	got := make([]byte, len(head))
+	if _, err := io.ReadFull(r, got); err != nil || !bytes.Equal(got, head) {
+		return c.truncate(head)
+	}
+
+	off := int64(len(head))
+	var hdr [diskEntryHeaderLen]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			break
+		}
+		first, last := binary.BigEndian.Uint32(hdr[0:]), binary.BigEndian.Uint32(hdr[4:])
+		depth, n := hdr[8], binary.BigEndian.Uint32(hdr[9:])
+		if _, err := r.Discard(int(n)); err != nil || int(depth) >= len(c.spans) || first > last {
+			break
+		}
+		c.spans[depth] = append(c.spans[depth], diskSpan{first, last, off + diskEntryHeaderLen, n})
+		off += diskEntryHeaderLen + int64(n)
+	}
+	for d := range c.spans {
+		sort.Slice(c.spans[d], func(i, j int) bool { return c.spans[d][i].first < c.spans[d][j].first })
+	}
+	if off > c.maxBytes {
+		return c.truncate(head) // Filled under a larger bound
+	}
+	c.size = off
+	return c.f.Truncate(off)
+}
+
+// truncate empties the cache file, leaving only head, and the index.
+// Internal function.
+func (c *diskCache) truncate(head []byte) error {
+	c.spans = [len(c.spans)][]diskSpan{}
+	if err := c.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := c.f.WriteAt(head, 0); err != nil {
+		return err
+	}
+	c.size = int64(len(head))
+	return nil
+}
+
+// get returns the cached result for ipNum at depth, if any, for the database
+// numbered gen. The entry is read under the lock, as put and reset may start
+// the file over and reuse its offsets.
+// Internal function.
+func (c *diskCache) get(gen uint64, ipNum uint32, depth recordDepth) (*LocationInfo, bool) {
+	var info *LocationInfo
+	c.mu.RLock()
+	spans := c.spans[depth]
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].last >= ipNum })
+	if i < len(spans) && spans[i].first <= ipNum && c.f != nil && gen == c.gen {
+		buf := make([]byte, spans[i].n)
+		if _, err := c.f.ReadAt(buf, spans[i].off); err == nil {
+			info = decodeDiskRecord(buf)
+		}
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	if info != nil {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	c.mu.Unlock()
+	return info, info != nil
+}
+
+// put caches info, read from the database numbered gen, for the span r at
+// depth. Spans overlapping cached ones are clipped; both are parts of the
+// same range. Results of a database Reload replaced are not cached.
+// Internal function.
+func (c *diskCache) put(gen uint64, r ipRange, depth recordDepth, info *LocationInfo) {
+	payload, err := encodeDiskRecord(info)
+	if err != nil {
+		return
+	}
+	entry := int64(diskEntryHeaderLen + len(payload))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil || gen != c.gen {
+		return
+	}
+	if c.size+entry > c.maxBytes {
+		head := make([]byte, len(diskCacheMagic)+sha256.Size)
+		if _, err := c.f.ReadAt(head, 0); err != nil || c.truncate(head) != nil || c.size+entry > c.maxBytes {
+			return
+		}
+		c.resets++
+	}
+
+	spans := c.spans[depth]
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].first > r.first })
+	if i > 0 && spans[i-1].last >= r.first {
+		r.first = spans[i-1].last + 1
+	}
+	if i < len(spans) && spans[i].first <= r.last {
+		r.last = spans[i].first - 1
+	}
+	if r.first > r.last {
+		return // Cached concurrently
+	}
+
+	buf := make([]byte, diskEntryHeaderLen, entry)
+	binary.BigEndian.PutUint32(buf[0:], r.first)
+	binary.BigEndian.PutUint32(buf[4:], r.last)
+	buf[8] = byte(depth)
+	binary.BigEndian.PutUint32(buf[9:], uint32(len(payload)))
+	buf = append(buf, payload...)
+	if _, err := c.f.WriteAt(buf, c.size); err != nil {
+		return
+	}
+	sp := diskSpan{r.first, r.last, c.size + diskEntryHeaderLen, uint32(len(payload))}
+	c.spans[depth] = append(spans[:i], append([]diskSpan{sp}, spans[i:]...)...)
+	c.size += entry
+}
+
+// stats returns a snapshot of the cache counters.
+// Internal function.
+func (c *diskCache) stats() *DiskCacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	st := &DiskCacheStats{
+		Path:     c.path,
+		Bytes:    c.size,
+		MaxBytes: c.maxBytes,
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Resets:   c.resets,
+	}
+	for _, spans := range c.spans {
+		st.Entries += len(spans)
+	}
+	return st
+}
+
+// reset ties the cache to the database Reload loaded, numbered gen and
+// identified by id, starting the file over if it was filled from another one.
+// Internal function.
+func (c *diskCache) reset(id [sha256.Size]byte, gen uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen = gen
+	c.spans = [len(c.spans)][]diskSpan{}
+	if c.f == nil {
+		return nil
+	}
+	return c.load(id[:])
+}
+
+// close closes the cache file.
+// Internal function.
+func (c *diskCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.f == nil {
+		return nil
+	}
+	err := c.f.Close()
+	c.f = nil
+	return err
+}
+
+// encodeDiskRecord serializes a parsed result.
+// Internal function.
+func encodeDiskRecord(info *LocationInfo) ([]byte, error) {
+	rec := diskRecord{City: info.City, Region: info.Region, Country: info.Country}
+	if info.City != nil {
+		rec.RegionSeek, rec.CountryID = info.City.regionSeek, info.City.countryID
+	}
+	if info.Region != nil {
+		rec.CountrySeek = info.Region.countrySeek
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeDiskRecord restores a result stored by encodeDiskRecord, or returns
+// nil if data is not one.
+// Internal function.
+func decodeDiskRecord(data []byte) *LocationInfo {
+	var rec diskRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return nil
+	}
+	info := &LocationInfo{City: rec.City, Region: rec.Region, Country: rec.Country}
+	if info.City != nil {
+		info.City.regionSeek, info.City.countryID = rec.RegionSeek, rec.CountryID
+	}
+	if info.Region != nil {
+		info.Region.countrySeek = rec.CountrySeek
+	}
+	info.setPrecision()
+	return info
+}
+
+// diskGet returns the disk cache result for ipNum at depth from db, if any.
+// Patched addresses are not looked up, as the patch takes precedence.
+// Internal function.
+func (s *SxGeo) diskGet(db *dbState, ipNum uint32, depth recordDepth) (*LocationInfo, bool) {
+	if s.disk == nil {
+		return nil, false
+	}
+	if _, patched := s.overlayRange(ipNum); patched || s.isReservedSpecial(ipNum) {
+		return nil, false
+	}
+	info, ok := s.disk.get(db.gen, ipNum, depth)
+	if ok && s.intern {
+		internLocation(info)
+	}
+	return info, ok
+}
+
+// diskPut caches info, parsed down to depth from db, for the span r returned
+// by lookupSpan. Patched spans are not cached.
+// Internal function.
+func (s *SxGeo) diskPut(db *dbState, r ipRange, depth recordDepth, info *LocationInfo) {
+	if s.disk != nil && info != nil && r.block >= 0 {
+		s.disk.put(db.gen, r, depth, info)
+	}
+}
@@ -0,0 +1,75 @@
+package sxgo_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+func TestDiskCacheResetsDuringLookups(t *testing.T) {
+	path := miniCityPath(t)
+	// A bound one entry fills, so the file starts over all the time
+	geo, err := sxgo.New(path, sxgo.ModeFile, sxgo.WithDiskCache(filepath.Join(t.TempDir(), "cache"), 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	lookups := []struct {
+		ip   string
+		want place
+	}{
+		{"1.0.0.1", losAngeles},
+		{"5.0.0.1", ukraine},
+		{"5.1.0.1", kyiv},
+		{"46.0.128.1", moscow},
+		{"185.0.0.1", germany},
+		{"223.255.255.1", munich},
+	}
+	for i := range 10 { // Ranges of one window alternate between Moscow and Kazan
+		want := moscow
+		if i%2 == 1 {
+			want = kazan
+		}
+		lookups = append(lookups, struct {
+			ip   string
+			want place
+		}{fmt.Sprintf("93.158.%d.1", i), want})
+	}
+
+	for range 2 { // The second lookup is answered from the cache
+		if info, err := geo.GetCityFull("46.0.128.1"); err != nil || placeOf(info) != moscow {
+			t.Fatalf("GetCityFull(46.0.128.1) = %+v, %v", placeOf(info), err)
+		}
+	}
+	if st := geo.Stats().DiskCache; st == nil || st.Hits != 1 {
+		t.Fatalf("disk cache stats = %+v, want a hit", st)
+	}
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range 500 {
+				l := lookups[(g+n)%len(lookups)]
+				info, err := geo.GetCityFull(l.ip)
+				if err != nil {
+					t.Errorf("GetCityFull(%s): %v", l.ip, err)
+					return
+				}
+				if got := placeOf(info); got != l.want {
+					t.Errorf("GetCityFull(%s) = %+v, want %+v", l.ip, got, l.want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	st := geo.Stats().DiskCache
+	if st.Resets == 0 || st.Bytes > st.MaxBytes {
+		t.Errorf("disk cache stats = %+v, want resets within the bound", st)
+	}
+}
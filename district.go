@@ -0,0 +1,49 @@
+package sxgo
+
+// District is a grouping of regions above the region level, such as a
+// federal district of Russia.
+type District struct {
+	Code   string `json:"code"`              // Stable identifier, e.g. "volga".
+	NameRU string `json:"name_ru,omitempty"` // Name in Russian.
+	NameEN string `json:"name_en,omitempty"` // Name in English.
+}
+
+// WithDistricts sets Region.District on GetCityFull results whose region ISO
+// 3166-2 code (e.g. "RU-MOW") is a key of districts. The map is not copied
+// and must not be modified afterwards.
+func WithDistricts(districts map[string]District) Option {
+	return func(s *SxGeo) {
+		s.districts = districts
+	}
+}
+
+// WithRussianDistricts sets Region.District to the federal district (округ)
+// of Russian regions, as listed by RussianFederalDistricts.
+func WithRussianDistricts() Option {
+	return WithDistricts(RussianFederalDistricts())
+}
+
+// RussianFederalDistricts returns the federal districts of Russia keyed by
+// region ISO 3166-2 code, as of the 2018 reassignment of Buryatia and
+// Zabaykalsky Krai to the Far Eastern district. The map is a fresh copy that
+// may be modified, e.g. before passing it to WithDistricts.
+func RussianFederalDistricts() map[string]District {
+	m := make(map[string]District, 85)
+	for _, d := range ruDistricts {
+		for _, iso := range d.regions {
+			m[iso] = d.District
+		}
+	}
+	return m
+}
+
+// attachDistrict sets info.Region.District from the configured districts.
+// Internal function.
+func (s *SxGeo) attachDistrict(info *LocationInfo) {
+	if s.districts == nil || info.Region == nil {
+		return
+	}
+	if d, ok := s.districts[info.Region.ISO]; ok {
+		info.Region.District = &d
+	}
+}
@@ -6,7 +6,7 @@
 // The package supports different operating modes:
 //   - ModeFile: Reads directly from the .dat file on each lookup (lower memory, slower).
 //   - ModeMemory: Loads the entire database into RAM for fast lookups (higher memory).
-//   - ModeBatch: Pre-parses indexes for potentially faster batch processing (used with ModeMemory).
+//   - ModeBatch: Kept for compatibility; indexes are now parsed in every mode.
 //
 // Basic Usage:
 //
@@ -27,4 +27,9 @@
 //	} else {
 //	    fmt.Println("Location not found for IP.")
 //	}
+//
+// Building with the sxgo_minimal tag leaves out the enrichment tables (see
+// package countries, EUVATRates, RussianFederalDistricts and
+// DefaultRegulations) to keep binaries
+// small; lookups are unaffected.
 package sxgo
@@ -0,0 +1,60 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors, to be tested with errors.Is. ErrInvalidIP is always
+// returned (wrapped) for addresses that cannot be parsed. By default lookups
+// report addresses without a location as (nil, nil), "" or 0; with
+// WithNotFoundErrors they return the other errors instead.
+var (
+	// ErrNotFound means the database has no location for the address.
+	ErrNotFound = errors.New("sxgo: address not found")
+	// ErrReservedRange means the address is in a reserved range (0/8, 10/8,
+	// 127/8, first bytes beyond the byte index and WithReservedRanges).
+	ErrReservedRange = errors.New("sxgo: address is in a reserved range")
+	// ErrInvalidIP means the input is not an IPv4 address (or, with
+	// WithIPv6Derivation, an IPv6 address embedding one).
+	ErrInvalidIP = errors.New("sxgo: invalid IPv4 address")
+	// ErrUnsupportedDB means the database cannot answer the lookup, e.g.
+	// GetCity on a Country database.
+	ErrUnsupportedDB = errors.New("sxgo: lookup not supported by the database")
+)
+
+// WithNotFoundErrors makes GetCountry, GetCountryID, GetCity, GetCityFull,
+// GetCityRegion, Get, LookupID, GetLazy and the Addr and IP variants of the
+// lookups (GetCityAddr, ...) return ErrNotFound, ErrReservedRange or
+// ErrUnsupportedDB (wrapped) instead of an empty result with a nil error, so
+// callers can tell the cases apart. Other methods, and the per-input results
+// of planners, are unaffected.
+func WithNotFoundErrors() Option {
+	return func(s *SxGeo) {
+		s.notFoundErrors = true
+	}
+}
+
+// missing returns the error explaining why ip has no result, with
+// WithNotFoundErrors, or nil. city tells whether the lookup needs a City
+// database.
+// Internal function.
+func (s *SxGeo) missing(ip string, city bool) error {
+	if !s.notFoundErrors {
+		return nil
+	}
+	db := s.acquire()
+	defer db.unpin()
+	if city && !db.seekIDs() {
+		return fmt.Errorf("%w: city lookup on a Country database", ErrUnsupportedDB)
+	}
+	if num, _, err := s.parseIP(ip); err == nil && (db.isReservedByte(num>>24) || s.isReservedSpecial(num)) {
+		return fmt.Errorf("%w: %s", ErrReservedRange, ip)
+	}
+	if s.zeroIDErrors {
+		if zerr := s.diagnoseZeroID(db, ip); zerr != nil {
+			return zerr
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, ip)
+}
@@ -28,7 +28,6 @@ func main() {
 	// Choose Mode:
 	// - sxgo.ModeFile   (Low memory, reads from disk)
 	// - sxgo.ModeMemory (Fastest, loads all to RAM)
-	// - Combine with sxgo.ModeBatch for index optimization: sxgo.ModeMemory | sxgo.ModeBatch
 	mode := sxgo.ModeMemory
 	// Use the default package identifier 'sxgo' to access New and ModeMemory
 	geo, err := sxgo.New(dbFile, mode)
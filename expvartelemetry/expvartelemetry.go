@@ -0,0 +1,46 @@
+// Package expvartelemetry publishes sxgo telemetry (see sxgo.WithTelemetry)
+// through the standard expvar package, so it shows up under /debug/vars
+// without any metrics dependency.
+//
+// Counters are published under their metric name. Each duration metric is
+// published as two variables, name_count and name_sum (in seconds), from
+// which averages can be derived.
+package expvartelemetry
+
+import (
+	"expvar"
+	"time"
+)
+
+// Telemetry implements sxgo.Telemetry on an expvar.Map.
+type Telemetry struct {
+	m *expvar.Map
+}
+
+// New publishes a new map named name holding the metrics. Like
+// expvar.Publish, it panics if name is already in use.
+func New(name string) *Telemetry {
+	return &Telemetry{m: expvar.NewMap(name)}
+}
+
+// FromMap returns a Telemetry adding the metrics to m, e.g. a map already
+// published by the program or one not published at all.
+func FromMap(m *expvar.Map) *Telemetry {
+	return &Telemetry{m: m}
+}
+
+// Map returns the map holding the metrics.
+func (t *Telemetry) Map() *expvar.Map {
+	return t.m
+}
+
+// CounterAdd adds delta to the counter name.
+func (t *Telemetry) CounterAdd(name string, delta uint64) {
+	t.m.Add(name, int64(delta))
+}
+
+// ObserveDuration adds one observation of d to name_count and name_sum.
+func (t *Telemetry) ObserveDuration(name string, d time.Duration) {
+	t.m.Add(name+"_count", 1)
+	t.m.AddFloat(name+"_sum", d.Seconds())
+}
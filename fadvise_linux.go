@@ -0,0 +1,21 @@
+//go:build amd64 || arm64
+
+package sxgo
+
+import (
+	"os"
+	"syscall"
+)
+
+// posixFadvRandom is POSIX_FADV_RANDOM from <fcntl.h>.
+const posixFadvRandom = 1
+
+// fadviseRandom advises the kernel that f is read randomly.
+// Internal function.
+func fadviseRandom(f *os.File) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, posixFadvRandom, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
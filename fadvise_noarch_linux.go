@@ -0,0 +1,12 @@
+//go:build !amd64 && !arm64
+
+package sxgo
+
+import "os"
+
+// fadviseRandom is a no-op on Linux architectures whose fadvise64 system
+// call takes split 64-bit arguments.
+// Internal function.
+func fadviseRandom(f *os.File) error {
+	return nil
+}
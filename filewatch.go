@@ -0,0 +1,256 @@
+package sxgo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrFileModified is returned by ModeFile lookups with WithFileWatch once the
+// open database file has been rewritten in place into another database: its
+// data no longer matches the header and indexes the instance has loaded.
+var ErrFileModified = errors.New("sxgo: database file was modified in place")
+
+// FileEventKind identifies what WithFileWatch noticed about the database file.
+type FileEventKind int
+
+const (
+	// FileReopened: the path was replaced by the same database (identical
+	// header and indexes), or an in-place rewrite restored it; lookups now
+	// read the file at the path.
+	FileReopened FileEventKind = iota + 1
+	// FileDeleted: the path was removed. Lookups keep reading the open file,
+	// which stays intact until the instance is closed.
+	FileDeleted
+	// FileReplaced: the path was replaced by another database, e.g. a new
+	// release. Lookups keep reading the open file; load the new one with
+	// Reload or New.
+	FileReplaced
+	// FileModified: the open file was rewritten in place into another
+	// database. Lookups fail with ErrFileModified; load the new one with
+	// Reload or New.
+	FileModified
+	// FileReloaded: WithAutoReload reloaded the database after the file
+	// changed, or failed to if Err is set.
+	FileReloaded
+)
+
+// String returns the name of k, e.g. "reopened".
+func (k FileEventKind) String() string {
+	switch k {
+	case FileReopened:
+		return "reopened"
+	case FileDeleted:
+		return "deleted"
+	case FileReplaced:
+		return "replaced"
+	case FileModified:
+		return "modified"
+	case FileReloaded:
+		return "reloaded"
+	}
+	return fmt.Sprintf("FileEventKind(%d)", int(k))
+}
+
+// FileEvent reports a change of the database file (see WithFileWatch and
+// WithAutoReload).
+type FileEvent struct {
+	Time time.Time
+	Path string
+	Kind FileEventKind
+	Err  error // Why the file at the path could not be checked, if it could not
+}
+
+// fileWatch is the state of WithFileWatch.
+// This struct is internal.
+type fileWatch struct {
+	interval time.Duration
+	onEvent  func(FileEvent)
+
+	stale atomic.Bool   // Set while the open file was rewritten into another database
+	next  atomic.Int64  // Unix nanoseconds of the next check
+	armed atomic.Uint64 // 1 + dbState.gen of the watched database; 0 before startFileWatch
+
+	mu    sync.Mutex  // Serializes checks and replacing the state; guards the fields below
+	path  string      // Path the database was opened from
+	info  os.FileInfo // Of the file lookups read from
+	head  [32]byte    // SHA-256 of the file up to the data blocks
+	state FileEventKind
+}
+
+// WithFileWatch makes ModeFile instances check, at most every interval and
+// during lookups, whether the database file was deleted, replaced (e.g.
+// renamed over, as deployments rotate files) or rewritten in place, instead
+// of silently reading a half-replaced file. A replacement holding the same
+// database (identical header and indexes) is reopened automatically; any
+// other change leaves lookups on the open file, or failing with
+// ErrFileModified if that file itself changed, and calls for loading the new
+// database with Reload (see also WithAutoReload) or New. onEvent, if not nil,
+// is called once per change, from the lookup that noticed it, and must not
+// block. Ignored in ModeMemory.
+func WithFileWatch(interval time.Duration, onEvent func(FileEvent)) Option {
+	return func(s *SxGeo) {
+		s.watch = &fileWatch{interval: interval, onEvent: onEvent}
+	}
+}
+
+// startFileWatch records the identity of the ModeFile handle of db once New
+// or Reload has read the database from path.
+// Internal function.
+func (s *SxGeo) startFileWatch(db *dbState, path string) error {
+	w := s.watch
+	info, err := db.f.Stat()
+	if err != nil {
+		return err
+	}
+	head, err := s.headDigest(db, db.f)
+	if err != nil {
+		return err
+	}
+	w.path, w.info, w.head = path, info, head
+	w.next.Store(time.Now().Add(w.interval).UnixNano())
+	w.armed.Store(db.gen + 1)
+	return nil
+}
+
+// file returns the handle ModeFile reads of db go to, checking the database
+// file first when WithFileWatch is due. A check that reopens the file
+// replaces the state; lookups under way finish on db. Reads of a database
+// still being loaded are not checked.
+// Internal function.
+func (s *SxGeo) file(db *dbState) (*os.File, error) {
+	w := s.watch
+	if w == nil || db.gen+1 > w.armed.Load() {
+		return db.f, nil
+	}
+	if now := time.Now(); now.UnixNano() >= w.next.Load() {
+		s.checkFile(now)
+	}
+	if w.stale.Load() {
+		return nil, ErrFileModified
+	}
+	return db.f, nil
+}
+
+// checkFile compares the file at the watched path with the one lookups read
+// from, reopening or reporting as WithFileWatch describes. Lookups arriving
+// while another one checks carry on without waiting.
+// Internal function.
+func (s *SxGeo) checkFile(now time.Time) {
+	w := s.watch
+	if !w.mu.TryLock() {
+		return
+	}
+	defer w.mu.Unlock()
+	cur := s.db()
+	if cur.f == nil || now.UnixNano() < w.next.Load() {
+		return // Closed, or checked meanwhile
+	}
+	w.next.Store(now.Add(w.interval).UnixNano())
+
+	info, err := os.Stat(w.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		w.report(now, FileDeleted, nil)
+	case err != nil:
+		// Possibly transient; check again next time
+	case !os.SameFile(info, w.info):
+		f, err := s.openReplacement(cur, info)
+		if err != nil {
+			w.report(now, FileReplaced, err)
+			return
+		}
+		if f == nil {
+			w.report(now, FileReplaced, nil)
+			return
+		}
+		next := *cur
+		next.f, next.ref = f, newDBRef(nil, f)
+		s.state.Store(&next)
+		cur.retire() // Closed once the lookups still reading it are done
+		w.info = info
+		w.stale.Store(false)
+		w.report(now, FileReopened, nil)
+	case info.Size() != w.info.Size() || !info.ModTime().Equal(w.info.ModTime()):
+		head, err := s.headDigest(cur, cur.f)
+		if err != nil || info.Size() != w.info.Size() || head != w.head {
+			w.stale.Store(true)
+			w.report(now, FileModified, err)
+			return
+		}
+		w.info = info
+		if w.stale.Swap(false) {
+			w.report(now, FileReopened, nil)
+		}
+	}
+}
+
+// openReplacement opens the file now at the watched path, described by info,
+// the way New opened the database. It returns nil if the file holds another
+// database than the one loaded.
+// Internal function.
+func (s *SxGeo) openReplacement(db *dbState, info os.FileInfo) (*os.File, error) {
+	w := s.watch
+	if info.Size() != w.info.Size() {
+		return nil, nil
+	}
+	open := os.Open
+	if s.tuning.directIO {
+		open = openDirect
+	}
+	f, err := open(w.path)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s.headDigest(db, f)
+	if err != nil || head != w.head {
+		f.Close()
+		return nil, err
+	}
+	if s.tuning.fadviseRandom {
+		if err := fadviseRandom(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("fadvise: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// headDigest returns the SHA-256 of f from its start through the indexes of
+// db, which together determine how the data blocks are read.
+// Internal function.
+func (s *SxGeo) headDigest(db *dbState, f *os.File) ([32]byte, error) {
+	align := s.tuning.alignment
+	h := sha256.New()
+	r := readerAtFunc(func(p []byte, off int64) (int, error) {
+		return alignedReadAt(f, p, off, align)
+	})
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, db.dbBegin)); err != nil {
+		return [32]byte{}, err
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// report calls onEvent for kind unless it was the last event reported;
+// FileReopened is always reported and ends the previous state.
+// Internal function.
+func (w *fileWatch) report(now time.Time, kind FileEventKind, err error) {
+	if kind == w.state && err == nil {
+		return
+	}
+	w.state = kind
+	if kind == FileReopened {
+		w.state = 0
+	}
+	if w.onEvent != nil {
+		w.onEvent(FileEvent{Time: now, Path: w.path, Kind: kind, Err: err})
+	}
+}
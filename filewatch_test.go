@@ -0,0 +1,58 @@
+package sxgo_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idanyas/sxgo"
+)
+
+func TestFileWatchReopenReleasesFile(t *testing.T) {
+	// Finalizers must not close leaked handles behind the test's back
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	path := miniCityPath(t)
+	var reopened atomic.Int32
+	geo, err := sxgo.New(path, sxgo.ModeFile, sxgo.WithFileWatch(time.Nanosecond, func(e sxgo.FileEvent) {
+		if e.Kind == sxgo.FileReopened {
+			reopened.Add(1)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	before := openFiles(t)
+	stop := make(chan struct{})
+	wg := lookUpWhile(t, geo, 4, "46.0.128.1", moscow, stop)
+	var once sync.Once
+	halt := func() {
+		once.Do(func() { close(stop) })
+		wg.Wait()
+	}
+	defer halt()
+	for i := range 20 {
+		// Deployments rotate files by renaming a copy over the path
+		tmp := filepath.Join(t.TempDir(), "next.dat")
+		if err := os.WriteFile(tmp, miniCity, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+		for deadline := time.Now().Add(5 * time.Second); reopened.Load() <= int32(i); {
+			if time.Now().After(deadline) {
+				t.Fatalf("replacement %d was not reopened", i+1)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	halt() // Lookups under way may still read a replaced file
+	if after := openFiles(t); after > before {
+		t.Errorf("%d files open after 20 reopens, %d before", after, before)
+	}
+}
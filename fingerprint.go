@@ -0,0 +1,77 @@
+package sxgo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// fingerprintState caches the result of Fingerprint.
+// This struct is internal.
+type fingerprintState struct {
+	mu   sync.Mutex
+	done bool
+	sum  [32]byte
+}
+
+// Fingerprint returns the SHA-256 digest of the database contents, from the
+// header through the end of the cities block (or of the country block, if the
+// database stores it separately). It identifies a database version
+// exactly and is identical in every mode. It is computed on first use (reading
+// the file in ModeFile) and cached.
+func (s *SxGeo) Fingerprint() ([32]byte, error) {
+	db := s.acquire()
+	defer db.unpin()
+	return s.fingerprintOf(db)
+}
+
+// fingerprintOf implements Fingerprint for db.
+// Internal function.
+func (s *SxGeo) fingerprintOf(db *dbState) ([32]byte, error) {
+	db.fingerprint.mu.Lock()
+	defer db.fingerprint.mu.Unlock()
+	if db.fingerprint.done {
+		return db.fingerprint.sum, nil
+	}
+
+	h := sha256.New()
+	h.Write(db.rawHead)
+	if db.byteIndexStr != nil { // Raw indexes are the file bytes themselves
+		h.Write(db.byteIndexStr)
+		h.Write(db.mainIndexStr)
+	} else { // Parsed indexes re-encode to the same bytes
+		var b [4]byte
+		for _, v := range db.byteIndexArr {
+			binary.BigEndian.PutUint32(b[:], v)
+			h.Write(b[:])
+		}
+		for _, v := range db.mainIndexArr {
+			binary.BigEndian.PutUint32(b[:], v)
+			h.Write(b[:])
+		}
+	}
+
+	if s.memoryMode && !db.fileReads {
+		h.Write(db.dbData)
+		h.Write(db.regionsData)
+		h.Write(db.citiesData)
+		if db.separateCountries {
+			h.Write(db.countriesData)
+		}
+	} else {
+		if db.f == nil {
+			return [32]byte{}, errors.New("sxgo: cannot fingerprint: file handle is nil")
+		}
+		end := max(db.citiesBegin+int64(db.header.citySize), db.countriesBegin+int64(db.countryBlockLen()))
+		if _, err := io.Copy(h, io.NewSectionReader(readerAtFunc(func(p []byte, off int64) (int, error) { return s.readAt(db, p, off) }), db.dbBegin, end-db.dbBegin)); err != nil {
+			return [32]byte{}, fmt.Errorf("sxgo: failed to read database for fingerprint: %w", err)
+		}
+	}
+
+	h.Sum(db.fingerprint.sum[:0])
+	db.fingerprint.done = true
+	return db.fingerprint.sum, nil
+}
@@ -0,0 +1,45 @@
+package sxgo
+
+import "time"
+
+// WithUpdateCadence declares how often the database is replaced, e.g.
+// 30*24*time.Hour for monthly releases. Lookup results then carry ValidUntil
+// (see LocationInfo), so caches and HTTP layers can expire them when newer
+// data is expected. d <= 0 disables it.
+func WithUpdateCadence(d time.Duration) Option {
+	return func(s *SxGeo) {
+		s.cadence = max(d, 0)
+	}
+}
+
+// ValidUntil returns when a database newer than the loaded one is expected:
+// the first time after now that is a whole number of update cadences past the
+// database creation time. The step keeps results of an overdue database from
+// expiring on arrival. It returns the zero time without WithUpdateCadence.
+func (s *SxGeo) ValidUntil() time.Time {
+	return s.validUntil(time.Now())
+}
+
+// validUntil is ValidUntil at time now.
+// Internal function.
+func (s *SxGeo) validUntil(now time.Time) time.Time {
+	db := s.db()
+	if s.cadence <= 0 {
+		return time.Time{}
+	}
+	created := time.Unix(int64(db.header.timestamp), 0).UTC()
+	if now.Before(created) {
+		return created.Add(s.cadence)
+	}
+	n := now.Sub(created)/s.cadence + 1
+	return created.Add(n * s.cadence)
+}
+
+// stampValidity sets info.ValidUntil if an update cadence is configured.
+// Internal function.
+func (s *SxGeo) stampValidity(info *LocationInfo) {
+	if s.cadence > 0 {
+		t := s.validUntil(time.Now())
+		info.ValidUntil = &t
+	}
+}
@@ -0,0 +1,310 @@
+// Package geodns exposes Sypex Geo lookups over DNS, DNSBL style.
+//
+// A query for the reversed IPv4 address under the configured zone, e.g.
+//
+//	3.134.158.93.geo.example.com
+//
+// is answered from the wrapped SxGeo instance:
+//   - TXT: "country=RU" "region=RU-MOW" "city=Moscow", one character-string
+//     per field so values may contain spaces (empty fields are omitted).
+//   - A:   127.0.0.<country ID>, the usual DNSBL encoding.
+//
+// Addresses that are not found (or are reserved) and other names in the zone
+// yield NXDOMAIN, names outside the zone are REFUSED. Only the standard
+// library is used.
+package geodns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/countries"
+)
+
+// DNS wire constants used by the server.
+const (
+	typeA   = 1
+	typeTXT = 16
+	classIN = 1
+
+	rcodeSuccess  = 0
+	rcodeFormErr  = 1
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+	rcodeNotImp   = 4
+	rcodeRefused  = 5
+
+	headerLen  = 12
+	maxUDPSize = 512
+)
+
+// DefaultTTL is used for answers when Server.TTL is zero.
+const DefaultTTL = 3600
+
+// DefaultMaxInFlight is used by Serve when Server.MaxInFlight is zero.
+const DefaultMaxInFlight = 256
+
+// Server answers DNS queries with geolocation data.
+// It is safe for concurrent use as long as the underlying SxGeo instance is.
+type Server struct {
+	Geo  *sxgo.SxGeo // Database used to answer queries.
+	Zone string      // Zone suffix, e.g. "geo.example.com" (trailing dot optional).
+	TTL  uint32      // TTL of answer records; DefaultTTL if zero.
+	// MaxInFlight bounds the queries Serve answers at once; DefaultMaxInFlight
+	// if zero. Further queries wait in the socket buffer.
+	MaxInFlight int
+}
+
+// errFormat marks a malformed query; the caller answers with FORMERR when possible.
+var errFormat = errors.New("geodns: malformed query")
+
+// ListenAndServe listens on the UDP address addr and serves queries until an error occurs.
+func (s *Server) ListenAndServe(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("geodns: failed to listen on %q: %w", addr, err)
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
+
+// Serve reads queries from conn and answers each in its own goroutine, up to
+// MaxInFlight at once. It returns when reading from conn fails (e.g. after
+// conn is closed).
+func (s *Server) Serve(conn net.PacketConn) error {
+	limit := s.MaxInFlight
+	if limit <= 0 {
+		limit = DefaultMaxInFlight
+	}
+	sem := make(chan struct{}, limit)
+	buf := make([]byte, maxUDPSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			resp, err := s.Answer(query)
+			if err != nil {
+				return // Not even a header to reply to
+			}
+			conn.WriteTo(resp, addr) // Best effort, as with any UDP responder
+		}()
+	}
+}
+
+// Answer builds the DNS response for a single wire-format query.
+// An error is returned only when the query is too short to carry a header;
+// every other problem is reported to the client through the response code.
+func (s *Server) Answer(query []byte) ([]byte, error) {
+	if len(query) < headerLen {
+		return nil, errFormat
+	}
+	id := binary.BigEndian.Uint16(query[0:2])
+	flags := binary.BigEndian.Uint16(query[2:4])
+	if flags&0x8000 != 0 { // QR set: this is a response, ignore it
+		return nil, errFormat
+	}
+	opcode := (flags >> 11) & 0xF
+	rd := flags & 0x0100
+
+	name, qtype, qclass, qend, err := parseQuestion(query)
+	if err != nil {
+		return reply(id, opcode, rd, rcodeFormErr, nil, nil), nil
+	}
+	question := query[headerLen:qend]
+	if opcode != 0 {
+		return reply(id, opcode, rd, rcodeNotImp, question, nil), nil
+	}
+	if qclass != classIN {
+		return reply(id, opcode, rd, rcodeRefused, question, nil), nil
+	}
+
+	ip, inZone, ok := s.addrFromName(name)
+	switch {
+	case !inZone:
+		return reply(id, opcode, rd, rcodeRefused, question, nil), nil
+	case ip == "" && ok: // The zone apex exists but has no records
+		return reply(id, opcode, rd, rcodeSuccess, question, nil), nil
+	case !ok:
+		return reply(id, opcode, rd, rcodeNXDomain, question, nil), nil
+	}
+
+	countryID, fields, err := s.locate(ip)
+	if err != nil {
+		return reply(id, opcode, rd, rcodeServFail, question, nil), nil
+	}
+	if countryID == 0 {
+		return reply(id, opcode, rd, rcodeNXDomain, question, nil), nil
+	}
+
+	var rdata []byte
+	switch qtype {
+	case typeA:
+		rdata = []byte{127, 0, 0, countryID}
+	case typeTXT:
+		for _, f := range fields {
+			rdata = append(rdata, txtData(f)...)
+		}
+	default:
+		// Name exists but has no records of the requested type (NODATA).
+		return reply(id, opcode, rd, rcodeSuccess, question, nil), nil
+	}
+
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	answer := make([]byte, 0, 12+len(rdata))
+	answer = append(answer, 0xC0, headerLen) // Compression pointer to the question name
+	answer = binary.BigEndian.AppendUint16(answer, qtype)
+	answer = binary.BigEndian.AppendUint16(answer, classIN)
+	answer = binary.BigEndian.AppendUint32(answer, ttl)
+	answer = binary.BigEndian.AppendUint16(answer, uint16(len(rdata)))
+	answer = append(answer, rdata...)
+	return reply(id, opcode, rd, rcodeSuccess, question, answer), nil
+}
+
+// locate looks ip up once and returns its country ID, 0 if it is not found,
+// and the "key=value" fields of the TXT answer: GetCityFull, or GetCountryID
+// where the database or instance only knows countries.
+func (s *Server) locate(ip string) (countryID uint8, fields []string, err error) {
+	loc, err := s.Geo.GetCityFull(ip)
+	if loc == nil && (err == nil || missing(err) ||
+		errors.Is(err, sxgo.ErrUnsupportedDB) || errors.Is(err, sxgo.ErrCountryOnly)) {
+		if (err == nil || missing(err)) && s.Geo.Capabilities().HasCities {
+			return 0, nil, nil // Searched and not found
+		}
+		id, err := s.Geo.GetCountryID(ip)
+		if err != nil && !missing(err) {
+			return 0, nil, err
+		}
+		if id == 0 {
+			return 0, nil, nil
+		}
+		return uint8(id), []string{"country=" + countries.ISO(uint8(id))}, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if loc.Country == nil {
+		return 0, nil, nil
+	}
+	fields = append(fields, "country="+loc.Country.ISO)
+	if loc.Region != nil && loc.Region.ISO != "" {
+		fields = append(fields, "region="+loc.Region.ISO)
+	}
+	if loc.City != nil && loc.City.NameEN != "" {
+		fields = append(fields, "city="+loc.City.NameEN)
+	}
+	return loc.Country.ID, fields, nil
+}
+
+// missing reports whether err is a miss reported by an instance using
+// sxgo.WithNotFoundErrors, which the server answers like an empty result.
+func missing(err error) bool {
+	return errors.Is(err, sxgo.ErrNotFound) || errors.Is(err, sxgo.ErrReservedRange)
+}
+
+// addrFromName converts "<d>.<c>.<b>.<a>.<zone>" into "a.b.c.d". inZone
+// reports whether name is in the zone at all, and ok whether it is an
+// address or the zone apex (ip "").
+func (s *Server) addrFromName(name string) (ip string, inZone, ok bool) {
+	zone := strings.ToLower(strings.TrimSuffix(s.Zone, "."))
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if zone != "" {
+		if name == zone {
+			return "", true, true
+		}
+		if !strings.HasSuffix(name, "."+zone) {
+			return "", false, false
+		}
+		name = strings.TrimSuffix(name, "."+zone)
+	}
+	labels := strings.Split(name, ".")
+	if len(labels) != 4 {
+		return "", true, false
+	}
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	for _, l := range labels {
+		if n, err := strconv.Atoi(l); err != nil || n < 0 || n > 255 || l != strconv.Itoa(n) {
+			return "", true, false
+		}
+	}
+	return strings.Join(labels, "."), true, true
+}
+
+// parseQuestion extracts the first (and only supported) question of a query.
+// qend is the offset just past the question section.
+func parseQuestion(msg []byte) (name string, qtype, qclass uint16, qend int, err error) {
+	if binary.BigEndian.Uint16(msg[4:6]) != 1 {
+		return "", 0, 0, 0, errFormat
+	}
+	off := headerLen
+	var labels []string
+	for {
+		if off >= len(msg) {
+			return "", 0, 0, 0, errFormat
+		}
+		l := int(msg[off])
+		off++
+		if l == 0 {
+			break
+		}
+		if l&0xC0 != 0 || off+l > len(msg) { // Compression is not used in questions
+			return "", 0, 0, 0, errFormat
+		}
+		labels = append(labels, string(msg[off:off+l]))
+		off += l
+	}
+	if off+4 > len(msg) {
+		return "", 0, 0, 0, errFormat
+	}
+	qtype = binary.BigEndian.Uint16(msg[off : off+2])
+	qclass = binary.BigEndian.Uint16(msg[off+2 : off+4])
+	return strings.Join(labels, "."), qtype, qclass, off + 4, nil
+}
+
+// reply assembles a response message. question and answer may be nil.
+func reply(id, opcode, rd uint16, rcode int, question, answer []byte) []byte {
+	flags := uint16(0x8000) | opcode<<11 | 0x0400 | rd | uint16(rcode) // QR, AA
+	msg := make([]byte, headerLen, headerLen+len(question)+len(answer))
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[2:4], flags)
+	if question != nil {
+		binary.BigEndian.PutUint16(msg[4:6], 1)
+	}
+	if answer != nil {
+		binary.BigEndian.PutUint16(msg[6:8], 1)
+	}
+	msg = append(msg, question...)
+	return append(msg, answer...)
+}
+
+// txtData encodes s as TXT RDATA, splitting it into 255-byte character-strings.
+func txtData(s string) []byte {
+	var out []byte
+	for {
+		chunk := s
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		out = append(out, byte(len(chunk)))
+		out = append(out, chunk...)
+		s = s[len(chunk):]
+		if s == "" {
+			return out
+		}
+	}
+}
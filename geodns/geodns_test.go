@@ -0,0 +1,116 @@
+package geodns
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// query returns a wire-format query for name and qtype.
+func query(name string, qtype uint16) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, l := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(l)))
+		msg = append(msg, l...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+// parseAnswer returns the response code of resp and the RDATA of its answer,
+// if it has one.
+func parseAnswer(t *testing.T, q, resp []byte) (rcode int, rdata []byte) {
+	t.Helper()
+	if len(resp) < len(q) {
+		t.Fatalf("response of %d bytes", len(resp))
+	}
+	rcode = int(binary.BigEndian.Uint16(resp[2:4]) & 0xF)
+	if binary.BigEndian.Uint16(resp[6:8]) == 0 {
+		return rcode, nil
+	}
+	ans := resp[len(q):]
+	n := int(binary.BigEndian.Uint16(ans[10:12]))
+	return rcode, ans[12 : 12+n]
+}
+
+// txtStrings splits TXT RDATA into its character-strings.
+func txtStrings(rdata []byte) []string {
+	var out []string
+	for len(rdata) > 0 {
+		n := int(rdata[0])
+		out = append(out, string(rdata[1:1+n]))
+		rdata = rdata[1+n:]
+	}
+	return out
+}
+
+func TestAnswer(t *testing.T) {
+	geo, err := sxgo.New("../testdata/minicity.dat", sxgo.ModeMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	s := &Server{Geo: geo, Zone: "geo.example.com."}
+
+	tests := []struct {
+		name  string
+		qtype uint16
+		rcode int
+		txt   []string // TXT character-strings
+		a     []byte
+	}{
+		{"1.0.0.1.geo.example.com", typeTXT, rcodeSuccess, []string{"country=US", "region=US-CA", "city=Los Angeles"}, nil},
+		{"1.0.0.1.geo.example.com", typeA, rcodeSuccess, nil, []byte{127, 0, 0, 225}},
+		{"1.0.0.5.geo.example.com", typeTXT, rcodeSuccess, []string{"country=UA"}, nil},
+		{"1.0.128.1.geo.example.com", typeTXT, rcodeNXDomain, nil, nil},
+		{"1.1.168.192.geo.example.com", typeA, rcodeNXDomain, nil, nil},
+		{"www.geo.example.com", typeA, rcodeNXDomain, nil, nil},
+		{"geo.example.com", typeA, rcodeSuccess, nil, nil},
+		{"1.0.0.1.example.org", typeA, rcodeRefused, nil, nil},
+	}
+	for _, tt := range tests {
+		q := query(tt.name, tt.qtype)
+		before := geo.Stats().Lookups
+		resp, err := s.Answer(q)
+		if err != nil {
+			t.Fatalf("Answer(%s): %v", tt.name, err)
+		}
+		rcode, rdata := parseAnswer(t, q, resp)
+		if rcode != tt.rcode {
+			t.Errorf("%s type %d: rcode %d, want %d", tt.name, tt.qtype, rcode, tt.rcode)
+			continue
+		}
+		switch {
+		case tt.txt != nil:
+			if got := txtStrings(rdata); !slices.Equal(got, tt.txt) {
+				t.Errorf("%s TXT = %q, want %q", tt.name, got, tt.txt)
+			}
+		case !slices.Equal(rdata, tt.a):
+			t.Errorf("%s A = %v, want %v", tt.name, rdata, tt.a)
+		}
+		if n := geo.Stats().Lookups - before; n > 1 {
+			t.Errorf("%s type %d took %d lookups, want at most 1", tt.name, tt.qtype, n)
+		}
+	}
+}
+
+func TestAnswerCountryDatabase(t *testing.T) {
+	geo, err := sxgo.New("../testdata/minicity.dat", sxgo.ModeMemory, sxgo.WithCountryOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	s := &Server{Geo: geo, Zone: "geo.example.com"}
+	q := query("1.0.128.46.geo.example.com", typeTXT)
+	resp, err := s.Answer(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode, rdata := parseAnswer(t, q, resp); rcode != rcodeSuccess || !slices.Equal(txtStrings(rdata), []string{"country=RU"}) {
+		t.Errorf("TXT = %d %q, want country=RU", rcode, txtStrings(rdata))
+	}
+}
@@ -0,0 +1,99 @@
+package geohttp
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Format renders /city and /country answers with a template instead of as
+// JSON, e.g. to serve the legacy formats of a service being replaced (see
+// Handler.Formats).
+type Format struct {
+	// ContentType of the responses; "text/plain; charset=utf-8" if empty.
+	// Its media type also selects the format from the Accept header.
+	ContentType string
+	// Template is executed with the Result (/city) or the CountryResult
+	// (/country) of the address, errors included; for /city, e.g.
+	// {{with .Country}}{{.ISO}}{{end}}, {{with .City}}{{.NameEN}}{{end}}.
+	Template *template.Template
+}
+
+// contentType returns the Content-Type of the responses of f.
+func (f *Format) contentType() string {
+	if f.ContentType == "" {
+		return "text/plain; charset=utf-8"
+	}
+	return f.ContentType
+}
+
+// format returns the format r asks for and its name: the one named by
+// ?format=, else the first (by name) whose media type the Accept header lists
+// first, else nil and "" for JSON. It fails for unknown names. With Formats,
+// it adds Accept to the Vary header of the response.
+func (h *Handler) format(w http.ResponseWriter, r *http.Request) (*Format, string, error) {
+	if len(h.Formats) > 0 {
+		w.Header().Add("Vary", "Accept")
+	}
+	if name := r.URL.Query().Get("format"); name != "" {
+		if name == "json" {
+			return nil, "", nil
+		}
+		f, ok := h.Formats[name]
+		if !ok {
+			return nil, "", fmt.Errorf("geohttp: unknown format %q", name)
+		}
+		return &f, name, nil
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" || len(h.Formats) == 0 {
+		return nil, "", nil
+	}
+	names := make([]string, 0, len(h.Formats))
+	for name := range h.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, a := range strings.Split(accept, ",") {
+		want, _, err := mime.ParseMediaType(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+		if want == "application/json" {
+			return nil, "", nil
+		}
+		for _, name := range names {
+			f := h.Formats[name]
+			if f.ContentType == "" {
+				continue // Only selected by name
+			}
+			if mt, _, err := mime.ParseMediaType(f.ContentType); err == nil && mt == want {
+				return &f, name, nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// render writes v with the given status as f, or as JSON if f is nil.
+// Failing templates answer 500.
+func (h *Handler) render(w http.ResponseWriter, f *Format, code int, v any) {
+	if f == nil {
+		writeJSON(w, code, v)
+		return
+	}
+	var buf bytes.Buffer
+	if err := f.Template.Execute(&buf, v); err != nil {
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("geohttp: failed to render answer: %v", err)})
+		return
+	}
+	w.Header().Set("Content-Type", f.contentType())
+	w.WriteHeader(code)
+	w.Write(buf.Bytes()) // The client may be gone; nothing to report to
+}
@@ -0,0 +1,319 @@
+// Package geohttp exposes Sypex Geo lookups over HTTP as JSON.
+//
+// Handler serves:
+//   - GET /city/{ip}: the GetCityFull result, e.g.
+//     {"ip":"93.158.134.3","city":{...},"region":{...},"country":{...}}.
+//   - GET /country/{ip}: the ISO code of the country, {"ip":"...","country":"RU"}.
+//   - POST /batch: a JSON array of addresses in, an array of /city results
+//     (or /country results with ?level=country) out, in input order.
+//   - GET /about: the database metadata of SxGeo.Info, including the license
+//     and the attribution notice to show to users of the data.
+//   - GET /traffic, with Handler.TrafficWindow set: the countries and cities
+//     answered over that sliding window, most answered first (at most
+//     ?limit=, DefaultTrafficLimit by default), as a TrafficReport.
+//
+// Errors are JSON too, {"ip":"...","error":"..."}: 400 for invalid addresses,
+// 404 for addresses without a location, 501 for lookups the database cannot
+// answer (e.g. /city on a Country database). Within a batch, each result
+// carries its own error and the response is 200.
+//
+// Handler.Formats renders /city and /country answers with Go templates
+// instead, e.g. as plain text "RU, Moscow", selected by ?format= or the
+// Accept header.
+//
+// With Handler.CacheMaxAge set, /city and /country answers (200 and 404) carry
+// Cache-Control and an ETag made of the database fingerprint and the hash of
+// the range the address resolves to (see SxGeo.Fingerprint and
+// SxGeo.RangeHash), so CDNs and proxies can cache them per address and
+// revalidate with If-None-Match (304) across database updates. Only the
+// standard library is used.
+package geohttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/idanyas/sxgo"
+)
+
+// DefaultMaxBatch is the addresses a /batch request may hold when
+// Handler.MaxBatch is zero.
+const DefaultMaxBatch = 1000
+
+// maxAddrBytes bounds the JSON size of one address in a /batch request body.
+const maxAddrBytes = 64
+
+// errNotFound is reported for addresses without a location.
+var errNotFound = errors.New("geohttp: address not found")
+
+// Handler answers lookup requests from an SxGeo instance. Lookups see
+// databases swapped in by SxGeo.Reload (or WithAutoReload) at once, without
+// failing requests in flight. It is safe for concurrent use as long as the
+// underlying SxGeo instance is; do not change its fields once it serves.
+type Handler struct {
+	Geo      *sxgo.SxGeo // Database used to answer requests.
+	MaxBatch int         // Addresses per /batch request; DefaultMaxBatch if zero.
+	// CacheMaxAge, if positive, makes /city and /country answers cacheable
+	// for that long (see the package documentation).
+	CacheMaxAge time.Duration
+	// TrafficWindow, if positive, counts the places answered by /city,
+	// /country and /batch over that sliding window for GET /traffic. 304
+	// answers (see CacheMaxAge) skip the lookup and do not count.
+	TrafficWindow time.Duration
+	// Formats are the custom renderings of /city and /country answers by
+	// name, selected with ?format=<name> or the Accept header (see Format).
+	// "json", the default, cannot be replaced.
+	Formats map[string]Format
+
+	once    sync.Once
+	mux     *http.ServeMux
+	traffic *traffic // With TrafficWindow
+}
+
+// Result is the answer for one address of /city and /batch.
+type Result struct {
+	IP string `json:"ip"`
+	*sxgo.LocationInfo
+	Error string `json:"error,omitempty"`
+}
+
+// CountryResult is the answer for one address of /country and /batch with
+// ?level=country.
+type CountryResult struct {
+	IP      string `json:"ip"`
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code.
+	Error   string `json:"error,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.mux = http.NewServeMux()
+		h.mux.HandleFunc("GET /city/{ip}", h.city)
+		h.mux.HandleFunc("GET /country/{ip}", h.country)
+		h.mux.HandleFunc("POST /batch", h.batch)
+		h.mux.HandleFunc("GET /about", h.about)
+		if h.TrafficWindow > 0 {
+			h.traffic = newTraffic(h.TrafficWindow)
+			h.mux.HandleFunc("GET /traffic", h.trafficReport)
+		}
+	})
+	h.mux.ServeHTTP(w, r)
+}
+
+// city serves /city/{ip}.
+func (h *Handler) city(w http.ResponseWriter, r *http.Request) {
+	ip := r.PathValue("ip")
+	f, name, err := h.format(w, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Result{IP: ip, Error: err.Error()})
+		return
+	}
+	if !h.Geo.Info().Type.IsCity() {
+		h.render(w, f, http.StatusNotImplemented, Result{IP: ip, Error: "geohttp: city lookup on a Country database"})
+		return
+	}
+	tag := h.etag(ip, name)
+	if h.notModified(w, r, tag) {
+		return
+	}
+	info, err := h.Geo.GetCityFull(ip)
+	if err == nil && info == nil {
+		err = errNotFound
+	}
+	if err != nil {
+		code := status(err)
+		h.setCache(w, tag, code)
+		h.render(w, f, code, Result{IP: ip, Error: err.Error()})
+		return
+	}
+	h.count(countryISO(info), info)
+	h.setCache(w, tag, http.StatusOK)
+	h.render(w, f, http.StatusOK, Result{IP: ip, LocationInfo: info})
+}
+
+// country serves /country/{ip}.
+func (h *Handler) country(w http.ResponseWriter, r *http.Request) {
+	ip := r.PathValue("ip")
+	f, name, err := h.format(w, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, CountryResult{IP: ip, Error: err.Error()})
+		return
+	}
+	tag := h.etag(ip, name)
+	if h.notModified(w, r, tag) {
+		return
+	}
+	res, err := h.lookupCountry(ip)
+	h.count(res.Country, nil)
+	code := http.StatusOK
+	if err != nil {
+		code = status(err)
+	}
+	h.setCache(w, tag, code)
+	h.render(w, f, code, res)
+}
+
+// etag returns the entity tag of the answers for ip in the named format: the
+// database version and the range ip resolves to, which together decide them,
+// and the format unless it is JSON. It is "" without CacheMaxAge or if ip
+// has no range. Computed before the lookup, a tag never outlives the
+// database it came from: across a reload, a new answer may carry the old
+// tag, which the next revalidation replaces.
+func (h *Handler) etag(ip, format string) string {
+	if h.CacheMaxAge <= 0 {
+		return ""
+	}
+	fp, err := h.Geo.Fingerprint()
+	if err != nil {
+		return ""
+	}
+	rh, err := h.Geo.RangeHash(ip)
+	if err != nil {
+		return ""
+	}
+	if format != "" {
+		return fmt.Sprintf(`"%x-%016x-%s"`, fp[:8], rh, format)
+	}
+	return fmt.Sprintf(`"%x-%016x"`, fp[:8], rh)
+}
+
+// notModified answers 304 and reports true if the request's If-None-Match
+// holds tag.
+func (h *Handler) notModified(w http.ResponseWriter, r *http.Request, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, t := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == tag || t == "*" {
+			h.setCache(w, tag, http.StatusNotModified)
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// setCache sets the caching headers for tag on answers with a status worth
+// caching.
+func (h *Handler) setCache(w http.ResponseWriter, tag string, code int) {
+	if tag == "" {
+		return
+	}
+	switch code {
+	case http.StatusOK, http.StatusNotFound, http.StatusNotModified:
+		w.Header().Set("ETag", tag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(h.CacheMaxAge/time.Second)))
+	}
+}
+
+// batch serves /batch.
+func (h *Handler) batch(w http.ResponseWriter, r *http.Request) {
+	maxBatch := h.MaxBatch
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBatch
+	}
+	var ips []string
+	body := http.MaxBytesReader(w, r.Body, int64(maxBatch)*maxAddrBytes)
+	if err := json.NewDecoder(body).Decode(&ips); err != nil {
+		code := http.StatusBadRequest
+		if errors.As(err, new(*http.MaxBytesError)) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, code, map[string]string{"error": fmt.Sprintf("geohttp: invalid batch: %v", err)})
+		return
+	}
+	if len(ips) > maxBatch {
+		writeJSON(w, http.StatusRequestEntityTooLarge,
+			map[string]string{"error": fmt.Sprintf("geohttp: batch of %d addresses exceeds %d", len(ips), maxBatch)})
+		return
+	}
+
+	switch level := r.URL.Query().Get("level"); level {
+	case "country":
+		out := make([]CountryResult, len(ips))
+		for i, ip := range ips {
+			out[i], _ = h.lookupCountry(ip)
+			h.count(out[i].Country, nil)
+		}
+		writeJSON(w, http.StatusOK, out)
+	case "", "city":
+		if !h.Geo.Info().Type.IsCity() {
+			writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "geohttp: city lookup on a Country database"})
+			return
+		}
+		infos, _, err := h.Geo.NewPlanner(true).LookupContext(r.Context(), ips)
+		if err != nil {
+			writeJSON(w, status(err), map[string]string{"error": err.Error()})
+			return
+		}
+		out := make([]Result, len(ips))
+		for i, ip := range ips {
+			out[i] = Result{IP: ip, LocationInfo: infos[i]}
+			h.count(countryISO(infos[i]), infos[i])
+			if infos[i] == nil {
+				out[i].Error = errNotFound.Error()
+				if _, err := h.Geo.GetCountryID(ip); err != nil {
+					out[i].Error = err.Error() // The planner does not tell invalid addresses apart
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, out)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("geohttp: unknown level %q", level)})
+	}
+}
+
+// about serves /about.
+func (h *Handler) about(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Geo.Info())
+}
+
+// lookupCountry resolves the country of ip, returning the lookup error
+// also set in the result.
+func (h *Handler) lookupCountry(ip string) (CountryResult, error) {
+	iso, err := h.Geo.GetCountry(ip)
+	if err == nil && iso == "" {
+		err = errNotFound
+	}
+	if err != nil {
+		return CountryResult{IP: ip, Error: err.Error()}, err
+	}
+	return CountryResult{IP: ip, Country: iso}, nil
+}
+
+// countryISO returns the ISO code of the country of info, or "".
+func countryISO(info *sxgo.LocationInfo) string {
+	if info == nil || info.Country == nil {
+		return ""
+	}
+	return info.Country.ISO
+}
+
+// status returns the HTTP status reporting a lookup error.
+func status(err error) int {
+	switch {
+	case errors.Is(err, sxgo.ErrInvalidIP):
+		return http.StatusBadRequest
+	case errors.Is(err, errNotFound), errors.Is(err, sxgo.ErrNotFound), errors.Is(err, sxgo.ErrReservedRange):
+		return http.StatusNotFound
+	case errors.Is(err, sxgo.ErrUnsupportedDB), errors.Is(err, sxgo.ErrCountryOnly):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes v as the JSON response with the given status.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v) // The client may be gone; nothing to report to
+}
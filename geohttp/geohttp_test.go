@@ -0,0 +1,228 @@
+package geohttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/dbwriter"
+)
+
+// open opens the mini City database of the sxgo tests.
+func open(t *testing.T) *sxgo.SxGeo {
+	t.Helper()
+	geo, err := sxgo.New("../testdata/minicity.dat", sxgo.ModeMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { geo.Close() })
+	return geo
+}
+
+// serve sends r to h and returns the response.
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decode unmarshals the body of w into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body, err)
+	}
+}
+
+// cityAnswer is the part of a /city answer the tests compare; Result does not
+// unmarshal, as Precision marshals to a string only.
+type cityAnswer struct {
+	IP   string `json:"ip"`
+	City *struct {
+		NameEN string `json:"name_en"`
+	} `json:"city"`
+	Country *struct {
+		ISO string `json:"iso"`
+	} `json:"country"`
+	Error string `json:"error"`
+}
+
+// names returns the English city name and country code of a.
+func (a cityAnswer) names() (city, iso string) {
+	if a.City != nil {
+		city = a.City.NameEN
+	}
+	if a.Country != nil {
+		iso = a.Country.ISO
+	}
+	return city, iso
+}
+
+func TestCity(t *testing.T) {
+	h := &Handler{Geo: open(t)}
+	tests := []struct {
+		ip   string
+		code int
+		city string // English name
+		iso  string // Country code
+	}{
+		{"93.158.0.1", http.StatusOK, "Moscow", "RU"},
+		{"5.0.255.255", http.StatusOK, "", "UA"}, // Country-level range
+		{"1.0.128.0", http.StatusNotFound, "", ""},
+		{"bogus", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		w := serve(h, httptest.NewRequest("GET", "/city/"+tt.ip, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET /city/%s: status %d, want %d", tt.ip, w.Code, tt.code)
+			continue
+		}
+		var res cityAnswer
+		decode(t, w, &res)
+		city, iso := res.names()
+		if res.IP != tt.ip || city != tt.city || iso != tt.iso || (tt.code == http.StatusOK) != (res.Error == "") {
+			t.Errorf("GET /city/%s = %s", tt.ip, w.Body)
+		}
+	}
+}
+
+func TestCountry(t *testing.T) {
+	h := &Handler{Geo: open(t)}
+	tests := []struct {
+		ip   string
+		code int
+		iso  string
+	}{
+		{"93.158.0.1", http.StatusOK, "RU"},
+		{"5.0.255.255", http.StatusOK, "UA"},
+		{"1.0.128.0", http.StatusNotFound, ""},
+		{"bogus", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := serve(h, httptest.NewRequest("GET", "/country/"+tt.ip, nil))
+		var res CountryResult
+		decode(t, w, &res)
+		if w.Code != tt.code || res.IP != tt.ip || res.Country != tt.iso || (tt.code == http.StatusOK) != (res.Error == "") {
+			t.Errorf("GET /country/%s: status %d, %s; want %d, country %q", tt.ip, w.Code, w.Body, tt.code, tt.iso)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	h := &Handler{Geo: open(t), MaxBatch: 4}
+	body := `["93.158.0.1","bogus","1.0.128.0","93.158.9.0"]`
+
+	w := serve(h, httptest.NewRequest("POST", "/batch", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /batch: status %d, %s", w.Code, w.Body)
+	}
+	var cities []cityAnswer
+	decode(t, w, &cities)
+	wantCity := []string{"Moscow", "", "", "Kazan"}
+	if len(cities) != len(wantCity) {
+		t.Fatalf("POST /batch: %d results, want %d", len(cities), len(wantCity))
+	}
+	for i, res := range cities {
+		if city, _ := res.names(); city != wantCity[i] || (city == "") != (res.Error != "") {
+			t.Errorf("POST /batch: result %d = %+v, want city %q", i, res, wantCity[i])
+		}
+	}
+	if !strings.Contains(cities[1].Error, "invalid") || strings.Contains(cities[2].Error, "invalid") {
+		t.Errorf("POST /batch: errors %q and %q, want an invalid address and a miss", cities[1].Error, cities[2].Error)
+	}
+
+	w = serve(h, httptest.NewRequest("POST", "/batch?level=country", strings.NewReader(body)))
+	var countries []CountryResult
+	decode(t, w, &countries)
+	var got []string
+	for _, res := range countries {
+		got = append(got, res.Country)
+	}
+	if w.Code != http.StatusOK || strings.Join(got, ",") != "RU,,,RU" {
+		t.Errorf("POST /batch?level=country: status %d, countries %q", w.Code, got)
+	}
+
+	w = serve(h, httptest.NewRequest("POST", "/batch", strings.NewReader(`["1.1.1.1","1.1.1.2","1.1.1.3","1.1.1.4","1.1.1.5"]`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST /batch past MaxBatch: status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	w = serve(h, httptest.NewRequest("POST", "/batch", strings.NewReader(`{`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST /batch with invalid JSON: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCityOnCountryDatabase(t *testing.T) {
+	ru := &dbwriter.Country{ID: 185}
+	db := &dbwriter.Database{Type: dbwriter.TypeCountry, Blocks: []dbwriter.Block{{Start: 93 << 24, Country: ru}}}
+	data, err := db.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "SxGeo.dat")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	geo, err := sxgo.New(path, sxgo.ModeMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	h := &Handler{Geo: geo}
+	if w := serve(h, httptest.NewRequest("GET", "/city/93.1.2.3", nil)); w.Code != http.StatusNotImplemented {
+		t.Errorf("GET /city on a Country database: status %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+	var res CountryResult
+	w := serve(h, httptest.NewRequest("GET", "/country/93.1.2.3", nil))
+	decode(t, w, &res)
+	if w.Code != http.StatusOK || res.Country != "RU" {
+		t.Errorf("GET /country on a Country database: status %d, %s", w.Code, w.Body)
+	}
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	h := &Handler{Geo: open(t), CacheMaxAge: time.Hour}
+	for _, path := range []string{"/city/", "/country/"} {
+		w := serve(h, httptest.NewRequest("GET", path+"93.158.0.1", nil))
+		tag := w.Header().Get("ETag")
+		if w.Code != http.StatusOK || tag == "" || w.Header().Get("Cache-Control") != "public, max-age=3600" {
+			t.Fatalf("GET %s: status %d, headers %v", path, w.Code, w.Header())
+		}
+		// Addresses of the same range share the tag; other ranges do not
+		if other := serve(h, httptest.NewRequest("GET", path+"93.158.0.2", nil)).Header().Get("ETag"); other != tag {
+			t.Errorf("GET %s: ETag %s in the same range, want %s", path, other, tag)
+		}
+		if other := serve(h, httptest.NewRequest("GET", path+"93.158.9.0", nil)).Header().Get("ETag"); other == tag {
+			t.Errorf("GET %s: ETag %s shared with another range", path, tag)
+		}
+
+		r := httptest.NewRequest("GET", path+"93.158.0.1", nil)
+		r.Header.Set("If-None-Match", `"other", W/`+tag)
+		w = serve(h, r)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != tag {
+			t.Errorf("GET %s revalidation: status %d, %d bytes, ETag %s", path, w.Code, w.Body.Len(), w.Header().Get("ETag"))
+		}
+		r.Header.Set("If-None-Match", `"other"`)
+		if w := serve(h, r); w.Code != http.StatusOK {
+			t.Errorf("GET %s with a stale tag: status %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+
+	// Misses are cacheable, invalid addresses are not
+	if w := serve(h, httptest.NewRequest("GET", "/city/1.0.128.0", nil)); w.Code != http.StatusNotFound || w.Header().Get("ETag") == "" {
+		t.Errorf("GET /city miss: status %d, headers %v", w.Code, w.Header())
+	}
+	if w := serve(h, httptest.NewRequest("GET", "/city/bogus", nil)); w.Header().Get("ETag") != "" || w.Header().Get("Cache-Control") != "" {
+		t.Errorf("GET /city invalid: headers %v", w.Header())
+	}
+	// Without CacheMaxAge, answers carry no caching headers
+	if w := serve(&Handler{Geo: h.Geo}, httptest.NewRequest("GET", "/city/93.158.0.1", nil)); w.Header().Get("ETag") != "" {
+		t.Errorf("GET /city without CacheMaxAge: headers %v", w.Header())
+	}
+}
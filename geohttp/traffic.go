@@ -0,0 +1,159 @@
+package geohttp
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/idanyas/sxgo"
+)
+
+// trafficBuckets is the number of buckets a traffic window is split into;
+// counts leave the window one bucket at a time.
+const trafficBuckets = 60
+
+// DefaultTrafficLimit is the places per list GET /traffic returns without
+// ?limit.
+const DefaultTrafficLimit = 100
+
+// TrafficReport is the answer of GET /traffic: the places answered over the
+// last Handler.TrafficWindow.
+type TrafficReport struct {
+	Window    string         `json:"window"`    // Span counted, e.g. "1h0m0s".
+	Countries []CountryCount `json:"countries"` // Most answered first.
+	Cities    []CityCount    `json:"cities"`    // Most answered first; only city lookups count.
+}
+
+// CountryCount is the number of answers locating addresses in a country.
+type CountryCount struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code.
+	Count   int    `json:"count"`
+}
+
+// CityCount is the number of answers locating addresses in a city.
+type CityCount struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code.
+	CityID  uint32 `json:"city_id"`
+	City    string `json:"city"` // English name of the city, else the Russian one.
+	Count   int    `json:"count"`
+}
+
+// place is a counted answer; cityID is 0 for country answers.
+// This struct is internal.
+type place struct {
+	country string
+	cityID  uint32
+	city    string
+}
+
+// trafficBucket holds the counts of one slice of the window.
+// This struct is internal.
+type trafficBucket struct {
+	epoch  int64 // Slice of time the counts belong to, in bucket spans since the Unix epoch
+	counts map[place]int
+}
+
+// traffic counts answered places over a sliding window.
+// This struct is internal.
+type traffic struct {
+	mu      sync.Mutex
+	window  time.Duration
+	span    time.Duration // Of a bucket
+	buckets [trafficBuckets]trafficBucket
+}
+
+// newTraffic returns a counter over window.
+// Internal function.
+func newTraffic(window time.Duration) *traffic {
+	return &traffic{window: window, span: max(window/trafficBuckets, time.Nanosecond)}
+}
+
+// record counts an answer locating an address in country and, if info has
+// one, its city.
+// Internal function.
+func (t *traffic) record(country string, info *sxgo.LocationInfo) {
+	if country == "" {
+		return
+	}
+	p := place{country: country}
+	if info != nil && info.City != nil && info.City.ID != 0 {
+		p.cityID, p.city = info.City.ID, info.City.NameEN
+		if p.city == "" {
+			p.city = info.City.NameRU
+		}
+	}
+	epoch := time.Now().UnixNano() / int64(t.span)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := &t.buckets[epoch%trafficBuckets]
+	if b.epoch != epoch || b.counts == nil {
+		b.epoch, b.counts = epoch, make(map[place]int)
+	}
+	b.counts[p]++
+}
+
+// report sums the buckets of the window into the limit most answered
+// countries and cities.
+// Internal function.
+func (t *traffic) report(limit int) TrafficReport {
+	countries := make(map[string]int)
+	cities := make(map[place]int)
+	epoch := time.Now().UnixNano() / int64(t.span)
+	t.mu.Lock()
+	for i := range t.buckets {
+		b := &t.buckets[i]
+		if b.epoch <= epoch-trafficBuckets {
+			continue // Left the window
+		}
+		for p, n := range b.counts {
+			countries[p.country] += n
+			if p.cityID != 0 {
+				cities[p] += n
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	rep := TrafficReport{Window: t.window.String(), Countries: []CountryCount{}, Cities: []CityCount{}}
+	for c, n := range countries {
+		rep.Countries = append(rep.Countries, CountryCount{Country: c, Count: n})
+	}
+	sort.Slice(rep.Countries, func(i, j int) bool {
+		a, b := rep.Countries[i], rep.Countries[j]
+		return a.Count > b.Count || a.Count == b.Count && a.Country < b.Country
+	})
+	for p, n := range cities {
+		rep.Cities = append(rep.Cities, CityCount{Country: p.country, CityID: p.cityID, City: p.city, Count: n})
+	}
+	sort.Slice(rep.Cities, func(i, j int) bool {
+		a, b := rep.Cities[i], rep.Cities[j]
+		return a.Count > b.Count || a.Count == b.Count && a.CityID < b.CityID
+	})
+	rep.Countries = rep.Countries[:min(len(rep.Countries), limit)]
+	rep.Cities = rep.Cities[:min(len(rep.Cities), limit)]
+	return rep
+}
+
+// trafficReport serves /traffic.
+func (h *Handler) trafficReport(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultTrafficLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "geohttp: invalid limit " + strconv.Quote(v)})
+			return
+		}
+		limit = n
+	}
+	writeJSON(w, http.StatusOK, h.traffic.report(limit))
+}
+
+// count records an answer with TrafficWindow.
+// Internal function.
+func (h *Handler) count(country string, info *sxgo.LocationInfo) {
+	if h.traffic != nil {
+		h.traffic.record(country, info)
+	}
+}
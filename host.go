@@ -0,0 +1,81 @@
+package sxgo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// HostLookup is the result of LookupHost.
+type HostLookup struct {
+	Host      string        `json:"host"`
+	Addresses []HostAddress `json:"addresses"` // In resolver order.
+	// Country is the ISO code most of the located addresses agree on (the
+	// first seen on a tie); empty if none was located.
+	Country string `json:"country,omitempty"`
+	// Consensus is true when at least one address was located to a country
+	// and all such addresses are in Country.
+	Consensus bool `json:"consensus"`
+}
+
+// HostAddress is one resolved address of a host and its location.
+type HostAddress struct {
+	IP netip.Addr `json:"ip"`
+	// Location is the GetCity result; nil if the address is not found,
+	// reserved, or IPv6 that cannot be looked up (see WithIPv6Derivation),
+	// and on instances without city lookups (see LookupHost).
+	Location *LocationInfo `json:"location,omitempty"`
+	// Country is the ISO code of the address's country; empty if unknown.
+	Country string `json:"country,omitempty"`
+}
+
+// LookupHost resolves the A and AAAA records of host and locates every
+// address, e.g. to vet third-party endpoints. Addresses are located as by
+// GetCity; IPv6 addresses are only located when they embed an IPv4 address
+// and WithIPv6Derivation is used. With a Country database or WithCountryOnly,
+// addresses are located to their country only, as by GetCountry. An error is
+// returned if resolution fails or the database cannot be read.
+func (s *SxGeo) LookupHost(ctx context.Context, host string) (*HostLookup, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to resolve host %s: %w", host, err)
+	}
+
+	cities := s.db().seekIDs() && !s.countryOnly // Otherwise the consensus needs only countries
+	res := &HostLookup{Host: host, Addresses: make([]HostAddress, 0, len(addrs))}
+	counts := make(map[string]int)
+	var order []string // Countries in first-seen order
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		ha := HostAddress{IP: addr}
+		ip := addr.String()
+		if _, _, err := s.parseIP(ip); err == nil && cities {
+			if ha.Location, err = s.lookup("GetCity", s.cityLookup, depthCity, ip, nil); err != nil {
+				return nil, fmt.Errorf("sxgo: failed to locate %s for host %s: %w", ip, host, err)
+			}
+			if l := ha.Location; l != nil && l.Country != nil {
+				ha.Country = l.Country.ISO
+			}
+		} else if err == nil {
+			if ha.Country, err = s.country(ip); err != nil {
+				return nil, fmt.Errorf("sxgo: failed to locate %s for host %s: %w", ip, host, err)
+			}
+		}
+		if iso := ha.Country; iso != "" {
+			if counts[iso] == 0 {
+				order = append(order, iso)
+			}
+			counts[iso]++
+		}
+		res.Addresses = append(res.Addresses, ha)
+	}
+
+	for _, iso := range order {
+		if counts[iso] > counts[res.Country] {
+			res.Country = iso
+		}
+	}
+	res.Consensus = len(order) == 1
+	return res, nil
+}
@@ -0,0 +1,190 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImportResult describes the outcome of ImportDatabase.
+type ImportResult struct {
+	Source   string    `json:"source"`             // Staged file chosen.
+	Manifest *Manifest `json:"manifest,omitempty"` // Its bundle manifest; nil for a .dat file.
+	Created  time.Time `json:"created"`            // Creation time of the staged database.
+	// Installed is the creation time of the database at the destination
+	// before the import; zero if there was none or it could not be opened.
+	Installed time.Time `json:"installed"`
+	Updated   bool      `json:"updated"` // Whether the destination was replaced.
+}
+
+// ErrImportMismatch is returned (wrapped) by ImportDatabase when no staged
+// database has the type and charset of the database installed at the
+// destination.
+var ErrImportMismatch = errors.New("sxgo: staged database does not match the installed one")
+
+// ImportDatabase installs a database staged on local storage (e.g. a mounted
+// USB drive) at dest, for hosts without access to the download servers. src
+// is a bundle or .dat file, or a directory whose newest database (by header
+// creation time, then name) is used. Staged databases must open with New, so
+// bundles are verified against their manifest. If a database is installed at
+// dest, only staged databases of its type and charset are considered (e.g.
+// SxGeoCity.dat, not the SxGeo.dat shipped alongside it), and
+// ErrImportMismatch is returned if there is none. dest is only replaced,
+// atomically, by a strictly newer database; Updated reports whether it was. A
+// bundle is installed as the bare database when dest does not have the
+// BundleExt extension, and a .dat file is bundled when it does.
+func ImportDatabase(src, dest string) (*ImportResult, error) {
+	var installed *DatabaseInfo
+	if cur, err := New(dest, ModeFile); err == nil {
+		info := cur.Info()
+		installed = &info
+		cur.Close()
+	}
+	res, err := newestStaged(src, installed)
+	if err != nil {
+		return nil, err
+	}
+	if installed != nil {
+		res.Installed = installed.Created
+	}
+	if !res.Created.After(res.Installed) {
+		return res, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to stage import of %q: %w", res.Source, err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	err = copyStaged(tmp, res, isBundle(dest))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to copy %q: %w", res.Source, err)
+	}
+
+	// The copy must open under the destination's extension before replacing it
+	check := tmp.Name() + filepath.Ext(dest)
+	if err := os.Rename(tmp.Name(), check); err != nil {
+		return nil, fmt.Errorf("sxgo: failed to stage import of %q: %w", res.Source, err)
+	}
+	defer os.Remove(check)
+	geo, err := New(check, ModeFile)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: imported copy of %q is invalid: %w", res.Source, err)
+	}
+	geo.Close()
+	if err := os.Rename(check, dest); err != nil {
+		return nil, fmt.Errorf("sxgo: failed to install %q: %w", res.Source, err)
+	}
+	res.Updated = true
+	return res, nil
+}
+
+// newestStaged opens the database at src, or each one in the directory src,
+// and describes the newest of those with the type and charset of want, if
+// not nil.
+// Internal function.
+func newestStaged(src string, want *DatabaseInfo) (*ImportResult, error) {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to read import source: %w", err)
+	}
+	paths := []string{src}
+	if fi.IsDir() {
+		if paths, err = databaseFiles(src); err != nil {
+			return nil, fmt.Errorf("sxgo: failed to read import source: %w", err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("sxgo: no .dat or %s file in %q", BundleExt, src)
+		}
+	}
+
+	var best *ImportResult
+	for _, p := range paths {
+		geo, err := New(p, ModeFile)
+		if err != nil {
+			return nil, fmt.Errorf("sxgo: staged database rejected: %w", err)
+		}
+		info := geo.Info()
+		r := &ImportResult{Source: p, Manifest: geo.Manifest(), Created: info.Created}
+		geo.Close()
+		if want != nil && (info.Type != want.Type || info.Charset != want.Charset) {
+			if len(paths) == 1 {
+				return nil, fmt.Errorf("%w: %q is a %s database, the installed one is a %s",
+					ErrImportMismatch, p, databaseKind(info), databaseKind(*want))
+			}
+			continue
+		}
+		if best == nil || r.Created.After(best.Created) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no %s database in %q", ErrImportMismatch, databaseKind(*want), src)
+	}
+	return best, nil
+}
+
+// databaseKind describes the type and charset of a database, e.g.
+// "SxGeo City (utf-8)".
+// Internal function.
+func databaseKind(info DatabaseInfo) string {
+	return fmt.Sprintf("%s (%s)", info.Type, info.Charset)
+}
+
+// copyStaged writes the staged database of res to w, as a bundle if bundle is
+// set and as a bare database otherwise.
+// Internal function.
+func copyStaged(w io.Writer, res *ImportResult, bundle bool) error {
+	if bundle && res.Manifest == nil {
+		return WriteBundle(w, res.Source, Manifest{})
+	}
+	f, err := os.Open(res.Source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if bundle || res.Manifest == nil {
+		_, err = io.Copy(w, f)
+		return err
+	}
+	b, err := readBundle(f)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, io.NewSectionReader(f, b.offset, b.size))
+	return err
+}
+
+// databaseFiles returns the paths of the .dat and bundle files in dir, by
+// name.
+// Internal function.
+func databaseFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.Type().IsRegular() && (ext == ".dat" || ext == BundleExt) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// isBundle reports whether New opens path as a bundle.
+// Internal function.
+func isBundle(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), BundleExt)
+}
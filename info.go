@@ -0,0 +1,52 @@
+package sxgo
+
+import "time"
+
+// DatabaseInfo is the typed counterpart of About: metadata from the database header.
+type DatabaseInfo struct {
+	Type        DBType    `json:"type"`         // Database type.
+	Charset     Charset   `json:"charset"`      // Character set of names.
+	Version     uint8     `json:"version"`      // Format version (22 for v2.2).
+	Created     time.Time `json:"created"`      // Creation time (UTC).
+	IPRanges    uint32    `json:"ip_ranges"`    // Number of IP range blocks.
+	IDLength    uint8     `json:"id_length"`    // Size of IDs in range blocks, in bytes.
+	PackFormats []string  `json:"pack_formats"` // Record formats for country, region, city.
+	// License and Attribution come from WithAttribution or the bundle
+	// manifest (see Manifest); empty if neither provides them.
+	License     string `json:"license,omitempty"`
+	Attribution string `json:"attribution,omitempty"`
+}
+
+// WithAttribution sets the license and the attribution notice reported by
+// Info and About, e.g. for services that must credit the data provider in
+// their responses. Each overrides the bundle manifest's value when non-empty.
+func WithAttribution(license, attribution string) Option {
+	return func(s *SxGeo) {
+		s.license, s.attribution = license, attribution
+	}
+}
+
+// Info returns typed metadata about the loaded database.
+func (s *SxGeo) Info() DatabaseInfo {
+	db := s.db()
+	info := DatabaseInfo{
+		Type:        DBType(db.header.dbType),
+		Charset:     Charset(db.header.charset),
+		Version:     db.header.version,
+		Created:     time.Unix(int64(db.header.timestamp), 0).UTC(),
+		IPRanges:    db.header.dbItems,
+		IDLength:    db.header.idLen,
+		PackFormats: append([]string(nil), db.packFormats...),
+		License:     s.license,
+		Attribution: s.attribution,
+	}
+	if m := db.bundle; m != nil {
+		if info.License == "" {
+			info.License = m.License
+		}
+		if info.Attribution == "" {
+			info.Attribution = m.Attribution
+		}
+	}
+	return info
+}
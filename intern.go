@@ -0,0 +1,46 @@
+package sxgo
+
+import "unique"
+
+// WithStringInterning makes records decoded by lookups share the backing
+// storage of identical strings (names and ISO codes), which repeat across
+// many results. It reduces the heap of services that retain many
+// LocationInfo values, at the cost of a hash table probe per string decoded.
+// Interned strings are freed once no result refers to them.
+func WithStringInterning(enabled bool) Option {
+	return func(s *SxGeo) {
+		s.intern = enabled
+	}
+}
+
+// internRecord replaces the strings of a decoded record with their canonical
+// copies.
+// Internal function.
+func internRecord(rec map[string]interface{}) {
+	for k, v := range rec {
+		if str, ok := v.(string); ok && str != "" {
+			rec[k] = internString(str)
+		}
+	}
+}
+
+// internLocation replaces the names and ISO codes of info with their
+// canonical copies.
+// Internal function.
+func internLocation(info *LocationInfo) {
+	if c := info.City; c != nil {
+		c.NameRU, c.NameEN = internString(c.NameRU), internString(c.NameEN)
+	}
+	if r := info.Region; r != nil {
+		r.NameRU, r.NameEN, r.ISO = internString(r.NameRU), internString(r.NameEN), internString(r.ISO)
+	}
+	if c := info.Country; c != nil {
+		c.NameRU, c.NameEN, c.ISO = internString(c.NameRU), internString(c.NameEN), internString(c.ISO)
+	}
+}
+
+// internString returns the canonical copy of str.
+// Internal function.
+func internString(str string) string {
+	return unique.Make(str).Value()
+}
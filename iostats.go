@@ -0,0 +1,116 @@
+package sxgo
+
+import "sync/atomic"
+
+// IOStats measures the database I/O of an instance, to compare the cost of
+// ModeFile and ModeMemory from observed reads.
+type IOStats struct {
+	// LoadBytes were read by New: header and indexes, plus every data block
+	// in ModeMemory.
+	LoadBytes uint64 `json:"load_bytes"`
+	// Reads and Bytes count file reads after New: lookups in ModeFile, and
+	// methods scanning the file such as Fingerprint.
+	Reads uint64 `json:"reads"`
+	Bytes uint64 `json:"bytes"`
+	// BytesPerLookup is Bytes divided by Stats.Lookups (0 before any lookup).
+	BytesPerLookup float64 `json:"bytes_per_lookup"`
+	// Sections splits LoadBytes plus Bytes by the part of the file read.
+	Sections IOSections `json:"sections"`
+}
+
+// IOSections holds byte counts per database section.
+type IOSections struct {
+	Index     uint64 `json:"index"`     // Header, pack formats, byte and main indexes.
+	Blocks    uint64 `json:"blocks"`    // Range blocks.
+	Regions   uint64 `json:"regions"`   // Region records.
+	Cities    uint64 `json:"cities"`    // City records.
+	Countries uint64 `json:"countries"` // Country records.
+}
+
+// Sections tracked by ioCounters.
+const (
+	sectionIndex = iota
+	sectionBlocks
+	sectionRegions
+	sectionCities
+	sectionCountries
+	sectionCount
+)
+
+// ioCounters holds the counters behind IOStats.
+// This struct is internal.
+type ioCounters struct {
+	load, reads, bytes atomic.Uint64
+	sections           [sectionCount]atomic.Uint64
+}
+
+// countLoad records n bytes at offset off read by New.
+// Internal function.
+func (s *SxGeo) countLoad(db *dbState, off, n int64) {
+	s.stats.io.load.Add(uint64(n))
+	s.countSections(db, off, n)
+}
+
+// countRead records a file read of n bytes at offset off after New.
+// Internal function.
+func (s *SxGeo) countRead(db *dbState, off int64, n int) {
+	s.stats.io.reads.Add(1)
+	s.stats.io.bytes.Add(uint64(n))
+	if t := s.telemetry; t != nil {
+		t.CounterAdd(MetricFileReads, 1)
+		t.CounterAdd(MetricFileReadBytes, uint64(n))
+	}
+	s.countSections(db, off, int64(n))
+}
+
+// countSections adds the n bytes at offset off to the sections of db they overlap.
+// Country records inside the cities block count as countries, not cities.
+// Internal function.
+func (s *SxGeo) countSections(db *dbState, off, n int64) {
+	if n <= 0 {
+		return
+	}
+	end := off + n
+	overlap := func(from, to int64) uint64 {
+		return uint64(max(0, min(end, to)-max(off, from)))
+	}
+	c := &s.stats.io.sections
+	citiesEnd := db.citiesBegin + int64(db.header.citySize)
+	countries := overlap(db.countriesBegin, db.countriesBegin+int64(min(db.header.countrySize, db.countryBlockLen())))
+	cities := overlap(db.citiesBegin, citiesEnd)
+	if !db.separateCountries {
+		cities -= countries
+	}
+	for i, v := range [sectionCount]uint64{
+		sectionIndex:     overlap(0, db.dbBegin),
+		sectionBlocks:    overlap(db.dbBegin, db.regionsBegin),
+		sectionRegions:   overlap(db.regionsBegin, db.citiesBegin),
+		sectionCities:    cities,
+		sectionCountries: countries,
+	} {
+		if v > 0 {
+			c[i].Add(v)
+		}
+	}
+}
+
+// snapshot returns the I/O counters as IOStats, given the lookup count.
+// Internal function.
+func (c *ioCounters) snapshot(lookups uint64) IOStats {
+	st := IOStats{
+		LoadBytes: c.load.Load(),
+		Reads:     c.reads.Load(),
+		Bytes:     c.bytes.Load(),
+		Sections: IOSections{
+			Index:     c.sections[sectionIndex].Load(),
+			Blocks:    c.sections[sectionBlocks].Load(),
+			Regions:   c.sections[sectionRegions].Load(),
+			Cities:    c.sections[sectionCities].Load(),
+			Countries: c.sections[sectionCountries].Load(),
+		},
+	}
+	if lookups > 0 {
+		st.BytesPerLookup = float64(st.Bytes) / float64(lookups)
+	}
+	return st
+}
@@ -0,0 +1,218 @@
+package sxgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// IPHashAlgorithm selects the keyed hash an IPHasher computes.
+type IPHashAlgorithm int
+
+const (
+	// HashHMACSHA256 is HMAC-SHA256 truncated to 128 bits. Keys may have any
+	// length; 32 random bytes are recommended.
+	HashHMACSHA256 IPHashAlgorithm = iota
+	// HashSipHash is SipHash-2-4, a fast 64-bit keyed hash suited to high
+	// volumes. Keys must be 16 bytes.
+	HashSipHash
+)
+
+// String returns the name of the algorithm.
+func (a IPHashAlgorithm) String() string {
+	switch a {
+	case HashHMACSHA256:
+		return "hmac-sha256"
+	case HashSipHash:
+		return "siphash-2-4"
+	}
+	return fmt.Sprintf("IPHashAlgorithm(%d)", int(a))
+}
+
+// ipHashKey is a key of an IPHasher and its ID.
+// This struct is internal.
+type ipHashKey struct {
+	id  string
+	key []byte
+}
+
+// IPHasher computes keyed hashes of IP addresses, so telemetry can count and
+// correlate clients without containing their addresses: without the key, a
+// hash cannot be reversed by hashing candidate addresses. Keys carry an ID,
+// which prefixes the hashes ("id:hex") when not empty, and can be rotated;
+// the previous key is kept so hashes made before a rotation can still be
+// matched. An IPHasher is safe for concurrent use.
+type IPHasher struct {
+	alg  IPHashAlgorithm
+	mu   sync.RWMutex
+	cur  ipHashKey
+	prev *ipHashKey
+}
+
+// NewIPHasher returns an IPHasher using alg with the given key.
+func NewIPHasher(alg IPHashAlgorithm, keyID string, key []byte) (*IPHasher, error) {
+	h := &IPHasher{alg: alg}
+	if err := h.Rotate(keyID, key); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Rotate makes key, with ID keyID, the current key. The previous current key
+// is kept for Match; older keys are forgotten. keyID must not contain ':'.
+func (h *IPHasher) Rotate(keyID string, key []byte) error {
+	if strings.Contains(keyID, ":") {
+		return fmt.Errorf("sxgo: invalid IP hash key ID %q: contains ':'", keyID)
+	}
+	switch h.alg {
+	case HashHMACSHA256:
+		if len(key) == 0 {
+			return errors.New("sxgo: IP hash key is empty")
+		}
+	case HashSipHash:
+		if len(key) != 16 {
+			return fmt.Errorf("sxgo: SipHash key must be 16 bytes, got %d", len(key))
+		}
+	default:
+		return fmt.Errorf("sxgo: unknown IP hash algorithm %v", h.alg)
+	}
+	k := ipHashKey{id: keyID, key: append([]byte(nil), key...)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cur.key != nil {
+		prev := h.cur
+		h.prev = &prev
+	}
+	h.cur = k
+	return nil
+}
+
+// Hash returns the hash of ip under the current key, in hex, prefixed with
+// the key ID and ':' if it is not empty. Addresses are normalized first, so
+// all spellings of an address (including IPv4-mapped IPv6) hash alike;
+// strings that are not addresses are hashed as they are.
+func (h *IPHasher) Hash(ip string) string {
+	h.mu.RLock()
+	k := h.cur
+	h.mu.RUnlock()
+	return formatIPHash(k.id, h.sum(k.key, ip))
+}
+
+// Sum64 returns the first 64 bits of the hash of ip under the current key,
+// for sampling and sharding.
+func (h *IPHasher) Sum64(ip string) uint64 {
+	h.mu.RLock()
+	k := h.cur
+	h.mu.RUnlock()
+	return binary.BigEndian.Uint64(h.sum(k.key, ip))
+}
+
+// Match reports whether hashed is the Hash of ip under the current or the
+// previous key, as selected by the key ID it carries.
+func (h *IPHasher) Match(ip, hashed string) bool {
+	id, _, found := strings.Cut(hashed, ":")
+	if !found {
+		id = ""
+	}
+	h.mu.RLock()
+	keys := []ipHashKey{h.cur}
+	if h.prev != nil {
+		keys = append(keys, *h.prev)
+	}
+	h.mu.RUnlock()
+	for _, k := range keys {
+		if k.id == id && hmac.Equal([]byte(formatIPHash(k.id, h.sum(k.key, ip))), []byte(hashed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sum hashes the normalized form of ip with key.
+// Internal function.
+func (h *IPHasher) sum(key []byte, ip string) []byte {
+	msg := []byte(ip)
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		msg, _ = addr.Unmap().MarshalBinary()
+	}
+	if h.alg == HashSipHash {
+		var out [8]byte
+		binary.BigEndian.PutUint64(out[:], sipHash24(key, msg))
+		return out[:]
+	}
+	m := hmac.New(sha256.New, key)
+	m.Write(msg)
+	return m.Sum(nil)[:16]
+}
+
+// formatIPHash renders a hash as Hash returns it.
+// Internal function.
+func formatIPHash(id string, sum []byte) string {
+	if id == "" {
+		return hex.EncodeToString(sum)
+	}
+	return id + ":" + hex.EncodeToString(sum)
+}
+
+// sipHash24 computes SipHash-2-4 of msg with a 16-byte key.
+// Internal function.
+func sipHash24(key, msg []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13) ^ v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16) ^ v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21) ^ v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17) ^ v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(msg)
+	for ; len(msg) >= 8; msg = msg[8:] {
+		m := binary.LittleEndian.Uint64(msg)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	last := uint64(n) << 56
+	for i, b := range msg {
+		last |= uint64(b) << (8 * i)
+	}
+	v3 ^= last
+	round()
+	round()
+	v0 ^= last
+
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		round()
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// WithIPHasher sets the hasher identifying addresses in telemetry: the slow
+// lookup log (see WithSlowLookupLog) records SlowLookup.IPHash with it, and
+// the lookup audit log (see WithLookupAudit) uses it when LookupAudit sets
+// neither Hasher nor Key.
+func WithIPHasher(h *IPHasher) Option {
+	return func(s *SxGeo) {
+		s.hasher = h
+	}
+}
@@ -0,0 +1,184 @@
+package sxgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LazyLocation is a lookup result that decodes names on first use, from the
+// record bytes it retains. Numeric fields (IDs, coordinates) are decoded by
+// GetLazy; names, the costly part of a record, only when Name asks for them,
+// so pipelines that mostly inspect the country do not pay for them. It
+// describes the record the address resolves to: a city, or a region or
+// country for ranges known only to that level. It is safe for concurrent use.
+type LazyLocation struct {
+	s           *SxGeo
+	db          *dbState // Database the record comes from
+	kind        recordKind
+	format      string
+	raw         []byte // Record bytes; aliases the loaded data in ModeMemory, copied in ModeMMap
+	DerivedFrom string // As LocationInfo.DerivedFrom.
+
+	mu     sync.Mutex
+	fields map[string]interface{} // Decoded so far
+}
+
+// GetLazy looks up ip like GetCity, returning a LazyLocation. Results are not
+// post-processed: middleware, post-processors, country remapping and call
+// options do not apply; use GetCity where they matter.
+// Returns (nil, nil) where GetCity does, or the same errors with
+// WithNotFoundErrors.
+func (s *SxGeo) GetLazy(ip string) (*LazyLocation, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if !db.seekIDs() {
+		return nil, s.missing(ip, true) // Not a city database
+	}
+	if s.countryOnly {
+		return nil, fmt.Errorf("sxgo: lazy lookup failed for IP %s: %w", ip, ErrCountryOnly)
+	}
+	ipNum, derived, err := s.parseIP(ip)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: lazy lookup failed for IP %s: %w", ip, err)
+	}
+	seek, err := s.lookupNum(db, ipNum)
+	if err != nil {
+		if errors.Is(err, ErrReservedRange) {
+			return nil, s.missing(ip, true) // Treat reserved range as not found
+		}
+		return nil, fmt.Errorf("sxgo: lazy lookup failed for IP %s: %w", ip, err)
+	}
+	if seek == 0 {
+		return nil, s.missing(ip, true)
+	}
+
+	l := &LazyLocation{s: s, db: db, kind: db.recordKind(seek), DerivedFrom: derived}
+	dataType, maxSize := 2, db.header.maxCity
+	switch l.kind {
+	case recordCountry:
+		dataType, maxSize = 0, db.header.maxCountry
+	case recordRegion:
+		dataType, maxSize = 1, db.header.maxRegion
+	}
+	if dataType >= len(db.packFormats) || db.packFormats[dataType] == "" {
+		return nil, fmt.Errorf("sxgo: lazy lookup failed for IP %s: database is missing pack format %d", ip, dataType)
+	}
+	l.format = db.packFormats[dataType]
+	data, err := s.recordBytes(db, seek, maxSize, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: lazy lookup failed for IP %s: %w", ip, err)
+	}
+	fields, n, err := unpackSelect(l.format, data, func(string) bool { return false })
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: parsing record failed for IP %s (seek %d): %w", ip, seek, err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("sxgo: record data not found or empty for IP %s (seek %d)", ip, seek)
+	}
+	l.raw, l.fields = data[:n], fields
+	if db.mapping != nil {
+		l.raw = bytes.Clone(l.raw) // The mapping may be gone by the time names are decoded
+	}
+	return l, nil
+}
+
+// CountryID returns the country ID, or 0 if unknown.
+func (l *LazyLocation) CountryID() uint8 {
+	switch l.kind {
+	case recordCountry:
+		return getUint8(l.field("id"), "id")
+	case recordRegion:
+		if !l.db.pin() {
+			return 0 // Closed or reloaded, and the mapping is gone
+		}
+		defer l.db.unpin()
+		if c, _ := l.s.regionCountry(l.db, l.field("iso", "country_seek")); c != nil {
+			return c.ID
+		}
+		return 0
+	}
+	return getUint8(l.field("country_id"), "country_id")
+}
+
+// CountryISO returns the ISO 3166-1 alpha-2 country code, or "" if unknown.
+func (l *LazyLocation) CountryISO() string {
+	if id := l.CountryID(); id > 0 {
+		return getISO(uint32(id))
+	}
+	return ""
+}
+
+// CityID returns the city ID, or 0 if the result is not a city.
+func (l *LazyLocation) CityID() uint32 {
+	if l.kind != recordCity {
+		return 0
+	}
+	return getUint32(l.field("id"), "id")
+}
+
+// Name returns the name of the place in lang, "ru" or "en" (any other value
+// selects "en"), decoding it on first use.
+func (l *LazyLocation) Name(lang string) string {
+	key := "name_en"
+	if strings.EqualFold(lang, "ru") {
+		key = "name_ru"
+	}
+	return getString(l.field(key), key)
+}
+
+// Coordinates returns the coordinates of the place; ok is false if the
+// record has none (regions never do).
+func (l *LazyLocation) Coordinates() (lat, lon float64, ok bool) {
+	f := l.field("lat", "lon")
+	lat, lon = getFloat(f, "lat"), getFloat(f, "lon")
+	return lat, lon, hasCoords(lat, lon)
+}
+
+// field returns the decoded fields, decoding the named ones first if needed.
+// The returned map must not be modified.
+// Internal function.
+func (l *LazyLocation) field(names ...string) map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var missing []string
+	for _, name := range names {
+		if _, ok := l.fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return l.fields
+	}
+
+	want := func(name string) bool {
+		for _, m := range missing {
+			if m == name {
+				return true
+			}
+		}
+		return false
+	}
+	decoded, _, err := unpackSelect(l.format, l.raw, want)
+	if err != nil {
+		return l.fields
+	}
+	fields := make(map[string]interface{}, len(l.fields)+len(missing))
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	for _, name := range missing {
+		if v, ok := decoded[name]; ok {
+			if str, isStr := v.(string); isStr && l.s.intern {
+				v = internString(str)
+			}
+			fields[name] = v
+		} else {
+			fields[name] = nil // Not in the record; do not decode again
+		}
+	}
+	l.fields = fields
+	return fields
+}
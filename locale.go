@@ -0,0 +1,36 @@
+package sxgo
+
+import "github.com/idanyas/sxgo/countries"
+
+// LocaleSuggestion holds personalization defaults for a visitor's country.
+type LocaleSuggestion struct {
+	Country    string   `json:"country"`    // ISO 3166-1 alpha-2 code the suggestion is for.
+	Locales    []string `json:"locales"`    // BCP 47 locale tags, most widely used first.
+	Currencies []string `json:"currencies"` // ISO 4217 currency codes, primary first.
+}
+
+// SuggestLocale returns locale and currency candidates for the country of ip,
+// e.g. en-US/USD or ru-RU/RUB, for geo-defaulted storefront settings.
+// Returns (nil, nil) if the country is unknown or not in the table; callers
+// should fall back to their own default (and always let users override).
+func (s *SxGeo) SuggestLocale(ip string) (*LocaleSuggestion, error) {
+	iso, err := s.country(ip)
+	if err != nil || iso == "" {
+		return nil, err
+	}
+	return SuggestLocaleForCountry(iso), nil
+}
+
+// SuggestLocaleForCountry is SuggestLocale for a known ISO 3166-1 alpha-2
+// country code (case-insensitive). Returns nil for countries not in the table.
+func SuggestLocaleForCountry(iso string) *LocaleSuggestion {
+	c, ok := countries.ByISO(iso)
+	if !ok || len(c.Locales) == 0 {
+		return nil
+	}
+	return &LocaleSuggestion{
+		Country:    c.ISO,
+		Locales:    c.Locales,
+		Currencies: c.Currencies,
+	}
+}
@@ -0,0 +1,30 @@
+package sxgo
+
+// WithMatchedRange makes GetCity, GetCityFull, GetCityRegion and Planner set
+// LocationInfo.Range to the range of the database block the address matched
+// (or of the patch, see ApplyPatch), so callers can cache results per range
+// and see how wide a match is. Ranges are clipped to the address's /8, as
+// the database indexes blocks by first byte; RangeHash hashes the same
+// range. Finding the bounds costs a walk of the /8's blocks per lookup (a
+// read of them in ModeFile). Synthetic and special-range results have no
+// Range.
+func WithMatchedRange() Option {
+	return func(s *SxGeo) {
+		s.matchedRange = true
+	}
+}
+
+// attachRange sets the Range of info, resolved from ipNum in db, with
+// WithMatchedRange.
+// Internal function.
+func (s *SxGeo) attachRange(db *dbState, ipNum uint32, info *LocationInfo) error {
+	if !s.matchedRange || info == nil {
+		return nil
+	}
+	r, _, err := s.rangeOf(db, ipNum)
+	if err != nil {
+		return err
+	}
+	info.Range = &IPRange{First: uint32ToAddr(r.first), Last: uint32ToAddr(r.last)}
+	return nil
+}
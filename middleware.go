@@ -0,0 +1,36 @@
+package sxgo
+
+// LookupFunc resolves an IP address string to location information.
+// It follows the GetCity/GetCityFull conventions: (nil, nil) means not found.
+type LookupFunc func(ip string) (*LocationInfo, error)
+
+// LookupMiddleware wraps a LookupFunc to add cross-cutting behaviour such as
+// caching, metrics, overrides or anonymization. A middleware may call next,
+// short-circuit it, or modify its result.
+type LookupMiddleware func(next LookupFunc) LookupFunc
+
+// WithMiddleware appends middleware to the chain wrapping GetCity, GetCityRegion
+// and GetCityFull.
+// The first middleware registered is the outermost one, i.e. it sees the call first
+// and the result last. Post-processors (WithPostProcessor) run inside the chain,
+// before any middleware sees the result.
+// GetCountry and GetCountryID are not affected.
+func WithMiddleware(mw ...LookupMiddleware) Option {
+	return func(s *SxGeo) {
+		for _, m := range mw {
+			if m != nil {
+				s.middleware = append(s.middleware, m)
+			}
+		}
+	}
+}
+
+// chainLookup wraps base in mws so that mws[0] is the outermost layer.
+// Internal function.
+func chainLookup(base LookupFunc, mws []LookupMiddleware) LookupFunc {
+	fn := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		fn = mws[i](fn)
+	}
+	return fn
+}
@@ -0,0 +1,133 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// errMMapUnsupported is returned by mmapFile on platforms without mmap.
+var errMMapUnsupported = errors.New("mmap is not supported on this platform")
+
+// mapDatabase maps the database file of db with ModeMMap and points the data
+// sections at the mapping, switching the instance to in-memory reads. It
+// reports false, leaving db untouched, without ModeMMap or where mmap is not
+// supported. Country-only instances (WithCountryOnly) keep every section
+// mapped, as pages are only read in when used.
+// Internal function.
+func (s *SxGeo) mapDatabase(db *dbState) (bool, error) {
+	if !s.mmapMode || db.end <= db.dbBegin {
+		return false, nil
+	}
+	data, err := mmapFile(db.f, db.end)
+	if errors.Is(err, errMMapUnsupported) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	need := max(db.citiesBegin+int64(db.header.citySize), db.countriesBegin+int64(db.header.countrySize))
+	if int64(len(data)) < need {
+		munmap(data)
+		return false, fmt.Errorf("database is truncated: %d bytes, need %d", len(data), need)
+	}
+
+	section := func(off int64, size uint32) []byte {
+		if size == 0 {
+			return nil
+		}
+		end := off + int64(size)
+		return data[off:end:end]
+	}
+	db.mapping = data
+	db.ref = newDBRef(data, nil)
+	db.dbData = section(db.dbBegin, db.header.dbItems*db.blockSize)
+	db.regionsData = section(db.regionsBegin, db.header.regionSize)
+	db.citiesData = section(db.citiesBegin, db.header.citySize)
+	db.countriesData = db.citiesData
+	if db.separateCountries {
+		db.countriesData = section(db.countriesBegin, db.header.countrySize)
+	}
+	if !s.memoryMode { // Already set when Reload maps the file again
+		s.memoryMode = true
+	}
+	return true, nil
+}
+
+// dbRef counts the users of a mapping or ModeFile handle so that it is
+// unmapped or closed only once none is left: the dbState publishing it, until
+// Close, Reload or a WithFileWatch reopen retire it, and the calls reading it
+// meanwhile (see acquire).
+// This struct is internal.
+type dbRef struct {
+	data    []byte       // Mapping to unmap, if any
+	f       *os.File     // Handle to close, if any
+	users   atomic.Int64 // 0 once released
+	retired atomic.Bool
+}
+
+// newDBRef returns a dbRef for the mapping data or the handle f, with the
+// publishing dbState as its only user.
+// Internal function.
+func newDBRef(data []byte, f *os.File) *dbRef {
+	r := &dbRef{data: data, f: f}
+	r.users.Store(1)
+	return r
+}
+
+// acquire returns the database lookups currently read, pinned so that its
+// mapping stays in place and its file open until the caller calls unpin.
+// Internal function.
+func (s *SxGeo) acquire() *dbState {
+	for {
+		db := s.db()
+		if db.pin() {
+			return db
+		}
+		// Retired meanwhile; Close and Reload publish its successor first
+	}
+}
+
+// pin adds a user to the mapping or file of db, if it has one, reporting
+// false if it was released already.
+// Internal function.
+func (db *dbState) pin() bool {
+	if db.ref == nil {
+		return true
+	}
+	for {
+		n := db.ref.users.Load()
+		if n == 0 {
+			return false
+		}
+		if db.ref.users.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// unpin removes a user added by pin or acquire, unmapping or closing the
+// file if it was the last one.
+// Internal function.
+func (db *dbState) unpin() error {
+	if db.ref == nil || db.ref.users.Add(-1) != 0 {
+		return nil
+	}
+	if db.ref.data != nil {
+		return munmap(db.ref.data)
+	}
+	return db.ref.f.Close()
+}
+
+// retire drops the reference db holds on its mapping or file once it is no
+// longer published: the file is unmapped or closed now if no call is reading
+// it, else when the last one is done. Only the first call has an effect.
+// Internal function.
+func (db *dbState) retire() error {
+	if db.ref == nil || !db.ref.retired.CompareAndSwap(false, true) {
+		return nil
+	}
+	return db.unpin()
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package sxgo
+
+import "os"
+
+// mmapFile is not available on this platform; ModeMMap falls back to
+// ModeFile.
+// Internal function.
+func mmapFile(f *os.File, size int64) ([]byte, error) {
+	return nil, errMMapUnsupported
+}
+
+// munmap is never called on this platform.
+// Internal function.
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,183 @@
+package sxgo
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameNormalization selects the steps NormalizeName applies to place names,
+// so that joins against external datasets (GeoNames, internal city tables)
+// do not fail on formatting differences. Steps combine with |.
+type NameNormalization uint8
+
+const (
+	// NormalizeNFC composes a letter followed by a combining accent into the
+	// precomposed letter ("й" as "и" + U+0306 becomes U+0439), for Latin,
+	// Greek and Cyrillic letters. It covers the decomposed forms names come
+	// in, but is not a complete Unicode NFC implementation.
+	NormalizeNFC NameNormalization = 1 << iota
+	// NormalizeApostrophes replaces typographic apostrophes and the modifier
+	// letters used in transliterations (’ ʼ ʹ ` ´ ′) with "'", as in "Oblast'".
+	NormalizeApostrophes
+	// NormalizeSpace trims the name and collapses runs of white space.
+	NormalizeSpace
+	// NormalizeTitleCase upper-cases the first letter of each word, words
+	// being separated by white space and hyphens; letters after an apostrophe
+	// do not start a word ("Kam'yanets", not "Kam'Yanets"). Other letters
+	// are left as they are.
+	NormalizeTitleCase
+	// NormalizeFold lower-cases the name, for case-insensitive join keys.
+	// It is applied last, so it overrides NormalizeTitleCase.
+	NormalizeFold
+)
+
+// DefaultNameNormalization fixes encoding and formatting differences without
+// changing letter case.
+const DefaultNameNormalization = NormalizeNFC | NormalizeApostrophes | NormalizeSpace
+
+// WithNameNormalization normalizes the names of lookup results (city, region
+// and country, in both languages) with NormalizeName, before the
+// post-processors run (see WithPostProcessor). Apply NormalizeName with the
+// same steps to the names of the dataset the results are joined with.
+func WithNameNormalization(n NameNormalization) Option {
+	return func(s *SxGeo) {
+		s.nameNorm = n
+	}
+}
+
+// NormalizeName applies the steps of n to name.
+func NormalizeName(name string, n NameNormalization) string {
+	if n&NormalizeNFC != 0 {
+		name = composeMarks(name)
+	}
+	if n&NormalizeApostrophes != 0 {
+		name = apostropheReplacer.Replace(name)
+	}
+	if n&NormalizeSpace != 0 {
+		name = strings.Join(strings.Fields(name), " ")
+	}
+	if n&NormalizeTitleCase != 0 {
+		name = titleCase(name)
+	}
+	if n&NormalizeFold != 0 {
+		name = strings.ToLower(name)
+	}
+	return name
+}
+
+// normalizeNames applies the instance's name normalization to info.
+// Internal function.
+func (s *SxGeo) normalizeNames(info *LocationInfo) {
+	n := s.nameNorm
+	if n == 0 {
+		return
+	}
+	if c := info.City; c != nil {
+		c.NameRU, c.NameEN = NormalizeName(c.NameRU, n), NormalizeName(c.NameEN, n)
+	}
+	if r := info.Region; r != nil {
+		r.NameRU, r.NameEN = NormalizeName(r.NameRU, n), NormalizeName(r.NameEN, n)
+	}
+	if c := info.Country; c != nil {
+		c.NameRU, c.NameEN = NormalizeName(c.NameRU, n), NormalizeName(c.NameEN, n)
+	}
+}
+
+// apostropheReplacer maps apostrophe look-alikes to "'".
+var apostropheReplacer = strings.NewReplacer(
+	"’", "'", // Right single quotation mark
+	"‘", "'", // Left single quotation mark
+	"ʼ", "'", // Modifier letter apostrophe
+	"ʹ", "'", // Modifier letter prime
+	"`", "'",
+	"´", "'", // Acute accent
+	"′", "'", // Prime
+)
+
+// titleCase implements NormalizeTitleCase.
+// Internal function.
+func titleCase(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	start := true
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r) || r == '-':
+			start = true
+		case start && unicode.IsLetter(r):
+			r = unicode.ToTitle(r)
+			start = false
+		default:
+			start = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// composeMarks implements NormalizeNFC.
+// Internal function.
+func composeMarks(name string) string {
+	if !strings.ContainsFunc(name, func(r rune) bool { return unicode.Is(unicode.Mn, r) }) {
+		return name
+	}
+	table := compositions()
+	out := make([]rune, 0, utf8.RuneCountInString(name))
+	for _, r := range name {
+		if n := len(out); n > 0 && unicode.Is(unicode.Mn, r) {
+			if c, ok := table[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compositions returns the (letter, mark) -> precomposed letter table built
+// from markCompositions on first use.
+// Internal function.
+var compositions = sync.OnceValue(func() map[[2]rune]rune {
+	table := make(map[[2]rune]rune)
+	for mark, pairs := range markCompositions {
+		runes := []rune(pairs)
+		for i := 0; i+1 < len(runes); i += 2 {
+			table[[2]rune{runes[i], mark}] = runes[i+1]
+		}
+	}
+	return table
+})
+
+// markCompositions lists, per combining mark, pairs of a letter and the
+// precomposed letter it forms with the mark (Unicode canonical compositions
+// in the Latin, Greek and Cyrillic blocks).
+var markCompositions = map[rune]string{
+	0x0300: "AÀEÈIÌOÒUÙaàeèiìoòuùÜǛüǜNǸnǹЕЀИЍеѐиѝĒḔēḕŌṐōṑWẀwẁÂẦâầĂẰăằÊỀêềÔỒôồƠỜơờƯỪưừYỲyỳ",                                                                                                     // Combining Grave Accent
+	0x0301: "AÁEÉIÍOÓUÚYÝaáeéiíoóuúyýCĆcćLĹlĺNŃnńRŔrŕSŚsśZŹzźÜǗüǘGǴgǵÅǺåǻÆǼæǽØǾøǿ¨΅ΑΆΕΈΗΉΙΊΟΌΥΎΩΏϊΐαάεέηήιίϋΰοόυύωώϒϓГЃКЌгѓкќÇḈçḉĒḖēḗÏḮïḯKḰkḱMḾmḿÕṌõṍŌṒōṓPṔpṕŨṸũṹWẂwẃÂẤâấĂẮăắÊẾêếÔỐôốƠỚơớƯỨưứ", // Combining Acute Accent
+	0x0302: "AÂEÊIÎOÔUÛaâeêiîoôuûCĈcĉGĜgĝHĤhĥJĴjĵSŜsŝWŴwŵYŶyŷZẐzẑẠẬạậẸỆẹệỌỘọộ",                                                                                                                 // Combining Circumflex Accent
+	0x0303: "AÃNÑOÕaãnñoõIĨiĩUŨuũVṼvṽÂẪâẫĂẴăẵEẼeẽÊỄêễÔỖôỗƠỠơỡƯỮưữYỸyỹ",                                                                                                                         // Combining Tilde
+	0x0304: "AĀaāEĒeēIĪiīOŌoōUŪuūÜǕüǖÄǞäǟȦǠȧǡÆǢæǣǪǬǫǭÖȪöȫÕȬõȭȮȰȯȱYȲyȳИӢиӣУӮуӯGḠgḡḶḸḷḹṚṜṛṝ",                                                                                                     // Combining Macron
+	0x0306: "AĂaăEĔeĕGĞgğIĬiĭOŎoŏUŬuŭУЎИЙийуўЖӁжӂАӐаӑЕӖеӗȨḜȩḝẠẶạặ",                                                                                                                             // Combining Breve
+	0x0307: "CĊcċEĖeėGĠgġIİZŻzżAȦaȧOȮoȯBḂbḃDḊdḋFḞfḟHḢhḣMṀmṁNṄnṅPṖpṗRṘrṙSṠsṡŚṤśṥŠṦšṧṢṨṣṩTṪtṫWẆwẇXẊxẋYẎyẏſẛ",                                                                                     // Combining Dot Above
+	0x0308: "AÄEËIÏOÖUÜaäeëiïoöuüyÿYŸΙΪΥΫιϊυϋϒϔЕЁІЇеёіїАӒаӓӘӚәӛЖӜжӝЗӞзӟИӤиӥОӦоӧӨӪөӫЭӬэӭУӰуӱЧӴчӵЫӸыӹHḦhḧÕṎõṏŪṺūṻWẄwẅXẌxẍtẗ",                                                                     // Combining Diaeresis
+	0x0309: "AẢaảÂẨâẩĂẲăẳEẺeẻÊỂêểIỈiỉOỎoỏÔỔôổƠỞơởUỦuủƯỬưửYỶyỷ",                                                                                                                                 // Combining Hook Above
+	0x030A: "AÅaåUŮuůwẘyẙ",                                                                                                                                                                     // Combining Ring Above
+	0x030B: "OŐoőUŰuűУӲуӳ",                                                                                                                                                                     // Combining Double Acute Accent
+	0x030C: "CČcčDĎdďEĚeěLĽlľNŇnňRŘrřSŠsšTŤtťZŽzžAǍaǎIǏiǐOǑoǒUǓuǔÜǙüǚGǦgǧKǨkǩƷǮʒǯjǰHȞhȟ",                                                                                                       // Combining Caron
+	0x030F: "AȀaȁEȄeȅIȈiȉOȌoȍRȐrȑUȔuȕѴѶѵѷ",                                                                                                                                                     // Combining Double Grave Accent
+	0x0311: "AȂaȃEȆeȇIȊiȋOȎoȏRȒrȓUȖuȗ",                                                                                                                                                         // Combining Inverted Breve
+	0x031B: "OƠoơUƯuư",                                                                                                                                                                         // Combining Horn
+	0x0323: "BḄbḅDḌdḍHḤhḥKḲkḳLḶlḷMṂmṃNṆnṇRṚrṛSṢsṣTṬtṭVṾvṿWẈwẉZẒzẓAẠaạEẸeẹIỊiịOỌoọƠỢơợUỤuụƯỰưựYỴyỵ",                                                                                             // Combining Dot Below
+	0x0324: "UṲuṳ",                                                                                                                                                                             // Combining Diaeresis Below
+	0x0325: "AḀaḁ",                                                                                                                                                                             // Combining Ring Below
+	0x0326: "SȘsșTȚtț",                                                                                                                                                                         // Combining Comma Below
+	0x0327: "CÇcçGĢgģKĶkķLĻlļNŅnņRŖrŗSŞsşTŢtţEȨeȩDḐdḑHḨhḩ",                                                                                                                                     // Combining Cedilla
+	0x0328: "AĄaąEĘeęIĮiįUŲuųOǪoǫ",                                                                                                                                                             // Combining Ogonek
+	0x032D: "DḒdḓEḘeḙLḼlḽNṊnṋTṰtṱUṶuṷ",                                                                                                                                                         // Combining Circumflex Accent Below
+	0x032E: "HḪhḫ",                                                                                                                                                                             // Combining Breve Below
+	0x0330: "EḚeḛIḬiḭUṴuṵ",                                                                                                                                                                     // Combining Tilde Below
+	0x0331: "BḆbḇDḎdḏKḴkḵLḺlḻNṈnṉRṞrṟTṮtṯZẔzẕhẖ",                                                                                                                                               // Combining Macron Below
+}
@@ -0,0 +1,49 @@
+package sxgo
+
+// Option configures optional behaviour of an SxGeo instance.
+// Options are passed to New after the mode and applied in order.
+type Option func(*SxGeo)
+
+// PostProcessor inspects or modifies a lookup result before it is returned.
+// ip is the address string as passed by the caller. info is never nil.
+// Typical uses are mapping cities to sales regions via info.Annotate or
+// rewriting names; the processor must not retain info after returning.
+type PostProcessor func(ip string, info *LocationInfo)
+
+// WithPostProcessor registers fn to run on every non-nil result of GetCity,
+// GetCityRegion, GetCityFull (and Get on City databases). Processors run in
+// registration order.
+func WithPostProcessor(fn PostProcessor) Option {
+	return func(s *SxGeo) {
+		if fn != nil {
+			s.postProcessors = append(s.postProcessors, fn)
+		}
+	}
+}
+
+// postProcess applies country remapping (see WithCountryRemap), attaches the
+// country's regulation (see WithRegulations), stamps info with its validity
+// (see WithUpdateCadence), normalizes names (see WithNameNormalization) and
+// runs the registered post-processors on it.
+// Internal function.
+func (s *SxGeo) postProcess(ip string, info *LocationInfo) {
+	s.remapCountry(info)
+	s.attachRegulation(info)
+	s.stampValidity(info)
+	s.normalizeNames(info)
+	for _, fn := range s.postProcessors {
+		fn(ip, info)
+	}
+}
+
+// WithRawIndexes keeps the byte and main indexes as raw file bytes and decodes
+// entries during each search, as ModeFile used to do. It saves a few hundred
+// kilobytes at the cost of slower lookups.
+//
+// Deprecated: indexes are parsed into arrays in every mode by default, which is
+// faster; the raw index path will be removed in a future release.
+func WithRawIndexes() Option {
+	return func(s *SxGeo) {
+		s.rawIndexes = true
+	}
+}
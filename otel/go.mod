@@ -0,0 +1,9 @@
+module github.com/idanyas/sxgo/otel
+
+go 1.23
+
+require (
+	github.com/idanyas/sxgo v0.1.0
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/metric v1.35.0
+)
@@ -0,0 +1,65 @@
+// Package otel reports sxgo telemetry (see sxgo.WithTelemetry) as
+// OpenTelemetry metrics. It is a separate module, so that only programs
+// using it depend on OpenTelemetry.
+//
+// Counters become Int64Counter instruments and durations Float64Histogram
+// instruments in seconds, named as listed by sxgo.Metrics.
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Telemetry implements sxgo.Telemetry with OpenTelemetry instruments.
+type Telemetry struct {
+	counters  map[string]metric.Int64Counter
+	durations map[string]metric.Float64Histogram
+	attrs     metric.MeasurementOption
+}
+
+// New creates the instruments of sxgo.Metrics with meter and returns the
+// Telemetry recording them. attrs are attached to every measurement, e.g. to
+// tell instances apart.
+func New(meter metric.Meter, attrs ...attribute.KeyValue) (*Telemetry, error) {
+	t := &Telemetry{
+		counters:  make(map[string]metric.Int64Counter),
+		durations: make(map[string]metric.Float64Histogram),
+		attrs:     metric.WithAttributes(attrs...),
+	}
+	for _, m := range sxgo.Metrics() {
+		if m.Duration {
+			h, err := meter.Float64Histogram(m.Name, metric.WithDescription(m.Help), metric.WithUnit("s"))
+			if err != nil {
+				return nil, err
+			}
+			t.durations[m.Name] = h
+			continue
+		}
+		c, err := meter.Int64Counter(m.Name, metric.WithDescription(m.Help))
+		if err != nil {
+			return nil, err
+		}
+		t.counters[m.Name] = c
+	}
+	return t, nil
+}
+
+// CounterAdd adds delta to the counter name; unknown names are ignored.
+func (t *Telemetry) CounterAdd(name string, delta uint64) {
+	if c, ok := t.counters[name]; ok {
+		c.Add(context.Background(), int64(delta), t.attrs)
+	}
+}
+
+// ObserveDuration records d in the histogram name, in seconds; unknown names
+// are ignored.
+func (t *Telemetry) ObserveDuration(name string, d time.Duration) {
+	if h, ok := t.durations[name]; ok {
+		h.Record(context.Background(), d.Seconds(), t.attrs)
+	}
+}
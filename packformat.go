@@ -0,0 +1,244 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pack type codes: the first character of each field of a pack format, as in
+// "T:country_id/N5:lat/b:name_en".
+const (
+	PackInt8        byte = 't' // Signed 8-bit integer.
+	PackUint8       byte = 'T' // Unsigned 8-bit integer.
+	PackInt16       byte = 's' // Signed 16-bit integer.
+	PackUint16      byte = 'S' // Unsigned 16-bit integer.
+	PackInt24       byte = 'm' // Signed 24-bit integer.
+	PackUint24      byte = 'M' // Unsigned 24-bit integer.
+	PackInt32       byte = 'i' // Signed 32-bit integer.
+	PackUint32      byte = 'I' // Unsigned 32-bit integer.
+	PackFloat32     byte = 'f' // 32-bit float.
+	PackFloat64     byte = 'd' // 64-bit float.
+	PackDecimal16   byte = 'n' // 16-bit fixed-point number; optional decimals suffix, e.g. "n2".
+	PackDecimal32   byte = 'N' // 32-bit fixed-point number; optional decimals suffix, e.g. "N5".
+	PackFixedString byte = 'c' // Fixed-width string; required width suffix, e.g. "c2".
+	PackString      byte = 'b' // Null-terminated string.
+)
+
+// packTypeCodes lists the recognized codes in declaration order.
+var packTypeCodes = []byte{
+	PackInt8, PackUint8, PackInt16, PackUint16, PackInt24, PackUint24, PackInt32, PackUint32,
+	PackFloat32, PackFloat64, PackDecimal16, PackDecimal32, PackFixedString, PackString,
+}
+
+// PackTypeCodes returns the pack type codes records can be decoded with.
+func PackTypeCodes() []byte {
+	return append([]byte(nil), packTypeCodes...)
+}
+
+// ValidatePackFormat checks that format, e.g. "T:country_id/N5:lat/b:name_en",
+// only uses recognized type codes with valid suffixes and names each field
+// once, so custom databases can be checked before their records are read.
+func ValidatePackFormat(format string) error {
+	if format == "" {
+		return errors.New("sxgo: empty pack format")
+	}
+	seen := make(map[string]bool)
+	for i, part := range strings.Split(format, "/") {
+		code, name, ok := strings.Cut(part, ":")
+		if !ok || code == "" || name == "" {
+			return fmt.Errorf("sxgo: pack format field %d: %q is not code:name", i, part)
+		}
+		if seen[name] {
+			return fmt.Errorf("sxgo: pack format field %d: duplicate name %q", i, name)
+		}
+		seen[name] = true
+		if err := validatePackCode(code); err != nil {
+			return fmt.Errorf("sxgo: pack format field %d (%q): %w", i, name, err)
+		}
+	}
+	return nil
+}
+
+// validatePackCode checks one type code and its suffix.
+// Internal function.
+func validatePackCode(code string) error {
+	c, suffix := code[0], code[1:]
+	switch c {
+	case PackDecimal16, PackDecimal32:
+		if suffix == "" {
+			return nil // Scale 0
+		}
+		if n, err := strconv.Atoi(suffix); err != nil || n < 0 || n > 9 {
+			return fmt.Errorf("invalid decimals %q for %q", suffix, c)
+		}
+		return nil
+	case PackFixedString:
+		if n, err := strconv.Atoi(suffix); err != nil || n <= 0 {
+			return fmt.Errorf("invalid width %q for %q", suffix, c)
+		}
+		return nil
+	}
+	if !slices.Contains(packTypeCodes, c) {
+		return fmt.Errorf("unsupported type code %q", c)
+	}
+	if suffix != "" {
+		return fmt.Errorf("unexpected suffix %q for %q", suffix, c)
+	}
+	return nil
+}
+
+// PackField describes a field of a custom database record for DesignPackFormat.
+type PackField struct {
+	Name string       // Field name as stored in the format, e.g. "lat". Must not contain '/' or ':'.
+	Type reflect.Kind // Go kind of the value: Bool, Int*, Uint*, Float32, Float64 or String.
+
+	// Min and Max bound the values the field will hold (in natural units, before
+	// any decimal scaling). When Max > Min they narrow the chosen type; otherwise
+	// the full range of Type is assumed.
+	Min, Max int64
+
+	// Decimals stores floats as fixed-point numbers with this many decimal
+	// places (n/N codes, as official databases do for coordinates) instead of f/d.
+	Decimals int
+
+	// Size stores strings with this fixed width (c code); 0 means
+	// null-terminated (b code).
+	Size int
+}
+
+// intCode is an integer pack code and the values it can hold.
+type intCode struct {
+	code     byte
+	min, max int64
+}
+
+// intCodes lists integer pack codes from narrowest to widest, unsigned first.
+var intCodes = []intCode{
+	{PackUint8, 0, math.MaxUint8},
+	{PackInt8, math.MinInt8, math.MaxInt8},
+	{PackUint16, 0, math.MaxUint16},
+	{PackInt16, math.MinInt16, math.MaxInt16},
+	{PackUint24, 0, 1<<24 - 1},
+	{PackInt24, -1 << 23, 1<<23 - 1},
+	{PackUint32, 0, math.MaxUint32},
+	{PackInt32, math.MinInt32, math.MaxInt32},
+}
+
+// DesignPackFormat returns the most compact SxGeo pack format string for
+// fields, e.g. "T:country_id/M:id/N5:lat/N5:lon/b:name_en", choosing the
+// narrowest of t/T/s/S/m/M/i/I for integers, n/N (or f/d) for floats and c/b
+// for strings. Use it when building custom databases.
+func DesignPackFormat(fields []PackField) (string, error) {
+	parts := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f.Name == "" || strings.ContainsAny(f.Name, "/:") {
+			return "", fmt.Errorf("sxgo: invalid pack field name %q", f.Name)
+		}
+		if seen[f.Name] {
+			return "", fmt.Errorf("sxgo: duplicate pack field %q", f.Name)
+		}
+		seen[f.Name] = true
+
+		code, err := packCode(f)
+		if err != nil {
+			return "", fmt.Errorf("sxgo: pack field %q: %w", f.Name, err)
+		}
+		parts = append(parts, code+":"+f.Name)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// packCode picks the type code (with length/scale suffix) for one field.
+// Internal function.
+func packCode(f PackField) (string, error) {
+	bounded := f.Max > f.Min
+	switch f.Type {
+	case reflect.Bool:
+		return "T", nil
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		lo, hi := f.Min, f.Max
+		if !bounded {
+			var ok bool
+			if lo, hi, ok = kindRange(f.Type); !ok {
+				return "", fmt.Errorf("%s values need Min/Max within 32 bits", f.Type)
+			}
+		}
+		if c, ok := narrowestInt(lo, hi); ok {
+			return string(c), nil
+		}
+		return "", fmt.Errorf("range [%d, %d] does not fit a 32-bit integer", lo, hi)
+
+	case reflect.Float32, reflect.Float64:
+		if f.Decimals < 0 || f.Decimals > 9 {
+			return "", fmt.Errorf("unsupported decimals %d", f.Decimals)
+		}
+		if f.Decimals == 0 {
+			if f.Type == reflect.Float32 {
+				return "f", nil
+			}
+			return "d", nil
+		}
+		scale := strconv.Itoa(f.Decimals)
+		if !bounded {
+			return "N" + scale, nil
+		}
+		lo := float64(f.Min) * math.Pow10(f.Decimals)
+		hi := float64(f.Max) * math.Pow10(f.Decimals)
+		switch {
+		case lo >= math.MinInt16 && hi <= math.MaxInt16:
+			return "n" + scale, nil
+		case lo >= math.MinInt32 && hi <= math.MaxInt32:
+			return "N" + scale, nil
+		}
+		return "", fmt.Errorf("range [%d, %d] with %d decimals does not fit a 32-bit integer", f.Min, f.Max, f.Decimals)
+
+	case reflect.String:
+		if f.Size < 0 {
+			return "", fmt.Errorf("invalid string size %d", f.Size)
+		}
+		if f.Size > 0 {
+			return "c" + strconv.Itoa(f.Size), nil
+		}
+		return "b", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", f.Type)
+}
+
+// kindRange returns the value range of fixed-size integer kinds that fit 32 bits.
+// Internal function.
+func kindRange(k reflect.Kind) (lo, hi int64, ok bool) {
+	switch k {
+	case reflect.Int8:
+		return math.MinInt8, math.MaxInt8, true
+	case reflect.Int16:
+		return math.MinInt16, math.MaxInt16, true
+	case reflect.Int32:
+		return math.MinInt32, math.MaxInt32, true
+	case reflect.Uint8:
+		return 0, math.MaxUint8, true
+	case reflect.Uint16:
+		return 0, math.MaxUint16, true
+	case reflect.Uint32:
+		return 0, math.MaxUint32, true
+	}
+	return 0, 0, false
+}
+
+// narrowestInt returns the narrowest integer code holding [lo, hi].
+// Internal function.
+func narrowestInt(lo, hi int64) (byte, bool) {
+	for _, c := range intCodes {
+		if lo >= c.min && hi <= c.max {
+			return c.code, true
+		}
+	}
+	return 0, false
+}
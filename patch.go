@@ -0,0 +1,225 @@
+package sxgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Patch file layout. A patch file is a sequence of sections, each:
+//
+//	magic   [4]byte  "SxGP"
+//	version uint8    patchVersion
+//	base    [32]byte Fingerprint of the database the section applies to
+//	count   uint32   number of entries
+//	entries count × (first uint32, last uint32, id uint32)
+//
+// All integers are big-endian. New sections are appended to the end of the
+// file, so a patch file only ever grows; later entries win where ranges overlap.
+const (
+	patchMagic     = "SxGP"
+	patchVersion   = 1
+	patchEntryLen  = 12
+	patchHeaderLen = 4 + 1 + 32 + 4
+)
+
+// ErrPatchBase is returned by ApplyPatch when a patch was made for a different database.
+var ErrPatchBase = errors.New("sxgo: patch does not match database fingerprint")
+
+// PatchEntry maps an inclusive IPv4 range to a database ID: a country ID for
+// Country DBs, or a record seek into the cities block for City DBs (the value
+// lookups resolve to internally). ID 0 marks the range as unknown.
+type PatchEntry struct {
+	Range IPRange `json:"range"`
+	ID    uint32  `json:"id"`
+}
+
+// Patch is one section of a patch file: ranges added or changed since the base database.
+type Patch struct {
+	Base    [32]byte     // Fingerprint of the database the patch applies to.
+	Entries []PatchEntry // Added/changed ranges, applied in order.
+}
+
+// patchRange is an overlay entry in integer form.
+// This struct is internal.
+type patchRange struct {
+	first, last, id uint32
+}
+
+// Add appends an entry mapping r to id.
+func (p *Patch) Add(r IPRange, id uint32) {
+	p.Entries = append(p.Entries, PatchEntry{Range: r, ID: id})
+}
+
+// WriteTo encodes p as a single section. Appending the output to an existing
+// patch file extends it.
+func (p *Patch) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, 0, patchHeaderLen+len(p.Entries)*patchEntryLen)
+	buf = append(buf, patchMagic...)
+	buf = append(buf, patchVersion)
+	buf = append(buf, p.Base[:]...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(p.Entries)))
+	for _, e := range p.Entries {
+		first, last := addrToUint32(e.Range.First), addrToUint32(e.Range.Last)
+		if !e.Range.First.Unmap().Is4() || !e.Range.Last.Unmap().Is4() || first > last {
+			return 0, fmt.Errorf("sxgo: invalid patch range %s", e.Range)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, first)
+		buf = binary.BigEndian.AppendUint32(buf, last)
+		buf = binary.BigEndian.AppendUint32(buf, e.ID)
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadPatches decodes every section of a patch file.
+func ReadPatches(r io.Reader) ([]*Patch, error) {
+	br := bufio.NewReader(r)
+	var patches []*Patch
+	for {
+		head := make([]byte, patchHeaderLen)
+		if _, err := io.ReadFull(br, head); err != nil {
+			if errors.Is(err, io.EOF) {
+				return patches, nil // Clean end between sections
+			}
+			return nil, fmt.Errorf("sxgo: truncated patch section header: %w", err)
+		}
+		if string(head[:4]) != patchMagic {
+			return nil, errors.New("sxgo: invalid patch signature")
+		}
+		if head[4] != patchVersion {
+			return nil, fmt.Errorf("sxgo: unsupported patch version %d", head[4])
+		}
+		p := &Patch{}
+		copy(p.Base[:], head[5:37])
+		count := binary.BigEndian.Uint32(head[37:41])
+		entry := make([]byte, patchEntryLen)
+		for i := uint32(0); i < count; i++ {
+			if _, err := io.ReadFull(br, entry); err != nil {
+				return nil, fmt.Errorf("sxgo: truncated patch entry %d: %w", i, err)
+			}
+			first := binary.BigEndian.Uint32(entry[0:4])
+			last := binary.BigEndian.Uint32(entry[4:8])
+			if first > last {
+				return nil, fmt.Errorf("sxgo: invalid patch entry %d: first address after last", i)
+			}
+			p.Add(IPRange{First: uint32ToAddr(first), Last: uint32ToAddr(last)}, binary.BigEndian.Uint32(entry[8:12]))
+		}
+		patches = append(patches, p)
+	}
+}
+
+// ApplyPatch reads a patch file and overlays its ranges on the database, so
+// monthly updates can ship as small patches instead of a full .dat. Every
+// section must have been made for this database (see Fingerprint), otherwise
+// ErrPatchBase is returned and nothing is applied. Patches accumulate across
+// calls; later entries win where ranges overlap. Reload removes them.
+//
+// Overlaid ranges take effect for all lookups (reserved ranges stay reserved).
+// Range analysis (DescribeCIDR, MatchCIDRs, AnalyzeRanges) describes the
+// unpatched block table. It is safe to call concurrently with lookups.
+func (s *SxGeo) ApplyPatch(r io.Reader) error {
+	patches, err := ReadPatches(r)
+	if err != nil {
+		return err
+	}
+	// Reload must not swap the database between the checks and the store,
+	// nor another ApplyPatch store its ranges meanwhile
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	fp, err := s.Fingerprint()
+	if err != nil {
+		return err
+	}
+
+	var ranges []patchRange
+	if cur := s.overlay.Load(); cur != nil {
+		ranges = slices.Clone(*cur)
+	}
+	for _, p := range patches {
+		if p.Base != fp {
+			return ErrPatchBase
+		}
+		for _, e := range p.Entries {
+			if err := s.validatePatchID(e.ID); err != nil {
+				return err
+			}
+			ranges = overlayInsert(ranges, patchRange{
+				first: addrToUint32(e.Range.First),
+				last:  addrToUint32(e.Range.Last),
+				id:    e.ID,
+			})
+		}
+	}
+	s.overlay.Store(&ranges)
+	return nil
+}
+
+// validatePatchID checks that id can be a lookup result for this database.
+// Internal function.
+func (s *SxGeo) validatePatchID(id uint32) error {
+	db := s.db()
+	if db.header.idLen < 4 && id >= 1<<(8*uint32(db.header.idLen)) {
+		return fmt.Errorf("sxgo: patch ID %d does not fit %d-byte IDs", id, db.header.idLen)
+	}
+	if db.layout.HasCities && id >= db.header.citySize {
+		return fmt.Errorf("sxgo: patch seek %d is beyond the cities block (%d bytes)", id, db.header.citySize)
+	}
+	if !db.layout.HasCities && db.layout.HasRegions && id >= db.header.regionSize {
+		return fmt.Errorf("sxgo: patch seek %d is beyond the regions block (%d bytes)", id, db.header.regionSize)
+	}
+	return nil
+}
+
+// overlayRange returns the overlay range covering ipNum, if any.
+// Internal function.
+func (s *SxGeo) overlayRange(ipNum uint32) (patchRange, bool) {
+	cur := s.overlay.Load()
+	if cur == nil {
+		return patchRange{}, false
+	}
+	ranges := *cur
+	i, _ := slices.BinarySearchFunc(ranges, ipNum, func(r patchRange, ip uint32) int {
+		switch {
+		case r.last < ip:
+			return -1
+		case r.first > ip:
+			return 1
+		}
+		return 0
+	})
+	if i < len(ranges) && ranges[i].first <= ipNum && ipNum <= ranges[i].last {
+		return ranges[i], true
+	}
+	return patchRange{}, false
+}
+
+// overlayInsert inserts e into the sorted, non-overlapping ranges, trimming or
+// splitting whatever it overlaps.
+// Internal function.
+func overlayInsert(ranges []patchRange, e patchRange) []patchRange {
+	// First range ending at or after e.first, and first range starting after e.last.
+	i, _ := slices.BinarySearchFunc(ranges, e.first, func(r patchRange, ip uint32) int {
+		if r.last < ip {
+			return -1
+		}
+		return 1
+	})
+	j := i
+	for j < len(ranges) && ranges[j].first <= e.last {
+		j++
+	}
+
+	repl := make([]patchRange, 0, 3)
+	if i < j && ranges[i].first < e.first { // Keep the head of the first overlapped range
+		repl = append(repl, patchRange{first: ranges[i].first, last: e.first - 1, id: ranges[i].id})
+	}
+	repl = append(repl, e)
+	if i < j && ranges[j-1].last > e.last { // Keep the tail of the last overlapped range
+		repl = append(repl, patchRange{first: e.last + 1, last: ranges[j-1].last, id: ranges[j-1].id})
+	}
+	return slices.Replace(ranges, i, j, repl...)
+}
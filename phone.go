@@ -0,0 +1,129 @@
+package sxgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/idanyas/sxgo/countries"
+)
+
+// PhoneStatus is the outcome of comparing a phone number's country with an
+// IP address's country.
+type PhoneStatus int
+
+const (
+	// PhoneUnknown means the IP address has no known country.
+	PhoneUnknown PhoneStatus = iota
+	// PhoneMatch means the IP country uses the phone's calling code.
+	PhoneMatch
+	// PhoneMismatch means the IP country does not use the phone's calling code.
+	PhoneMismatch
+)
+
+// String returns "unknown", "match" or "mismatch".
+func (p PhoneStatus) String() string {
+	switch p {
+	case PhoneMatch:
+		return "match"
+	case PhoneMismatch:
+		return "mismatch"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the status as its String form, e.g. in JSON.
+func (p PhoneStatus) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// PhoneCheck is the result of CheckPhoneCountry.
+type PhoneCheck struct {
+	CallingCode    string      `json:"calling_code"`    // Country calling code of the phone, e.g. "49".
+	PhoneCountries []string    `json:"phone_countries"` // ISO codes of the countries using CallingCode.
+	IPCountry      string      `json:"ip_country"`      // ISO code of the IP address; "" if unknown.
+	Status         PhoneStatus `json:"status"`
+}
+
+// CheckPhoneCountry compares the country calling code of phone, given in
+// international format ("+49 30 1234567" or "0049..."), with the country of
+// ip. A mismatch is a common signup-fraud signal, but also normal for
+// travellers and VPN users; treat it as one input among several.
+func (s *SxGeo) CheckPhoneCountry(ip, phone string) (*PhoneCheck, error) {
+	code, countries, err := CallingCodeCountries(phone)
+	if err != nil {
+		return nil, err
+	}
+	iso, err := s.country(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &PhoneCheck{CallingCode: code, PhoneCountries: countries, IPCountry: iso}
+	switch {
+	case iso == "":
+		pc.Status = PhoneUnknown
+	case containsString(countries, iso):
+		pc.Status = PhoneMatch
+	default:
+		pc.Status = PhoneMismatch
+	}
+	return pc, nil
+}
+
+// CallingCodeCountries returns the country calling code of phone, given in
+// international format (leading "+" or "00"; spaces, dots, dashes and
+// parentheses are ignored), and the ISO codes of the countries using it.
+// Shared codes list several countries, e.g. "1" (NANP) or "7" (Russia and,
+// for numbers starting with 76 and 77, Kazakhstan).
+func CallingCodeCountries(phone string) (code string, isos []string, err error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '+':
+			return r
+		case r == ' ', r == '-', r == '.', r == '(', r == ')':
+			return -1
+		}
+		return 'x' // Invalid character, rejected below
+	}, strings.TrimSpace(phone))
+
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	default:
+		return "", nil, fmt.Errorf("sxgo: phone number %q is not in international format", phone)
+	}
+	if len(digits) < 7 || len(digits) > 15 || strings.ContainsAny(digits, "+x") {
+		return "", nil, fmt.Errorf("sxgo: invalid phone number %q", phone)
+	}
+
+	// Calling codes are prefix-free, so at most one prefix matches
+	for n := 1; n <= 3; n++ {
+		code = digits[:n]
+		isos = countries.ByCallingCode(code)
+		if isos == nil {
+			continue
+		}
+		if code == "7" { // Shared by Russia and, for 76 and 77, Kazakhstan
+			if digits[1] == '6' || digits[1] == '7' {
+				isos = []string{"KZ"}
+			} else {
+				isos = []string{"RU"}
+			}
+		}
+		return code, isos, nil
+	}
+	return "", nil, fmt.Errorf("sxgo: unknown calling code in phone number %q", phone)
+}
+
+// containsString reports whether list contains v.
+// Internal function.
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package sxgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PHPField is an entry of a PHPArray.
+type PHPField struct {
+	Key   string
+	Value interface{} // int64, float64, string or PHPArray
+}
+
+// PHPArray is an ordered map, as PHP associative arrays are: it marshals to
+// a JSON object with the keys in order, and a nil PHPArray marshals to false,
+// as PHP lookups report addresses not found.
+type PHPArray []PHPField
+
+// Get returns the value of key, or nil if a has no such key.
+func (a PHPArray) Get(key string) interface{} {
+	for _, f := range a {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes a as PHP's json_encode does, up to string escaping:
+// keys in order, integers without a fraction and other floats with one.
+func (a PHPArray) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("false"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range a {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		switch v := f.Value.(type) {
+		case float64:
+			s := strconv.FormatFloat(v, 'f', -1, 64)
+			if !strings.Contains(s, ".") && !math.IsInf(v, 0) && !math.IsNaN(v) {
+				s += ".0" // PHP keeps floats distinguishable from integers
+			}
+			buf.WriteString(s)
+		default:
+			val, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(val)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// GetCityPHP looks up ip like the getCity method of the reference PHP SxGeo
+// class and returns its result with the same layout: "city" and "country"
+// arrays with the fields of the pack formats, in their order. It eases
+// replacing legacy PHP endpoints without breaking their consumers. Results
+// are not post-processed and synthetic locations do not apply.
+// Returns (nil, nil) where PHP returns false: addresses not found, reserved
+// ranges and databases without cities.
+func (s *SxGeo) GetCityPHP(ip string) (PHPArray, error) {
+	return s.lookupPHP(ip, false)
+}
+
+// GetCityFullPHP is GetCityPHP for the getCityFull method, whose result has
+// "city", "region" and "country" arrays.
+func (s *SxGeo) GetCityFullPHP(ip string) (PHPArray, error) {
+	return s.lookupPHP(ip, true)
+}
+
+// lookupPHP implements GetCityPHP and GetCityFullPHP.
+// Internal function.
+func (s *SxGeo) lookupPHP(ip string, full bool) (PHPArray, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if !db.layout.HasCities || len(db.packFormats) < 3 {
+		return nil, nil // PHP reads any ID as a city seek; we decline instead
+	}
+	ipNum, _, err := s.parseIP(ip)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: PHP city lookup failed for IP %s: %w", ip, err)
+	}
+	seek, err := s.lookupNum(db, ipNum)
+	if err != nil {
+		if errors.Is(err, ErrReservedRange) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("sxgo: PHP city lookup failed for IP %s: %w", ip, err)
+	}
+	if seek == 0 {
+		return nil, nil
+	}
+	res, err := s.parsePHP(db, seek, full)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: parsing PHP city failed for IP %s (seek %d): %w", ip, seek, err)
+	}
+	return res, nil
+}
+
+// parsePHP mirrors parseCity of the PHP class, including its handling of
+// country-level ranges: an all-default city with the country's coordinates,
+// keeping the country_id field other cities drop.
+// Internal function.
+func (s *SxGeo) parsePHP(db *dbState, seek uint32, full bool) (PHPArray, error) {
+	var city, country PHPArray
+	onlyCountry := false
+	switch db.recordKind(seek) {
+	case recordCountry:
+		c, err := s.phpRecord(db, seek, db.header.maxCountry, 0)
+		if err != nil {
+			return nil, err
+		}
+		country = c
+		city = phpDecode(db.packFormats[2], nil)
+		for i := range city {
+			if f := city[i].Key; f == "lat" || f == "lon" {
+				city[i].Value = country.Get(f)
+			}
+		}
+		onlyCountry = true
+	default:
+		c, err := s.phpRecord(db, seek, db.header.maxCity, 2)
+		if err != nil {
+			return nil, err
+		}
+		id := phpUint32(c.Get("country_id"))
+		city = c.without("country_id")
+		country = PHPArray{{"id", int64(id)}, {"iso", getISO(id)}}
+	}
+
+	if !full {
+		// PHP keeps only the ID and ISO code, of country records too
+		country = PHPArray{{"id", country.Get("id")}, {"iso", country.Get("iso")}}
+		return PHPArray{{"city", city.without("region_seek")}, {"country", country}}, nil
+	}
+	region, err := s.phpRecord(db, phpUint32(city.Get("region_seek")), db.header.maxRegion, 1)
+	if err != nil {
+		return nil, err
+	}
+	if !onlyCountry {
+		if country, err = s.phpRecord(db, phpUint32(region.Get("country_seek")), db.header.maxCountry, 0); err != nil {
+			return nil, err
+		}
+	}
+	return PHPArray{
+		{"city", city.without("region_seek")},
+		{"region", region.without("country_seek")},
+		{"country", country},
+	}, nil
+}
+
+// phpRecord reads a record as the readData method of the PHP class does:
+// seek 0 or a missing record yields the defaults of every field. PHP skips
+// seek 0 even where a record is stored there (the first country record), so
+// this does too: consumers expect what PHP returned.
+// Internal function.
+func (s *SxGeo) phpRecord(db *dbState, seek uint32, maxSize uint16, dataType int) (PHPArray, error) {
+	format := ""
+	if dataType < len(db.packFormats) {
+		format = db.packFormats[dataType]
+	}
+	if seek == 0 || format == "" {
+		return phpDecode(format, nil), nil
+	}
+	rec, err := s.readData(db, seek, maxSize, dataType)
+	if err != nil {
+		return nil, err
+	}
+	return phpDecode(format, rec), nil
+}
+
+// phpDecode orders the fields of rec, unpacked with format, and converts them
+// to the types PHP's unpack yields: integers, floats for fractional decimals
+// and float codes, strings. Fields missing from rec take PHP's defaults, ""
+// for strings and 0 otherwise.
+// Internal function.
+func phpDecode(format string, rec map[string]interface{}) PHPArray {
+	if format == "" {
+		return PHPArray{}
+	}
+	parts := strings.Split(format, "/")
+	out := make(PHPArray, 0, len(parts))
+	for _, part := range parts {
+		code, name, _ := strings.Cut(part, ":")
+		if code == "" {
+			continue
+		}
+		v, ok := rec[name]
+		switch {
+		case code[0] == PackString || code[0] == PackFixedString:
+			str, _ := v.(string)
+			out = append(out, PHPField{name, str})
+		case !ok:
+			out = append(out, PHPField{name, int64(0)})
+		case code[0] == PackFloat32 || code[0] == PackFloat64:
+			out = append(out, PHPField{name, getFloat(rec, name)})
+		case code[0] == PackDecimal16 || code[0] == PackDecimal32:
+			// PHP divides by a power of ten, an integer when exact
+			f := getFloat(rec, name)
+			if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+				out = append(out, PHPField{name, int64(f)})
+			} else {
+				out = append(out, PHPField{name, f})
+			}
+		default:
+			out = append(out, PHPField{name, phpInt(v)})
+		}
+	}
+	return out
+}
+
+// without returns a copy of a without key, as PHP's unset leaves it.
+// Internal function.
+func (a PHPArray) without(key string) PHPArray {
+	out := make(PHPArray, 0, len(a))
+	for _, f := range a {
+		if f.Key != key {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// phpInt converts an unpacked integer of any width to int64.
+// Internal function.
+func phpInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int8:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return 0
+}
+
+// phpUint32 converts a PHPArray integer value to a seek or ID.
+// Internal function.
+func phpUint32(v interface{}) uint32 {
+	n, _ := v.(int64)
+	return uint32(n)
+}
@@ -0,0 +1,89 @@
+package sxgo
+
+// Placeholders sets how unknown parts of a result surface (see
+// WithPlaceholders). The zero value leaves them as they are: nil City,
+// Region or Country, and empty names and codes. Which parts are missing
+// depends on how far the database locates a range; placeholders give API
+// responses a consistent shape regardless.
+type Placeholders struct {
+	// Fill replaces a nil City or Country, and a nil Region in results of
+	// GetCityRegion and GetCityFull, with a placeholder: a copy of the part
+	// of Sentinel if it has one, else a zero struct.
+	Fill bool
+	// Name replaces empty names, e.g. "Unknown"; "" keeps them empty.
+	Name string
+	// ISO replaces empty country and region codes, e.g. "??"; "" keeps
+	// them empty.
+	ISO string
+	// Sentinel provides the placeholders for missing parts with Fill.
+	Sentinel *LocationInfo
+}
+
+// PlaceholdersUnknown fills missing parts and labels unknown names
+// "Unknown" and unknown codes "??".
+var PlaceholdersUnknown = Placeholders{Fill: true, Name: "Unknown", ISO: "??"}
+
+// WithPlaceholders applies p to the result: missing parts and empty names
+// and codes are replaced as p specifies. Precision still describes the
+// levels the database located, and results not found stay nil. Names left
+// out by WithLang are not replaced; parts left out by WithProjection are
+// not filled.
+func WithPlaceholders(p Placeholders) CallOption {
+	return func(c *callConfig) {
+		c.placeholders = &p
+	}
+}
+
+// fillPlaceholders applies p to info, a result of a lookup down to depth.
+// Internal function.
+func fillPlaceholders(info *LocationInfo, p *Placeholders, depth recordDepth) {
+	if p.Fill {
+		var sentinel LocationInfo
+		if p.Sentinel != nil {
+			sentinel = *p.Sentinel.clone()
+		}
+		if info.City == nil {
+			info.City = &City{}
+			if sentinel.City != nil {
+				info.City = sentinel.City
+			}
+		}
+		if info.Region == nil && depth >= depthRegion {
+			info.Region = &Region{}
+			if sentinel.Region != nil {
+				info.Region = sentinel.Region
+			}
+		}
+		if info.Country == nil {
+			info.Country = &Country{}
+			if sentinel.Country != nil {
+				info.Country = sentinel.Country
+			}
+		}
+	}
+
+	name := func(s *string) {
+		if *s == "" {
+			*s = p.Name
+		}
+	}
+	iso := func(s *string) {
+		if *s == "" {
+			*s = p.ISO
+		}
+	}
+	if info.City != nil {
+		name(&info.City.NameRU)
+		name(&info.City.NameEN)
+	}
+	if info.Region != nil {
+		name(&info.Region.NameRU)
+		name(&info.Region.NameEN)
+		iso(&info.Region.ISO)
+	}
+	if info.Country != nil {
+		name(&info.Country.NameRU)
+		name(&info.Country.NameEN)
+		iso(&info.Country.ISO)
+	}
+}
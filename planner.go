@@ -0,0 +1,417 @@
+package sxgo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Planner resolves large lists of addresses for batch jobs. Instead of one
+// lookup per input, it deduplicates the addresses, sorts them, reads each
+// first-byte window of the block table once and resolves the sorted addresses
+// with a cursor moving through its ranges, decodes each distinct record once,
+// and fans the results back out to the input positions.
+//
+// Results match GetCity (or GetCityFull) except that middleware registered
+// with WithMiddleware is not applied, as it wraps single lookups.
+// A Planner is safe for concurrent use.
+type Planner struct {
+	geo         *SxGeo
+	full        bool
+	parallelism int // Windows walked and records decoded concurrently
+}
+
+// PlanStats describes the work done by one Planner.Lookup call.
+type PlanStats struct {
+	Inputs  int `json:"inputs"`  // Addresses passed in.
+	Unique  int `json:"unique"`  // Distinct valid addresses resolved.
+	Invalid int `json:"invalid"` // Inputs that are not valid addresses.
+	Windows int `json:"windows"` // First-byte windows of the block table walked.
+	Records int `json:"records"` // Distinct records decoded.
+}
+
+// NewPlanner returns a Planner producing GetCityFull results if full is true,
+// GetCity results otherwise.
+func (s *SxGeo) NewPlanner(full bool) *Planner {
+	return &Planner{geo: s, full: full, parallelism: 1}
+}
+
+// WithParallelism returns a copy of the Planner that walks up to n windows
+// and decodes up to n records concurrently. In ModeFile this keeps n reads
+// in flight, which pays off on storage serving many concurrent reads (NVMe);
+// sequential lookups leave most of its throughput unused. In ModeMemory it
+// spreads the work over n goroutines. n < 1 means 1.
+func (p *Planner) WithParallelism(n int) *Planner {
+	c := *p
+	c.parallelism = max(n, 1)
+	return &c
+}
+
+// plannedIP is one distinct input address.
+// This struct is internal.
+type plannedIP struct {
+	ip      string // First spelling seen
+	num     uint32
+	derived string
+	seek    uint32
+	pos     []int // Input positions
+
+	first, last uint32 // Range resolving to seek (WithMatchedRange)
+}
+
+// Lookup resolves ips and returns one result per input, in input order; nil
+// means not found, as for GetCity. Invalid addresses also yield nil and are
+// counted in the stats rather than failing the batch. Inputs with the same
+// address get separate copies of the result. An error is returned only if
+// the database cannot be read.
+func (p *Planner) Lookup(ips []string) ([]*LocationInfo, PlanStats, error) {
+	return p.LookupContext(context.Background(), ips)
+}
+
+// LookupContext is Lookup with cancellation: once ctx is done, no further
+// windows or records are read and ctx.Err() is returned. With parallelism,
+// the first read error likewise stops the remaining work.
+func (p *Planner) LookupContext(ctx context.Context, ips []string) ([]*LocationInfo, PlanStats, error) {
+	out := make([]*LocationInfo, len(ips))
+	pl, err := p.plan(ctx, ips, func(i int, info *LocationInfo) { out[i] = info })
+	defer pl.db.unpin()
+	if err != nil {
+		return nil, pl.stats, err
+	}
+
+	for _, it := range pl.items {
+		rec := pl.record(it)
+		if rec == nil {
+			continue
+		}
+		for _, i := range it.pos {
+			out[i] = pl.finish(rec.clone(), it, ips[i])
+		}
+	}
+	return out, pl.stats, nil
+}
+
+// PlanDict is a dictionary-encoded batch result: each distinct location
+// once, and per input the index of its location. For jobs resolving millions
+// of addresses to a few thousand locations, it takes a fraction of the memory
+// of one result per input.
+type PlanDict struct {
+	Locations []*LocationInfo `json:"locations"` // Distinct locations.
+	Index     []int32         `json:"index"`     // Per input, index into Locations; -1 if not found.
+}
+
+// At returns the location of input i, or nil if it was not found. The
+// result is shared with every input of the same location.
+func (d *PlanDict) At(i int) *LocationInfo {
+	if k := d.Index[i]; k >= 0 {
+		return d.Locations[k]
+	}
+	return nil
+}
+
+// LookupDict resolves ips like Lookup, but returns each distinct location
+// once. Inputs share a location when they resolve to the same record (or
+// special-purpose range) with the same DerivedFrom; post-processors run once
+// per location, with one of the inputs resolving to it as the address.
+// Synthetic locations get an entry per input.
+func (p *Planner) LookupDict(ips []string) (*PlanDict, PlanStats, error) {
+	return p.LookupDictContext(context.Background(), ips)
+}
+
+// LookupDictContext is LookupDict with cancellation, as for LookupContext.
+func (p *Planner) LookupDictContext(ctx context.Context, ips []string) (*PlanDict, PlanStats, error) {
+	d := &PlanDict{Index: make([]int32, len(ips))}
+	for i := range d.Index {
+		d.Index[i] = -1
+	}
+	pl, err := p.plan(ctx, ips, func(i int, info *LocationInfo) {
+		d.Index[i] = int32(len(d.Locations))
+		d.Locations = append(d.Locations, info)
+	})
+	defer pl.db.unpin()
+	if err != nil {
+		return nil, pl.stats, err
+	}
+
+	type key struct {
+		seek    uint32
+		special Special
+		derived string
+		first   uint32 // Range, with WithMatchedRange
+	}
+	first := make(map[key]int32)
+	for _, it := range pl.items {
+		rec := pl.record(it)
+		if rec == nil {
+			continue
+		}
+		k := key{seek: it.seek, special: rec.Special, derived: it.derived}
+		if pl.records[it.seek] == nil {
+			k.seek = 0 // Special-purpose location, keyed by its label
+		} else if p.geo.matchedRange {
+			k.first = it.first
+		}
+		idx, ok := first[k]
+		if !ok {
+			idx = int32(len(d.Locations))
+			d.Locations = append(d.Locations, pl.finish(rec.clone(), it, ips[it.pos[0]]))
+			first[k] = idx
+		}
+		for _, i := range it.pos {
+			d.Index[i] = idx
+		}
+	}
+	return d, pl.stats, nil
+}
+
+// plan is the work Lookup and LookupDict share: it deduplicates ips,
+// resolves the distinct addresses and decodes the distinct records.
+// Synthetic locations are passed to direct, finished, as they are found.
+// Internal function.
+func (p *Planner) plan(ctx context.Context, ips []string, direct func(i int, info *LocationInfo)) (*plan, error) {
+	s := p.geo
+	db := s.acquire() // Unpinned by the caller once the results are finished
+	pl := &plan{p: p, db: db, stats: PlanStats{Inputs: len(ips)}}
+	pl.depth = depthCity
+	if p.full {
+		pl.depth = depthFull
+	}
+	st := &pl.stats
+
+	// Deduplicate by spelling, then by address
+	seen := make(map[string]*plannedIP, len(ips))
+	byNum := make(map[uint32]*plannedIP, len(ips))
+	var items []*plannedIP
+	for i, ip := range ips {
+		if it, ok := seen[ip]; ok {
+			if it != nil {
+				it.pos = append(it.pos, i)
+			} else {
+				st.Invalid++ // Every position of an invalid spelling counts
+			}
+			continue
+		}
+		if loc, ok := s.syntheticLocation(ip, p.full); ok {
+			s.postProcess(ip, loc)
+			direct(i, s.applyCallOptions(loc, nil, pl.depth))
+			continue // Rare; resolved directly, not deduplicated
+		}
+		num, derived, err := s.parseIP(ip)
+		if err != nil {
+			seen[ip] = nil
+			st.Invalid++
+			continue
+		}
+		if it, ok := byNum[num]; ok {
+			seen[ip] = it
+			it.pos = append(it.pos, i)
+			continue
+		}
+		it := &plannedIP{ip: ip, num: num, derived: derived, pos: []int{i}}
+		seen[ip], byNum[num] = it, it
+		items = append(items, it)
+	}
+	st.Unique = len(items)
+	if !db.seekIDs() {
+		return pl, nil // Not a city database; GetCity finds nothing either
+	}
+	if s.countryOnly {
+		return pl, fmt.Errorf("sxgo: planner lookup failed: %w", ErrCountryOnly)
+	}
+	s.stats.lookups.Add(uint64(len(items)))
+	s.count(MetricLookups, uint64(len(items)))
+	slices.SortFunc(items, func(a, b *plannedIP) int { return cmp.Compare(a.num, b.num) })
+
+	// Resolve seeks one first-byte window at a time
+	var windows [][]*plannedIP
+	for k := 0; k < len(items); {
+		end := k + 1
+		for end < len(items) && items[end].num>>24 == items[k].num>>24 {
+			end++
+		}
+		windows = append(windows, items[k:end])
+		k = end
+	}
+	st.Windows = len(windows)
+	err := p.run(ctx, len(windows), func(w int) error {
+		if err := p.resolveWindow(db, windows[w]); err != nil {
+			return fmt.Errorf("sxgo: planner failed to resolve %s: %w", windows[w][0].ip, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return pl, err
+	}
+
+	// Decode each distinct record once
+	var owners []*plannedIP // First item of each distinct seek
+	records := make(map[uint32]*LocationInfo)
+	for _, it := range items {
+		if _, ok := records[it.seek]; it.seek != 0 && !ok {
+			records[it.seek] = nil
+			owners = append(owners, it)
+		}
+	}
+	decoded := make([]*LocationInfo, len(owners))
+	err = p.run(ctx, len(owners), func(r int) error {
+		it := owners[r]
+		rec, err := s.parseCity(db, it.seek, p.full)
+		if err != nil {
+			return fmt.Errorf("sxgo: planner failed to parse record for IP %s (seek %d): %w", it.ip, it.seek, err)
+		}
+		decoded[r] = rec
+		return nil
+	})
+	if err != nil {
+		return pl, err
+	}
+	for r, it := range owners {
+		records[it.seek] = decoded[r]
+	}
+	st.Records = len(records)
+	pl.items, pl.records = items, records
+	return pl, nil
+}
+
+// plan holds the distinct addresses and records of a batch.
+// This struct is internal.
+type plan struct {
+	p       *Planner
+	db      *dbState // Database the records were read from, pinned (see acquire)
+	depth   recordDepth
+	stats   PlanStats
+	items   []*plannedIP             // Distinct addresses, sorted
+	records map[uint32]*LocationInfo // Decoded records by seek
+}
+
+// record returns the decoded record of it, or its special-purpose location
+// (see WithSpecialRanges); nil if it is not found. The result is shared and
+// must be cloned before use.
+// Internal function.
+func (pl *plan) record(it *plannedIP) *LocationInfo {
+	if rec := pl.records[it.seek]; rec != nil {
+		return rec
+	}
+	return pl.p.geo.specialLocation(it.num)
+}
+
+// finish completes a copy of the record of it as the lookups do for address
+// ip.
+// Internal function.
+func (pl *plan) finish(info *LocationInfo, it *plannedIP, ip string) *LocationInfo {
+	s := pl.p.geo
+	info.DerivedFrom = it.derived
+	if s.matchedRange && pl.records[it.seek] != nil { // Special-purpose locations have no Range
+		info.Range = &IPRange{First: uint32ToAddr(it.first), Last: uint32ToAddr(it.last)}
+	}
+	s.attachCentroid(pl.db, info)
+	if pl.p.full {
+		s.attachDistrict(info)
+	}
+	s.postProcess(ip, info)
+	return s.applyCallOptions(info, nil, pl.depth)
+}
+
+// resolveWindow sets the seek of items, sorted and sharing their first byte,
+// walking the ranges between the first and last of them once.
+// Internal function.
+func (p *Planner) resolveWindow(db *dbState, items []*plannedIP) error {
+	s := p.geo
+	if db.isReservedByte(items[0].num >> 24) {
+		return nil // Seeks stay 0: not found
+	}
+
+	// Patched addresses are resolved by the overlay; the cursor skips them
+	pending := items[:0:0]
+	for _, it := range items {
+		if s.isReservedSpecial(it.num) {
+			continue // Seek stays 0: not found
+		}
+		if r, ok := s.overlayRange(it.num); ok {
+			it.seek, it.first, it.last = r.id, r.first, r.last
+		} else {
+			pending = append(pending, it)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	lo, hi := pending[0].num, pending[len(pending)-1].num
+	if s.matchedRange {
+		lo, hi = lo&^0xFFFFFF, hi|0xFFFFFF // Unclipped ranges, as rangeOf reports them
+	}
+	k := 0
+	err := s.walkRanges(db, lo, hi, func(r ipRange) error {
+		for ; k < len(pending) && pending[k].num <= r.last; k++ {
+			pending[k].seek, pending[k].first, pending[k].last = r.id, r.first, r.last
+		}
+		if k == len(pending) {
+			return errRangeFound // All resolved; stop reading
+		}
+		return nil
+	})
+	if errors.Is(err, errRangeFound) {
+		return nil
+	}
+	return err
+}
+
+// run calls fn for 0 <= i < n on up to p.parallelism goroutines. It stops
+// starting calls once one fails or ctx is done, and returns the first error.
+// Internal function.
+func (p *Planner) run(ctx context.Context, n int, fn func(i int) error) error {
+	if p.parallelism <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	sem := make(chan struct{}, p.parallelism)
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if ctx.Err() != nil {
+				return
+			}
+			if err := fn(i); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		return ctx.Err() // The caller's context is done
+	}
+	return firstErr
+}
@@ -0,0 +1,59 @@
+package sxgo_test
+
+import (
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// plannerInputs repeat addresses, spell one address twice and repeat
+// invalid spellings, as enrichment batches do.
+var plannerInputs = []string{
+	"93.158.0.1", "bogus", "5.1.0.0", "93.158.0.1", "::ffff:93.158.0.1",
+	"1.0.128.0", "bogus", "223.255.255.255", "999.1.1.1", "93.158.9.0", "bogus",
+}
+
+func TestPlannerLookup(t *testing.T) {
+	path := miniCityPath(t)
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			geo, err := sxgo.New(path, m.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer geo.Close()
+			for _, n := range []int{1, 4} {
+				p := geo.NewPlanner(true).WithParallelism(n)
+				got, st, err := p.Lookup(plannerInputs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				dict, dst, err := p.LookupDict(plannerInputs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := sxgo.PlanStats{Inputs: 11, Unique: 5, Invalid: 4}
+				for _, s := range []sxgo.PlanStats{st, dst} {
+					if s.Inputs != want.Inputs || s.Unique != want.Unique || s.Invalid != want.Invalid {
+						t.Errorf("parallelism %d: stats %+v, want %d inputs, %d unique, %d invalid", n, s, want.Inputs, want.Unique, want.Invalid)
+					}
+				}
+				for i, ip := range plannerInputs {
+					info, err := geo.GetCityFull(ip)
+					if err != nil {
+						info = nil // Invalid inputs yield nil
+					}
+					if placeOf(got[i]) != placeOf(info) {
+						t.Errorf("parallelism %d: Lookup[%d] (%s) = %+v, want %+v", n, i, ip, placeOf(got[i]), placeOf(info))
+					}
+					if placeOf(dict.At(i)) != placeOf(info) {
+						t.Errorf("parallelism %d: LookupDict.At(%d) (%s) = %+v, want %+v", n, i, ip, placeOf(dict.At(i)), placeOf(info))
+					}
+				}
+				if got[0] == got[3] {
+					t.Error("inputs of the same address share a Lookup result")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package sxgo
+
+import "strings"
+
+// Precision is a set of flags telling which levels of a location a lookup
+// result identifies. Databases do not resolve every range to a city: ranges
+// may point to a country record, and databases may lack the cities block
+// (results then stop at the region) or the regions block.
+type Precision uint8
+
+const (
+	// PrecisionCountry means the result identifies the country.
+	PrecisionCountry Precision = 1 << iota
+	// PrecisionRegion means the result identifies the region.
+	PrecisionRegion
+	// PrecisionCity means the result identifies the city.
+	PrecisionCity
+)
+
+// Has reports whether all flags of q are set in p.
+func (p Precision) Has(q Precision) bool {
+	return p&q == q
+}
+
+// String returns the set levels from finest to coarsest, e.g. "city,country",
+// or "" if none are set.
+func (p Precision) String() string {
+	var levels []string
+	for _, l := range []struct {
+		flag Precision
+		name string
+	}{{PrecisionCity, "city"}, {PrecisionRegion, "region"}, {PrecisionCountry, "country"}} {
+		if p.Has(l.flag) {
+			levels = append(levels, l.name)
+		}
+	}
+	return strings.Join(levels, ",")
+}
+
+// MarshalText encodes the precision as its String form, e.g. in JSON.
+func (p Precision) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// setPrecision sets l.Precision from the levels present in l.
+// Internal function.
+func (l *LocationInfo) setPrecision() {
+	l.Precision = 0
+	if l.City != nil {
+		l.Precision |= PrecisionCity
+	}
+	if l.Region != nil {
+		l.Precision |= PrecisionRegion
+	}
+	if l.Country != nil {
+		l.Precision |= PrecisionCountry
+	}
+}
+
+// recordKind identifies what a block ID refers to.
+type recordKind int
+
+const (
+	recordCountryID recordKind = iota // The ID is a country ID (Country DBs).
+	recordCountry                     // A country record at the start of the cities block.
+	recordRegion                      // A region record (databases without cities).
+	recordCity                        // A city record.
+)
+
+// seekIDs reports whether block IDs are record seeks rather than country IDs.
+// Internal function.
+func (db *dbState) seekIDs() bool {
+	return db.layout.HasCities || db.layout.HasRegions
+}
+
+// recordKind tells what block ID num refers to. Seeks before the first city
+// record are country records; other seeks are city records, or region records
+// if the database has regions but no cities.
+// Internal function.
+func (db *dbState) recordKind(num uint32) recordKind {
+	switch {
+	case !db.seekIDs():
+		return recordCountryID
+	case db.layout.HasCountryRecords && num < db.cityRecordsStart():
+		return recordCountry
+	case db.layout.HasCities:
+		return recordCity
+	}
+	return recordRegion
+}
+
+// cityRecordsStart returns the offset of the first city record in the cities
+// block: past the country records, unless those are stored separately.
+// Internal function.
+func (db *dbState) cityRecordsStart() uint32 {
+	if db.separateCountries {
+		return 0
+	}
+	return db.header.countrySize
+}
+
+// countryBlockLen returns the size of the block country seeks point into.
+// Internal function.
+func (db *dbState) countryBlockLen() uint32 {
+	if db.separateCountries {
+		return db.header.countrySize
+	}
+	return db.header.citySize
+}
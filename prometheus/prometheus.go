@@ -0,0 +1,73 @@
+// Package prometheus exports sxgo telemetry (see sxgo.WithTelemetry) as
+// Prometheus metrics. It is a separate module, so that only programs using
+// it depend on the Prometheus client.
+//
+// Counters become Prometheus counters and durations histograms, named as
+// listed by sxgo.Metrics.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DurationBuckets are the histogram buckets of duration metrics, in seconds:
+// 1µs to about 0.26s, as lookups take microseconds in memory and up to
+// milliseconds on slow storage.
+var DurationBuckets = prometheus.ExponentialBuckets(1e-6, 4, 10)
+
+// Telemetry implements sxgo.Telemetry with Prometheus metrics.
+type Telemetry struct {
+	counters  map[string]prometheus.Counter
+	durations map[string]prometheus.Observer
+}
+
+// New registers the metrics of sxgo.Metrics with reg and returns the
+// Telemetry updating them. labels are attached to every metric, e.g. to tell
+// instances apart when several are registered with the same registry.
+func New(reg prometheus.Registerer, labels prometheus.Labels) (*Telemetry, error) {
+	t := &Telemetry{
+		counters:  make(map[string]prometheus.Counter),
+		durations: make(map[string]prometheus.Observer),
+	}
+	for _, m := range sxgo.Metrics() {
+		var c prometheus.Collector
+		if m.Duration {
+			h := prometheus.NewHistogram(prometheus.HistogramOpts{
+				Name:        m.Name,
+				Help:        m.Help,
+				ConstLabels: labels,
+				Buckets:     DurationBuckets,
+			})
+			t.durations[m.Name], c = h, h
+		} else {
+			ctr := prometheus.NewCounter(prometheus.CounterOpts{
+				Name:        m.Name,
+				Help:        m.Help,
+				ConstLabels: labels,
+			})
+			t.counters[m.Name], c = ctr, ctr
+		}
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// CounterAdd adds delta to the counter name; unknown names are ignored.
+func (t *Telemetry) CounterAdd(name string, delta uint64) {
+	if c, ok := t.counters[name]; ok {
+		c.Add(float64(delta))
+	}
+}
+
+// ObserveDuration records d in the histogram name, in seconds; unknown names
+// are ignored.
+func (t *Telemetry) ObserveDuration(name string, d time.Duration) {
+	if h, ok := t.durations[name]; ok {
+		h.Observe(d.Seconds())
+	}
+}
@@ -0,0 +1,146 @@
+package sxgo
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CountryQuota is a token bucket: requests are allowed at Rate per second on
+// average, with bursts of up to Burst requests. With Rate 0, Burst requests
+// are allowed in total.
+type CountryQuota struct {
+	Rate  float64 `json:"rate"`  // Tokens added per second.
+	Burst int     `json:"burst"` // Bucket size; a full bucket allows Burst requests at once.
+}
+
+// GeoRateLimiter applies per-country request quotas: each country shares one
+// token bucket, keyed by the ISO code its addresses resolve to, e.g. to cap
+// the traffic a service accepts from countries it sees abuse from. It is
+// safe for concurrent use.
+type GeoRateLimiter struct {
+	geo    *SxGeo
+	quotas map[string]CountryQuota // By upper-case ISO code; "" for unknown countries
+	def    *CountryQuota           // Quota of countries not in quotas; nil for none
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket is the state of one country's quota.
+// This struct is internal.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewGeoRateLimiter returns a GeoRateLimiter resolving countries with geo.
+// quotas maps ISO codes (case-insensitive) to their quota; the key "" holds
+// the quota of addresses without a country (not found, reserved or invalid).
+// def, if not nil, applies to every country without its own quota; otherwise
+// they are not limited. A quota with Burst < 1 blocks all requests.
+func NewGeoRateLimiter(geo *SxGeo, quotas map[string]CountryQuota, def *CountryQuota) *GeoRateLimiter {
+	l := &GeoRateLimiter{
+		geo:     geo,
+		quotas:  make(map[string]CountryQuota, len(quotas)),
+		buckets: make(map[string]*tokenBucket),
+	}
+	for iso, q := range quotas {
+		l.quotas[strings.ToUpper(iso)] = q
+	}
+	if def != nil {
+		q := *def
+		l.def = &q
+	}
+	return l
+}
+
+// Allow takes a token from the bucket of ip's country and reports whether
+// the request is allowed, along with the ISO code it was counted against. An
+// address that cannot be resolved is counted as unknown ("") and the lookup
+// error is returned with the decision.
+func (l *GeoRateLimiter) Allow(ip string) (ok bool, iso string, err error) {
+	iso, err = l.geo.GetCountry(ip)
+	if err != nil {
+		iso = ""
+	}
+	ok, _ = l.take(iso)
+	return ok, iso, err
+}
+
+// Middleware returns an http.Handler passing requests within their country's
+// quota to next and rejecting the others with 429 Too Many Requests and a
+// Retry-After header. clientIP extracts the address to locate from a request
+// (e.g. from a header set by a trusted proxy); if nil, the host of
+// RemoteAddr is used.
+func (l *GeoRateLimiter) Middleware(next http.Handler, clientIP func(*http.Request) string) http.Handler {
+	if clientIP == nil {
+		clientIP = remoteHost
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iso, err := l.geo.GetCountry(clientIP(r))
+		if err != nil {
+			iso = ""
+		}
+		ok, wait := l.take(iso)
+		if !ok {
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+			}
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take takes a token from the bucket of iso, reporting whether there was one
+// and otherwise how long until there is; 0 if never.
+// Internal function.
+func (l *GeoRateLimiter) take(iso string) (bool, time.Duration) {
+	q, ok := l.quotas[iso]
+	if !ok {
+		if l.def == nil {
+			return true, 0
+		}
+		q = *l.def
+	}
+	if q.Burst < 1 {
+		return false, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.buckets[iso]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(q.Burst), last: now}
+		l.buckets[iso] = b
+	}
+	if q.Rate > 0 {
+		b.tokens = min(float64(q.Burst), b.tokens+now.Sub(b.last).Seconds()*q.Rate)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if q.Rate <= 0 {
+		return false, 0
+	}
+	return false, time.Duration((1 - b.tokens) / q.Rate * float64(time.Second))
+}
+
+// remoteHost returns the host of r.RemoteAddr.
+// Internal function.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -4,17 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // readData reads and unpacks data (country, region, or city) from a given seek offset and max size.
-// dataType: 0=country, 1=region, 2=city (indices into s.packFormats)
-// seek: The offset relative to the beginning of the relevant data block (regionsBegin or citiesBegin).
+// dataType: 0=country, 1=region, 2=city (indices into db.packFormats)
+// seek: The offset relative to the beginning of the relevant data block (regionsBegin, citiesBegin or countriesBegin).
 // maxSize: The maximum number of bytes to read for this record.
-// Returns the unpacked data as a map or an error.
+// Returns the unpacked data as a map or an error. The map may be shared through
+// the record cache (WithRecordCache) and must not be modified.
 // Internal function.
-func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]interface{}, error) {
+func (s *SxGeo) readData(db *dbState, seek uint32, maxSize uint16, dataType int) (map[string]interface{}, error) {
 	// Validate data type and pack format existence
-	if dataType < 0 || dataType >= len(s.packFormats) || s.packFormats[dataType] == "" {
+	if dataType < 0 || dataType >= len(db.packFormats) || db.packFormats[dataType] == "" {
 		// Cannot unpack if format is missing. Return empty map, no error.
 		return make(map[string]interface{}), nil
 	}
@@ -24,21 +26,71 @@ func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]
 		return make(map[string]interface{}), nil
 	}
 
-	var data []byte // Byte slice containing the raw data for the record
+	key := recordKey{db.gen, dataType, seek}
+	records := s.records
+	if db.fileReads {
+		records = nil
+	}
+	if records != nil {
+		if rec, ok := records.get(key); ok {
+			s.count(MetricRecordCacheHits, 1)
+			return rec, nil
+		}
+		s.count(MetricRecordCacheMisses, 1)
+	}
 
-	if s.memoryMode {
+	buf := db.scratch.getRecord(int(maxSize))
+	defer db.scratch.putRecord(buf)
+	data, err := s.recordBytesInto(db, seek, maxSize, dataType, scratchBytes(buf))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return make(map[string]interface{}), nil
+	}
+
+	// Unpack the retrieved data using the appropriate format string
+	rec, n, err := unpackLen(db.packFormats[dataType], data) // unpackLen is defined in unpack.go
+	if err != nil {
+		return nil, err
+	}
+	if s.intern {
+		internRecord(rec)
+	}
+	if records != nil && len(rec) > 0 {
+		records.add(key, rec, n)
+	}
+	return rec, nil
+}
+
+// recordBytes returns the bytes of the record at seek, at most maxSize,
+// clamped to its block; nil if the block is missing or seek is at its end. In
+// ModeMemory the result aliases the loaded data and must not be modified.
+// Internal function.
+func (s *SxGeo) recordBytes(db *dbState, seek uint32, maxSize uint16, dataType int) ([]byte, error) {
+	return s.recordBytesInto(db, seek, maxSize, dataType, nil)
+}
+
+// recordBytesInto is recordBytes reading into buf in ModeFile if it is large
+// enough, so that the result aliases buf.
+// Internal function.
+func (s *SxGeo) recordBytesInto(db *dbState, seek uint32, maxSize uint16, dataType int, buf []byte) ([]byte, error) {
+	if s.memoryMode && !db.fileReads {
+		if db.countryTable != nil {
+			return nil, ErrCountryOnly // Records were not loaded
+		}
 		var sourceData []byte // Reference to the full data block (regions or cities)
 		var baseOffset int64  // Base offset (0 for relative seek in memory)
 
 		switch dataType {
 		case 0: // Country data (stored within the cities block in v2.2)
-			sourceData = s.citiesData
-			baseOffset = 0 // Seek is relative to start of citiesData
+			sourceData = db.countriesData
+			baseOffset = 0 // Seek is relative to start of countriesData
 		case 1: // Region data
-			sourceData = s.regionsData
+			sourceData = db.regionsData
 			baseOffset = 0 // Seek is relative to start of regionsData
 		case 2: // City data
-			sourceData = s.citiesData
+			sourceData = db.citiesData
 			baseOffset = 0 // Seek is relative to start of citiesData
 		default:
 			// Should be caught by earlier check
@@ -47,7 +99,7 @@ func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]
 
 		if sourceData == nil {
 			// Data block for this type wasn't loaded or doesn't exist (e.g., no regions)
-			return make(map[string]interface{}), nil // Return empty map, no error
+			return nil, nil // No data, no error
 		}
 
 		sourceLen := int64(len(sourceData))
@@ -66,30 +118,37 @@ func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]
 		// Check if the calculated slice is valid
 		if start >= end {
 			// Valid seek, but results in zero-length read (e.g., seek at end, or maxSize too large)
-			return make(map[string]interface{}), nil
+			return nil, nil
 		}
 
-		data = sourceData[start:end]
+		return sourceData[start:end], nil
 
 	} else { // File mode
-		if s.f == nil {
-			return nil, errors.New("file mode error: file handle is nil")
-		}
 		var absOffset int64 // Absolute offset in the .dat file
+		var blockLen uint32 // Size of the block holding the record
 
 		switch dataType {
-		case 0: // Country (relative to citiesBegin)
-			absOffset = s.citiesBegin + int64(seek)
+		case 0: // Country (relative to countriesBegin)
+			absOffset, blockLen = db.countriesBegin+int64(seek), db.countryBlockLen()
 		case 1: // Region (relative to regionsBegin)
-			absOffset = s.regionsBegin + int64(seek)
+			absOffset, blockLen = db.regionsBegin+int64(seek), db.header.regionSize
 		case 2: // City (relative to citiesBegin)
-			absOffset = s.citiesBegin + int64(seek)
+			absOffset, blockLen = db.citiesBegin+int64(seek), db.header.citySize
 		default:
 			return nil, fmt.Errorf("internal error: invalid data type %d in readData", dataType)
 		}
 
-		readBytes := make([]byte, maxSize)
-		n, err := s.f.ReadAt(readBytes, absOffset)
+		// Stay within the block, as in ModeMemory; a missing block reads as empty
+		if seek >= blockLen {
+			return nil, nil
+		}
+		var readBytes []byte
+		if need := int(min(uint32(maxSize), blockLen-seek)); len(buf) >= need {
+			readBytes = buf[:need]
+		} else {
+			readBytes = make([]byte, need)
+		}
+		n, err := s.readAt(db, readBytes, absOffset)
 
 		// Handle read errors
 		if err != nil && !errors.Is(err, io.EOF) {
@@ -99,13 +158,31 @@ func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]
 		// If EOF or no error, proceed with the bytes read (n)
 		if n == 0 {
 			// Read 0 bytes, likely seek was at or past EOF.
-			return make(map[string]interface{}), nil
+			return nil, nil
 		}
-		data = readBytes[:n] // Use only the bytes actually read
+		return readBytes[:n], nil // Use only the bytes actually read
 	}
+}
 
-	// Unpack the retrieved data using the appropriate format string
-	return unpack(s.packFormats[dataType], data) // unpack is defined in unpack.go
+// recordDepth selects which records linked from a city record parseCityDepth reads.
+type recordDepth int
+
+const (
+	depthCity   recordDepth = iota // The city record; the country comes from its country_id.
+	depthRegion                    // Also the region record.
+	depthFull                      // Also the country record the region points to.
+)
+
+// names returns how errors name a lookup and record parsing at depth.
+// Internal function.
+func (d recordDepth) names() (lookup, parsing string) {
+	switch d {
+	case depthFull:
+		return "full city lookup", "parsing full city"
+	case depthRegion:
+		return "city region lookup", "parsing city region"
+	}
+	return "city lookup", "parsing city"
 }
 
 // parseCity retrieves and structures City, Region, and Country information.
@@ -113,15 +190,30 @@ func (s *SxGeo) readData(seek uint32, maxSize uint16, dataType int) (map[string]
 // full: If true, attempts to load Region details as well.
 // Returns a LocationInfo struct or an error.
 // Internal function.
-func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
+func (s *SxGeo) parseCity(db *dbState, seek uint32, full bool) (*LocationInfo, error) {
+	if full {
+		return s.parseCityDepth(db, seek, depthFull)
+	}
+	return s.parseCityDepth(db, seek, depthCity)
+}
+
+// parseCityDepth is parseCity reading linked records down to depth.
+// Internal function.
+func (s *SxGeo) parseCityDepth(db *dbState, seek uint32, depth recordDepth) (*LocationInfo, error) {
 	// Ensure pack formats exist for required types (at least city=2, country=0)
 	requiredFormats := 3 // 0: Country, 1: Region, 2: City
-	if len(s.packFormats) < requiredFormats {
+	if len(db.packFormats) < requiredFormats {
 		// If only country/city (len 2 or less), parsing full might fail.
 		// Let readData handle missing formats individually.
-		// return nil, fmt.Errorf("insufficient pack formats defined (need %d, have %d)", requiredFormats, len(s.packFormats))
+		// return nil, fmt.Errorf("insufficient pack formats defined (need %d, have %d)", requiredFormats, len(db.packFormats))
 	}
-	if len(s.packFormats) <= 2 || s.packFormats[2] == "" {
+	switch db.recordKind(seek) {
+	case recordCountry:
+		return s.parseCountryRecord(db, seek)
+	case recordRegion:
+		return s.parseRegionRecord(db, seek)
+	}
+	if len(db.packFormats) <= 2 || db.packFormats[2] == "" {
 		return nil, errors.New("database is missing city pack format")
 	}
 	// Country format (index 0) is also needed, checked later if accessed.
@@ -131,7 +223,7 @@ func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
 	var err error
 
 	// --- 1. Read City Data ---
-	cityData, err = s.readData(seek, s.header.maxCity, 2) // Type 2 for City
+	cityData, err = s.readData(db, seek, db.header.maxCity, 2) // Type 2 for City
 	if err != nil {
 		return nil, fmt.Errorf("failed to read city data at seek %d: %w", seek, err)
 	}
@@ -156,16 +248,16 @@ func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
 	regionSeek := info.City.regionSeek
 	var countrySeek uint32 // Seek pointer found inside region data
 
-	if full && regionSeek > 0 && s.header.maxRegion > 0 {
+	if depth >= depthRegion && regionSeek > 0 && db.layout.HasRegions {
 		// Check if region format exists (index 1)
-		if len(s.packFormats) <= 1 || s.packFormats[1] == "" {
+		if len(db.packFormats) <= 1 || db.packFormats[1] == "" {
 			// Cannot get region details without region format. Proceed without it.
-			// Log this? Or ignore? Ignore for now.
+			s.warn(info, errors.New("database is missing region pack format"))
 		} else {
-			regionData, err = s.readData(regionSeek, s.header.maxRegion, 1) // Type 1 for Region
+			regionData, err = s.readData(db, regionSeek, db.header.maxRegion, 1) // Type 1 for Region
 			if err != nil {
-				// Failed to read region, proceed without it, but maybe log?
-				// return nil, fmt.Errorf("failed to read region data at seek %d: %w", regionSeek, err)
+				// Failed to read region, proceed without it (see WithWarnings).
+				s.warn(info, fmt.Errorf("failed to read region data at seek %d: %w", regionSeek, err))
 			} else if len(regionData) > 0 {
 				info.Region = &Region{
 					ID:     getUint32(regionData, "id"),
@@ -176,8 +268,10 @@ func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
 					countrySeek: getUint32(regionData, "country_seek"), // Store pointer from region
 				}
 				countrySeek = info.Region.countrySeek // Update countrySeek if region provided one
+			} else {
+				// Region remains nil.
+				s.warn(info, fmt.Errorf("region data not found or empty for seek %d", regionSeek))
 			}
-			// If regionData was empty, info.Region remains nil.
 		}
 	}
 
@@ -194,16 +288,17 @@ func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
 
 	countryIDToUse := info.City.countryID // Default to ID from city record
 
-	if countrySeek > 0 && s.header.maxCountry > 0 {
+	if depth == depthFull && countrySeek > 0 && db.layout.HasCountryRecords {
 		// We have a specific seek pointer from the region data.
 		// Check if country format exists (index 0)
-		if len(s.packFormats) == 0 || s.packFormats[0] == "" {
+		if len(db.packFormats) == 0 || db.packFormats[0] == "" {
 			// Cannot read country data without format. Rely on city's countryID below.
+			s.warn(info, errors.New("database is missing country pack format"))
 		} else {
-			countryData, err = s.readData(countrySeek, s.header.maxCountry, 0) // Type 0 for Country
+			countryData, err = s.readData(db, countrySeek, db.header.maxCountry, 0) // Type 0 for Country
 			if err != nil {
-				// Failed to read country, proceed using city's countryID, maybe log?
-				// return nil, fmt.Errorf("failed to read country data via region at seek %d: %w", countrySeek, err)
+				// Failed to read country, proceed using city's countryID (see WithWarnings).
+				s.warn(info, fmt.Errorf("failed to read country data via region at seek %d: %w", countrySeek, err))
 			}
 			// If read successful, update the ID from the data itself if available
 			if len(countryData) > 0 {
@@ -251,5 +346,92 @@ func (s *SxGeo) parseCity(seek uint32, full bool) (*LocationInfo, error) {
 		return nil, errors.New("internal error: failed to retrieve any location information after parsing")
 	}
 
+	info.setPrecision()
 	return info, nil
 }
+
+// parseCountryRecord builds a country-only result from the country record at
+// seek, for ranges known only to country level.
+// Internal function.
+func (s *SxGeo) parseCountryRecord(db *dbState, seek uint32) (*LocationInfo, error) {
+	countryData, err := s.readData(db, seek, db.header.maxCountry, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read country data at seek %d: %w", seek, err)
+	}
+	info := &LocationInfo{Country: countryFromRecord(countryData)}
+	if info.Country == nil {
+		return nil, fmt.Errorf("country data not found or empty for seek %d", seek)
+	}
+	info.setPrecision()
+	return info, nil
+}
+
+// parseRegionRecord builds a region-level result from the region record at
+// seek, for databases without a cities block. The country comes from the
+// region's country record if there is one, else from its ISO code prefix.
+// Internal function.
+func (s *SxGeo) parseRegionRecord(db *dbState, seek uint32) (*LocationInfo, error) {
+	regionData, err := s.readData(db, seek, db.header.maxRegion, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region data at seek %d: %w", seek, err)
+	}
+	if len(regionData) == 0 {
+		return nil, fmt.Errorf("region data not found or empty for seek %d", seek)
+	}
+	info := &LocationInfo{
+		Region: &Region{
+			ID:          getUint32(regionData, "id"),
+			NameRU:      getString(regionData, "name_ru"),
+			NameEN:      getString(regionData, "name_en"),
+			ISO:         getString(regionData, "iso"),
+			countrySeek: getUint32(regionData, "country_seek"),
+		},
+	}
+	if info.Country, err = s.regionCountry(db, regionData); err != nil {
+		s.warn(info, err)
+	}
+	info.setPrecision()
+	return info, nil
+}
+
+// regionCountry returns the country of an unpacked region record: its country
+// record if the database has one, else a minimal Country from the prefix of
+// the region's ISO 3166-2 code. Returns nil if neither is available. err
+// reports a country record that could not be read; the fallback is still
+// returned then.
+// Internal function.
+func (s *SxGeo) regionCountry(db *dbState, regionData map[string]interface{}) (c *Country, err error) {
+	if seek := getUint32(regionData, "country_seek"); db.layout.HasCountryRecords && seek < db.header.countrySize {
+		var countryData map[string]interface{}
+		if countryData, err = s.readData(db, seek, db.header.maxCountry, 0); err == nil {
+			if c := countryFromRecord(countryData); c != nil {
+				return c, nil
+			}
+		} else {
+			err = fmt.Errorf("failed to read country data at seek %d: %w", seek, err)
+		}
+	}
+	iso, _, _ := strings.Cut(getString(regionData, "iso"), "-")
+	if id := getIDByISO(iso); id > 0 {
+		return &Country{ID: id, ISO: getISO(uint32(id))}, err
+	}
+	return nil, err
+}
+
+// countryFromRecord builds a Country from an unpacked country record.
+// Returns nil if the record has no known country ID.
+// Internal function.
+func countryFromRecord(countryData map[string]interface{}) *Country {
+	id := getUint8(countryData, "id")
+	if id == 0 {
+		return nil
+	}
+	return &Country{
+		ID:     id,
+		ISO:    getISO(uint32(id)),
+		Lat:    getFloat(countryData, "lat"),
+		Lon:    getFloat(countryData, "lon"),
+		NameRU: getString(countryData, "name_ru"),
+		NameEN: getString(countryData, "name_en"),
+	}
+}
@@ -0,0 +1,119 @@
+package sxgo
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RecentLookup aggregates the recent lookups that found the same place
+// (see WithRecentLookups).
+type RecentLookup struct {
+	Country string    `json:"country"`           // ISO code; "" for addresses not found.
+	CityID  uint32    `json:"city_id,omitempty"` // 0 if the result had no city.
+	City    string    `json:"city,omitempty"`    // English name of the city, else the Russian one.
+	Count   int       `json:"count"`             // Lookups among the recent ones.
+	Last    time.Time `json:"last"`              // Time of the latest of them.
+}
+
+// recentEntry is a lookup in the recent lookups ring.
+// This struct is internal.
+type recentEntry struct {
+	country string
+	cityID  uint32
+	city    string
+	time    time.Time
+}
+
+// recentRing keeps the last lookups for RecentLookups.
+// This struct is internal.
+type recentRing struct {
+	mu      sync.Mutex
+	entries []recentEntry
+	next    int  // Slot the next lookup is written to
+	full    bool // Whether every slot has been written
+}
+
+// WithRecentLookups keeps the results of the last n lookups (GetCity,
+// GetCityRegion, GetCityFull and GetCountryID, GetCountry included) for
+// RecentLookups, a cheap view for debugging dashboards of small deployments.
+// Only the place is kept, never the address. n <= 0 disables it.
+func WithRecentLookups(n int) Option {
+	return func(s *SxGeo) {
+		s.recent = nil
+		if n > 0 {
+			s.recent = &recentRing{entries: make([]recentEntry, n)}
+		}
+	}
+}
+
+// RecentLookups summarizes the lookups kept with WithRecentLookups by place,
+// most frequent first (then most recent). It returns nil without
+// WithRecentLookups.
+func (s *SxGeo) RecentLookups() []RecentLookup {
+	r := s.recent
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	type place struct {
+		country string
+		cityID  uint32
+	}
+	byPlace := make(map[place]*RecentLookup)
+	var out []*RecentLookup
+	for _, e := range r.entries[:n] {
+		k := place{e.country, e.cityID}
+		agg := byPlace[k]
+		if agg == nil {
+			agg = &RecentLookup{Country: e.country, CityID: e.cityID, City: e.city}
+			byPlace[k] = agg
+			out = append(out, agg)
+		}
+		agg.Count++
+		if e.time.After(agg.Last) {
+			agg.Last = e.time
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Last.After(out[j].Last)
+	})
+	res := make([]RecentLookup, len(out))
+	for i, agg := range out {
+		res[i] = *agg
+	}
+	return res
+}
+
+// add records a lookup result; info is nil for addresses not found.
+// Internal function.
+func (r *recentRing) add(info *LocationInfo) {
+	e := recentEntry{time: time.Now()}
+	if info != nil {
+		if info.Country != nil {
+			e.country = info.Country.ISO
+		}
+		if info.City != nil {
+			e.cityID, e.city = info.City.ID, info.City.NameEN
+			if e.city == "" {
+				e.city = info.City.NameRU
+			}
+		}
+	}
+	r.mu.Lock()
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+	r.mu.Unlock()
+}
@@ -0,0 +1,162 @@
+package sxgo
+
+import (
+	"container/list"
+	"sync"
+)
+
+// RecordCacheStats describes the decoded-record cache (see WithRecordCache).
+type RecordCacheStats struct {
+	Entries   int    `json:"entries"`   // Records cached.
+	Bytes     int64  `json:"bytes"`     // Estimated memory used by the cached records.
+	MaxBytes  int64  `json:"max_bytes"` // Configured budget.
+	Hits      uint64 `json:"hits"`      // Record reads answered from the cache.
+	Misses    uint64 `json:"misses"`    // Record reads that had to decode.
+	Evictions uint64 `json:"evictions"` // Records dropped to stay within the budget.
+}
+
+// recordKey identifies a record: the database it was read from (see
+// dbState.gen), its pack format index and seek.
+// This struct is internal.
+type recordKey struct {
+	gen      uint64
+	dataType int
+	seek     uint32
+}
+
+// recordEntry is a cached record and its estimated cost in bytes.
+// This struct is internal.
+type recordEntry struct {
+	key  recordKey
+	rec  map[string]interface{}
+	cost int64
+}
+
+// recordCache is an LRU cache of decoded records bounded by estimated bytes.
+// Cached maps are shared between lookups and must not be modified.
+// This struct is internal.
+type recordCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	lru      *list.List // Of *recordEntry, most recently used first
+	index    map[recordKey]*list.Element
+
+	hits, misses, evictions uint64
+}
+
+// WithRecordCache caches decoded city, region and country records by seek,
+// keeping their estimated memory use within maxBytes by evicting the least
+// recently used. It helps ModeFile most, where every uncached record is a
+// file read; in ModeMemory it only saves decoding. Occupancy is reported by
+// Stats. maxBytes <= 0 disables the cache.
+func WithRecordCache(maxBytes int64) Option {
+	return func(s *SxGeo) {
+		if maxBytes <= 0 {
+			s.records = nil
+			return
+		}
+		s.records = &recordCache{
+			maxBytes: maxBytes,
+			lru:      list.New(),
+			index:    make(map[recordKey]*list.Element),
+		}
+	}
+}
+
+// get returns the cached record for key, if any.
+// Internal function.
+func (c *recordCache) get(key recordKey) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.index[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(el)
+	return el.Value.(*recordEntry).rec, true
+}
+
+// add caches rec, decoded from size bytes, evicting records as needed.
+// Records costing more than the whole budget are not cached.
+// Internal function.
+func (c *recordCache) add(key recordKey, rec map[string]interface{}, size int) {
+	c.insert(key, rec, recordCost(rec, size))
+}
+
+// insert caches rec at the given cost, as add does.
+// Internal function.
+func (c *recordCache) insert(key recordKey, rec map[string]interface{}, cost int64) {
+	if cost > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.index[key]; ok {
+		return // Decoded concurrently by another lookup
+	}
+	c.index[key] = c.lru.PushFront(&recordEntry{key: key, rec: rec, cost: cost})
+	c.bytes += cost
+	for c.bytes > c.maxBytes {
+		e := c.lru.Remove(c.lru.Back()).(*recordEntry)
+		delete(c.index, e.key)
+		c.bytes -= e.cost
+		c.evictions++
+	}
+}
+
+// retain drops the records of databases other than gen, once Reload has
+// replaced them.
+// Internal function.
+func (c *recordCache) retain(gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*recordEntry); e.key.gen != gen {
+			c.lru.Remove(el)
+			delete(c.index, e.key)
+			c.bytes -= e.cost
+		}
+		el = next
+	}
+}
+
+// entries returns the cached records, least recently used first, so inserting
+// them in order into another cache reproduces the order.
+// Internal function.
+func (c *recordCache) entries() []recordEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]recordEntry, 0, c.lru.Len())
+	for el := c.lru.Back(); el != nil; el = el.Prev() {
+		out = append(out, *el.Value.(*recordEntry))
+	}
+	return out
+}
+
+// stats returns a snapshot of the cache counters.
+// Internal function.
+func (c *recordCache) stats() *RecordCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &RecordCacheStats{
+		Entries:   c.lru.Len(),
+		Bytes:     c.bytes,
+		MaxBytes:  c.maxBytes,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// recordCost estimates the memory held by a cached record decoded from size
+// bytes: the decoded values (strings are at most size bytes in total), map
+// buckets per field and the LRU bookkeeping.
+// Internal function.
+func recordCost(rec map[string]interface{}, size int) int64 {
+	const perField, perEntry = 64, 160
+	return int64(size) + int64(len(rec))*perField + perEntry
+}
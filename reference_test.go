@@ -0,0 +1,196 @@
+package sxgo_test
+
+import (
+	_ "embed"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/sxgeotest"
+)
+
+//go:generate go run testdata/minicity.go
+
+// miniCity is the City database written by testdata/minicity.go, with cp1251
+// names.
+//
+//go:embed testdata/minicity.dat
+var miniCity []byte
+
+// modes are the modes the tests open databases in.
+var modes = []struct {
+	name string
+	mode uint
+}{
+	{"file", sxgo.ModeFile},
+	{"memory", sxgo.ModeMemory},
+	{"mmap", sxgo.ModeMMap},
+}
+
+// miniCityPath writes miniCity to a temporary file for sxgo.New.
+func miniCityPath(tb testing.TB) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "minicity.dat")
+	if err := os.WriteFile(path, miniCity, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// place is the part of a lookup result the reference tests compare.
+type place struct {
+	city      uint32 // City ID; 0 for none.
+	cityRU    string
+	lat, lon  float64
+	region    string // ISO code; "" for none.
+	country   string // ISO code; "" for none.
+	countryRU string
+	precision string
+}
+
+// placeOf flattens info; nil yields the zero place.
+func placeOf(info *sxgo.LocationInfo) place {
+	var p place
+	if info == nil {
+		return p
+	}
+	if c := info.City; c != nil {
+		p.city, p.cityRU, p.lat, p.lon = c.ID, c.NameRU, c.Lat, c.Lon
+	}
+	if r := info.Region; r != nil {
+		p.region = r.ISO
+	}
+	if c := info.Country; c != nil {
+		p.country, p.countryRU = c.ISO, c.NameRU
+	}
+	p.precision = info.Precision.String()
+	return p
+}
+
+// Names in miniCity, in cp1251.
+const (
+	cp1251LosAngeles = "\xcb\xee\xf1-\xc0\xed\xe4\xe6\xe5\xeb\xe5\xf1"
+	cp1251USA        = "\xd1\xd8\xc0"
+	cp1251Ukraine    = "\xd3\xea\xf0\xe0\xe8\xed\xe0"
+	cp1251Kyiv       = "\xca\xe8\xe5\xe2"
+	cp1251Sochi      = "\xd1\xee\xf7\xe8"
+	cp1251Zelenograd = "\xc7\xe5\xeb\xe5\xed\xee\xe3\xf0\xe0\xe4"
+	cp1251Moscow     = "\xcc\xee\xf1\xea\xe2\xe0"
+	cp1251Russia     = "\xd0\xee\xf1\xf1\xe8\xff"
+	cp1251Kazan      = "\xca\xe0\xe7\xe0\xed\xfc"
+	cp1251Munich     = "\xcc\xfe\xed\xf5\xe5\xed"
+	cp1251Germany    = "\xc3\xe5\xf0\xec\xe0\xed\xe8\xff"
+)
+
+var (
+	losAngeles = place{5368361, cp1251LosAngeles, 34.05223, -118.24368, "US-CA", "US", cp1251USA, "city,region,country"}
+	kyiv       = place{703448, cp1251Kyiv, 50.45466, 30.5238, "UA-30", "UA", cp1251Ukraine, "city,region,country"}
+	moscow     = place{524901, cp1251Moscow, 55.75222, 37.61556, "RU-MOW", "RU", cp1251Russia, "city,region,country"}
+	kazan      = place{551487, cp1251Kazan, 55.78874, 49.12214, "RU-TA", "RU", cp1251Russia, "city,region,country"}
+	munich     = place{2867714, cp1251Munich, 48.13743, 11.57549, "DE-BY", "DE", cp1251Germany, "city,region,country"}
+	ukraine    = place{country: "UA", countryRU: cp1251Ukraine, precision: "country"}
+	germany    = place{country: "DE", countryRU: cp1251Germany, precision: "country"}
+)
+
+func TestMiniCityLookups(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want place
+	}{
+		{"first block start", "1.0.0.0", losAngeles},
+		{"first block end", "1.0.127.255", losAngeles},
+		{"unknown range", "1.0.128.0", place{}},
+		{"empty window", "2.0.0.1", place{}},
+		{"country level", "5.0.255.255", ukraine},
+		{"city after country level", "5.1.0.0", kyiv},
+		{"below first block of window", "46.0.0.1", kyiv},
+		// Without a region, the country comes from the city's country ID alone
+		{"missing region", "46.0.16.0", place{491422, cp1251Sochi, 43.60281, 39.73415, "", "RU", "", "city,country"}},
+		{"zero coordinates", "46.0.127.255", place{463343, cp1251Zelenograd, 0, 0, "RU-MOW", "RU", cp1251Russia, "city,region,country"}},
+		// Main index partitions hold 8 blocks: 93.158.0.0 is block 7, the last of the first
+		{"first partition end", "93.158.0.255", moscow},
+		{"second partition start", "93.158.1.0", kazan},
+		{"second partition end", "93.158.8.255", moscow},
+		{"third partition start", "93.158.9.0", kazan},
+		{"last partition", "93.158.19.1", kazan},
+		{"after window blocks", "93.158.20.0", place{}},
+		{"cgnat located by database", "100.64.1.1", moscow},
+		{"below last block", "223.0.0.0", germany},
+		{"last block", "223.255.255.0", munich},
+		{"last address of table", "223.255.255.255", munich},
+		{"reserved first byte 0", "0.1.2.3", place{}},
+		{"reserved first byte 10", "10.1.2.3", place{}},
+		{"reserved first byte 127", "127.0.0.1", place{}},
+		{"past byte index", "224.0.0.1", place{}},
+	}
+	path := miniCityPath(t)
+	for _, m := range modes {
+		geo, err := sxgo.New(path, m.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", m.name, err)
+		}
+		defer geo.Close()
+		for _, tt := range tests {
+			t.Run(m.name+"/"+tt.name, func(t *testing.T) {
+				info, err := geo.GetCityFull(tt.ip)
+				if err != nil {
+					t.Fatalf("GetCityFull(%s): %v", tt.ip, err)
+				}
+				if got := placeOf(info); got != tt.want {
+					t.Errorf("GetCityFull(%s) = %+v, want %+v", tt.ip, got, tt.want)
+				}
+				iso, err := geo.GetCountry(tt.ip)
+				if err != nil {
+					t.Fatalf("GetCountry(%s): %v", tt.ip, err)
+				}
+				if iso != tt.want.country {
+					t.Errorf("GetCountry(%s) = %q, want %q", tt.ip, iso, tt.want.country)
+				}
+			})
+		}
+	}
+}
+
+func TestMiniCityInfo(t *testing.T) {
+	geo, err := sxgo.New(miniCityPath(t), sxgo.ModeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	info := geo.Info()
+	if info.Type != sxgo.DBTypeCity || info.Charset != sxgo.CharsetCP1251 || info.Version != 22 || info.IPRanges != 32 || info.IDLength != 3 {
+		t.Errorf("Info() = %+v", info)
+	}
+}
+
+func TestMiniCityReservedRanges(t *testing.T) {
+	geo, err := sxgo.New(miniCityPath(t), sxgo.ModeMemory, sxgo.WithReservedRanges(sxgo.SpecialCGNAT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	info, err := geo.GetCityFull("100.64.1.1")
+	if err != nil || info != nil {
+		t.Errorf("GetCityFull(100.64.1.1) = %+v, %v; want nil, nil", info, err)
+	}
+	// Range walks agree with lookups: below 100.64.0.0 the /9 resolves to the
+	// unknown block before the window, and the rest is CGNAT space
+	sum, err := geo.DescribeCIDR(netip.MustParsePrefix("100.0.0.0/9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Unknown != sum.Addresses {
+		t.Errorf("DescribeCIDR(100.0.0.0/9) = %+v, want every address unknown", sum)
+	}
+}
+
+func TestMiniCityConformance(t *testing.T) {
+	path := miniCityPath(t)
+	sxgeotest.RunConformance(t, path)
+	t.Run("reserved-ranges", func(t *testing.T) {
+		sxgeotest.RunConformance(t, path, sxgo.WithReservedRanges())
+	})
+}
@@ -0,0 +1,126 @@
+package sxgo
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// RegionRef returns the reference of the city's region record, for RegionAt,
+// or 0 if the city has none. Results built by middleware or overrides rather
+// than read from the database carry no references.
+func (c *City) RegionRef() uint32 {
+	return c.regionSeek
+}
+
+// CountryID returns the country ID stored in the city record, or 0 if
+// unknown. It is what GetCity reports when it does not read country records.
+func (c *City) CountryID() uint8 {
+	return c.countryID
+}
+
+// CountryRef returns the reference of the region's country record, for
+// CountryAt, or 0 if the region has none.
+func (r *Region) CountryRef() uint32 {
+	return r.countrySeek
+}
+
+// RegionAt reads the region record ref refers to (see City.RegionRef), for
+// callers following the record chain themselves, e.g. after GetCity.
+// Returns (nil, nil) for ref 0 or if the database has no regions.
+func (s *SxGeo) RegionAt(ref uint32) (*Region, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if ref == 0 || !db.layout.HasRegions {
+		return nil, nil
+	}
+	if ref >= db.header.regionSize {
+		return nil, fmt.Errorf("sxgo: region reference %d out of range", ref)
+	}
+	if s.countryOnly {
+		return nil, fmt.Errorf("sxgo: failed to read region at %d: %w", ref, ErrCountryOnly)
+	}
+	regionData, err := s.readData(db, ref, db.header.maxRegion, 1)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to read region at %d: %w", ref, err)
+	}
+	if len(regionData) == 0 {
+		return nil, fmt.Errorf("sxgo: region data not found or empty for reference %d", ref)
+	}
+	return &Region{
+		ID:          getUint32(regionData, "id"),
+		NameRU:      getString(regionData, "name_ru"),
+		NameEN:      getString(regionData, "name_en"),
+		ISO:         getString(regionData, "iso"),
+		countrySeek: getUint32(regionData, "country_seek"),
+	}, nil
+}
+
+// CountryAt reads the country record ref refers to (see Region.CountryRef).
+// Returns (nil, nil) for ref 0 or if the database has no country records.
+func (s *SxGeo) CountryAt(ref uint32) (*Country, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if ref == 0 || !db.layout.HasCountryRecords {
+		return nil, nil
+	}
+	if ref >= db.header.countrySize {
+		return nil, fmt.Errorf("sxgo: country reference %d out of range", ref)
+	}
+	if s.countryOnly {
+		return nil, fmt.Errorf("sxgo: failed to read country at %d: %w", ref, ErrCountryOnly)
+	}
+	countryData, err := s.readData(db, ref, db.header.maxCountry, 0)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to read country at %d: %w", ref, err)
+	}
+	c := countryFromRecord(countryData)
+	if c == nil {
+		return nil, fmt.Errorf("sxgo: country data not found or empty for reference %d", ref)
+	}
+	return c, nil
+}
+
+// RawCityAt returns a copy of the bytes of the city record at seek (see
+// LookupID and CityRecord.Seek), as many as the longest city record takes;
+// decoders consume what they need, e.g. those generated by GenerateDecoders.
+// Returns nil if the database has no city at seek.
+func (s *SxGeo) RawCityAt(seek uint32) ([]byte, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if !db.layout.HasCities {
+		return nil, nil
+	}
+	return s.rawRecord(db, "city", seek, db.header.maxCity, 2)
+}
+
+// RawRegionAt is RawCityAt for the region record ref refers to (see
+// City.RegionRef).
+func (s *SxGeo) RawRegionAt(ref uint32) ([]byte, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if !db.layout.HasRegions {
+		return nil, nil
+	}
+	return s.rawRecord(db, "region", ref, db.header.maxRegion, 1)
+}
+
+// RawCountryAt is RawCityAt for the country record ref refers to (see
+// Region.CountryRef).
+func (s *SxGeo) RawCountryAt(ref uint32) ([]byte, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if !db.layout.HasCountryRecords {
+		return nil, nil
+	}
+	return s.rawRecord(db, "country", ref, db.header.maxCountry, 0)
+}
+
+// rawRecord implements the Raw*At methods.
+// Internal function.
+func (s *SxGeo) rawRecord(db *dbState, kind string, seek uint32, maxSize uint16, dataType int) ([]byte, error) {
+	data, err := s.recordBytes(db, seek, maxSize, dataType)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to read %s record at %d: %w", kind, seek, err)
+	}
+	return bytes.Clone(data), nil
+}
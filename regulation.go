@@ -0,0 +1,87 @@
+package sxgo
+
+import "strings"
+
+// Regulation describes the privacy rules a country applies to online
+// services, as consent-management integrations need them. It is a default
+// for building consent flows, not legal advice.
+type Regulation struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code.
+	// Law names the main data protection law, e.g. "GDPR", "UK GDPR" or
+	// "LGPD"; empty if the table has none for the country.
+	Law string `json:"law,omitempty"`
+	// GDPR reports whether the EU GDPR or its UK counterpart applies, i.e.
+	// the country is in the EEA or the United Kingdom.
+	GDPR bool `json:"gdpr"`
+	// ConsentAge is the age below which a child's data may only be processed
+	// with parental consent (for the EEA, the national choice under GDPR
+	// Article 8); 0 if unknown.
+	ConsentAge int `json:"consent_age,omitempty"`
+	// Regional reports that rules also vary below country level (e.g. US
+	// state laws such as the CCPA), so region-level results should be
+	// consulted.
+	Regional bool `json:"regional,omitempty"`
+}
+
+// RegulationTable maps ISO country codes to their regulation.
+type RegulationTable map[string]Regulation
+
+// DefaultRegulations returns the regulations of the EEA, the United Kingdom
+// and a few other major jurisdictions as of 2025. Laws and consent ages
+// change: review the table with counsel and pass an amended one to
+// WithRegulations as needed. The table is a fresh copy that may be modified.
+func DefaultRegulations() RegulationTable {
+	t := make(RegulationTable, len(regulations))
+	for iso, r := range regulations {
+		r.Country = iso
+		t[iso] = r
+	}
+	return t
+}
+
+// WithRegulations sets Country.Regulation on City lookup results from t
+// (DefaultRegulations if t is nil), and the table used by
+// RegulationOf. The map is not copied and must not be modified afterwards.
+func WithRegulations(t RegulationTable) Option {
+	return func(s *SxGeo) {
+		if t == nil {
+			t = DefaultRegulations()
+		}
+		s.regulations = t
+	}
+}
+
+// RegulationOf returns the regulation of the country of ip, from the table
+// set with WithRegulations or DefaultRegulations. Countries missing from the
+// table yield a Regulation with only Country set.
+// Returns (nil, nil) if the country is unknown.
+func (s *SxGeo) RegulationOf(ip string) (*Regulation, error) {
+	iso, err := s.country(ip)
+	if err != nil || iso == "" {
+		return nil, err
+	}
+	t := s.regulations
+	if t == nil {
+		t = regulations
+	}
+	return t.lookup(iso), nil
+}
+
+// attachRegulation sets info.Country.Regulation from the table set with
+// WithRegulations.
+// Internal function.
+func (s *SxGeo) attachRegulation(info *LocationInfo) {
+	if s.regulations == nil || info.Country == nil || info.Country.ISO == "" {
+		return
+	}
+	info.Country.Regulation = s.regulations.lookup(info.Country.ISO)
+}
+
+// lookup returns the regulation of iso, or one with only Country set.
+// Internal function.
+func (t RegulationTable) lookup(iso string) *Regulation {
+	iso = strings.ToUpper(iso)
+	r := t[iso]
+	r.Country = iso
+	return &r
+}
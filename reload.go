@@ -0,0 +1,165 @@
+package sxgo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// lazyTables holds the tables built from a database's records on first use.
+// They belong to the dbState they were built from, so a reloaded database
+// starts without them.
+// This struct is internal.
+type lazyTables struct {
+	centroidsOnce sync.Once
+	centroids     map[uint32][2]float64 // Region seek -> lat, lon (WithRegionCentroids)
+
+	countriesOnce sync.Once
+	countries     map[uint8]*Country // Country records by ID (WithCountryRemap)
+}
+
+// autoReload is the state of WithAutoReload.
+// This struct is internal.
+type autoReload struct {
+	interval time.Duration
+	onEvent  func(FileEvent)
+	stop     chan struct{} // Closed by Close
+}
+
+// Reload reads the database file New opened again, e.g. after a monthly
+// update was installed at its path (see ImportDatabase), and switches all
+// lookups to it at once: each lookup reads either the old or the new
+// database throughout, never a mix, and none waits for the load. The new
+// database is loaded in full with the mode and options of the instance
+// before the switch, so on error lookups carry on with the old one.
+//
+// Caches of the old database are dropped (the record cache, region
+// centroids and country records; the disk cache starts over for a
+// different database), as are ranges installed by ApplyPatch, which were
+// made for the old database. Memory and file handles of the old database are
+// released once the lookups still reading it are done. Reload is safe to
+// call concurrently with lookups; it fails for instances made by
+// NewFromSnapshot and after Close.
+func (s *SxGeo) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if s.closed {
+		return errors.New("sxgo: cannot reload a closed instance")
+	}
+	if s.path == "" {
+		return errors.New("sxgo: cannot reload: the instance was not opened from a database file")
+	}
+
+	old := s.db()
+	db, err := s.load(s.path, old.gen+1)
+	if err != nil {
+		return err
+	}
+	if s.disk != nil {
+		if err := s.disk.reset(sha256.Sum256(db.rawHead), db.gen); err != nil {
+			db.release()
+			return fmt.Errorf("sxgo: failed to reset disk cache %q: %w", s.disk.path, err)
+		}
+	}
+
+	if w := s.watch; w != nil {
+		// Checks must not replace the state meanwhile
+		w.mu.Lock()
+		s.state.Store(db)
+		err = s.startFileWatch(db, s.path)
+		w.stale.Store(false)
+		w.state = 0
+		w.mu.Unlock()
+	} else {
+		s.state.Store(db)
+	}
+	s.overlay.Store(nil)
+	if s.records != nil {
+		s.records.retain(db.gen)
+	}
+	// Unmapped or closed once the lookups reading the old database are done
+	// (see acquire)
+	old.retire()
+	if err != nil {
+		return fmt.Errorf("sxgo: reloaded %q but failed to watch it: %w", s.path, err)
+	}
+	return nil
+}
+
+// WithAutoReload makes the instance check the database file every interval
+// in the background and call Reload when another file was installed at its
+// path (renamed over it) or the file was rewritten, so long-running services
+// pick up database updates without restarting. onEvent, if not nil, is
+// called after each attempt with a FileReloaded event carrying the error of
+// a failed one; a file that failed to load is tried again once it changes.
+// Checks stop when the instance is closed. It has no effect on instances
+// made by NewFromSnapshot.
+func WithAutoReload(interval time.Duration, onEvent func(FileEvent)) Option {
+	return func(s *SxGeo) {
+		if interval <= 0 {
+			s.autoReload = nil
+			return
+		}
+		s.autoReload = &autoReload{interval: interval, onEvent: onEvent}
+	}
+}
+
+// startAutoReload starts the checks of WithAutoReload once New has loaded
+// the database.
+// Internal function.
+func (s *SxGeo) startAutoReload() {
+	r := s.autoReload
+	r.stop = make(chan struct{})
+	go func() {
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+		var failed os.FileInfo // The file that last failed to load
+		for {
+			select {
+			case <-r.stop:
+				return
+			case now := <-t.C:
+				info, err := os.Stat(s.path)
+				if err != nil || sameFileVersion(info, s.db().source) || sameFileVersion(info, failed) {
+					continue // Missing (possibly mid-rename), unchanged or known bad
+				}
+				err = s.Reload()
+				failed = nil
+				if err != nil {
+					failed = info
+				}
+				select {
+				case <-r.stop:
+					return // Closed meanwhile
+				default:
+				}
+				if r.onEvent != nil {
+					r.onEvent(FileEvent{Time: now, Path: s.path, Kind: FileReloaded, Err: err})
+				}
+			}
+		}
+	}()
+}
+
+// sameFileVersion reports whether a and b describe the same file with the
+// same size and modification time; false if either is nil.
+// Internal function.
+func sameFileVersion(a, b os.FileInfo) bool {
+	return a != nil && b != nil && os.SameFile(a, b) &&
+		a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// release closes the file and mapping of a database that was never
+// published.
+// Internal function.
+func (db *dbState) release() {
+	if db.mapping != nil {
+		munmap(db.mapping)
+	}
+	if db.f != nil {
+		db.f.Close()
+	}
+}
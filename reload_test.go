@@ -0,0 +1,102 @@
+package sxgo_test
+
+import (
+	"os"
+	"runtime/debug"
+	"sync"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// openFiles returns the number of descriptors the process has open, skipping
+// the test where /proc is not available.
+func openFiles(tb testing.TB) int {
+	tb.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		tb.Skipf("cannot count open files: %v", err)
+	}
+	return len(fds)
+}
+
+// lookUpWhile looks up ip on n goroutines until stop is closed, failing t if
+// an answer is not want. The returned WaitGroup is done once they stopped.
+func lookUpWhile(t *testing.T, geo *sxgo.SxGeo, n int, ip string, want place, stop <-chan struct{}) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				info, err := geo.GetCityFull(ip)
+				if err != nil {
+					t.Errorf("GetCityFull(%s): %v", ip, err)
+					return
+				}
+				if got := placeOf(info); got != want {
+					t.Errorf("GetCityFull(%s) = %+v, want %+v", ip, got, want)
+					return
+				}
+			}
+		}()
+	}
+	return &wg
+}
+
+func TestReloadDuringLookups(t *testing.T) {
+	// Finalizers must not close leaked handles behind the test's back
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	path := miniCityPath(t)
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			geo, err := sxgo.New(path, m.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer geo.Close()
+			before := openFiles(t)
+			stop := make(chan struct{})
+			wg := lookUpWhile(t, geo, 4, "46.0.128.1", moscow, stop)
+			for range 50 {
+				if err := geo.Reload(); err != nil {
+					t.Error(err)
+					break
+				}
+			}
+			close(stop)
+			wg.Wait()
+			// The handles of replaced databases are closed once no lookup reads
+			// them, not when the garbage collector gets to them
+			if after := openFiles(t); after > before {
+				t.Errorf("%d files open after 50 reloads, %d before", after, before)
+			}
+		})
+	}
+}
+
+func TestCloseReleasesFile(t *testing.T) {
+	path := miniCityPath(t)
+	before := openFiles(t)
+	geo, err := sxgo.New(path, sxgo.ModeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := geo.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := geo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if after := openFiles(t); after != before {
+		t.Errorf("%d files open after Close, %d before New", after, before)
+	}
+	if _, err := geo.GetCityFull("46.0.128.1"); err == nil {
+		t.Error("GetCityFull after Close succeeded")
+	}
+}
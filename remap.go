@@ -0,0 +1,153 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CountryRemap shows a different country for the listed cities and regions,
+// e.g. to present disputed territories as a jurisdiction or app store requires.
+type CountryRemap struct {
+	Country   string   // ISO 3166-1 alpha-2 code of the country to show.
+	CityIDs   []uint32 // Cities shown in Country.
+	RegionIDs []uint32 // Regions whose cities are shown in Country.
+}
+
+// remapState holds the rules of WithCountryRemap.
+// This struct is internal.
+type remapState struct {
+	cities  map[uint32]uint8 // City ID -> country ID
+	regions map[uint32]uint8 // Region ID -> country ID
+}
+
+// WithCountryRemap replaces the country of lookup results whose city or region
+// is listed in rules. City rules take precedence over region rules, and later
+// rules over earlier ones; rules naming an unknown country code are ignored.
+// The replacement is as detailed as the country it replaces: names and
+// coordinates come from the database's record for the new country when the
+// original had them. It applies to LocationInfo results before post-processors
+// run; GetCountry, GetCountryID and IsRestricted keep reporting the database
+// country. Region rules cost GetCity one region record read per lookup.
+func WithCountryRemap(rules ...CountryRemap) Option {
+	return func(s *SxGeo) {
+		if s.remap == nil {
+			s.remap = &remapState{cities: make(map[uint32]uint8), regions: make(map[uint32]uint8)}
+		}
+		for _, r := range rules {
+			id := getIDByISO(r.Country)
+			if id == 0 {
+				continue
+			}
+			for _, c := range r.CityIDs {
+				s.remap.cities[c] = id
+			}
+			for _, reg := range r.RegionIDs {
+				s.remap.regions[reg] = id
+			}
+		}
+	}
+}
+
+// remapCountry applies the WithCountryRemap rules to info.
+// Internal function.
+func (s *SxGeo) remapCountry(info *LocationInfo) {
+	r := s.remap
+	if r == nil {
+		return
+	}
+	id, ok := uint8(0), false
+	if info.City != nil {
+		id, ok = r.cities[info.City.ID]
+	}
+	if !ok && len(r.regions) > 0 {
+		if regionID, found := s.regionIDOf(info); found {
+			id, ok = r.regions[regionID]
+		}
+	}
+	if !ok || (info.Country != nil && info.Country.ID == id) {
+		return
+	}
+
+	c := &Country{ID: id, ISO: getISO(uint32(id))}
+	if old := info.Country; old != nil && (old.NameEN != "" || old.NameRU != "" || hasCoords(old.Lat, old.Lon)) {
+		if rec, found := s.countryRecords()[id]; found {
+			*c = *rec
+		}
+	}
+	info.Country = c
+}
+
+// regionIDOf returns the region ID of info, reading the region record of its
+// city if the result does not include the region.
+// Internal function.
+func (s *SxGeo) regionIDOf(info *LocationInfo) (uint32, bool) {
+	db := s.acquire()
+	defer db.unpin()
+	if info.Region != nil {
+		return info.Region.ID, true
+	}
+	if info.City == nil || info.City.regionSeek == 0 || !db.layout.HasRegions {
+		return 0, false
+	}
+	region, err := s.readData(db, info.City.regionSeek, db.header.maxRegion, 1)
+	if err != nil || len(region) == 0 {
+		return 0, false
+	}
+	return getUint32(region, "id"), true
+}
+
+// countryRecords returns the country records by ID, reading them on first
+// use. If they cannot be read, the map is empty.
+// Internal function.
+func (s *SxGeo) countryRecords() map[uint8]*Country {
+	db := s.acquire()
+	defer db.unpin()
+	t := db.tables
+	t.countriesOnce.Do(func() {
+		t.countries = make(map[uint8]*Country)
+		_ = s.walkCountries(db, func(rec map[string]interface{}) error {
+			if c := countryFromRecord(rec); c != nil {
+				t.countries[c.ID] = c
+			}
+			return nil
+		})
+	})
+	return t.countries
+}
+
+// walkCountries calls fn for every country record of db in storage order.
+// Returning an error from fn stops the walk.
+// Internal function.
+func (s *SxGeo) walkCountries(db *dbState, fn func(rec map[string]interface{}) error) error {
+	if !db.layout.HasCountryRecords {
+		return errors.New("database has no country records")
+	}
+
+	data := db.countriesData
+	if !s.memoryMode {
+		data = make([]byte, min(db.header.countrySize, db.countryBlockLen()))
+		n, err := s.readAt(db, data, db.countriesBegin)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read country records: %w", err)
+		}
+		data = data[:n]
+	}
+	data = data[:min(len(data), int(db.header.countrySize))]
+
+	for off := 0; off < len(data); {
+		end := min(off+int(db.header.maxCountry), len(data))
+		rec, n, err := unpackLen(db.packFormats[0], data[off:end])
+		if err != nil {
+			return fmt.Errorf("failed to unpack country at seek %d: %w", off, err)
+		}
+		if n == 0 {
+			break
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		off += n
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrReadTimeout is returned (wrapped) when a ModeFile read does not finish
+// within RetryPolicy.ReadTimeout. It is transient.
+var ErrReadTimeout = errors.New("sxgo: read timed out")
+
+// RetryPolicy controls how ModeFile reads during lookups are retried, for
+// databases on network file systems where reads can fail or stall briefly.
+// The zero value performs a single read without a deadline.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per read, including the
+	// first. Values below 1 mean 1.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry; it doubles for every
+	// further retry, up to MaxBackoff (0 means unbounded).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// ReadTimeout bounds each attempt. A read still pending when it expires
+	// is abandoned (it keeps running in the background) and the attempt fails
+	// with ErrReadTimeout. 0 means no deadline.
+	ReadTimeout time.Duration
+
+	// Retryable reports whether a failed attempt may be retried.
+	// nil means IsTransient.
+	Retryable func(err error) bool
+}
+
+// WithRetryPolicy applies p to every database read made by lookups in ModeFile.
+// Reads done by New are not retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *SxGeo) {
+		s.retry = &p
+	}
+}
+
+// IsTransient reports whether err, as returned by a lookup, is likely to go
+// away if the lookup is retried: read timeouts, interrupted or would-block
+// system calls and I/O errors such as those of soft-mounted NFS. Invalid IP
+// addresses, corrupt data and closed handles are permanent.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrReadTimeout) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	if transient, ok := transientErrno(err); ok {
+		return transient
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+// readAt reads len(buf) bytes at absolute offset off of the database file,
+// applying the retry policy. As with io.ReaderAt, a short read returns io.EOF,
+// which is never retried. The bytes read are counted in Stats.IO.
+// Internal function.
+func (s *SxGeo) readAt(db *dbState, buf []byte, off int64) (n int, err error) {
+	f, err := s.file(db)
+	if err != nil {
+		return 0, err
+	}
+	if f == nil {
+		return 0, errors.New("file mode error: file handle is nil")
+	}
+	defer func() { s.countRead(db, off, n) }()
+	align := s.tuning.alignment
+	p := s.retry
+	if p == nil {
+		return alignedReadAt(f, buf, off, align)
+	}
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	delay := p.Backoff
+	for attempt := 1; ; attempt++ {
+		n, err := readAtTimeout(f, buf, off, align, p.ReadTimeout)
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if attempt >= p.MaxAttempts || !retryable(err) {
+			if attempt > 1 {
+				err = fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return n, err
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+			delay *= 2
+			if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+				delay = p.MaxBackoff
+			}
+		}
+	}
+}
+
+// readAtTimeout is alignedReadAt bounded by timeout (none if timeout <= 0).
+// The read goes to a private buffer so an abandoned read cannot write to buf.
+// Internal function.
+func readAtTimeout(f *os.File, buf []byte, off int64, align int, timeout time.Duration) (int, error) {
+	if timeout <= 0 {
+		return alignedReadAt(f, buf, off, align)
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	tmp := make([]byte, len(buf))
+	done := make(chan result, 1)
+	go func() {
+		n, err := alignedReadAt(f, tmp, off, align)
+		done <- result{n, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		copy(buf, tmp[:r.n])
+		return r.n, r.err
+	case <-timer.C:
+		return 0, fmt.Errorf("%w after %s at offset %d", ErrReadTimeout, timeout, off)
+	}
+}
+
+// readerAtFunc adapts a ReadAt-style function to io.ReaderAt.
+// Internal type.
+type readerAtFunc func(p []byte, off int64) (int, error)
+
+// ReadAt calls fn(p, off).
+func (fn readerAtFunc) ReadAt(p []byte, off int64) (int, error) {
+	return fn(p, off)
+}
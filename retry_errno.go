@@ -0,0 +1,24 @@
+//go:build !plan9
+
+package sxgo
+
+import (
+	"errors"
+	"syscall"
+)
+
+// transientErrno classifies the system call error in err, if any, for
+// IsTransient: interrupted, would-block, busy, timed out and I/O errors are
+// transient, other errnos permanent.
+// Internal function.
+func transientErrno(err error) (transient, ok bool) {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false, false
+	}
+	switch errno {
+	case syscall.EINTR, syscall.EAGAIN, syscall.EBUSY, syscall.ETIMEDOUT, syscall.EIO:
+		return true, true
+	}
+	return false, true
+}
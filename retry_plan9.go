@@ -0,0 +1,8 @@
+package sxgo
+
+// transientErrno reports that err carries no errno: Plan 9 system calls fail
+// with error strings, so IsTransient only recognizes timeouts there.
+// Internal function.
+func transientErrno(err error) (transient, ok bool) {
+	return false, false
+}
@@ -0,0 +1,344 @@
+package sxgo_test
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/countries"
+	"github.com/idanyas/sxgo/dbwriter"
+)
+
+// roundTripDatabases is the number of random databases of each type
+// TestRoundTrip writes and reads back.
+const roundTripDatabases = 40
+
+// TestRoundTrip writes random databases with dbwriter and checks that every
+// mode and main index policy answers the first, last and some interior
+// addresses of every range, and of the first-byte windows, as the blocks
+// written say.
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3179, 1))
+	for _, typ := range []uint8{dbwriter.TypeCity, dbwriter.TypeCountry} {
+		for n := 0; n < roundTripDatabases; n++ {
+			db := randomDatabase(rng, typ)
+			data, err := db.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "random.dat")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			addrs := probeAddresses(rng, db)
+			name := fmt.Sprintf("type%d/%d", typ, n)
+			for _, m := range modes {
+				for _, p := range []sxgo.MainIndexPolicy{sxgo.MainIndexAlways, sxgo.MainIndexNever} {
+					geo, err := sxgo.New(path, m.mode, sxgo.WithMainIndexPolicy(p))
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					for _, a := range addrs {
+						checkRoundTrip(t, fmt.Sprintf("%s/%s/policy%d", name, m.name, p), geo, db, a)
+					}
+					geo.Close()
+				}
+			}
+			if t.Failed() {
+				t.Fatalf("%s: %d blocks, %d per partition, first-byte index of %d", name, len(db.Blocks), db.RangeBlocks, db.ByteIndexLen)
+			}
+		}
+	}
+}
+
+// checkRoundTrip compares the answers of geo for addr with the block of db
+// it lies in.
+func checkRoundTrip(t *testing.T, name string, geo *sxgo.SxGeo, db *dbwriter.Database, addr uint32) {
+	t.Helper()
+	ip := fmt.Sprintf("%d.%d.%d.%d", addr>>24, addr>>16&0xFF, addr>>8&0xFF, addr&0xFF)
+	var want dbwriter.Block // Zero for addresses not found
+	if i := blockOf(db, addr); i >= 0 {
+		want = db.Blocks[i]
+	}
+	var wantCountry uint8
+	switch {
+	case want.City != nil:
+		wantCountry = want.City.CountryID
+	case want.Country != nil:
+		wantCountry = want.Country.ID
+	}
+	id, err := geo.GetCountryID(ip)
+	if err != nil {
+		t.Fatalf("%s: GetCountryID(%s): %v", name, ip, err)
+	}
+	if id != uint32(wantCountry) {
+		t.Errorf("%s: GetCountryID(%s) = %d, want %d", name, ip, id, wantCountry)
+	}
+	if db.Type == dbwriter.TypeCountry {
+		return
+	}
+
+	info, err := geo.GetCityFull(ip)
+	if err != nil {
+		t.Fatalf("%s: GetCityFull(%s): %v", name, ip, err)
+	}
+	if got, want := cityOf(info), wantCity(want); got != want {
+		t.Errorf("%s: GetCityFull(%s) = %+v, want %+v", name, ip, got, want)
+	}
+}
+
+// roundTripCity is the part of a GetCityFull result TestRoundTrip compares.
+type roundTripCity struct {
+	city       uint32
+	cityEN     string
+	lat, lon   float64
+	region     string
+	country    uint8
+	countryISO string
+	countryEN  string
+}
+
+// cityOf flattens info; nil yields the zero roundTripCity.
+func cityOf(info *sxgo.LocationInfo) roundTripCity {
+	var c roundTripCity
+	if info == nil {
+		return c
+	}
+	if ci := info.City; ci != nil {
+		c.city, c.cityEN, c.lat, c.lon = ci.ID, ci.NameEN, ci.Lat, ci.Lon
+	}
+	if r := info.Region; r != nil {
+		c.region = r.ISO
+	}
+	if co := info.Country; co != nil {
+		c.country, c.countryISO, c.countryEN = co.ID, co.ISO, co.NameEN
+	}
+	return c
+}
+
+// wantCity is the GetCityFull result b should give. Without a region, the
+// country of a city is known by its ID and code only.
+func wantCity(b dbwriter.Block) roundTripCity {
+	var c roundTripCity
+	if co := b.Country; co != nil {
+		c.country, c.countryISO, c.countryEN = co.ID, co.ISO, co.NameEN
+	}
+	ci := b.City
+	if ci == nil {
+		return c
+	}
+	c.city, c.cityEN, c.lat, c.lon = ci.ID, ci.NameEN, ci.Lat, ci.Lon
+	c.country, c.countryISO = ci.CountryID, countries.ISO(ci.CountryID)
+	if r := ci.Region; r != nil {
+		c.region = r.ISO
+		c.countryISO, c.countryEN = r.Country.ISO, r.Country.NameEN
+	}
+	return c
+}
+
+// blockOf returns the index of the block addr resolves to in db, or -1 if it
+// is not found: the last block of its first-byte window starting at or below
+// it, or, below the first block of the window, the block before the window.
+// Windows without blocks and the first bytes 0, 10, 127 and those past the
+// first-byte index are not found.
+func blockOf(db *dbwriter.Database, addr uint32) int {
+	f := addr >> 24
+	if f == 0 || f == 10 || f == 127 || f >= uint32(db.ByteIndexLen) {
+		return -1
+	}
+	first := slices.IndexFunc(db.Blocks, func(b dbwriter.Block) bool { return b.Start>>24 == f })
+	if first < 0 {
+		return -1
+	}
+	i := first
+	for i+1 < len(db.Blocks) && db.Blocks[i+1].Start <= addr && db.Blocks[i+1].Start>>24 == f {
+		i++
+	}
+	if db.Blocks[i].Start > addr {
+		return first - 1
+	}
+	return i
+}
+
+// probeAddresses returns the first and last addresses of every block range
+// and of every first-byte window with blocks, with the addresses next to
+// them and random ones in between.
+func probeAddresses(rng *rand.Rand, db *dbwriter.Database) []uint32 {
+	var addrs []uint32
+	for i, b := range db.Blocks {
+		next := b.Start | 0xFFFFFF + 1 // The end of the window
+		if i+1 < len(db.Blocks) && db.Blocks[i+1].Start < next {
+			next = db.Blocks[i+1].Start
+		}
+		window := b.Start &^ 0xFFFFFF
+		addrs = append(addrs, window, b.Start-1, b.Start, b.Start+1, next-1, next, b.Start+rng.Uint32N(next-b.Start))
+		if b.Start > window {
+			addrs = append(addrs, window+rng.Uint32N(b.Start-window))
+		}
+	}
+	return addrs
+}
+
+// randomDatabase returns a database of the given type with random records and
+// up to a few hundred blocks in a few first bytes, so that windows span
+// several main index partitions.
+func randomDatabase(rng *rand.Rand, typ uint8) *dbwriter.Database {
+	db := &dbwriter.Database{
+		Type:         typ,
+		Charset:      dbwriter.CharsetUTF8,
+		Created:      time.Unix(1700000000, 0),
+		ByteIndexLen: uint8(128 + rng.IntN(dbwriter.DefaultByteIndexLen-128+1)),
+		RangeBlocks:  uint16(1 + rng.IntN(16)),
+	}
+	for n := 1 + rng.IntN(6); len(db.Countries) < n; {
+		id := uint8(1 + rng.IntN(255))
+		if len(countries.ISO(id)) != 2 || slices.ContainsFunc(db.Countries, func(c *dbwriter.Country) bool { return c.ID == id }) {
+			continue // Record codes are those of the IDs, as in real databases
+		}
+		db.Countries = append(db.Countries, &dbwriter.Country{
+			ID:     id,
+			ISO:    countries.ISO(id),
+			Lat:    float64(rng.IntN(18001)-9000) / 100,
+			Lon:    float64(rng.IntN(36001)-18000) / 100,
+			NameRU: fmt.Sprintf("Страна %d", id),
+			NameEN: fmt.Sprintf("Country %d", id),
+		})
+	}
+	if typ == dbwriter.TypeCity {
+		for i := range rng.IntN(6) {
+			co := db.Countries[rng.IntN(len(db.Countries))]
+			db.Regions = append(db.Regions, &dbwriter.Region{
+				ID:      rng.Uint32N(1 << 24),
+				Country: co,
+				ISO:     fmt.Sprintf("%s-%d", co.ISO, i),
+				NameRU:  fmt.Sprintf("Регион %d", i),
+				NameEN:  fmt.Sprintf("Region %d", i),
+			})
+		}
+		for i := range 1 + rng.IntN(10) {
+			c := &dbwriter.City{
+				ID:     rng.Uint32N(1 << 24),
+				Lat:    float64(rng.IntN(18000001)-9000000) / 1e5,
+				Lon:    float64(rng.IntN(36000001)-18000000) / 1e5,
+				NameRU: fmt.Sprintf("Город %d", i),
+				NameEN: fmt.Sprintf("City %d", i),
+			}
+			if len(db.Regions) > 0 && rng.IntN(4) > 0 {
+				c.Region = db.Regions[rng.IntN(len(db.Regions))]
+				c.CountryID = c.Region.Country.ID
+			} else {
+				c.CountryID = db.Countries[rng.IntN(len(db.Countries))].ID
+			}
+			db.Cities = append(db.Cities, c)
+		}
+	}
+
+	var firstBytes []uint32
+	for len(firstBytes) < 1+rng.IntN(8) {
+		f := 1 + rng.Uint32N(uint32(db.ByteIndexLen)-1)
+		if f != 10 && f != 127 {
+			firstBytes = append(firstBytes, f)
+		}
+	}
+	starts := make(map[uint32]bool)
+	for range 1 + rng.IntN(300) {
+		start := firstBytes[rng.IntN(len(firstBytes))] << 24
+		if rng.IntN(8) > 0 { // Some windows start at their first address
+			start |= rng.Uint32N(1 << 24)
+		}
+		starts[start] = true
+	}
+	for _, start := range slices.Sorted(maps.Keys(starts)) {
+		b := dbwriter.Block{Start: start}
+		switch r := rng.IntN(10); {
+		case r < 2: // Not found
+		case r < 5 || typ == dbwriter.TypeCountry:
+			b.Country = db.Countries[rng.IntN(len(db.Countries))]
+		default:
+			b.City = db.Cities[rng.IntN(len(db.Cities))]
+		}
+		db.Blocks = append(db.Blocks, b)
+	}
+	return db
+}
+
+// TestRoundTripHeader writes a City database with another format version,
+// 4-byte IDs and custom pack formats, and checks that sxgo reports the
+// header as written and reads the records through the formats.
+func TestRoundTripHeader(t *testing.T) {
+	de := &dbwriter.Country{ID: 56, ISO: "DE", Lat: 51.5, Lon: 10.5, NameEN: "Germany"}
+	by := &dbwriter.Region{ID: 2951839, Country: de, ISO: "DE-BY", NameEN: "Bavaria"}
+	munich := &dbwriter.City{ID: 2867714, Region: by, CountryID: 56, Lat: 48.13743, Lon: 11.57549, NameEN: "Munich",
+		Extra: map[string]any{"population": 1488202}}
+	db := &dbwriter.Database{
+		Version:       21,
+		Type:          dbwriter.TypeCity,
+		Charset:       dbwriter.CharsetUTF8,
+		Created:       time.Unix(1700000000, 0),
+		IDLen:         4,
+		CountryFormat: "c2:iso/T:id/d:lat/d:lon/b:name_en",
+		RegionFormat:  "I:id/I:country_seek/c5:iso/b:name_en",
+		CityFormat:    "I:id/I:region_seek/T:country_id/d:lat/d:lon/b:name_en/I:population",
+		Countries:     []*dbwriter.Country{de},
+		Regions:       []*dbwriter.Region{by},
+		Cities:        []*dbwriter.City{munich},
+		Blocks:        []dbwriter.Block{{Start: 5 << 24, City: munich}},
+	}
+	data, err := db.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "custom.dat")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			geo, err := sxgo.New(path, m.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer geo.Close()
+			info := geo.Info()
+			if info.Version != 21 || info.IDLength != 4 || !slices.Equal(info.PackFormats, []string{db.CountryFormat, db.RegionFormat, db.CityFormat}) {
+				t.Errorf("Info() = version %d, ID length %d, formats %q", info.Version, info.IDLength, info.PackFormats)
+			}
+			loc, err := geo.GetCityFull("5.1.2.3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := roundTripCity{city: 2867714, cityEN: "Munich", lat: 48.13743, lon: 11.57549, region: "DE-BY", country: 56, countryISO: "DE", countryEN: "Germany"}
+			if got := cityOf(loc); got != want {
+				t.Errorf("GetCityFull = %+v, want %+v", got, want)
+			}
+			php, err := geo.GetCityPHP("5.1.2.3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if city, _ := php.Get("city").(sxgo.PHPArray); city == nil || fmt.Sprint(city.Get("population")) != "1488202" {
+				t.Errorf("GetCityPHP city = %v, want population 1488202", php.Get("city"))
+			}
+		})
+	}
+}
+
+func TestRoundTripFieldOverflow(t *testing.T) {
+	db := &dbwriter.Database{
+		Type:    dbwriter.TypeCity,
+		Regions: []*dbwriter.Region{{ID: 1 << 24, ISO: "DE-BY"}}, // M:id holds 3 bytes
+		Blocks:  []dbwriter.Block{{Start: 5 << 24}},
+	}
+	if _, err := db.Encode(); err == nil {
+		t.Error("Encode succeeded for a region ID past its field")
+	}
+	db = &dbwriter.Database{Type: dbwriter.TypeCity, CityFormat: "X:id", Blocks: db.Blocks}
+	if _, err := db.Encode(); err == nil {
+		t.Error("Encode succeeded for an invalid city format")
+	}
+}
@@ -0,0 +1,64 @@
+package sxgo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+)
+
+// RangeHash returns a stable 64-bit hash of the database range ip resolves
+// to: its bounds (clipped to the address's /8) and the stored ID. All
+// addresses of a range share the hash, and services using the same database
+// version (see Fingerprint) compute the same value, so it can key consistent
+// per-range sampling or sharding. Patched ranges (ApplyPatch) are honoured;
+// synthetic locations are not.
+func (s *SxGeo) RangeHash(ip string) (uint64, error) {
+	db := s.acquire()
+	defer db.unpin()
+	ipNum, _, err := s.parseIP(ip)
+	if err != nil {
+		return 0, fmt.Errorf("sxgo: failed to hash range of IP %s: %w", ip, err)
+	}
+	r, _, err := s.rangeOf(db, ipNum)
+	if err != nil {
+		return 0, fmt.Errorf("sxgo: failed to hash range of IP %s: %w", ip, err)
+	}
+	return hashRange(r), nil
+}
+
+// InSample reports whether the range of ip is in a sample of the given share
+// of ranges (0..1, e.g. 0.01 for 1%), deciding by RangeHash. Samples are
+// nested: a range sampled at a rate is sampled at every higher rate.
+func (s *SxGeo) InSample(ip string, rate float64) (bool, error) {
+	h, err := s.RangeHash(ip)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case rate <= 0:
+		return false, nil
+	case rate >= 1:
+		return true, nil
+	}
+	return float64(h>>11) < rate*(1<<53), nil // Top 53 bits, exact in a float64
+}
+
+// hashRange hashes the bounds and ID of r with FNV-1a, then mixes the result
+// so every bit is evenly distributed.
+// Internal function.
+func hashRange(r ipRange) uint64 {
+	var b [12]byte
+	binary.BigEndian.PutUint32(b[0:], r.first)
+	binary.BigEndian.PutUint32(b[4:], r.last)
+	binary.BigEndian.PutUint32(b[8:], r.id)
+	h := fnv.New64a()
+	h.Write(b[:])
+	x := h.Sum64()
+	// splitmix64 finalizer
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
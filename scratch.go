@@ -0,0 +1,93 @@
+package sxgo
+
+import "sync"
+
+// scratchBuffers pools the buffers ModeFile lookups read blocks and records
+// into (see WithScratchBuffers). Reads larger than a pooled buffer, e.g. of
+// a whole first-byte window when the main index is not used, allocate as
+// before.
+// This struct is internal.
+type scratchBuffers struct {
+	blocks    sync.Pool // *[]byte of blockLen bytes
+	records   sync.Pool // *[]byte of recordLen bytes
+	blockLen  int       // One main index partition, or a window MainIndexAuto reads whole
+	recordLen int       // The largest country, region or city record
+}
+
+// WithScratchBuffers makes ModeFile lookups read block partitions and
+// records into pooled buffers instead of allocating them on every lookup,
+// removing the main allocations of the file-mode lookup path. The pools are
+// safe for concurrent use and sized from the database header: one partition
+// of the main index (or 4 KiB, if larger) for blocks, the largest record for
+// records. It has no effect in ModeMemory, which reads the loaded data in
+// place.
+func WithScratchBuffers() Option {
+	return func(s *SxGeo) {
+		s.scratch = true
+	}
+}
+
+// newScratchBuffers returns the buffer pools sized for db.
+// Internal function.
+func newScratchBuffers(db *dbState) *scratchBuffers {
+	h := db.header
+	return &scratchBuffers{
+		blockLen:  max(int(db.blockSize)*int(h.rangeBlocks), mainIndexScanBytes),
+		recordLen: int(max(h.maxCity, h.maxRegion, h.maxCountry)),
+	}
+}
+
+// getBlocks returns a pooled buffer for n bytes of blocks, or nil if buffers
+// are not pooled or n exceeds the pooled size. Pass it to putBlocks when done.
+// Internal function.
+func (b *scratchBuffers) getBlocks(n int) *[]byte {
+	if b == nil || n > b.blockLen {
+		return nil
+	}
+	return scratchGet(&b.blocks, b.blockLen)
+}
+
+// getRecord is getBlocks for a record of n bytes.
+// Internal function.
+func (b *scratchBuffers) getRecord(n int) *[]byte {
+	if b == nil || n > b.recordLen {
+		return nil
+	}
+	return scratchGet(&b.records, b.recordLen)
+}
+
+// putBlocks returns a buffer from getBlocks to its pool; nil is ignored.
+// Internal function.
+func (b *scratchBuffers) putBlocks(buf *[]byte) {
+	if buf != nil {
+		b.blocks.Put(buf)
+	}
+}
+
+// putRecord returns a buffer from getRecord to its pool; nil is ignored.
+// Internal function.
+func (b *scratchBuffers) putRecord(buf *[]byte) {
+	if buf != nil {
+		b.records.Put(buf)
+	}
+}
+
+// scratchGet takes a buffer of size bytes from pool, allocating one if it is
+// empty.
+// Internal function.
+func scratchGet(pool *sync.Pool, size int) *[]byte {
+	if buf, ok := pool.Get().(*[]byte); ok {
+		return buf
+	}
+	buf := make([]byte, size)
+	return &buf
+}
+
+// scratchBytes returns the buffer buf points to, or nil.
+// Internal function.
+func scratchBytes(buf *[]byte) []byte {
+	if buf == nil {
+		return nil
+	}
+	return *buf
+}
@@ -0,0 +1,120 @@
+package sxgo
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Sources of the country in a ScreeningEvent.
+const (
+	SourceDatabase  = "database"  // A range of the DB block table.
+	SourcePatch     = "patch"     // A range installed by ApplyPatch.
+	SourceSynthetic = "synthetic" // A synthetic location (WithSyntheticLocations).
+	SourceReserved  = "reserved"  // A reserved first byte (0, 10, 127, ...); never resolved.
+)
+
+// ScreeningEvent is the audit record of one IsRestricted check.
+type ScreeningEvent struct {
+	Time       time.Time  `json:"time"`
+	IP         netip.Addr `json:"ip"`         // IPv4 address checked (after IPv6 derivation).
+	Country    string     `json:"country"`    // ISO code the address resolved to; "" if unknown.
+	Restricted bool       `json:"restricted"` // Whether Country is on the screening list.
+
+	// Range is the range the address matched: the evidence the decision
+	// rests on. Database ranges are clipped to the address's /8.
+	Range  IPRange `json:"range"`
+	Source string  `json:"source"` // SourceDatabase, SourcePatch, SourceSynthetic or SourceReserved.
+	Block  int64   `json:"block"`  // DB block of the range; -1 if not from the block table.
+}
+
+// WithRestrictedCountries adds ISO 3166-1 alpha-2 codes (case-insensitive) to
+// the screening list used by IsRestricted. The library ships no default list:
+// which countries are restricted is a legal decision for the caller.
+func WithRestrictedCountries(isos ...string) Option {
+	return func(s *SxGeo) {
+		if s.restricted == nil {
+			s.restricted = make(map[string]bool, len(isos))
+		}
+		for _, iso := range isos {
+			s.restricted[strings.ToUpper(iso)] = true
+		}
+	}
+}
+
+// WithScreeningAudit registers fn to receive a ScreeningEvent for every
+// IsRestricted check that resolves an address, restricted or not. It runs
+// synchronously and must be safe for concurrent use. Logging Fingerprint
+// once alongside the events identifies the database version they refer to.
+func WithScreeningAudit(fn func(ScreeningEvent)) Option {
+	return func(s *SxGeo) {
+		if fn != nil {
+			s.screeningAudit = append(s.screeningAudit, fn)
+		}
+	}
+}
+
+// IsRestricted reports whether the country of ip is on the screening list
+// (see WithRestrictedCountries). Addresses of unknown country are not
+// restricted; the audit event records them with an empty Country, so callers
+// can apply their own policy. Only IPv4 addresses are supported, as for the
+// other lookups; geolocation is one screening signal and is easily evaded by
+// VPNs and proxies.
+func (s *SxGeo) IsRestricted(ip string) (bool, error) {
+	ev, err := s.screen(ip)
+	if err != nil {
+		return false, fmt.Errorf("sxgo: failed to screen IP %s: %w", ip, err)
+	}
+	for _, fn := range s.screeningAudit {
+		fn(ev)
+	}
+	return ev.Restricted, nil
+}
+
+// screen resolves ip the way GetCountry does and builds its audit event.
+// Internal function.
+func (s *SxGeo) screen(ip string) (ScreeningEvent, error) {
+	db := s.acquire()
+	defer db.unpin()
+	ipNum, _, err := s.parseIP(ip)
+	if err != nil {
+		return ScreeningEvent{}, err
+	}
+	ev := ScreeningEvent{Time: time.Now(), IP: uint32ToAddr(ipNum), Block: -1}
+
+	if e, ok := s.syntheticMatch(ip); ok {
+		if e.loc.Country != nil {
+			ev.Country = e.loc.Country.ISO
+		}
+		lo, hi, _ := prefixBounds(e.prefix)
+		ev.Range = IPRange{First: uint32ToAddr(lo), Last: uint32ToAddr(hi)}
+		ev.Source = SourceSynthetic
+		ev.Restricted = s.restricted[ev.Country]
+		return ev, nil
+	}
+
+	r, patched, err := s.rangeOf(db, ipNum)
+	if err != nil {
+		return ScreeningEvent{}, err
+	}
+	ev.Range = IPRange{First: uint32ToAddr(r.first), Last: uint32ToAddr(r.last)}
+	ev.Block = r.block
+	switch {
+	case db.isReservedByte(ipNum>>24) || s.isReservedSpecial(ipNum):
+		ev.Source = SourceReserved
+		return ev, nil
+	case patched:
+		ev.Source = SourcePatch
+	default:
+		ev.Source = SourceDatabase
+	}
+
+	countryID, _, err := s.resolveNum(db, r.id)
+	if err != nil {
+		return ScreeningEvent{}, err
+	}
+	ev.Country = getISO(countryID)
+	ev.Restricted = s.restricted[ev.Country]
+	return ev, nil
+}
@@ -1,492 +1,345 @@
 package sxgo
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
+	"net/netip"
+	"sort"
+	"time"
 )
 
-// Special error for reserved ranges, treated internally as "not found".
-var errReservedRange = errors.New("IP address is in a reserved or local range")
+// SearchFunc finds the smallest i in [0, n) for which key(i) > target, or n if
+// there is none. Keys are sorted in ascending order. Both the main index and
+// the DB blocks are searched through it, so an alternative strategy (e.g.
+// interpolation search over the key values) only has to be written once.
+type SearchFunc func(n int, key func(i int) uint32, target uint32) int
 
-// getNum finds the internal ID (for country DB) or seek position (for city DB)
-// for a given IP address.
-// Returns 0 and potentially errReservedRange if IP is local/reserved.
-// Returns 0 and other error for invalid IP format or DB read issues.
-// Internal function.
-func (s *SxGeo) getNum(ipStr string) (uint32, error) {
-	ipNum, ok := ip2long(ipStr)
-	if !ok {
-		return 0, fmt.Errorf("invalid IPv4 address: %q", ipStr)
-	}
-
-	ipBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(ipBytes, ipNum)
-	ip1 := uint32(ipBytes[0]) // First byte
-
-	// --- First Byte Index Lookup ---
-	// Handle reserved/local ranges (similar to original PHP logic)
-	// Check 0.x.x.x, 10.x.x.x, 127.x.x.x
-	// Also check if ip1 is beyond the bounds of the byte index.
-	byteIndexLen := uint32(s.header.byteIndexLen)
-	if ip1 == 0 || ip1 == 10 || ip1 == 127 || ip1 >= byteIndexLen {
-		// Return a specific error that callers can check if needed,
-		// otherwise treat as "not found" (return 0, nil in public methods).
-		return 0, errReservedRange
-	}
-
-	// Find block range using the first byte index
-	var minBlock, maxBlock uint32
-	useParsedIndexes := s.batchMode || s.memoryMode
-
-	if useParsedIndexes { // Use pre-parsed array
-		// Bounds check: ip1 is >= 1 and < byteIndexLen here
-		minBlock = s.byteIndexArr[ip1-1] // Index for previous byte determines start
-		maxBlock = s.byteIndexArr[ip1]   // Index for this byte determines end
-	} else { // Use raw byte string
-		// Bounds already checked conceptually (ip1 < byteIndexLen)
-		// ip1 > 0 enforced by initial check
-		minOffset := (ip1 - 1) * 4
-		maxOffset := ip1 * 4
-		minBlock = binary.BigEndian.Uint32(s.byteIndexStr[minOffset : minOffset+4])
-		maxBlock = binary.BigEndian.Uint32(s.byteIndexStr[maxOffset : maxOffset+4])
-	}
-
-	// --- Main Index Search (if range is large) ---
-	var searchMin, searchMax uint32
-	rangeBlocks := uint32(s.header.rangeBlocks) // Cast to uint32 for calculations
-
-	if maxBlock-minBlock > rangeBlocks {
-		// Range is large, use main index to narrow down
-		if rangeBlocks == 0 {
-			// Should be caught by header validation, but safeguard
-			return 0, errors.New("database header range is zero, cannot search main index")
-		}
+// BinarySearch is the default SearchFunc.
+func BinarySearch(n int, key func(i int) uint32, target uint32) int {
+	return sort.Search(n, func(i int) bool { return key(i) > target })
+}
 
-		// Calculate range within the main index array/string
-		// Note: PHP logic used floor(block / range). Integer division achieves this.
-		mainIdxMin := minBlock / rangeBlocks
-		// PHP: ceil(maxBlock / range) - 1 => floor((maxBlock-1) / range) ?
-		// Let's try (maxBlock - 1) / rangeBlocks which seems equivalent for positive numbers
-		mainIdxMax := (maxBlock - 1) / rangeBlocks
+// SearchStage identifies a step of a lookup reported to a search hook.
+type SearchStage int
 
-		// Ensure mainIdxMax is not less than mainIdxMin
-		if mainIdxMax < mainIdxMin {
-			mainIdxMax = mainIdxMin // Can happen if maxBlock is just inside the next range
-		}
+const (
+	// StageMainIndex is the main index search narrowing a large first-byte
+	// window to one partition of blocks.
+	StageMainIndex SearchStage = iota
+	// StageBlocks is the search over the DB blocks themselves.
+	StageBlocks
+)
 
-		// Search the main index to find the relevant partition
-		part := s.searchIdx(ipBytes, mainIdxMin, mainIdxMax) // `part` is the index in mainIndexArr/Str
-
-		// Calculate the search range in the main DB based on the main index result
-		if part == 0 {
-			searchMin = 0 // Should start from the beginning of the section? Or minBlock? Let's use minBlock.
-			searchMin = minBlock
-		} else {
-			// The `part` index points to the *start* of the block range.
-			// The search should start from the block indicated by `part`'s value * range.
-			// Let's re-evaluate PHP logic. `search_idx` returns the index `min` where `ip > main_index[min-1]` and `ip <= main_index[min]`
-			// It seems `part` corresponds to the block *containing* or *just after* the IP.
-			// search_min = $this->main_index[$part-1] * $this->header['range'] ??? No, that uses the IP value.
-			// PHP sets: $min = $part ? $this->main_index[$part-1] : 0;
-			// $max = $this->main_index[$part];
-			// Wait, PHP searchIdx returns index `i` such that `ip >= block[i]`
-			// And searchDb uses `min = part * range`, `max = (part+1) * range`
-			// Let's stick to that:
-			searchMin = part * rangeBlocks
-		}
+// String returns the name of the stage.
+func (st SearchStage) String() string {
+	switch st {
+	case StageMainIndex:
+		return "main_index"
+	case StageBlocks:
+		return "blocks"
+	}
+	return fmt.Sprintf("SearchStage(%d)", int(st))
+}
 
-		// Max range: Use part+1, clamped by total DB items
-		if part >= uint32(s.header.mainIndexLen) { // If part points past the end of main index
-			searchMax = s.header.dbItems
-		} else {
-			searchMax = (part + 1) * rangeBlocks
-		}
+// SearchEvent describes one search step of a lookup.
+type SearchEvent struct {
+	Stage SearchStage
+	IP    netip.Addr // Address being looked up.
 
-		// Ensure search range stays within the initial bounds derived from the first byte index
-		if searchMin < minBlock {
-			searchMin = minBlock
-		}
-		if searchMax > maxBlock {
-			searchMax = maxBlock
-		}
+	// From and To are the entries searched: main index entries for
+	// StageMainIndex, DB block numbers for StageBlocks ([From, To)).
+	From, To uint32
+	// Result is the first entry whose key is above the address. For
+	// StageBlocks the address resolves to block Result-1.
+	Result uint32
+
+	Probes  int           // Keys compared by the SearchFunc.
+	Elapsed time.Duration // Time spent in the SearchFunc (excluding reads).
+}
 
-	} else {
-		// Range is small enough, search directly within the first byte's block range
-		searchMin = minBlock
-		searchMax = maxBlock
-	}
-
-	// --- Final DB Block Search ---
-
-	// Final checks on search range before searching DB blocks
-	if searchMin >= searchMax {
-		// This can happen in edge cases. If searchMin points to a valid block, search just that one.
-		// Or if the IP is smaller than the first block in the range.
-		// PHP's `search_db` handles `min == max` by returning the ID of `db[min-1]`
-		// Let's try searching the block `searchMin-1` if `searchMin > 0`, otherwise fail.
-		// However, `searchDb` function expects a range `min` to `max` (exclusive?).
-		// If min==max, it implies the IP might match the block *before* min.
-		// Let's adjust searchDb to handle this or return error here.
-		// For now, let's ensure `max` is at least `min + 1` if `min` is valid.
-		if searchMin < s.header.dbItems {
-			searchMax = searchMin + 1 // Search the single block at searchMin
-		} else {
-			// searchMin is already out of bounds.
-			// This might indicate the IP is larger than any in the DB.
-			// The searchDb logic might handle this by returning the last ID.
-			// Let's try searching the last valid block if searchMin points beyond the end.
-			if s.header.dbItems > 0 {
-				searchMin = s.header.dbItems - 1
-				searchMax = s.header.dbItems // searchDb range is [min, max)
-			} else {
-				return 0, fmt.Errorf("search range invalid (searchMin %d >= searchMax %d) and DB is empty", searchMin, searchMax)
-			}
-			// return 0, fmt.Errorf("search range invalid (searchMin %d >= searchMax %d)", searchMin, searchMax)
+// WithSearchFunc replaces the binary search used over the main index and the
+// DB blocks. A nil fn is ignored.
+func WithSearchFunc(fn SearchFunc) Option {
+	return func(s *SxGeo) {
+		if fn != nil {
+			s.searchFunc = fn
 		}
 	}
-	// Ensure searchMax does not exceed total items
-	if searchMax > s.header.dbItems {
-		searchMax = s.header.dbItems
-	}
+}
 
-	// Perform the search within the final DB block range
-	var dbPartToSearch []byte
-	var searchOffset uint32 // Relative offset for searchDb if reading a partial block
+// MainIndexPolicy selects when lookups use the main index to narrow a
+// first-byte window before searching its blocks (see WithMainIndexPolicy).
+type MainIndexPolicy int
+
+const (
+	// MainIndexAlways narrows every window larger than one main index
+	// partition. This is the default.
+	MainIndexAlways MainIndexPolicy = iota
+	// MainIndexNever searches the blocks of the whole window.
+	MainIndexNever
+	// MainIndexAuto narrows a window only in ModeFile and only when reading
+	// all of its blocks would take more than mainIndexScanBytes. In
+	// ModeMemory narrowing saves no comparisons, so it is skipped.
+	MainIndexAuto
+)
 
-	if s.memoryMode {
-		if s.dbData == nil {
-			return 0, errors.New("cannot search: dbData not loaded in memory mode")
-		}
-		// Provide the relevant slice of the full dbData
-		startByte := int64(searchMin) * int64(s.blockSize)
-		// Ensure endByte doesn't exceed available data
-		endByte := int64(searchMax) * int64(s.blockSize)
-		if endByte > int64(len(s.dbData)) {
-			endByte = int64(len(s.dbData))
-		}
-		// Handle case where startByte might be >= endByte (e.g., searching beyond end)
-		if startByte >= endByte {
-			if s.header.dbItems > 0 {
-				// Try to return the ID of the very last block
-				lastBlockStart := int64(s.header.dbItems-1) * int64(s.blockSize)
-				idOffset := lastBlockStart + int64(dbBlockLenOffset)
-				if idOffset+int64(s.header.idLen) <= int64(len(s.dbData)) {
-					return s.decodeID(s.dbData[idOffset : idOffset+int64(s.header.idLen)])
-				}
-			}
-			return 0, fmt.Errorf("invalid memory search range calculated: start %d >= end %d", startByte, endByte)
-		}
+// mainIndexScanBytes is the largest window MainIndexAuto reads whole in
+// ModeFile: one page.
+const mainIndexScanBytes = 4096
 
-		dbPartToSearch = s.dbData[startByte:endByte]
-		searchOffset = 0 // Search relative to the start of this slice
-
-	} else { // File mode: read the relevant part of the DB file
-		readCount := searchMax - searchMin
-		if readCount == 0 {
-			// This case should ideally be handled by the searchMin >= searchMax logic above.
-			// If we reach here, something is inconsistent.
-			return 0, errors.New("calculated file search range has zero items unexpectedly")
-			// Try reading the block *before* searchMin?
-			// if searchMin > 0 {
-			// 	searchMin--
-			// 	readCount = 1
-			// } else {
-			// 	return 0, errors.New("calculated file search range has zero items at start")
-			// }
-		}
+// WithMainIndexPolicy selects when lookups use the main index. Databases with
+// a tiny partition size (header rangeBlocks) gain little from it and pay for
+// a second search; MainIndexNever or MainIndexAuto skip it.
+func WithMainIndexPolicy(p MainIndexPolicy) Option {
+	return func(s *SxGeo) {
+		s.mainIndexPolicy = p
+	}
+}
 
-		readLen := int64(readCount) * int64(s.blockSize)
-		readOffset := s.dbBegin + int64(searchMin)*int64(s.blockSize)
+// useMainIndex reports whether a window of n blocks is narrowed with the main
+// index before its blocks are searched.
+// Internal function.
+func (s *SxGeo) useMainIndex(db *dbState, n uint32) bool {
+	if n <= uint32(db.header.rangeBlocks) || db.header.mainIndexLen == 0 {
+		return false // Fits in one partition already
+	}
+	switch s.mainIndexPolicy {
+	case MainIndexNever:
+		return false
+	case MainIndexAuto:
+		return !s.memoryMode && int64(n)*int64(db.blockSize) > mainIndexScanBytes
+	}
+	return true
+}
 
-		if s.f == nil {
-			return 0, errors.New("cannot read file: file handle is nil (must be in memory mode but dbData is missing?)")
+// WithSearchHook registers fn to be called after every search step of a
+// lookup, e.g. for tracing or to compare search strategies. It runs
+// synchronously on the lookup path and must be safe for concurrent use.
+// Lookups answered by synthetic locations or patches do not reach it.
+func WithSearchHook(fn func(SearchEvent)) Option {
+	return func(s *SxGeo) {
+		if fn != nil {
+			s.searchHooks = append(s.searchHooks, fn)
 		}
+	}
+}
 
-		dbPart := make([]byte, readLen)
-		n, err := s.f.ReadAt(dbPart, readOffset)
-
-		// Handle read errors, especially EOF
-		if err != nil && !errors.Is(err, io.EOF) {
-			// Real read error
-			return 0, fmt.Errorf("failed to read DB part at offset %d (len %d): %w", readOffset, readLen, err)
-		}
-		// If EOF occurred, or no error, proceed with the bytes read (n).
-		// It's okay if n < readLen, especially if reading the last blocks.
-		if n == 0 {
-			// Read 0 bytes. Offset might be beyond EOF, or readLen was 0.
-			// If we expected to read data (readLen > 0), this is an issue.
-			if readLen > 0 {
-				// Could indicate IP is larger than anything in DB. What's the correct ID? Last one?
-				// Let's try getting the last ID. Need to read the last block.
-				if s.header.dbItems > 0 {
-					lastBlockOffset := s.dbBegin + int64(s.header.dbItems-1)*int64(s.blockSize)
-					lastBlockBytes := make([]byte, s.blockSize)
-					m, readErr := s.f.ReadAt(lastBlockBytes, lastBlockOffset)
-					if readErr == nil && m >= int(dbBlockLenOffset+s.header.idLen) {
-						return s.decodeID(lastBlockBytes[dbBlockLenOffset : dbBlockLenOffset+s.header.idLen])
-					}
-				}
-				// Fallback error if getting last ID failed or DB empty
-				return 0, fmt.Errorf("read 0 bytes at offset %d (EOF or bad range)", readOffset)
-			}
-			// If readLen was 0, then maybe okay, searchDb should handle empty input.
-		}
-		dbPartToSearch = dbPart[:n] // Use only the bytes actually read
-		searchOffset = 0            // Search relative to the start of dbPartToSearch
+// getNum finds the internal ID (for country DB) or seek position (for city DB)
+// for a given IP address.
+// Returns 0 and potentially ErrReservedRange if IP is local/reserved.
+// Returns 0 and other error for invalid IP format or DB read issues.
+// Internal function.
+func (s *SxGeo) getNum(db *dbState, ipStr string) (uint32, error) {
+	ipNum, _, err := s.parseIP(ipStr)
+	if err != nil {
+		return 0, err
 	}
+	return s.lookupNum(db, ipNum)
+}
 
-	// Perform the binary search on the retrieved data slice
-	return s.searchDb(dbPartToSearch, ipBytes, searchOffset, uint32(len(dbPartToSearch)/int(s.blockSize)))
+// lookupNum is getNum for an already parsed IPv4 address.
+// It updates the instance statistics (see Stats).
+// Internal function.
+func (s *SxGeo) lookupNum(db *dbState, ipNum uint32) (uint32, error) {
+	r, err := s.lookupSpan(db, ipNum)
+	return r.id, err
 }
 
-// searchIdx performs binary search on the main index (array or raw bytes).
-// ipBytes: 4-byte IP representation.
-// min, max: index range within mainIndexArr/mainIndexStr to search.
-// Returns the index `p` such that the IP belongs in the DB block range defined by `p`.
+// lookupSpan is lookupNum returning the resolved ID in r.id, along with the
+// addresses around ipNum known to resolve the same way from the blocks read:
+// r.first..r.last is part of ipNum's range, possibly all of it. r.block is
+// the block the ID comes from, or -1 for patched ranges and empty windows.
 // Internal function.
-func (s *SxGeo) searchIdx(ipBytes []byte, min, max uint32) uint32 {
-	ipNumSearch := binary.BigEndian.Uint32(ipBytes) // Use full IP for comparison in main index
-	useParsedIndexes := s.batchMode || s.memoryMode
-	var currentMax uint32 // Store the actual upper bound used in search
-
-	if useParsedIndexes { // Use array
-		indexLen := uint32(len(s.mainIndexArr))
-		if indexLen == 0 {
-			return min // Or 0? Return min seems safer if range was [min, max)
-		}
-		// Clamp max to the actual bounds of the array
-		if max >= indexLen {
-			max = indexLen - 1
-		}
-		currentMax = max // Store the adjusted max
-		if min > currentMax {
-			// If initial range was invalid or clamped max < min, return min
-			return min
+func (s *SxGeo) lookupSpan(db *dbState, ipNum uint32) (ipRange, error) {
+	s.stats.lookups.Add(1)
+	if s.stats.slow == nil && s.telemetry == nil {
+		r, _, err := s.searchNum(db, ipNum)
+		return r, err
+	}
+	start := time.Now()
+	r, blocks, err := s.searchNum(db, ipNum)
+	d := time.Since(start)
+	if s.stats.slow != nil {
+		mode := "file"
+		switch {
+		case db.mapping != nil:
+			mode = "mmap"
+		case s.memoryMode:
+			mode = "memory"
 		}
-
-		for max-min > 8 { // Binary search phase
-			mid := min + (max-min)/2 // Avoid overflow
-			if ipNumSearch > s.mainIndexArr[mid] {
-				min = mid + 1 // Standard binary search logic
-			} else {
-				max = mid // Keep mid in the upper range
-			}
+		s.stats.slow.record(ipNum, d, blocks, mode)
+	}
+	if t := s.telemetry; t != nil {
+		t.CounterAdd(MetricLookups, 1)
+		t.ObserveDuration(MetricLookupDuration, d)
+		if err != nil && !errors.Is(err, ErrReservedRange) {
+			t.CounterAdd(MetricLookupErrors, 1)
 		}
+	}
+	return r, err
+}
 
-		// Linear scan for the last few elements
-		// Find first element >= ipNumSearch
-		for min <= currentMax { // Use clamped max
-			if ipNumSearch > s.mainIndexArr[min] {
-				min++
-			} else {
-				break // Found the first element >= ipNumSearch
-			}
-		}
-	} else { // Use raw bytes
-		indexLenBytes := uint32(len(s.mainIndexStr))
-		if indexLenBytes == 0 {
-			return min
-		}
-		indexLen := indexLenBytes / 4 // Number of uint32 entries
-		if indexLen == 0 {
-			return min
-		}
-		// Clamp max to the actual bounds of the index data
-		if max >= indexLen {
-			max = indexLen - 1
-		}
-		currentMax = max
-		if min > currentMax {
-			return min
-		}
+// searchNum resolves ipNum as lookupSpan does and also reports the number of
+// DB blocks searched. The address resolves to the last block starting at or
+// below it. Within a first-byte window that is a block of the window, or,
+// below the window's first block, the block preceding the window (see
+// walkRanges).
+// Internal function.
+func (s *SxGeo) searchNum(db *dbState, ipNum uint32) (r ipRange, blocks uint32, err error) {
+	// Handle reserved/local ranges (similar to original PHP logic):
+	// 0.x.x.x, 10.x.x.x, 127.x.x.x and first bytes beyond the byte index,
+	// and the ranges configured with WithReservedRanges.
+	ip1 := ipNum >> 24
+	if db.isReservedByte(ip1) || s.isReservedSpecial(ipNum) {
+		// Return a specific error that callers can check if needed,
+		// otherwise treat as "not found" (return 0, nil in public methods).
+		return ipRange{}, 0, ErrReservedRange
+	}
 
-		for max-min > 8 { // Binary search phase
-			mid := min + (max-min)/2
-			offset := mid * 4
-			// Bounds check should be implicitly handled by max clamping, but double check theory
-			// If mid can reach currentMax, offset is max*4. Need offset+4 <= indexLenBytes
-			// max*4+4 <= indexLenBytes => (indexLen-1)*4+4 <= indexLenBytes => indexLen*4 <= indexLenBytes. Correct.
-			idxVal := binary.BigEndian.Uint32(s.mainIndexStr[offset : offset+4])
-			if ipNumSearch > idxVal {
-				min = mid + 1
-			} else {
-				max = mid
-			}
-		}
+	// Ranges installed by ApplyPatch take precedence over the block table
+	if p, ok := s.overlayRange(ipNum); ok {
+		return ipRange{first: p.first, last: p.last, id: p.id, block: -1}, 0, nil
+	}
 
-		// Linear scan phase
-		for min <= currentMax { // Use clamped max
-			offset := min * 4
-			// Check offset before reading (though clamping should prevent exceeding len)
-			if offset+4 > indexLenBytes {
-				break // Should not happen if logic is correct
-			}
-			idxVal := binary.BigEndian.Uint32(s.mainIndexStr[offset : offset+4])
-			if ipNumSearch > idxVal {
-				min++
-			} else {
-				break // Found first element >= ipNumSearch
-			}
-		}
+	// The first byte index gives the window of blocks for this first byte
+	minBlock, maxBlock := db.byteIndexAt(ip1-1), db.byteIndexAt(ip1)
+	if maxBlock > db.header.dbItems {
+		maxBlock = db.header.dbItems
+	}
+	if minBlock >= maxBlock {
+		// No blocks for this first byte
+		return ipRange{first: ipNum &^ 0xFFFFFF, last: ipNum | 0xFFFFFF, block: -1}, 0, nil
 	}
 
-	// `min` now points to the first index `p` in the main index where `mainIndex[p] >= ipNumSearch`.
-	// The PHP logic seems to relate this `min` value directly to the range calculation in getNum:
-	// `$p = $this->search_idx($ipn, 0, count($this->m_idx) - 1);`
-	// `$min = $p ? $this->m_idx[$p-1] : 0;` // Uses value at index p-1?
-	// `$max = $this->m_idx[$p];` // Uses value at index p?
-	// No, later PHP uses: `$min_s = $part * $this->header['range']`
-	// So, the *index* `min` found here is the `part` needed.
-	return min
+	lo, hi := minBlock, maxBlock
+	if s.useMainIndex(db, hi-lo) {
+		lo, hi = s.narrowBlocks(db, ipNum, minBlock, maxBlock)
+	}
+	r, err = s.searchBlocks(db, ipNum, lo, hi, lo == minBlock, hi == maxBlock)
+	return r, hi - lo, err
 }
 
-// searchDb performs binary search within a slice of DB blocks (`data`).
-// data: byte slice containing the DB blocks for the relevant range.
-// ipBytes: 4-byte representation of the IP to search for.
-// min, max: *relative* block indices within the `data` slice to search [min, max).
-// Returns the location ID (seek for city, ID for country) found for the IP.
+// narrowBlocks uses the main index to narrow the block window [minBlock, maxBlock)
+// to the partition of rangeBlocks blocks holding ipNum.
 // Internal function.
-func (s *SxGeo) searchDb(data []byte, ipBytes []byte, min, max uint32) (uint32, error) {
-	// Use only the last 3 bytes of the IP for comparison within DB blocks
-	ipSuffix := ipBytes[1:]
-	blockSize := s.blockSize // Local copy for convenience
-	dataLen := uint32(len(data))
-
-	// Basic validation: need enough data for at least one block comparison?
-	// Allow empty data? If data is empty, max should be 0.
-	if dataLen == 0 {
-		if max > min { // If the range expected data, return error
-			return 0, errors.New("searchDb called with empty data but non-empty range")
-		}
-		// If range was also empty [0, 0), maybe return 0 ID?
-		return 0, nil // No data, no ID found
+func (s *SxGeo) narrowBlocks(db *dbState, ipNum, minBlock, maxBlock uint32) (lo, hi uint32) {
+	rangeBlocks := uint32(db.header.rangeBlocks)
+	first, last := minBlock/rangeBlocks, (maxBlock-1)/rangeBlocks
+	if n := uint32(db.header.mainIndexLen); last >= n {
+		last = n - 1
 	}
-
-	// Ensure max is within the bounds of the actual data provided
-	numBlocksInData := dataLen / blockSize
-	if max > numBlocksInData {
-		max = numBlocksInData // Adjust max to the number of full blocks available
+	if first > last {
+		return minBlock, maxBlock
 	}
 
-	// Handle invalid range after adjustment (e.g., min >= max)
-	if min >= max {
-		// This means the search range is empty or invalid.
-		// PHP logic returns the ID from the block *before* `min`.
-		if min > 0 {
-			targetBlockIdx := int64(min - 1) // Index of the block before the invalid range start
-			idOffset := (targetBlockIdx * int64(blockSize)) + int64(dbBlockLenOffset)
-			idEndOffset := idOffset + int64(s.header.idLen)
-
-			// Check if this ID is within the bounds of the *original* data slice
-			if idOffset >= 0 && uint32(idEndOffset) <= dataLen {
-				return s.decodeID(data[idOffset:idEndOffset])
-			}
-		}
-		// If min was 0 or reading block min-1 failed, return "not found" (0) or error?
-		// Return 0 for not found seems consistent.
-		// return 0, fmt.Errorf("invalid search range in searchDb [min %d, max %d) after bounds check", min, max)
-		return 0, nil // Indicate not found within the provided data/range
-	}
-
-	// Store original min for edge case handling later
-	// origMin := min
-
-	// --- Binary Search Phase ---
-	currentMax := max // Use adjusted max for loop condition
-	for max-min > 8 {
-		mid := min + (max-min)/2 // Avoid overflow
-		blockOffset := int64(mid) * int64(blockSize)
-		// blockOffset + dbBlockLenOffset will be < dataLen because mid < max <= numBlocksInData
-
-		blockIP := data[blockOffset : blockOffset+int64(dbBlockLenOffset)] // Get 3 bytes IP part
-
-		// Compare ipSuffix with blockIP (byte by byte)
-		cmp := 0
-		for i := 0; i < dbBlockLenOffset; i++ {
-			if ipSuffix[i] > blockIP[i] {
-				cmp = 1
-				break
-			}
-			if ipSuffix[i] < blockIP[i] {
-				cmp = -1
-				break
-			}
-		}
+	// Entry p holds the first IP of the last block of partition p, so ipNum
+	// lies in the first partition whose entry is >= ipNum (ipNum > 0 here).
+	p := first + s.search(StageMainIndex, ipNum, first, last+1, db.mainIndexAt, ipNum-1)
 
-		if cmp > 0 { // ipSuffix > blockIP
-			min = mid + 1 // Standard binary search: exclude mid, search upper half
-		} else {
-			max = mid // ipSuffix <= blockIP: include mid, search lower half
-		}
+	lo, hi = max(p*rangeBlocks, minBlock), min((p+1)*rangeBlocks, maxBlock)
+	if p > last { // Past the indexed partitions; search the rest of the window
+		hi = maxBlock
 	}
+	return min(lo, hi), hi
+}
 
-	// --- Linear Scan Phase ---
-	// `min` is now the start of the small range to scan
-	// `max` (from binary search) is the end of the small range (exclusive?) - Let's use currentMax
-	// Scan from `min` up to `currentMax` (exclusive of currentMax)
-
-	// Find the first block where blockIP > ipSuffix
-	for min < currentMax { // Check blocks from index `min` up to `currentMax - 1`
-		blockOffset := int64(min) * int64(blockSize)
-		// blockOffset + dbBlockLenOffset < dataLen because min < currentMax <= numBlocksInData
-
-		blockIP := data[blockOffset : blockOffset+int64(dbBlockLenOffset)]
-
-		// Compare ipSuffix with blockIP
-		cmp := 0
-		for i := 0; i < dbBlockLenOffset; i++ {
-			if ipSuffix[i] > blockIP[i] {
-				cmp = 1
-				break
-			}
-			if ipSuffix[i] < blockIP[i] {
-				cmp = -1
-				break
-			}
-		}
+// searchBlocks returns the ID of the block ipNum resolves to, searching blocks
+// [lo, hi), and the span around ipNum known to resolve the same way (see
+// lookupSpan). When ipNum is below block lo, the ID of block lo-1 is returned.
+// windowStart and windowEnd tell whether lo and hi are the bounds of ipNum's
+// first-byte window, which then bound the span.
+// Internal function.
+func (s *SxGeo) searchBlocks(db *dbState, ipNum, lo, hi uint32, windowStart, windowEnd bool) (ipRange, error) {
+	r := ipRange{first: ipNum, last: ipNum, block: -1}
+	buf := db.scratch.getBlocks(int(hi-lo) * int(db.blockSize))
+	defer db.scratch.putBlocks(buf)
+	data, err := s.blockDataInto(db, lo, hi, scratchBytes(buf))
+	if err != nil {
+		return r, err
+	}
+	n := uint32(len(data)) / db.blockSize
+	if n == 0 && hi > lo {
+		return r, fmt.Errorf("blocks [%d, %d) could not be read", lo, hi)
+	}
 
-		if cmp < 0 { // ipSuffix < blockIP. We found the block *after* the one we need.
-			break
-		}
-		// if cmp >= 0 (ipSuffix >= blockIP), continue searching.
-		min++
+	// Blocks store the low 3 bytes of their first IP; all share ipNum's first byte
+	prefix := ipNum &^ 0xFFFFFF
+	key := func(i uint32) uint32 {
+		return prefix | blockSuffix(data, int(i-lo), db.blockSize)
+	}
+	i := s.search(StageBlocks, ipNum, lo, lo+n, key, ipNum)
+	if i < n {
+		r.last = key(lo+i) - 1
+	} else if windowEnd {
+		r.last = prefix | 0xFFFFFF
+	}
+	if i > 0 {
+		r.first, r.block = key(lo+i-1), int64(lo+i-1)
+		r.id, err = db.blockID(data, int(i-1))
+		return r, err
 	}
 
-	// `min` now points to the first block whose IP is strictly greater than the search IP suffix,
-	// OR it points to `currentMax` if the search IP was >= all blocks checked in the linear scan.
-	// The correct ID is in the block *before* this `min`.
-	targetBlockIdx := int64(min - 1)
+	// Below the first searched block: it belongs to the block before it
+	if windowStart {
+		r.first = prefix
+	}
+	if lo == 0 {
+		return r, nil
+	}
+	prev, err := s.blockDataInto(db, lo-1, lo, scratchBytes(buf)) // data is no longer used
+	if err != nil {
+		return r, err
+	}
+	if len(prev) == 0 {
+		return r, nil
+	}
+	r.block = int64(lo - 1)
+	r.id, err = db.blockID(prev, 0)
+	return r, err
+}
 
-	// Handle edge case: If `min` never advanced from `origMin`, it means the search IP
-	// was smaller than the IP of the very first block (`origMin`) in the search range.
-	// In this case, PHP's logic effectively returns the ID of the block *before* `origMin`.
-	// `targetBlockIdx` will be `origMin - 1`.
+// search runs the configured SearchFunc over entries [from, to) of key and
+// returns the offset from `from` of the first entry above target.
+// Steps are reported to the search hooks, if any.
+// Internal function.
+func (s *SxGeo) search(stage SearchStage, ipNum, from, to uint32, key func(i uint32) uint32, target uint32) uint32 {
+	n := int(to - from)
+	at := func(i int) uint32 { return key(from + uint32(i)) }
 
-	// Check if targetBlockIdx is valid (>= 0)
-	if targetBlockIdx < 0 {
-		// This implies the IP is smaller than the first block in the *entire searched segment*.
-		// This could happen if the range was [0, ...] and IP < block[0].
-		// Or if the range started mid-DB, and IP < block[origMin].
-		// What should we return? ID 0 (not found)?
-		return 0, nil // Consistent with "not found"
+	if len(s.searchHooks) == 0 {
+		return clampSearch(s.searchFunc(n, at, target), n)
 	}
 
-	// Calculate offset for the ID within the target block.
-	idOffset := (targetBlockIdx * int64(blockSize)) + int64(dbBlockLenOffset)
-	idEndOffset := idOffset + int64(s.header.idLen)
-
-	// Final boundary check on the calculated offsets against the input `data` slice length.
-	if uint32(idEndOffset) > dataLen {
-		// This could happen if targetBlockIdx points to the very last block, but the `data`
-		// slice was somehow truncated or didn't contain the full block.
-		// Or if targetBlockIdx calculation somehow went wrong.
-		// Try returning ID 0?
-		// return 0, fmt.Errorf("calculated ID offset %d-%d is out of data bounds %d (target block %d)", idOffset, idEndOffset, dataLen, targetBlockIdx)
-		return 0, nil // Indicate not found / data truncation issue
+	probes := 0
+	counted := func(i int) uint32 {
+		probes++
+		return at(i)
+	}
+	start := time.Now()
+	i := clampSearch(s.searchFunc(n, counted, target), n)
+	ev := SearchEvent{
+		Stage:   stage,
+		IP:      uint32ToAddr(ipNum),
+		From:    from,
+		To:      to,
+		Result:  from + i,
+		Probes:  probes,
+		Elapsed: time.Since(start),
 	}
+	for _, fn := range s.searchHooks {
+		fn(ev)
+	}
+	return i
+}
 
-	// Decode and return the ID
-	return s.decodeID(data[idOffset:idEndOffset])
+// clampSearch guards against a SearchFunc returning a position outside [0, n].
+// Internal function.
+func clampSearch(i, n int) uint32 {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return uint32(n)
+	}
+	return uint32(i)
 }
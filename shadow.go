@@ -0,0 +1,169 @@
+package sxgo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Disagreement is a lookup on which a ShadowResolver's primary and candidate
+// databases differ.
+type Disagreement struct {
+	Time      time.Time     `json:"time"`
+	IP        string        `json:"ip"`
+	Full      bool          `json:"full"`                // GetCityFull rather than GetCity.
+	Fields    []string      `json:"fields"`              // Levels that differ: "country", "region", "city".
+	Primary   *LocationInfo `json:"primary,omitempty"`   // Result served.
+	Candidate *LocationInfo `json:"candidate,omitempty"` // Result of the candidate database.
+	// Err is the candidate's lookup error, if any; Fields is then empty.
+	Err error `json:"-"`
+}
+
+// ShadowStats counts the work of a ShadowResolver.
+type ShadowStats struct {
+	Compared  uint64 `json:"compared"`  // Lookups repeated on the candidate.
+	Disagreed uint64 `json:"disagreed"` // Lookups reported to the sink.
+	Dropped   uint64 `json:"dropped"`   // Lookups not repeated because too many were in flight.
+}
+
+// ShadowResolver serves lookups from a primary database and repeats each one
+// asynchronously on a candidate database (e.g. next month's release),
+// reporting differences to a sink. It validates a new database on production
+// traffic before switching to it, without affecting the results served.
+// It is safe for concurrent use.
+type ShadowResolver struct {
+	primary, candidate *SxGeo
+	sink               func(Disagreement)
+	slots              chan struct{} // Bounds candidate lookups in flight
+	wg                 sync.WaitGroup
+
+	compared, disagreed, dropped atomic.Uint64
+}
+
+// DefaultShadowInFlight is the candidate lookups a ShadowResolver runs at once
+// when NewShadowResolver is given maxInFlight <= 0.
+const DefaultShadowInFlight = 64
+
+// NewShadowResolver returns a ShadowResolver serving from primary and
+// comparing with candidate. sink receives disagreements and candidate errors;
+// it runs on background goroutines and must be safe for concurrent use. At
+// most maxInFlight candidate lookups run at once; lookups beyond that are not
+// shadowed rather than slowing down the primary path.
+func NewShadowResolver(primary, candidate *SxGeo, sink func(Disagreement), maxInFlight int) *ShadowResolver {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultShadowInFlight
+	}
+	return &ShadowResolver{
+		primary:   primary,
+		candidate: candidate,
+		sink:      sink,
+		slots:     make(chan struct{}, maxInFlight),
+	}
+}
+
+// GetCity returns primary.GetCity(ip, opts...) and shadows the lookup.
+func (r *ShadowResolver) GetCity(ip string, opts ...CallOption) (*LocationInfo, error) {
+	info, err := r.primary.GetCity(ip, opts...)
+	if err == nil {
+		r.shadow(ip, false, info, opts)
+	}
+	return info, err
+}
+
+// GetCityFull returns primary.GetCityFull(ip, opts...) and shadows the lookup.
+func (r *ShadowResolver) GetCityFull(ip string, opts ...CallOption) (*LocationInfo, error) {
+	info, err := r.primary.GetCityFull(ip, opts...)
+	if err == nil {
+		r.shadow(ip, true, info, opts)
+	}
+	return info, err
+}
+
+// Wait blocks until all shadow lookups started so far have been compared.
+func (r *ShadowResolver) Wait() {
+	r.wg.Wait()
+}
+
+// Stats returns a snapshot of the resolver counters.
+func (r *ShadowResolver) Stats() ShadowStats {
+	return ShadowStats{
+		Compared:  r.compared.Load(),
+		Disagreed: r.disagreed.Load(),
+		Dropped:   r.dropped.Load(),
+	}
+}
+
+// shadow repeats a lookup on the candidate in the background, if a slot is free.
+// Internal function.
+func (r *ShadowResolver) shadow(ip string, full bool, served *LocationInfo, opts []CallOption) {
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		r.dropped.Add(1)
+		return
+	}
+	if served != nil {
+		served = served.clone() // The caller may modify the result it was given
+	}
+	r.wg.Add(1)
+	go func() {
+		defer func() { <-r.slots; r.wg.Done() }()
+		var cand *LocationInfo
+		var err error
+		if full {
+			cand, err = r.candidate.GetCityFull(ip, opts...)
+		} else {
+			cand, err = r.candidate.GetCity(ip, opts...)
+		}
+		r.compared.Add(1)
+
+		d := Disagreement{IP: ip, Full: full, Primary: served, Candidate: cand, Err: err}
+		if err == nil {
+			if d.Fields = diffLevels(served, cand); len(d.Fields) == 0 {
+				return
+			}
+		}
+		r.disagreed.Add(1)
+		if r.sink != nil {
+			d.Time = time.Now()
+			r.sink(d)
+		}
+	}()
+}
+
+// diffLevels returns the levels on which a and b identify different places:
+// country by ISO code, region and city by ID. A nil result has no levels.
+// Internal function.
+func diffLevels(a, b *LocationInfo) []string {
+	type levels struct {
+		country      string
+		region, city uint32
+	}
+	key := func(l *LocationInfo) (k levels) {
+		if l == nil {
+			return k
+		}
+		if l.Country != nil {
+			k.country = l.Country.ISO
+		}
+		if l.Region != nil {
+			k.region = l.Region.ID
+		}
+		if l.City != nil {
+			k.city = l.City.ID
+		}
+		return k
+	}
+	ka, kb := key(a), key(b)
+	var fields []string
+	if ka.country != kb.country {
+		fields = append(fields, "country")
+	}
+	if ka.region != kb.region {
+		fields = append(fields, "region")
+	}
+	if ka.city != kb.city {
+		fields = append(fields, "city")
+	}
+	return fields
+}
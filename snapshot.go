@@ -0,0 +1,230 @@
+package sxgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// snapshotMagic identifies snapshot files and their version.
+const snapshotMagic = "sxgo-snapshot-1"
+
+// snapshotFile is the gob-encoded content of a snapshot.
+// This struct is internal.
+type snapshotFile struct {
+	Magic                   string
+	Head                    []byte   // Header and pack formats, as in the database
+	ByteIndex, MainIndex    []uint32 // Parsed indexes
+	Blocks, Regions, Cities []byte
+	Countries               []byte // Separate country block; nil if stored in Cities
+	Fingerprint             [32]byte
+	Records                 []snapshotRecord // Decoded record cache, least recently used first
+}
+
+// snapshotRecord is a record cache entry in a snapshot.
+// This struct is internal.
+type snapshotRecord struct {
+	DataType int
+	Seek     uint32
+	Cost     int64
+	Fields   map[string]interface{}
+}
+
+// WriteSnapshot writes the loaded database, its parsed indexes and the
+// records held by the record cache (see WithRecordCache) to w, in a form
+// NewFromSnapshot loads without parsing. Snapshots suit large databases
+// whose cold start would otherwise be dominated by loading and warming up;
+// they are specific to this package version. In ModeFile the database
+// sections are read from the file. Country-only ModeMemory instances (see
+// WithCountryOnly) cannot write snapshots.
+func (s *SxGeo) WriteSnapshot(w io.Writer) error {
+	db := s.acquire()
+	defer db.unpin()
+	if db.countryTable != nil {
+		return fmt.Errorf("sxgo: cannot write snapshot: %w", ErrCountryOnly)
+	}
+	sum, err := s.fingerprintOf(db)
+	if err != nil {
+		return err
+	}
+	snap := snapshotFile{
+		Magic:       snapshotMagic,
+		Head:        db.rawHead,
+		ByteIndex:   db.byteIndexArr,
+		MainIndex:   db.mainIndexArr,
+		Fingerprint: sum,
+	}
+	if s.rawIndexes {
+		snap.ByteIndex, snap.MainIndex = decodeIndex(db.byteIndexStr), decodeIndex(db.mainIndexStr)
+	}
+
+	if s.memoryMode {
+		snap.Blocks, snap.Regions, snap.Cities = db.dbData, db.regionsData, db.citiesData
+		if db.separateCountries {
+			snap.Countries = db.countriesData
+		}
+	} else {
+		type section struct {
+			dst       *[]byte
+			off, size int64
+		}
+		sections := []section{
+			{&snap.Blocks, db.dbBegin, db.regionsBegin - db.dbBegin},
+			{&snap.Regions, db.regionsBegin, int64(db.header.regionSize)},
+			{&snap.Cities, db.citiesBegin, int64(db.header.citySize)},
+		}
+		if db.separateCountries {
+			sections = append(sections, section{&snap.Countries, db.countriesBegin, int64(db.header.countrySize)})
+		}
+		for _, sec := range sections {
+			if sec.size == 0 {
+				continue
+			}
+			buf := make([]byte, sec.size)
+			if _, err := s.readAt(db, buf, sec.off); err != nil {
+				return fmt.Errorf("sxgo: failed to read database for snapshot: %w", err)
+			}
+			*sec.dst = buf
+		}
+	}
+
+	if s.records != nil {
+		for _, e := range s.records.entries() {
+			if e.key.gen != db.gen {
+				continue // Read from a database Reload replaced
+			}
+			snap.Records = append(snap.Records, snapshotRecord{e.key.dataType, e.key.seek, e.cost, e.rec})
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := gob.NewEncoder(bw).Encode(&snap); err != nil {
+		return fmt.Errorf("sxgo: failed to write snapshot: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("sxgo: failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// NewFromSnapshot loads a snapshot written by WriteSnapshot into a ModeMemory
+// instance. opts apply as with New; with WithRecordCache, the cache starts
+// with the snapshot's records, as far as its budget allows.
+func NewFromSnapshot(path string, opts ...Option) (*SxGeo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("sxgo: failed to open snapshot %q: %w", path, err)
+	}
+	defer f.Close()
+	var snap snapshotFile
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&snap); err != nil || snap.Magic != snapshotMagic {
+		if err == nil {
+			err = errors.New("unknown format or version")
+		}
+		return nil, fmt.Errorf("sxgo: invalid snapshot %q: %w", path, err)
+	}
+
+	s := newSxGeo(ModeMemory, opts)
+	if err := s.loadSnapshot(&snap); err != nil {
+		return nil, fmt.Errorf("sxgo: invalid snapshot %q: %w", path, err)
+	}
+	if s.countryIndexed {
+		if err := s.buildCountryIndex(s.db()); err != nil {
+			return nil, fmt.Errorf("sxgo: failed to build country index from snapshot %q: %w", path, err)
+		}
+	}
+	if s.disk != nil {
+		if err := s.openDiskCache(); err != nil {
+			return nil, fmt.Errorf("sxgo: failed to open disk cache %q: %w", s.disk.path, err)
+		}
+	}
+	return s, nil
+}
+
+// loadSnapshot sets up s from a decoded snapshot, laying out the sections at
+// the offsets New computes for the database file.
+// Internal function.
+func (s *SxGeo) loadSnapshot(snap *snapshotFile) error {
+	if len(snap.Head) < dbHeaderLen {
+		return errors.New("header missing")
+	}
+	h, ok := parseHeader(snap.Head[:dbHeaderLen])
+	if !ok {
+		return errors.New("invalid header")
+	}
+	if len(snap.ByteIndex) != int(h.byteIndexLen) || len(snap.MainIndex) != int(h.mainIndexLen) ||
+		len(snap.Head) != dbHeaderLen+int(h.packSize) {
+		return errors.New("header does not match the indexes")
+	}
+	db := &dbState{header: h, rawHead: snap.Head, fingerprint: &fingerprintState{}, tables: &lazyTables{}}
+	db.blockSize = dbBlockLenOffset + uint32(h.idLen)
+	db.packFormats = []string{}
+	if h.packSize > 0 {
+		db.packFormats = strings.Split(strings.TrimRight(string(snap.Head[dbHeaderLen:]), "\x00"), "\x00")
+	}
+	if len(snap.Blocks) != int(h.dbItems*db.blockSize) || len(snap.Regions) != int(h.regionSize) ||
+		len(snap.Cities) != int(h.citySize) || (snap.Countries != nil && len(snap.Countries) != int(h.countrySize)) {
+		return errors.New("section sizes do not match the header")
+	}
+
+	db.byteIndexArr, db.mainIndexArr = snap.ByteIndex, snap.MainIndex
+	if s.rawIndexes {
+		db.byteIndexStr, db.mainIndexStr = encodeIndex(snap.ByteIndex), encodeIndex(snap.MainIndex)
+		db.byteIndexArr, db.mainIndexArr = nil, nil
+	}
+	db.dbData, db.regionsData, db.citiesData = snap.Blocks, snap.Regions, snap.Cities
+	db.countriesData = db.citiesData
+	if snap.Countries != nil {
+		db.countriesData = snap.Countries
+		db.separateCountries = true
+	}
+
+	db.dbBegin = int64(len(snap.Head)) + (int64(h.byteIndexLen)+int64(h.mainIndexLen))*4
+	db.regionsBegin = db.dbBegin + int64(len(snap.Blocks))
+	db.citiesBegin = db.regionsBegin + int64(len(snap.Regions))
+	db.countriesBegin = db.citiesBegin
+	db.end = db.citiesBegin + int64(len(snap.Cities))
+	if db.separateCountries {
+		db.countriesBegin = db.end
+		db.end += int64(len(snap.Countries))
+	}
+	db.layout = db.capabilities()
+	if err := db.validateIndexes(); err != nil {
+		return err
+	}
+	s.countLoad(db, 0, db.end)
+	db.fingerprint.sum, db.fingerprint.done = snap.Fingerprint, true
+	s.state.Store(db)
+
+	if s.records != nil {
+		for _, r := range snap.Records {
+			s.records.insert(recordKey{db.gen, r.DataType, r.Seek}, r.Fields, r.Cost)
+		}
+	}
+	return nil
+}
+
+// decodeIndex parses raw index bytes into entries.
+// Internal function.
+func decodeIndex(raw []byte) []uint32 {
+	out := make([]uint32, len(raw)/4)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint32(raw[i*4:])
+	}
+	return out
+}
+
+// encodeIndex is the inverse of decodeIndex.
+// Internal function.
+func encodeIndex(idx []uint32) []byte {
+	out := make([]byte, len(idx)*4)
+	for i, v := range idx {
+		binary.BigEndian.PutUint32(out[i*4:], v)
+	}
+	return out
+}
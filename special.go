@@ -0,0 +1,222 @@
+package sxgo
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Special labels an address range reserved for a purpose other than public
+// routing, which no database locates (see WithSpecialRanges, WithReservedRanges
+// and ClassifyIP).
+type Special string
+
+const (
+	// SpecialUnspecified is 0.0.0.0/8 ("this network") and ::.
+	SpecialUnspecified Special = "unspecified"
+	// SpecialLoopback is 127.0.0.0/8 and ::1.
+	SpecialLoopback Special = "loopback"
+	// SpecialPrivate is the RFC 1918 ranges and IPv6 unique local addresses.
+	SpecialPrivate Special = "private"
+	// SpecialCGNAT is the RFC 6598 shared address space, 100.64.0.0/10.
+	SpecialCGNAT Special = "cgnat"
+	// SpecialLinkLocal is 169.254.0.0/16 and fe80::/10.
+	SpecialLinkLocal Special = "link_local"
+	// SpecialDocumentation is the RFC 5737 TEST-NET ranges and 2001:db8::/32.
+	SpecialDocumentation Special = "documentation"
+	// SpecialBenchmarking is the RFC 2544 range, 198.18.0.0/15.
+	SpecialBenchmarking Special = "benchmarking"
+	// SpecialMulticast is 224.0.0.0/4 and ff00::/8.
+	SpecialMulticast Special = "multicast"
+	// SpecialBroadcast is 255.255.255.255.
+	SpecialBroadcast Special = "broadcast"
+	// SpecialReserved is the other IANA special-purpose ranges: 192.0.0.0/24
+	// and 240.0.0.0/4.
+	SpecialReserved Special = "reserved"
+)
+
+// specialPrefixes maps the ranges netip does not classify to their labels.
+var specialPrefixes = []struct {
+	prefix  netip.Prefix
+	special Special
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), SpecialUnspecified},
+	{netip.MustParsePrefix("100.64.0.0/10"), SpecialCGNAT},
+	{netip.MustParsePrefix("192.0.0.0/24"), SpecialReserved},
+	{netip.MustParsePrefix("192.0.2.0/24"), SpecialDocumentation},
+	{netip.MustParsePrefix("198.51.100.0/24"), SpecialDocumentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), SpecialDocumentation},
+	{netip.MustParsePrefix("2001:db8::/32"), SpecialDocumentation},
+	{netip.MustParsePrefix("198.18.0.0/15"), SpecialBenchmarking},
+	{netip.MustParsePrefix("255.255.255.255/32"), SpecialBroadcast},
+	{netip.MustParsePrefix("240.0.0.0/4"), SpecialReserved},
+}
+
+// netipPrefixes are the IPv4 ranges classifyAddr labels through netip.
+var netipPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+}
+
+// ClassifyIP returns the special-purpose range ip (IPv4 or IPv6) belongs to,
+// or "" for invalid and publicly routable addresses.
+func ClassifyIP(ip string) Special {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	return classifyAddr(addr.Unmap())
+}
+
+// classifyAddr is ClassifyIP for a parsed, unmapped address.
+// Internal function.
+func classifyAddr(addr netip.Addr) Special {
+	for _, p := range specialPrefixes {
+		if p.prefix.Contains(addr) {
+			return p.special
+		}
+	}
+	switch {
+	case addr.IsUnspecified():
+		return SpecialUnspecified
+	case addr.IsLoopback():
+		return SpecialLoopback
+	case addr.IsPrivate():
+		return SpecialPrivate
+	case addr.IsLinkLocalUnicast():
+		return SpecialLinkLocal
+	case addr.IsMulticast():
+		return SpecialMulticast
+	}
+	return ""
+}
+
+// WithSpecialRanges makes GetCity, GetCityFull, GetCityRegion and planners
+// answer addresses in special-purpose ranges (see ClassifyIP) that the database does
+// not locate with a LocationInfo carrying only Special, instead of (nil, nil),
+// so logs can label internal traffic. Such results are post-processed like
+// others. Country lookups are unaffected.
+func WithSpecialRanges() Option {
+	return func(s *SxGeo) {
+		s.specialRanges = true
+	}
+}
+
+// specialLocation returns the result for ipNum when the database does not
+// locate it: a LocationInfo labelling its special range with
+// WithSpecialRanges, or nil.
+// Internal function.
+func (s *SxGeo) specialLocation(ipNum uint32) *LocationInfo {
+	if !s.specialRanges {
+		return nil
+	}
+	sp := classifyAddr(uint32ToAddr(ipNum))
+	if sp == "" {
+		return nil
+	}
+	return &LocationInfo{Special: sp}
+}
+
+// WithReservedRanges makes every lookup treat addresses in the given
+// special-purpose ranges (all of them if none are given) as reserved, like
+// 0/8, 10/8 and 127/8 always are: not found, whatever the database contains
+// for them. Databases do locate some of these ranges (CGNAT and link-local
+// space, documentation prefixes) depending on their source; this makes
+// results for them consistent across database versions. Range walks
+// (WalkRanges, DescribeCIDR, DominantLocation, AnnotateSorted and
+// AnalyzeRanges) see them uncovered too. Synthetic locations (see
+// WithSyntheticLocations) still apply. Calls accumulate.
+func WithReservedRanges(kinds ...Special) Option {
+	return func(s *SxGeo) {
+		if len(kinds) == 0 {
+			kinds = []Special{SpecialUnspecified, SpecialLoopback, SpecialPrivate, SpecialCGNAT,
+				SpecialLinkLocal, SpecialDocumentation, SpecialBenchmarking, SpecialMulticast,
+				SpecialBroadcast, SpecialReserved}
+		}
+		if s.reserved == nil {
+			s.reserved = make(map[Special]bool, len(kinds))
+		}
+		for _, k := range kinds {
+			s.reserved[k] = true
+		}
+		s.reservedSpans = reservedSpans(s.reserved)
+	}
+}
+
+// reservedSpans returns the IPv4 ranges classifyAddr labels with one of
+// reserved, sorted and merged. Classification is constant between the bounds
+// of the labelled prefixes, so each stretch between bounds is classified by
+// its first address.
+// Internal function.
+func reservedSpans(reserved map[Special]bool) []ipRange {
+	bounds := []uint64{0, 1 << 32}
+	add := func(p netip.Prefix) {
+		if lo, hi, err := prefixBounds(p); err == nil {
+			bounds = append(bounds, uint64(lo), uint64(hi)+1)
+		}
+	}
+	for _, p := range specialPrefixes {
+		if p.prefix.Addr().Is4() {
+			add(p.prefix)
+		}
+	}
+	for _, p := range netipPrefixes {
+		add(p)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var spans []ipRange
+	for i := 0; i+1 < len(bounds); i++ {
+		first, last := uint32(bounds[i]), uint32(bounds[i+1]-1)
+		if !reserved[classifyAddr(uint32ToAddr(first))] {
+			continue
+		}
+		if n := len(spans); n > 0 && spans[n-1].last+1 == first {
+			spans[n-1].last = last
+			continue
+		}
+		spans = append(spans, ipRange{first: first, last: last, block: -1, reserved: true})
+	}
+	return spans
+}
+
+// isReservedSpecial reports whether ipNum is in a range configured with
+// WithReservedRanges.
+// Internal function.
+func (s *SxGeo) isReservedSpecial(ipNum uint32) bool {
+	return s.reserved != nil && s.reserved[classifyAddr(uint32ToAddr(ipNum))]
+}
+
+// splitReserved calls fn for the parts of r outside the ranges configured
+// with WithReservedRanges and, for the parts inside, with uncovered reserved
+// ranges, in address order.
+// Internal function.
+func (s *SxGeo) splitReserved(r ipRange, fn func(r ipRange) error) error {
+	for _, sp := range s.reservedSpans {
+		if sp.last < r.first {
+			continue
+		}
+		if sp.first > r.last {
+			break
+		}
+		if sp.first > r.first {
+			head := r
+			head.last = sp.first - 1
+			if err := fn(head); err != nil {
+				return err
+			}
+		}
+		if err := fn(ipRange{first: max(sp.first, r.first), last: min(sp.last, r.last), block: -1, reserved: true}); err != nil {
+			return err
+		}
+		if sp.last >= r.last {
+			return nil
+		}
+		r.first = sp.last + 1
+	}
+	return fn(r)
+}
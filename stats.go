@@ -0,0 +1,120 @@
+package sxgo
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of per-instance lookup statistics.
+type Stats struct {
+	// Lookups counts address lookups that reached the database (including
+	// reserved ranges), across all lookup methods.
+	Lookups uint64 `json:"lookups"`
+	// SlowLookups are the slowest lookups seen, slowest first.
+	// Only recorded with WithSlowLookupLog.
+	SlowLookups []SlowLookup `json:"slow_lookups,omitempty"`
+	// RecordCache describes the decoded-record cache; nil without WithRecordCache.
+	RecordCache *RecordCacheStats `json:"record_cache,omitempty"`
+	// IO measures database reads, overall and per section.
+	IO IOStats `json:"io"`
+	// DiskCache describes the on-disk result cache; nil without WithDiskCache.
+	DiskCache *DiskCacheStats `json:"disk_cache,omitempty"`
+}
+
+// SlowLookup describes one lookup recorded by the slow lookup log.
+// Duration covers resolving the address in the indexes and block table
+// (including file reads in ModeFile) but not decoding the location records.
+type SlowLookup struct {
+	IP       string        `json:"ip"`                // Address with the last octet zeroed, e.g. "203.0.113.0".
+	IPHash   string        `json:"ip_hash,omitempty"` // Keyed hash of the full address; only with WithIPHasher.
+	Duration time.Duration `json:"duration"`          // Time spent resolving the address.
+	Mode     string        `json:"mode"`              // "memory", "mmap" or "file".
+	Blocks   uint32        `json:"blocks"`            // DB blocks searched.
+	Time     time.Time     `json:"time"`              // When the lookup finished.
+}
+
+// statsState holds the counters behind Stats.
+// This struct is internal.
+type statsState struct {
+	lookups atomic.Uint64
+	slow    *slowLog // nil unless WithSlowLookupLog is used
+	io      ioCounters
+}
+
+// slowLog keeps the n slowest lookups, slowest first.
+// This struct is internal.
+type slowLog struct {
+	n       int
+	hasher  *IPHasher    // WithIPHasher; nil records no hash
+	floor   atomic.Int64 // Duration a lookup must exceed once the log is full
+	mu      sync.Mutex
+	entries []SlowLookup
+}
+
+// WithSlowLookupLog records the n slowest lookups for Stats, so pathological
+// addresses and file system latency can be spotted. Addresses are stored
+// anonymized. n <= 0 disables the log.
+func WithSlowLookupLog(n int) Option {
+	return func(s *SxGeo) {
+		if n <= 0 {
+			s.stats.slow = nil
+			return
+		}
+		s.stats.slow = &slowLog{n: n}
+	}
+}
+
+// Stats returns a snapshot of the instance statistics. It is safe for
+// concurrent use with lookups.
+func (s *SxGeo) Stats() Stats {
+	st := Stats{Lookups: s.stats.lookups.Load()}
+	st.IO = s.stats.io.snapshot(st.Lookups)
+	if l := s.stats.slow; l != nil {
+		l.mu.Lock()
+		st.SlowLookups = append([]SlowLookup(nil), l.entries...)
+		l.mu.Unlock()
+	}
+	if s.records != nil {
+		st.RecordCache = s.records.stats()
+	}
+	if s.disk != nil {
+		st.DiskCache = s.disk.stats()
+	}
+	return st
+}
+
+// record adds a lookup to the log if it is among the n slowest so far.
+// Internal function.
+func (l *slowLog) record(ipNum uint32, d time.Duration, blocks uint32, mode string) {
+	if int64(d) <= l.floor.Load() {
+		return // Cheap reject once the log is full
+	}
+
+	e := SlowLookup{
+		IP:       uint32ToAddr(ipNum &^ 0xFF).String(),
+		Duration: d,
+		Mode:     mode,
+		Blocks:   blocks,
+		Time:     time.Now(),
+	}
+	if l.hasher != nil {
+		e.IPHash = l.hasher.Hash(uint32ToAddr(ipNum).String())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := sort.Search(len(l.entries), func(i int) bool { return l.entries[i].Duration < d })
+	if i >= l.n {
+		return
+	}
+	if len(l.entries) < l.n {
+		l.entries = append(l.entries, SlowLookup{})
+	}
+	copy(l.entries[i+1:], l.entries[i:])
+	l.entries[i] = e
+	if len(l.entries) == l.n {
+		l.floor.Store(int64(l.entries[l.n-1].Duration))
+	}
+}
@@ -1,5 +1,7 @@
 package sxgo
 
+import "time"
+
 // LocationInfo holds the combined geolocation information for an IP address.
 // Depending on the lookup method (GetCity, GetCityFull) and the database contents,
 // some fields might be nil.
@@ -7,6 +9,45 @@ type LocationInfo struct {
 	City    *City    `json:"city,omitempty"`    // City details, nil if not found or not requested.
 	Region  *Region  `json:"region,omitempty"`  // Region details, nil if not found or not requested via GetCityFull.
 	Country *Country `json:"country,omitempty"` // Country details, nil if not found.
+
+	// DerivedFrom is set when the IPv4 address looked up was extracted from an IPv6
+	// address (see WithIPv6Derivation): Derived6to4 or DerivedTeredo. Empty otherwise.
+	DerivedFrom string `json:"derived_from,omitempty"`
+
+	// Special labels the special-purpose range of an address the database
+	// does not locate; set only with WithSpecialRanges, on results without
+	// City, Region or Country.
+	Special Special `json:"special,omitempty"`
+
+	// Precision tells which levels of the location the result identifies.
+	Precision Precision `json:"precision,omitempty"`
+
+	// Accuracy tells what the best coordinates of the result describe (see
+	// Coordinates). Only set with WithRegionCentroids.
+	Accuracy Accuracy `json:"accuracy,omitempty"`
+
+	// Range is the address range the result applies to; set only with
+	// WithMatchedRange.
+	Range *IPRange `json:"range,omitempty"`
+
+	// ValidUntil is when newer data is expected (see SxGeo.ValidUntil); set
+	// only with WithUpdateCadence. Caches may expire the result then.
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+
+	// Warnings lists the recoverable errors met while decoding the result,
+	// which may then lack parts; set only with WithWarnings.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Annotations holds free-form key/value pairs added by post-processors (see WithPostProcessor).
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Annotate sets an annotation on the result, allocating the map on first use.
+func (l *LocationInfo) Annotate(key, value string) {
+	if l.Annotations == nil {
+		l.Annotations = make(map[string]string)
+	}
+	l.Annotations[key] = value
 }
 
 // City information.
@@ -29,6 +70,15 @@ type Region struct {
 	NameEN string `json:"name_en,omitempty"` // Region name in English (if available).
 	ISO    string `json:"iso,omitempty"`     // ISO 3166-2 region code (e.g., "US-CA").
 
+	// District is the district the region belongs to, set only with
+	// WithDistricts or WithRussianDistricts.
+	District *District `json:"district,omitempty"`
+
+	// Lat and Lon are the centroid of the region's cities, set only when the
+	// city has no coordinates and WithRegionCentroids is used.
+	Lat float64 `json:"lat,omitempty"`
+	Lon float64 `json:"lon,omitempty"`
+
 	// Internal field, not part of public API or JSON output
 	countrySeek uint32 // Seek position for the country data.
 }
@@ -41,5 +91,13 @@ type Country struct {
 	Lon    float64 `json:"lon"`               // Longitude (often centroid).
 	NameRU string  `json:"name_ru,omitempty"` // Country name in Russian (if available).
 	NameEN string  `json:"name_en,omitempty"` // Country name in English (if available).
+
+	// Approximate is set when Lat and Lon come from the embedded centroid
+	// table rather than the database (see WithCountryCentroids).
+	Approximate bool `json:"approximate,omitempty"`
+
+	// Regulation is the country's privacy regulation, set only with
+	// WithRegulations.
+	Regulation *Regulation `json:"regulation,omitempty"`
 	// Timezone string  `json:"timezone,omitempty"` // Timezone information is not typically included in the base SxGeo City format handled here.
 }
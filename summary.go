@@ -0,0 +1,57 @@
+package sxgo
+
+// LocationSummary is a flat, comparable form of a lookup result, usable as a
+// map key, e.g. to count or deduplicate locations in analytics code.
+// Fields the result does not identify are zero.
+type LocationSummary struct {
+	CountryISO string    `json:"country_iso,omitempty"` // ISO 3166-1 alpha-2 country code.
+	RegionISO  string    `json:"region_iso,omitempty"`  // ISO 3166-2 region code.
+	CityID     uint32    `json:"city_id,omitempty"`     // City ID in the database.
+	CityName   string    `json:"city_name,omitempty"`   // City name in English, else in Russian.
+	Lat        float64   `json:"lat,omitempty"`         // Best latitude available (see LocationInfo.Coordinates).
+	Lon        float64   `json:"lon,omitempty"`         // Best longitude available.
+	Precision  Precision `json:"precision,omitempty"`   // Levels the result identifies.
+}
+
+// Summarize returns the summary of l. A nil l yields the zero summary.
+func (l *LocationInfo) Summarize() LocationSummary {
+	var sum LocationSummary
+	if l == nil {
+		return sum
+	}
+	if l.Country != nil {
+		sum.CountryISO = l.Country.ISO
+	}
+	if l.Region != nil {
+		sum.RegionISO = l.Region.ISO
+	}
+	if l.City != nil {
+		sum.CityID = l.City.ID
+		sum.CityName = l.City.NameEN
+		if sum.CityName == "" {
+			sum.CityName = l.City.NameRU
+		}
+	}
+	sum.Lat, sum.Lon, _ = l.Coordinates()
+	sum.Precision = l.Precision
+	return sum
+}
+
+// Summary looks up ip like GetCityRegion and returns the result as a
+// LocationSummary; with WithLang("ru"), CityName is the Russian name. For
+// country databases only CountryISO and Precision are set. Addresses not
+// found yield the zero summary.
+func (s *SxGeo) Summary(ip string, opts ...CallOption) (LocationSummary, error) {
+	if !s.db().seekIDs() {
+		iso, err := s.country(ip)
+		if err != nil || iso == "" {
+			return LocationSummary{}, err
+		}
+		return LocationSummary{CountryISO: iso, Precision: PrecisionCountry}, nil
+	}
+	info, err := s.lookup("GetCityRegion", s.cityRegionLookup, depthRegion, ip, opts)
+	if err != nil {
+		return LocationSummary{}, err
+	}
+	return info.Summarize(), nil
+}
@@ -8,50 +8,203 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // SxGeo provides methods for querying a Sypex Geo database file.
 type SxGeo struct {
-	f            *os.File // File handle (nil in ModeMemory after init)
-	header       *header  // Parsed database header
-	packFormats  []string // Unpacking formats for country, region, city
-	dbBegin      int64    // Offset where the main DB blocks start
-	regionsBegin int64    // Offset where region data starts
-	citiesBegin  int64    // Offset where city data starts
-	blockSize    uint32   // Size of one IP range block in the main DB (3 bytes IP + ID bytes)
+	// Database read by lookups; replaced as a whole, never modified once
+	// published (see dbState)
+	state atomic.Pointer[dbState]
 
 	// Mode flags
 	memoryMode bool
-	batchMode  bool
+	batchMode  bool // Kept for compatibility; indexes are parsed in every mode
+	mmapMode   bool // Map the file (ModeMMap); memoryMode is set once it is mapped
+	rawIndexes bool // Search raw index bytes instead of parsed arrays (WithRawIndexes)
+
+	// Optional behaviour configured via Option values
+	postProcessors  []PostProcessor     // Run on every City lookup result
+	middleware      []LookupMiddleware  // Wraps GetCity/GetCityFull, outermost first
+	synthetic       []syntheticEntry    // Fixed results for configured prefixes, most specific first
+	deriveIPv6      bool                // Extract embedded IPv4 from 6to4/Teredo addresses
+	searchFunc      SearchFunc          // Searches the main index and DB blocks
+	mainIndexPolicy MainIndexPolicy     // When lookups narrow windows with the main index
+	searchHooks     []func(SearchEvent) // Observe each search step
+	retry           *RetryPolicy        // Retries for ModeFile reads (nil: single attempt)
+	tuning          fileTuning          // Platform tuning of the ModeFile handle
+	callDefaults    []CallOption        // Applied to every GetCity/GetCityFull result
+	centroids       bool                // Fill region centroids (WithRegionCentroids)
+	countryCoords   bool                // Fill missing country coordinates (WithCountryCentroids)
+	districts       map[string]District // Region ISO code -> district (WithDistricts)
+	vatTable        VATTable            // VAT rates by country (nil: EUVATRates)
+	restricted      map[string]bool     // Screening list for IsRestricted, by ISO code
+	screeningAudit  []func(ScreeningEvent)
+	records         *recordCache      // Decoded records by seek (WithRecordCache)
+	cadence         time.Duration     // Expected database update interval (WithUpdateCadence)
+	remap           *remapState       // Display country overrides (WithCountryRemap)
+	license         string            // WithAttribution
+	attribution     string            // WithAttribution
+	disk            *diskCache        // Persistent result cache (WithDiskCache)
+	intern          bool              // Share identical decoded strings (WithStringInterning)
+	nameNorm        NameNormalization // Name normalization steps (WithNameNormalization)
+	specialRanges   bool              // Label special-purpose addresses (WithSpecialRanges)
+	reserved        map[Special]bool  // Ranges treated as reserved (WithReservedRanges)
+	reservedSpans   []ipRange         // IPv4 ranges of reserved, sorted and merged
+	audit           *auditState       // Lookup audit log (WithLookupAudit)
+	hasher          *IPHasher         // Hashes addresses in telemetry (WithIPHasher)
+	regulations     RegulationTable   // Privacy regulations by country (WithRegulations)
+	recent          *recentRing       // Last lookup results (WithRecentLookups)
+	watch           *fileWatch        // Database file rotation checks (WithFileWatch)
+	autoReload      *autoReload       // Background reloads (WithAutoReload)
+	warnings        bool              // Collect recoverable errors (WithWarnings)
+	countryIndexed  bool              // Build the country table (WithCountryIndex)
+	notFoundErrors  bool              // Report misses as errors (WithNotFoundErrors)
+	scratch         bool              // Pool ModeFile read buffers (WithScratchBuffers)
+	telemetry       Telemetry         // Metrics sink (WithTelemetry)
+	countryOnly     bool              // WithCountryOnly
+	strictVersion   bool              // Refuse newer format versions (WithStrictVersion)
+	zeroIDErrors    bool              // Explain misses on ID 0 (WithZeroIDDiagnostics)
+	matchedRange    bool              // Set LocationInfo.Range (WithMatchedRange)
+
+	// Runtime state
+	stats            statsState                   // Lookup counters and slow lookup log
+	overlay          atomic.Pointer[[]patchRange] // Ranges installed by ApplyPatch, sorted
+	cityLookup       LookupFunc                   // lookupDepth(ip, depthCity) wrapped in middleware
+	cityFullLookup   LookupFunc                   // lookupDepth(ip, depthFull) wrapped in middleware
+	cityRegionLookup LookupFunc                   // lookupDepth(ip, depthRegion) wrapped in middleware
+	path             string                       // Database file New opened, read again by Reload
+	reloadMu         sync.Mutex                   // Serializes Reload, Close and ApplyPatch
+	closed           bool                         // Set by Close; guarded by reloadMu
+}
+
+// dbState is the loaded database: header, indexes, data and file handle.
+// New and Reload build it and publish it through SxGeo.state; once published
+// it is immutable, so lookups read it without locks and replacing it (e.g.
+// when WithFileWatch reopens the file) never blocks them. A lookup loads it
+// once and passes it down, so it reads a single database throughout.
+// This struct is internal.
+type dbState struct {
+	gen          uint64      // Number of reloads before this database; tells cache entries apart
+	f            *os.File    // File handle (nil in ModeMemory after init)
+	source       os.FileInfo // The file as opened, to notice replacements (WithAutoReload)
+	header       *header     // Parsed database header
+	rawHead      []byte      // Raw header and pack format bytes
+	packFormats  []string    // Unpacking formats for country, region, city
+	dbBegin      int64       // Offset where the main DB blocks start
+	regionsBegin int64       // Offset where region data starts
+	citiesBegin  int64       // Offset where city data starts
+	// Offset where country records start: citiesBegin, or the end of the
+	// cities block if the database stores them separately (separateCountries)
+	countriesBegin    int64
+	separateCountries bool
+	// Bundle the database was opened from (see BundleExt), and the extent of
+	// the database within the file; base is 0 for plain .dat files
+	bundle    *Manifest
+	base, end int64
+	blockSize uint32       // Size of one IP range block in the main DB (3 bytes IP + ID bytes)
+	layout    Capabilities // Record blocks present, computed once by New
 
 	// Data and indexes (populated based on mode)
-	byteIndexStr []byte   // Raw byte index (used in ModeFile)
-	mainIndexStr []byte   // Raw main index (used in ModeFile)
-	byteIndexArr []uint32 // Parsed byte index (used if batchMode or memoryMode)
-	mainIndexArr []uint32 // Parsed main index (used if batchMode or memoryMode)
-	dbData       []byte   // Main database blocks (used in ModeMemory)
-	regionsData  []byte   // Region data (used in ModeMemory)
-	citiesData   []byte   // City data (used in ModeMemory)
+	byteIndexStr  []byte   // Raw byte index (used only with WithRawIndexes)
+	mainIndexStr  []byte   // Raw main index (used only with WithRawIndexes)
+	byteIndexArr  []uint32 // Parsed byte index (used by default in every mode)
+	mainIndexArr  []uint32 // Parsed main index (used by default in every mode)
+	dbData        []byte   // Main database blocks (used in ModeMemory)
+	regionsData   []byte   // Region data (used in ModeMemory)
+	citiesData    []byte   // City data (used in ModeMemory)
+	countriesData []byte   // Country data (used in ModeMemory; aliases citiesData unless separate)
+	mapping       []byte   // The mapped file, which the data above aliases (ModeMMap)
+	ref           *dbRef   // Users of mapping or f (see acquire); nil if neither
+
+	countryTable map[uint32]uint8  // Country ID by block ID (WithCountryOnly in ModeMemory)
+	countryIndex *countryIndex     // Flat country table (WithCountryIndex)
+	scratch      *scratchBuffers   // Pooled ModeFile read buffers (WithScratchBuffers)
+	fingerprint  *fingerprintState // Lazily computed content digest
+	tables       *lazyTables       // Tables derived from the records on first use
+
+	// Read records from f in ModeMemory too, bypassing the record cache,
+	// while the country table is built (see buildCountryTable)
+	fileReads bool
+}
+
+// db returns the database lookups currently read.
+// Internal function.
+func (s *SxGeo) db() *dbState {
+	return s.state.Load()
 }
 
 // New creates a new SxGeo instance to query the database file.
 //
 // dbFile is the path to the Sypex Geo .dat file (v2.2 format expected).
-// mode determines how the database is accessed (ModeFile, ModeMemory, ModeBatch).
+// mode determines how the database is accessed (ModeFile, ModeMemory).
 // Use ModeMemory for best performance if memory usage is acceptable.
-// Combine ModeBatch with ModeMemory (ModeMemory | ModeBatch) for potentially
-// faster lookups in high-throughput scenarios by pre-parsing indexes.
-func New(dbFile string, mode uint) (*SxGeo, error) {
+// Indexes are parsed into arrays in every mode; ModeBatch is still accepted
+// but no longer changes behaviour.
+// A file with the BundleExt extension is opened as a bundle: the database is
+// read from it in place, after its digest has been verified (see WriteBundle).
+// opts tune optional behaviour (see Option); they may be omitted.
+func New(dbFile string, mode uint, opts ...Option) (*SxGeo, error) {
+	s := newSxGeo(mode, opts)
+	db, err := s.load(dbFile, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.state.Store(db)
+	s.path = dbFile
+
+	if s.memoryMode {
+		s.watch = nil // No file left to watch
+	} else if s.watch != nil {
+		if err := s.startFileWatch(db, dbFile); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("sxgo: failed to watch %q: %w", dbFile, err)
+		}
+	}
+
+	if s.disk != nil {
+		if err := s.openDiskCache(); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("sxgo: failed to open disk cache %q: %w", s.disk.path, err)
+		}
+	}
+	if s.autoReload != nil {
+		s.startAutoReload()
+	}
+
+	return s, nil
+}
+
+// load reads the database in dbFile as configured for s, without publishing
+// it, for New and Reload; gen numbers it (see dbState).
+// Internal function.
+func (s *SxGeo) load(dbFile string, gen uint64) (*dbState, error) {
 	f, err := os.Open(dbFile)
 	if err != nil {
 		return nil, fmt.Errorf("sxgo: failed to open db file %q: %w", dbFile, err)
 	}
+	db := &dbState{gen: gen, f: f, fingerprint: &fingerprintState{}, tables: &lazyTables{}}
+	if db.source, err = f.Stat(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sxgo: failed to stat db file %q: %w", dbFile, err)
+	}
 
-	s := &SxGeo{
-		f:          f,
-		memoryMode: (mode & ModeMemory) != 0,
-		batchMode:  (mode & ModeBatch) != 0,
+	// Locate the database: the whole file, or the verified entry of a bundle
+	if isBundle(dbFile) {
+		b, err := readBundle(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("sxgo: invalid bundle %q: %w", dbFile, err)
+		}
+		db.bundle, db.base, db.end = b.manifest, b.offset, b.offset+b.size
+		if _, err := f.Seek(db.base, io.SeekStart); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("sxgo: failed to seek to database in bundle %q: %w", dbFile, err)
+		}
+	} else {
+		db.end = db.source.Size()
 	}
 
 	// Read and parse header
@@ -66,32 +219,34 @@ func New(dbFile string, mode uint) (*SxGeo, error) {
 		f.Close()
 		return nil, fmt.Errorf("sxgo: invalid header or signature in %q", dbFile)
 	}
-	s.header = h
-	s.blockSize = dbBlockLenOffset + uint32(s.header.idLen)
+	db.header = h
+	db.rawHead = headerBytes
+	db.blockSize = dbBlockLenOffset + uint32(db.header.idLen)
 
 	// Read pack formats if they exist
-	if s.header.packSize > 0 {
-		packBytes := make([]byte, s.header.packSize)
+	if db.header.packSize > 0 {
+		packBytes := make([]byte, db.header.packSize)
 		if _, err := io.ReadFull(f, packBytes); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: failed to read pack formats from %q: %w", dbFile, err)
 		}
+		db.rawHead = append(db.rawHead, packBytes...) // Kept for Fingerprint
 		// Split and remove potential empty string at the end if format ends with \x00
-		s.packFormats = strings.Split(strings.TrimRight(string(packBytes), "\x00"), "\x00")
+		db.packFormats = strings.Split(strings.TrimRight(string(packBytes), "\x00"), "\x00")
 	} else {
 		// Need at least city/country formats for city DBs
-		if s.header.maxCity > 0 {
+		if db.header.maxCity > 0 {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: database %q is a City DB but lacks pack formats", dbFile)
 		}
 		// Allow country DB without pack formats (though country names won't be available)
-		s.packFormats = []string{} // Ensure it's initialized
+		db.packFormats = []string{} // Ensure it's initialized
 	}
 
 	// --- Read Indexes ---
-	byteIndexSize := int64(s.header.byteIndexLen) * 4
-	mainIndexSize := int64(s.header.mainIndexLen) * 4
-	useParsedIndexes := s.batchMode || s.memoryMode
+	byteIndexSize := int64(db.header.byteIndexLen) * 4
+	mainIndexSize := int64(db.header.mainIndexLen) * 4
+	useParsedIndexes := !s.rawIndexes
 
 	if useParsedIndexes {
 		// Read raw indexes first
@@ -107,114 +262,214 @@ func New(dbFile string, mode uint) (*SxGeo, error) {
 		}
 
 		// Parse into arrays
-		s.byteIndexArr = make([]uint32, s.header.byteIndexLen)
-		s.mainIndexArr = make([]uint32, s.header.mainIndexLen)
-		for i := 0; i < int(s.header.byteIndexLen); i++ {
-			s.byteIndexArr[i] = binary.BigEndian.Uint32(rawBIdx[i*4 : (i+1)*4])
+		db.byteIndexArr = make([]uint32, db.header.byteIndexLen)
+		db.mainIndexArr = make([]uint32, db.header.mainIndexLen)
+		for i := 0; i < int(db.header.byteIndexLen); i++ {
+			db.byteIndexArr[i] = binary.BigEndian.Uint32(rawBIdx[i*4 : (i+1)*4])
 		}
-		for i := 0; i < int(s.header.mainIndexLen); i++ {
-			s.mainIndexArr[i] = binary.BigEndian.Uint32(rawMIdx[i*4 : (i+1)*4])
+		for i := 0; i < int(db.header.mainIndexLen); i++ {
+			db.mainIndexArr[i] = binary.BigEndian.Uint32(rawMIdx[i*4 : (i+1)*4])
 		}
 
-		// Keep raw slices only if needed for pure file mode (not typical here)
-		// Or potentially for a hybrid mode not currently implemented.
-		// For simplicity, we don't store them if using parsed indexes.
-		// If ModeFile is somehow combined, store them:
-		// if !s.memoryMode && !s.batchMode { s.byteIndexStr = rawBIdx; s.mainIndexStr = rawMIdx }
-
-	} else { // File mode - read raw bytes directly
-		s.byteIndexStr = make([]byte, byteIndexSize)
-		s.mainIndexStr = make([]byte, mainIndexSize)
-		if _, err := io.ReadFull(f, s.byteIndexStr); err != nil {
+	} else { // Deprecated raw mode - keep index bytes and decode entries during search
+		db.byteIndexStr = make([]byte, byteIndexSize)
+		db.mainIndexStr = make([]byte, mainIndexSize)
+		if _, err := io.ReadFull(f, db.byteIndexStr); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: failed to read byte index from %q: %w", dbFile, err)
 		}
-		if _, err := io.ReadFull(f, s.mainIndexStr); err != nil {
+		if _, err := io.ReadFull(f, db.mainIndexStr); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: failed to read main index from %q: %w", dbFile, err)
 		}
 	}
 
 	// Store current position as db_begin and calculate data block offsets
-	s.dbBegin, err = f.Seek(0, io.SeekCurrent)
+	db.dbBegin, err = f.Seek(0, io.SeekCurrent)
 	if err != nil {
 		f.Close()
 		return nil, fmt.Errorf("sxgo: failed get db_begin offset in %q: %w", dbFile, err)
 	}
-	s.regionsBegin = s.dbBegin + int64(s.header.dbItems*s.blockSize)
-	s.citiesBegin = s.regionsBegin + int64(s.header.regionSize)
+	db.regionsBegin = db.dbBegin + int64(db.header.dbItems*db.blockSize)
+	db.citiesBegin = db.regionsBegin + int64(db.header.regionSize)
 
-	// --- Load Data into Memory if Requested ---
-	if s.memoryMode {
+	// v2.2 keeps country records at the start of the cities block. A file
+	// ending exactly one country block past the cities block stores them there.
+	db.countriesBegin = db.citiesBegin
+	if db.header.countrySize > 0 &&
+		db.end == db.citiesBegin+int64(db.header.citySize)+int64(db.header.countrySize) {
+		db.separateCountries = true
+		db.countriesBegin = db.citiesBegin + int64(db.header.citySize)
+	}
+	db.layout = db.capabilities()
+	s.countLoad(db, db.base, db.dbBegin-db.base)
+	if err := s.checkVersion(db); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sxgo: cannot read %q: %w", dbFile, err)
+	}
+	if err := db.validateIndexes(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sxgo: corrupt index in %q: %w", dbFile, err)
+	}
+
+	// --- Map the file if requested, else load data into memory if requested ---
+	mapped, err := s.mapDatabase(db)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sxgo: failed to map %q: %w", dbFile, err)
+	}
+	if mapped {
+		f.Close() // The mapping outlives the handle
+		db.f = nil
+	} else if s.memoryMode {
 		// Load Main DB Data
-		dbSize := int64(s.header.dbItems * s.blockSize)
-		s.dbData = make([]byte, dbSize)
+		dbSize := int64(db.header.dbItems * db.blockSize)
+		db.dbData = make([]byte, dbSize)
 		// Seek back to start of DB data before reading
-		if _, err := f.Seek(s.dbBegin, io.SeekStart); err != nil {
+		if _, err := f.Seek(db.dbBegin, io.SeekStart); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: memory mode failed to seek to db data start in %q: %w", dbFile, err)
 		}
-		if _, err := io.ReadFull(f, s.dbData); err != nil {
+		if _, err := io.ReadFull(f, db.dbData); err != nil {
 			f.Close()
 			return nil, fmt.Errorf("sxgo: failed to read db data into memory from %q: %w", dbFile, err)
 		}
 
-		// Load Regions Data (if exists)
-		if s.header.regionSize > 0 {
-			s.regionsData = make([]byte, s.header.regionSize)
-			if _, err := f.Seek(s.regionsBegin, io.SeekStart); err != nil {
+		if s.countryOnly && db.seekIDs() {
+			// Keep the country of each record instead of the records
+			if err := s.buildCountryTable(db); err != nil {
 				f.Close()
-				return nil, fmt.Errorf("sxgo: memory mode failed to seek to regions data start in %q: %w", dbFile, err)
+				return nil, fmt.Errorf("sxgo: failed to build country table from %q: %w", dbFile, err)
 			}
-			if _, err := io.ReadFull(f, s.regionsData); err != nil {
-				f.Close()
-				return nil, fmt.Errorf("sxgo: failed to read regions data into memory from %q: %w", dbFile, err)
+			s.countLoad(db, db.dbBegin, dbSize)
+		} else {
+			// Load Regions Data (if exists)
+			if db.header.regionSize > 0 {
+				db.regionsData = make([]byte, db.header.regionSize)
+				if _, err := f.Seek(db.regionsBegin, io.SeekStart); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("sxgo: memory mode failed to seek to regions data start in %q: %w", dbFile, err)
+				}
+				if _, err := io.ReadFull(f, db.regionsData); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("sxgo: failed to read regions data into memory from %q: %w", dbFile, err)
+				}
 			}
-		}
 
-		// Load Cities Data (if exists - includes country data in v2.2)
-		if s.header.citySize > 0 {
-			s.citiesData = make([]byte, s.header.citySize)
-			if _, err := f.Seek(s.citiesBegin, io.SeekStart); err != nil {
-				f.Close()
-				return nil, fmt.Errorf("sxgo: memory mode failed to seek to cities data start in %q: %w", dbFile, err)
+			// Load Cities Data (if exists - includes country data in v2.2)
+			if db.header.citySize > 0 {
+				db.citiesData = make([]byte, db.header.citySize)
+				if _, err := f.Seek(db.citiesBegin, io.SeekStart); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("sxgo: memory mode failed to seek to cities data start in %q: %w", dbFile, err)
+				}
+				if _, err := io.ReadFull(f, db.citiesData); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("sxgo: failed to read cities data into memory from %q: %w", dbFile, err)
+				}
 			}
-			if _, err := io.ReadFull(f, s.citiesData); err != nil {
-				f.Close()
-				return nil, fmt.Errorf("sxgo: failed to read cities data into memory from %q: %w", dbFile, err)
+
+			// Load Countries Data (a separate block, or part of the cities data)
+			db.countriesData = db.citiesData
+			if db.separateCountries {
+				db.countriesData = make([]byte, db.header.countrySize)
+				if _, err := f.ReadAt(db.countriesData, db.countriesBegin); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("sxgo: failed to read countries data into memory from %q: %w", dbFile, err)
+				}
 			}
+
+			s.countLoad(db, db.dbBegin, max(db.citiesBegin+int64(db.header.citySize), db.countriesBegin+int64(db.header.countrySize))-db.dbBegin)
 		}
 
 		// Close the file after loading into memory
 		err = f.Close()
-		s.f = nil // Set file handle to nil
+		db.f = nil // Set file handle to nil
 		if err != nil {
 			// Non-fatal error, as data is in memory, but good to know.
 			// Could log this? For now, just ignore potential close error.
 			// return nil, fmt.Errorf("sxgo: error closing file after memory load %q: %w", dbFile, err)
 		}
+	} else if err := s.applyFileTuning(db, dbFile); err != nil {
+		db.f.Close()
+		return nil, fmt.Errorf("sxgo: failed to tune file access to %q: %w", dbFile, err)
+	}
+	if db.f != nil {
+		db.ref = newDBRef(nil, db.f) // Closed once retired and no lookup reads it
+	}
+	if s.scratch && !s.memoryMode {
+		db.scratch = newScratchBuffers(db)
 	}
 
-	return s, nil
+	if s.countryIndexed {
+		if err := s.buildCountryIndex(db); err != nil {
+			db.release()
+			return nil, fmt.Errorf("sxgo: failed to build country index from %q: %w", dbFile, err)
+		}
+	}
+	return db, nil
+}
+
+// newSxGeo returns an instance in the given mode with opts applied, ready for
+// New to load a database into.
+// Internal function.
+func newSxGeo(mode uint, opts []Option) *SxGeo {
+	s := &SxGeo{
+		memoryMode: (mode & ModeMemory) != 0,
+		batchMode:  (mode & ModeBatch) != 0,
+		mmapMode:   (mode & ModeMMap) != 0,
+		searchFunc: BinarySearch,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.stats.slow != nil {
+		s.stats.slow.hasher = s.hasher
+	}
+	s.cityLookup = chainLookup(func(ip string) (*LocationInfo, error) { return s.lookupDepth(ip, depthCity) }, s.middleware)
+	s.cityFullLookup = chainLookup(func(ip string) (*LocationInfo, error) { return s.lookupDepth(ip, depthFull) }, s.middleware)
+	s.cityRegionLookup = chainLookup(func(ip string) (*LocationInfo, error) { return s.lookupDepth(ip, depthRegion) }, s.middleware)
+	return s
 }
 
 // Close releases resources used by SxGeo.
 // It's primarily important to call this if using ModeFile to close the file handle.
 // It's safe to call even if using ModeMemory (it becomes a no-op).
+// With ModeMMap the file is unmapped once the lookups still running are
+// done; later lookups fail.
 func (s *SxGeo) Close() error {
-	if s.f != nil {
-		err := s.f.Close()
-		s.f = nil // Ensure it's nil after closing
-		if err != nil {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if r := s.autoReload; r != nil && !s.closed && r.stop != nil {
+		close(r.stop)
+	}
+	s.closed = true
+	if s.disk != nil {
+		if err := s.disk.close(); err != nil {
+			return fmt.Errorf("sxgo: error closing disk cache: %w", err)
+		}
+	}
+	if s.watch != nil { // Keep checks from reopening the file meanwhile
+		s.watch.mu.Lock()
+		defer s.watch.mu.Unlock()
+	}
+	if db := s.db(); db != nil && db.mapping != nil {
+		closed := *db
+		closed.dbData, closed.regionsData, closed.citiesData, closed.countriesData = nil, nil, nil, nil
+		closed.mapping, closed.ref = nil, nil // Later lookups fail instead of reading unmapped memory
+		s.state.Store(&closed)
+		if err := db.retire(); err != nil {
+			return fmt.Errorf("sxgo: error unmapping database file: %w", err)
+		}
+	}
+	if db := s.db(); db != nil && db.f != nil {
+		closed := *db
+		closed.f, closed.ref = nil, nil // Lookups fail instead of reading a closed handle
+		s.state.Store(&closed)
+		if err := db.retire(); err != nil {
 			return fmt.Errorf("sxgo: error closing database file: %w", err)
 		}
 	}
-	// Clear memory-loaded data? Optional, GC will handle it eventually.
-	// s.dbData = nil
-	// s.regionsData = nil
-	// s.citiesData = nil
-	// s.byteIndexArr = nil
-	// s.mainIndexArr = nil
 	return nil
 }
 
@@ -235,8 +490,8 @@ func ip2long(ipStr string) (uint32, bool) {
 
 // decodeID converts ID bytes (big-endian) to uint32 based on header.idLen.
 // This function is internal.
-func (s *SxGeo) decodeID(idBytes []byte) (uint32, error) {
-	expectedLen := int(s.header.idLen)
+func (db *dbState) decodeID(idBytes []byte) (uint32, error) {
+	expectedLen := int(db.header.idLen)
 	if len(idBytes) != expectedLen {
 		return 0, fmt.Errorf("incorrect number of bytes for ID: expected %d, got %d", expectedLen, len(idBytes))
 	}
@@ -258,12 +513,14 @@ func (s *SxGeo) decodeID(idBytes []byte) (uint32, error) {
 // Get retrieves location information based on the database type.
 // For City databases (SxGeoCity*.dat), it returns city, region (optional), and country info.
 // For Country databases (SxGeoCountry.dat), it returns only the country ISO code.
-// Returns (nil, nil) if the IP is not found or belongs to a reserved range.
+// Returns (nil, nil) if the IP is not found or belongs to a reserved range,
+// unless WithNotFoundErrors is used.
 // Returns (nil, error) for database access errors or invalid IP format.
 // Note: The return type is interface{} for compatibility with both DB types.
 // Consider using more specific methods like GetCityFull or GetCountry if you know the DB type.
 func (s *SxGeo) Get(ip string) (interface{}, error) {
-	if s.header.maxCity > 0 { // City database
+	db := s.db()
+	if db.seekIDs() { // City database (or a database with regions only)
 		// Delegates to GetCityFull for consistency, as GetCity might omit region info
 		// needed for a complete picture compared to just country ISO.
 		// If performance is critical and only basic city/country needed, could call GetCity.
@@ -274,10 +531,22 @@ func (s *SxGeo) Get(ip string) (interface{}, error) {
 }
 
 // GetCountry retrieves the two-letter ISO 3166-1 alpha-2 country code for the IP address.
-// Returns "" (empty string) and nil error if the IP is not found or maps to ID 0.
-// Returns ("", error) for database access errors or invalid IP format.
+// Returns "" (empty string) and nil error if the IP is not found or maps to ID 0,
+// unless WithNotFoundErrors is used.
+// Returns ("", error) for database access errors or invalid IP format (ErrInvalidIP).
 func (s *SxGeo) GetCountry(ip string) (string, error) {
-	id, err := s.GetCountryID(ip)
+	iso, err := s.country(ip)
+	if iso == "" && err == nil {
+		err = s.missing(ip, false)
+	}
+	return iso, err
+}
+
+// country is GetCountry reporting misses as "" with a nil error whatever the
+// options, for the methods built on it.
+// Internal function.
+func (s *SxGeo) country(ip string) (string, error) {
+	id, err := s.countryID(ip)
 	if err != nil {
 		// Propagate lookup/parsing errors
 		return "", fmt.Errorf("sxgo: failed to get country ID for IP %s: %w", ip, err)
@@ -292,13 +561,41 @@ func (s *SxGeo) GetCountry(ip string) (string, error) {
 }
 
 // GetCountryID retrieves the numeric country ID for the IP address.
-// Returns 0 and nil error if the IP is not found or maps to ID 0.
-// Returns (0, error) for database access errors or invalid IP format.
+// Returns 0 and nil error if the IP is not found or maps to ID 0, unless
+// WithNotFoundErrors is used.
+// Returns (0, error) for database access errors or invalid IP format (ErrInvalidIP).
 func (s *SxGeo) GetCountryID(ip string) (uint32, error) {
-	seekOrID, err := s.getNum(ip) // Find the location ID or block seek position
+	id, err := s.countryID(ip)
+	if id == 0 && err == nil {
+		err = s.missing(ip, false)
+	}
+	return id, err
+}
+
+// countryID is the audited GetCountryID, reporting misses as 0 with a nil
+// error whatever the options.
+// Internal function.
+func (s *SxGeo) countryID(ip string) (uint32, error) {
+	id, err := s.getCountryID(ip)
+	s.auditCountry(ip, id, err)
+	return id, err
+}
+
+// getCountryID is the unaudited implementation of GetCountryID.
+// Internal function.
+func (s *SxGeo) getCountryID(ip string) (uint32, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if loc, ok := s.syntheticLocation(ip, false); ok {
+		if loc.Country == nil {
+			return 0, nil
+		}
+		return uint32(loc.Country.ID), nil
+	}
+	seekOrID, err := s.getNum(db, ip) // Find the location ID or block seek position
 	if err != nil {
 		// Check if it's the specific "reserved range" error, which we treat as "not found" (ID 0)
-		if errors.Is(err, errReservedRange) {
+		if errors.Is(err, ErrReservedRange) {
 			return 0, nil
 		}
 		// Otherwise, propagate the error (invalid IP, DB read error, etc.)
@@ -310,146 +607,278 @@ func (s *SxGeo) GetCountryID(ip string) (uint32, error) {
 		return 0, nil
 	}
 
-	// If it's a City DB, the result (seekOrID) is a seek position into the city data.
-	// We need to parse the city data to find the associated country ID.
-	if s.header.maxCity > 0 {
-		// Parse just enough to get the country ID. We don't need full details (false).
-		// We only need the country ID stored within the city record itself.
-		cityInfo, err := s.readData(seekOrID, s.header.maxCity, 2) // Type 2 for City
+	countryID, _, err := s.resolveNum(db, seekOrID)
+	if err != nil {
+		// If parsing fails at this stage, it might indicate DB corruption or issues.
+		return 0, fmt.Errorf("sxgo: failed to read city data for country ID lookup for IP %s: %w", ip, err)
+	}
+	return countryID, nil
+}
+
+// LookupID returns the raw value the database stores for ip without decoding
+// any record: the country ID for Country DBs, or the seek offset of the city
+// record in the cities block for City DBs. It is the cheap first step of every
+// lookup, for callers keeping their own record caches or decoders; results are
+// only meaningful for the database they came from (see Fingerprint).
+// Returns 0 with a nil error if the IP is not found or in a reserved range,
+// unless WithNotFoundErrors is used.
+// Patched ranges (ApplyPatch) are honoured; synthetic locations are not, as
+// they have no record.
+func (s *SxGeo) LookupID(ip string) (uint32, error) {
+	db := s.acquire()
+	defer db.unpin()
+	num, err := s.getNum(db, ip)
+	if err != nil {
+		if errors.Is(err, ErrReservedRange) {
+			return 0, s.missing(ip, false)
+		}
+		return 0, fmt.Errorf("sxgo: failed to get DB number for IP %s: %w", ip, err)
+	}
+	if num == 0 {
+		return 0, s.missing(ip, false)
+	}
+	return num, nil
+}
+
+// resolveNum maps a getNum result to the country ID and city ID it stands for.
+// For Country DBs num is the country ID itself and cityID is always 0.
+// Internal function.
+func (s *SxGeo) resolveNum(db *dbState, num uint32) (countryID, cityID uint32, err error) {
+	if num == 0 {
+		return 0, 0, nil
+	}
+	if countryID, ok := db.countryTable[num]; ok {
+		return uint32(countryID), 0, nil
+	}
+	switch db.recordKind(num) {
+	case recordCountryID:
+		// If it's a Country DB, the result from getNum is the country ID directly.
+		return num, 0, nil
+	case recordCountry:
+		country, err := s.readData(db, num, db.header.maxCountry, 0)
 		if err != nil {
-			// If parsing fails at this stage, it might indicate DB corruption or issues.
-			return 0, fmt.Errorf("sxgo: failed to read city data for country ID lookup (seek %d) for IP %s: %w", seekOrID, ip, err)
+			return 0, 0, fmt.Errorf("failed to read country data at seek %d: %w", num, err)
 		}
-		if len(cityInfo) == 0 {
-			// Should not happen if seekOrID was valid, but handle defensively.
-			return 0, nil // No city info found, so no country ID.
+		return uint32(getUint8(country, "id")), 0, nil
+	case recordRegion:
+		region, err := s.readData(db, num, db.header.maxRegion, 1)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read region data at seek %d: %w", num, err)
 		}
-		// Extract country_id field defined in the pack format for cities.
-		// Assumes the field name is 'country_id'.
-		return uint32(getUint8(cityInfo, "country_id")), nil // Return 0 if field missing/invalid
+		if c, _ := s.regionCountry(db, region); c != nil {
+			return uint32(c.ID), 0, nil
+		}
+		return 0, 0, nil
 	}
 
-	// If it's a Country DB, the result from getNum is the country ID directly.
-	return seekOrID, nil
+	// If it's a City DB, the result is a seek position into the city data.
+	// We need to parse the city data to find the associated country ID.
+	cityInfo, err := s.readData(db, num, db.header.maxCity, 2) // Type 2 for City
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read city data at seek %d: %w", num, err)
+	}
+	if len(cityInfo) == 0 {
+		// Should not happen if num was valid, but handle defensively.
+		return 0, 0, nil // No city info found, so no country ID.
+	}
+	// Extract country_id field defined in the pack format for cities.
+	// Assumes the field name is 'country_id'.
+	return uint32(getUint8(cityInfo, "country_id")), getUint32(cityInfo, "id"), nil // 0 if field missing/invalid
 }
 
 // GetCity retrieves basic city and country information (ID, Lat, Lon, Names, Country ID/ISO).
 // Region information is *not* included in this call. Use GetCityFull for region details.
+// Ranges known only to country level, and databases without cities, yield
+// coarser results (see LocationInfo.Precision).
 // Returns (nil, nil) if the IP is not found, belongs to a reserved range, or if the database
-// is not a City database (e.g., SxGeoCountry.dat).
-// Returns (nil, error) for database access errors or invalid IP format.
-func (s *SxGeo) GetCity(ip string) (*LocationInfo, error) {
-	if s.header.maxCity == 0 {
+// is not a City database (e.g., SxGeoCountry.dat); WithNotFoundErrors
+// turns these into ErrNotFound, ErrReservedRange and ErrUnsupportedDB.
+// Returns (nil, error) for database access errors or invalid IP format (ErrInvalidIP).
+// Registered middleware (see WithMiddleware) wraps this lookup; opts (see
+// CallOption) then adjust the result for this call only.
+func (s *SxGeo) GetCity(ip string, opts ...CallOption) (*LocationInfo, error) {
+	info, err := s.lookup("GetCity", s.cityLookup, depthCity, ip, opts)
+	if info == nil && err == nil {
+		err = s.missing(ip, true)
+	}
+	return info, err
+}
+
+// lookup runs a city lookup of method (GetCity, GetCityFull or
+// GetCityRegion) through fn, applying opts and auditing it. Misses are
+// reported as (nil, nil) whatever the options.
+// Internal function.
+func (s *SxGeo) lookup(method string, fn LookupFunc, depth recordDepth, ip string, opts []CallOption) (*LocationInfo, error) {
+	info, err := fn(ip)
+	info = s.applyCallOptions(info, opts, depth)
+	s.auditLookup(method, ip, info, err, opts)
+	return info, err
+}
+
+// lookupDepth is the unwrapped implementation of GetCity, GetCityFull and
+// GetCityRegion, reading the records down to depth.
+// Internal function.
+func (s *SxGeo) lookupDepth(ip string, depth recordDepth) (*LocationInfo, error) {
+	db := s.acquire()
+	defer db.unpin()
+	if loc, ok := s.syntheticLocation(ip, depth != depthCity); ok {
+		s.postProcess(ip, loc)
+		return loc, nil
+	}
+	if !db.seekIDs() {
 		return nil, nil // Not a city database
 	}
-	seek, err := s.getNum(ip)
+	ipNum, derived, err := s.parseIP(ip)
 	if err != nil {
-		if errors.Is(err, errReservedRange) {
-			return nil, nil // Treat reserved range as not found
-		}
-		return nil, fmt.Errorf("sxgo: city lookup failed for IP %s: %w", ip, err)
+		lookup, _ := depth.names()
+		return nil, fmt.Errorf("sxgo: %s failed for IP %s: %w", lookup, ip, err)
 	}
-	if seek == 0 {
-		return nil, nil // Not found or handled internally by getNum
+	info, err := s.cityAt(db, ip, ipNum, depth)
+	if err != nil {
+		return nil, err
 	}
+	if info != nil {
+		info.DerivedFrom = derived
+		s.postProcess(ip, info)
+	}
+	return info, nil
+}
 
-	// Parse city data, but request *not* full details (false)
-	info, err := s.parseCity(seek, false)
-	if err != nil {
-		return nil, fmt.Errorf("sxgo: parsing city failed for IP %s (seek %d): %w", ip, seek, err)
+// cityAt resolves ipNum down to depth, from the disk cache if possible, and
+// completes the result as every city lookup does before post-processing:
+// matched range, special-purpose location, centroid and district.
+// Returns (nil, nil) if the address is not found or reserved.
+// Internal function.
+func (s *SxGeo) cityAt(db *dbState, ip string, ipNum uint32, depth recordDepth) (*LocationInfo, error) {
+	info, cached := s.diskGet(db, ipNum, depth)
+	if !cached {
+		var err error
+		if info, err = s.resolveCity(db, ip, ipNum, depth); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.attachRange(db, ipNum, info); err != nil {
+		lookup, _ := depth.names()
+		return nil, fmt.Errorf("sxgo: %s failed for IP %s: %w", lookup, ip, err)
+	}
+	if info == nil {
+		info = s.specialLocation(ipNum)
+	}
+	if info != nil {
+		s.attachCentroid(db, info)
+		if depth != depthCity {
+			s.attachDistrict(info)
+		}
 	}
-	// info might be nil if parsing failed internally despite no error return,
-	// or if the specific seek pointed to empty/invalid data structure.
 	return info, nil
 }
 
 // GetCityFull retrieves complete city, region, and country information.
 // Returns (nil, nil) if the IP is not found, belongs to a reserved range, or if the database
-// does not support city/region lookups (e.g., SxGeoCountry.dat).
-// Returns (nil, error) for database access errors or invalid IP format.
-func (s *SxGeo) GetCityFull(ip string) (*LocationInfo, error) {
-	// Check if DB supports cities (which implies regions/countries conceptually)
-	if s.header.maxCity == 0 {
-		return nil, nil // Not a city/region capable database
-	}
-	// Check if region data exists and pack format is available (needed for full details)
-	if s.header.maxRegion == 0 || len(s.packFormats) <= 1 || s.packFormats[1] == "" {
-		// Cannot fulfill "Full" request if regions aren't present or parsable.
-		// Fallback to GetCity? Or return error? Let's return error indicating inability.
-		// Although GetCity might still work, the user explicitly asked for full details.
-		// Alternatively, return partial data? Let's return what parseCity(seek, true) gives,
-		// it handles missing region internally.
-		// return nil, errors.New("sxgo: database lacks region data or format needed for GetCityFull")
-	}
-
-	seek, err := s.getNum(ip)
+// does not support city/region lookups (e.g., SxGeoCountry.dat); WithNotFoundErrors
+// turns these into ErrNotFound, ErrReservedRange and ErrUnsupportedDB.
+// Returns (nil, error) for database access errors or invalid IP format (ErrInvalidIP).
+// Registered middleware (see WithMiddleware) wraps this lookup; opts (see
+// CallOption) then adjust the result for this call only.
+func (s *SxGeo) GetCityFull(ip string, opts ...CallOption) (*LocationInfo, error) {
+	info, err := s.lookup("GetCityFull", s.cityFullLookup, depthFull, ip, opts)
+	if info == nil && err == nil {
+		err = s.missing(ip, true)
+	}
+	return info, err
+}
+
+// GetCityRegion retrieves city and region information with the country known
+// only by ID and ISO code, as GetCity reports it. It reads the city and region
+// records but skips the country record GetCityFull also reads, which suits
+// "city, region, country code" responses.
+// Returns (nil, nil) and (nil, error) as GetCityFull does.
+// Registered middleware (see WithMiddleware) wraps this lookup; opts (see
+// CallOption) then adjust the result for this call only.
+func (s *SxGeo) GetCityRegion(ip string, opts ...CallOption) (*LocationInfo, error) {
+	info, err := s.lookup("GetCityRegion", s.cityRegionLookup, depthRegion, ip, opts)
+	if info == nil && err == nil {
+		err = s.missing(ip, true)
+	}
+	return info, err
+}
+
+// resolveCity finds and parses the record of ipNum down to depth for
+// cityAt, and stores it in the disk cache, if one is used.
+// Returns (nil, nil) if the address is not found or reserved.
+// Internal function.
+func (s *SxGeo) resolveCity(db *dbState, ip string, ipNum uint32, depth recordDepth) (*LocationInfo, error) {
+	lookup, parsing := depth.names()
+	if s.countryOnly {
+		return nil, fmt.Errorf("sxgo: %s failed for IP %s: %w", lookup, ip, ErrCountryOnly)
+	}
+	span, err := s.lookupSpan(db, ipNum)
 	if err != nil {
-		if errors.Is(err, errReservedRange) {
+		if errors.Is(err, ErrReservedRange) {
 			return nil, nil // Treat reserved range as not found
 		}
-		return nil, fmt.Errorf("sxgo: full city lookup failed for IP %s: %w", ip, err)
+		return nil, fmt.Errorf("sxgo: %s failed for IP %s: %w", lookup, ip, err)
 	}
+	seek := span.id
 	if seek == 0 {
 		return nil, nil // Not found or handled internally by getNum
 	}
 
-	// Parse city data, requesting full details (true)
-	info, err := s.parseCity(seek, true)
+	info, err := s.parseCityDepth(db, seek, depth)
 	if err != nil {
-		return nil, fmt.Errorf("sxgo: parsing full city failed for IP %s (seek %d): %w", ip, seek, err)
+		return nil, fmt.Errorf("sxgo: %s failed for IP %s (seek %d): %w", parsing, ip, seek, err)
+	}
+	// Cached before post-processing, which depends on the call; partial
+	// results are not kept
+	if len(info.Warnings) == 0 {
+		s.diskPut(db, span, depth, info)
 	}
 	return info, nil
 }
 
 // About returns metadata about the loaded Sypex Geo database.
+// See Info for a typed variant.
 func (s *SxGeo) About() map[string]interface{} {
-	// Define known values based on SxGeo v2.2 documentation/common usage
-	charsets := map[uint8]string{0: "utf-8", 1: "latin1", 2: "cp1251"}
-	types := map[uint8]string{
-		1: "SxGeo Country",
-		2: "SxGeo City RU", 3: "SxGeo City EN", 4: "SxGeo City", // UTF?
-		5: "SxGeo City Max RU", 6: "SxGeo City Max EN", 7: "SxGeo City Max", // UTF?
-	}
-
-	charset := "unknown"
-	if cs, ok := charsets[s.header.charset]; ok {
-		charset = cs
-	}
-
-	dbType := "unknown"
-	if typ, ok := types[s.header.dbType]; ok {
-		dbType = typ
-	}
-
-	createdTime := time.Unix(int64(s.header.timestamp), 0).UTC()
-
-	return map[string]interface{}{
-		"Created":              createdTime.Format("2006-01-02 15:04:05 MST"),
-		"Timestamp":            s.header.timestamp,
-		"Charset":              charset,
-		"Type":                 dbType,
-		"Version":              s.header.version,
-		"Byte Index Entries":   s.header.byteIndexLen,
-		"Main Index Entries":   s.header.mainIndexLen,
-		"Blocks In Index Item": s.header.rangeBlocks,
-		"IP Database Items":    s.header.dbItems,
-		"ID Length (bytes)":    s.header.idLen,
-		"DB Block Size":        s.blockSize,
-		"Pack Format Strings":  s.packFormats, // Array of format strings
-		"DB Begin Offset":      s.dbBegin,
-		"Regions Begin Offset": s.regionsBegin,
-		"Cities Begin Offset":  s.citiesBegin,
+	db := s.db()
+	info := s.Info()
+	createdTime := info.Created
+
+	about := map[string]interface{}{
+		"Created":                createdTime.Format("2006-01-02 15:04:05 MST"),
+		"Timestamp":              db.header.timestamp,
+		"Charset":                info.Charset.String(),
+		"Type":                   info.Type.String(),
+		"Version":                db.header.version,
+		"Byte Index Entries":     db.header.byteIndexLen,
+		"Main Index Entries":     db.header.mainIndexLen,
+		"Blocks In Index Item":   db.header.rangeBlocks,
+		"IP Database Items":      db.header.dbItems,
+		"ID Length (bytes)":      db.header.idLen,
+		"DB Block Size":          db.blockSize,
+		"Pack Format Strings":    db.packFormats, // Array of format strings
+		"DB Begin Offset":        db.dbBegin,
+		"Regions Begin Offset":   db.regionsBegin,
+		"Cities Begin Offset":    db.citiesBegin,
+		"Countries Begin Offset": db.countriesBegin,
+		"Country Block Separate": db.separateCountries, // Otherwise country records lead the cities block
 		"City Meta": map[string]interface{}{
-			"Max Record Length": s.header.maxCity,
-			"Total Data Size":   s.header.citySize,
+			"Max Record Length": db.header.maxCity,
+			"Total Data Size":   db.header.citySize,
 		},
 		"Region Meta": map[string]interface{}{
-			"Max Record Length": s.header.maxRegion,
-			"Total Data Size":   s.header.regionSize,
+			"Max Record Length": db.header.maxRegion,
+			"Total Data Size":   db.header.regionSize,
 		},
 		"Country Meta": map[string]interface{}{
-			"Max Record Length": s.header.maxCountry,
-			"Total Data Size":   s.header.countrySize, // Often 0 in v2.2 as country data is with cities
+			"Max Record Length": db.header.maxCountry,
+			"Total Data Size":   db.header.countrySize, // Part of the cities block unless stored separately
 		},
 	}
+	if info.License != "" {
+		about["License"] = info.License
+	}
+	if info.Attribution != "" {
+		about["Attribution"] = info.Attribution
+	}
+	return about
 }
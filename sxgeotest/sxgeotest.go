@@ -0,0 +1,242 @@
+// Package sxgeotest checks that the ways of opening a Sypex Geo database
+// answer identically, for use in the tests of programs built on sxgo and of
+// sxgo itself.
+//
+// RunConformance opens one database file in every supported configuration:
+// ModeFile, ModeMemory, ModeMemory|ModeBatch, ModeMMap, raw indexes in both
+// modes, a record cache, pooled scratch buffers, a snapshot round trip and
+// country-only instances. It then compares their answers for a fixed set of
+// addresses (range edges of every first byte and pseudo-random addresses)
+// against ModeFile, through every lookup method and the planner.
+package sxgeotest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/idanyas/sxgo"
+)
+
+// randomAddresses is the number of pseudo-random addresses checked, on top of
+// the edges of every first byte.
+const randomAddresses = 4096
+
+// maxFailures bounds the mismatches reported per configuration.
+const maxFailures = 10
+
+// variant is one way of opening the database.
+// This struct is internal.
+type variant struct {
+	name        string
+	countryOnly bool // Only country lookups are compared
+	open        func(t *testing.T, path string, opts []sxgo.Option) (*sxgo.SxGeo, error)
+}
+
+// variants lists the configurations compared with the reference.
+var variants = []variant{
+	{name: "memory", open: openMode(sxgo.ModeMemory)},
+	{name: "memory-batch", open: openMode(sxgo.ModeMemory | sxgo.ModeBatch)},
+	{name: "mmap", open: openMode(sxgo.ModeMMap)},
+	{name: "file-raw-indexes", open: openMode(sxgo.ModeFile, sxgo.WithRawIndexes())},
+	{name: "memory-raw-indexes", open: openMode(sxgo.ModeMemory, sxgo.WithRawIndexes())},
+	{name: "file-record-cache", open: openMode(sxgo.ModeFile, sxgo.WithRecordCache(1<<20))},
+	{name: "file-scratch-buffers", open: openMode(sxgo.ModeFile, sxgo.WithScratchBuffers())},
+	{name: "snapshot", open: openSnapshot},
+	{name: "file-country-only", countryOnly: true, open: openMode(sxgo.ModeFile, sxgo.WithCountryOnly())},
+	{name: "memory-country-only", countryOnly: true, open: openMode(sxgo.ModeMemory, sxgo.WithCountryOnly())},
+	{name: "mmap-country-only", countryOnly: true, open: openMode(sxgo.ModeMMap, sxgo.WithCountryOnly())},
+}
+
+// RunConformance checks that every configuration of the database at path
+// returns the results ModeFile returns, reporting mismatches as errors of a
+// subtest per configuration. opts are passed to every instance, e.g. to
+// compare results with post-processing options enabled.
+func RunConformance(t *testing.T, path string, opts ...sxgo.Option) {
+	t.Helper()
+	ref, err := sxgo.New(path, sxgo.ModeFile, opts...)
+	if err != nil {
+		t.Fatalf("opening %s in ModeFile: %v", path, err)
+	}
+	defer ref.Close()
+
+	ips := Addresses()
+	want, err := collect(ref, ips, false)
+	if err != nil {
+		t.Fatalf("ModeFile: %v", err)
+	}
+	for _, v := range variants {
+		t.Run(v.name, func(t *testing.T) {
+			s, err := v.open(t, path, opts)
+			if err != nil {
+				t.Fatalf("opening %s: %v", path, err)
+			}
+			defer s.Close()
+			got, err := collect(s, ips, v.countryOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			compare(t, ips, want, got, v.countryOnly)
+		})
+	}
+}
+
+// Addresses returns the addresses RunConformance checks: the first, second
+// and last address of every first byte, then pseudo-random addresses from a
+// fixed seed.
+func Addresses() []string {
+	var ips []string
+	for b := 0; b < 256; b++ {
+		for _, suffix := range []uint32{0, 1, 0xFFFFFF} {
+			ips = append(ips, addr(uint32(b)<<24|suffix))
+		}
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < randomAddresses; i++ {
+		ips = append(ips, addr(r.Uint32()))
+	}
+	return ips
+}
+
+// results holds the answers of one instance, indexed like the addresses.
+// This struct is internal.
+type results struct {
+	countryID       []uint32
+	city, full, reg []*sxgo.LocationInfo
+	planCity        []*sxgo.LocationInfo
+	planFull        []*sxgo.LocationInfo
+}
+
+// collect queries s for every address.
+// Internal function.
+func collect(s *sxgo.SxGeo, ips []string, countryOnly bool) (*results, error) {
+	r := &results{countryID: make([]uint32, len(ips))}
+	for i, ip := range ips {
+		id, err := s.GetCountryID(ip)
+		if err != nil {
+			return nil, fmt.Errorf("GetCountryID(%s): %w", ip, err)
+		}
+		r.countryID[i] = id
+	}
+	if countryOnly {
+		return r, nil
+	}
+
+	lookups := []struct {
+		name string
+		dst  *[]*sxgo.LocationInfo
+		fn   func(string, ...sxgo.CallOption) (*sxgo.LocationInfo, error)
+	}{
+		{"GetCity", &r.city, s.GetCity},
+		{"GetCityFull", &r.full, s.GetCityFull},
+		{"GetCityRegion", &r.reg, s.GetCityRegion},
+	}
+	for _, l := range lookups {
+		*l.dst = make([]*sxgo.LocationInfo, len(ips))
+		for i, ip := range ips {
+			info, err := l.fn(ip)
+			if err != nil {
+				return nil, fmt.Errorf("%s(%s): %w", l.name, ip, err)
+			}
+			(*l.dst)[i] = info
+		}
+	}
+
+	var err error
+	if r.planCity, _, err = s.NewPlanner(false).Lookup(ips); err != nil {
+		return nil, fmt.Errorf("planner: %w", err)
+	}
+	if r.planFull, _, err = s.NewPlanner(true).Lookup(ips); err != nil {
+		return nil, fmt.Errorf("full planner: %w", err)
+	}
+	return r, nil
+}
+
+// compare reports the differences between want and got.
+// Internal function.
+func compare(t *testing.T, ips []string, want, got *results, countryOnly bool) {
+	t.Helper()
+	failures := 0
+	report := func(method, ip string, want, got any) {
+		t.Helper()
+		if failures++; failures <= maxFailures {
+			t.Errorf("%s(%s) = %s, want %s", method, ip, format(got), format(want))
+		}
+	}
+	for i, ip := range ips {
+		if want.countryID[i] != got.countryID[i] {
+			report("GetCountryID", ip, want.countryID[i], got.countryID[i])
+		}
+		if countryOnly {
+			continue
+		}
+		checks := []struct {
+			method    string
+			want, got *sxgo.LocationInfo
+		}{
+			{"GetCity", want.city[i], got.city[i]},
+			{"GetCityFull", want.full[i], got.full[i]},
+			{"GetCityRegion", want.reg[i], got.reg[i]},
+			{"Planner(false).Lookup", want.city[i], got.planCity[i]},
+			{"Planner(true).Lookup", want.full[i], got.planFull[i]},
+		}
+		for _, c := range checks {
+			if !reflect.DeepEqual(c.want, c.got) {
+				report(c.method, ip, c.want, c.got)
+			}
+		}
+	}
+	if failures > maxFailures {
+		t.Errorf("... and %d more mismatches", failures-maxFailures)
+	}
+}
+
+// format renders a result for error messages.
+// Internal function.
+func format(v any) string {
+	if info, ok := v.(*sxgo.LocationInfo); ok && info != nil {
+		return fmt.Sprintf("%+v", info.Summarize())
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// openMode returns an opener for New with mode and extra options.
+// Internal function.
+func openMode(mode uint, extra ...sxgo.Option) func(*testing.T, string, []sxgo.Option) (*sxgo.SxGeo, error) {
+	return func(_ *testing.T, path string, opts []sxgo.Option) (*sxgo.SxGeo, error) {
+		return sxgo.New(path, mode, append(append([]sxgo.Option(nil), opts...), extra...)...)
+	}
+}
+
+// openSnapshot writes a snapshot of the database and loads it.
+// Internal function.
+func openSnapshot(t *testing.T, path string, opts []sxgo.Option) (*sxgo.SxGeo, error) {
+	s, err := sxgo.New(path, sxgo.ModeFile, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	snap := filepath.Join(t.TempDir(), "db.snapshot")
+	f, err := os.Create(snap)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.WriteSnapshot(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return sxgo.NewFromSnapshot(snap, opts...)
+}
+
+// addr formats an IPv4 address given as an integer.
+// Internal function.
+func addr(ip uint32) string {
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}).String()
+}
@@ -0,0 +1,158 @@
+package sxgo
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// syntheticEntry pairs a prefix with the fixed result returned for it.
+type syntheticEntry struct {
+	prefix netip.Prefix
+	loc    LocationInfo
+}
+
+// TestLocations returns the synthetic locations used by WithTestLocations:
+// the RFC 5737 documentation ranges mapped to fixed, well-known cities.
+//
+//	192.0.2.0/24    (TEST-NET-1)  Moscow, RU
+//	198.51.100.0/24 (TEST-NET-2)  Berlin, DE
+//	203.0.113.0/24  (TEST-NET-3)  New York, US
+//
+// City and Region IDs are 0 as no database record backs these results.
+// A fresh map is returned on each call, so callers may modify it.
+func TestLocations() map[netip.Prefix]LocationInfo {
+	return map[netip.Prefix]LocationInfo{
+		netip.MustParsePrefix("192.0.2.0/24"): {
+			City:    &City{Lat: 55.75222, Lon: 37.61556, NameRU: "Москва", NameEN: "Moscow"},
+			Region:  &Region{NameRU: "Москва", NameEN: "Moscow", ISO: "RU-MOW"},
+			Country: &Country{ID: getIDByISO("RU"), ISO: "RU", Lat: 60, Lon: 100, NameRU: "Россия", NameEN: "Russia"},
+		},
+		netip.MustParsePrefix("198.51.100.0/24"): {
+			City:    &City{Lat: 52.52437, Lon: 13.41053, NameRU: "Берлин", NameEN: "Berlin"},
+			Region:  &Region{NameRU: "Берлин", NameEN: "Berlin", ISO: "DE-BE"},
+			Country: &Country{ID: getIDByISO("DE"), ISO: "DE", Lat: 51, Lon: 9, NameRU: "Германия", NameEN: "Germany"},
+		},
+		netip.MustParsePrefix("203.0.113.0/24"): {
+			City:    &City{Lat: 40.71427, Lon: -74.00597, NameRU: "Нью-Йорк", NameEN: "New York"},
+			Region:  &Region{NameRU: "Нью-Йорк", NameEN: "New York", ISO: "US-NY"},
+			Country: &Country{ID: getIDByISO("US"), ISO: "US", Lat: 38, Lon: -97, NameRU: "США", NameEN: "United States"},
+		},
+	}
+}
+
+// WithTestLocations answers lookups for the documentation ranges with the fixed
+// results from TestLocations instead of whatever the database contains, so
+// end-to-end tests and CI get stable, meaningful results.
+func WithTestLocations() Option {
+	return WithSyntheticLocations(TestLocations())
+}
+
+// WithSyntheticLocations answers lookups for IPv4 addresses inside the given
+// prefixes with fixed results, bypassing the database. When prefixes overlap,
+// the most specific one wins. GetCountry/GetCountryID report the synthetic
+// country; GetCity omits the synthetic region as it does for real lookups.
+// Country ID and ISO are derived from each other when only one is set.
+// Non-IPv4 prefixes are ignored. The map is copied.
+func WithSyntheticLocations(locs map[netip.Prefix]LocationInfo) Option {
+	return func(s *SxGeo) {
+		for p, loc := range locs {
+			p = p.Masked()
+			if !p.Addr().Is4() {
+				continue
+			}
+			c := loc.clone()
+			if c.Country != nil { // Fill in whichever of ID/ISO the caller left out
+				if c.Country.ID == 0 {
+					c.Country.ID = getIDByISO(c.Country.ISO)
+				}
+				if c.Country.ISO == "" {
+					c.Country.ISO = getISO(uint32(c.Country.ID))
+				}
+			}
+			s.synthetic = append(s.synthetic, syntheticEntry{prefix: p, loc: *c})
+		}
+		// Most specific prefixes first, so the first match is the best one
+		sort.SliceStable(s.synthetic, func(i, j int) bool {
+			return s.synthetic[i].prefix.Bits() > s.synthetic[j].prefix.Bits()
+		})
+	}
+}
+
+// syntheticLocation returns a copy of the synthetic result configured for ip, if any.
+// full=false drops the region, mirroring GetCity.
+// Internal function.
+func (s *SxGeo) syntheticLocation(ip string, full bool) (*LocationInfo, bool) {
+	e, ok := s.syntheticMatch(ip)
+	if !ok {
+		return nil, false
+	}
+	loc := e.loc.clone()
+	if !full {
+		loc.Region = nil
+	}
+	loc.setPrecision()
+	return loc, true
+}
+
+// syntheticMatch returns the most specific synthetic entry containing ip, if any.
+// Internal function.
+func (s *SxGeo) syntheticMatch(ip string) (syntheticEntry, bool) {
+	if len(s.synthetic) == 0 {
+		return syntheticEntry{}, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return syntheticEntry{}, false
+	}
+	addr = addr.Unmap()
+	for _, e := range s.synthetic {
+		if e.prefix.Contains(addr) {
+			return e, true
+		}
+	}
+	return syntheticEntry{}, false
+}
+
+// clone returns a deep copy of l, so callers can modify it freely.
+// Internal function.
+func (l *LocationInfo) clone() *LocationInfo {
+	c := *l
+	if l.City != nil {
+		city := *l.City
+		c.City = &city
+	}
+	if l.Region != nil {
+		region := *l.Region
+		if region.District != nil {
+			district := *region.District
+			region.District = &district
+		}
+		c.Region = &region
+	}
+	if l.Country != nil {
+		country := *l.Country
+		if country.Regulation != nil {
+			regulation := *country.Regulation
+			country.Regulation = &regulation
+		}
+		c.Country = &country
+	}
+	if l.Range != nil {
+		r := *l.Range
+		c.Range = &r
+	}
+	if l.ValidUntil != nil {
+		t := *l.ValidUntil
+		c.ValidUntil = &t
+	}
+	if l.Warnings != nil {
+		c.Warnings = append([]string(nil), l.Warnings...)
+	}
+	if l.Annotations != nil {
+		c.Annotations = make(map[string]string, len(l.Annotations))
+		for k, v := range l.Annotations {
+			c.Annotations[k] = v
+		}
+	}
+	return &c
+}
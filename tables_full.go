@@ -0,0 +1,78 @@
+//go:build !sxgo_minimal
+
+package sxgo
+
+// euVATRates lists the standard VAT rates of EU member states in percent.
+var euVATRates = map[string]float64{
+	"AT": 20, "BE": 21, "BG": 20, "CY": 19, "CZ": 21, "DE": 19, "DK": 25, "EE": 24, "ES": 21,
+	"FI": 25.5, "FR": 20, "GR": 24, "HR": 25, "HU": 27, "IE": 23, "IT": 22, "LT": 21, "LU": 17,
+	"LV": 21, "MT": 18, "NL": 21, "PL": 23, "PT": 23, "RO": 21, "SE": 25, "SI": 22, "SK": 23,
+}
+
+// ruDistricts lists the Russian federal districts and their regions.
+var ruDistricts = []struct {
+	District
+	regions []string
+}{
+	{District{"central", "Центральный федеральный округ", "Central Federal District"}, []string{
+		"RU-BEL", "RU-BRY", "RU-VLA", "RU-VOR", "RU-IVA", "RU-KLU", "RU-KOS", "RU-KRS", "RU-LIP",
+		"RU-MOS", "RU-MOW", "RU-ORL", "RU-RYA", "RU-SMO", "RU-TAM", "RU-TVE", "RU-TUL", "RU-YAR",
+	}},
+	{District{"northwestern", "Северо-Западный федеральный округ", "Northwestern Federal District"}, []string{
+		"RU-KR", "RU-KO", "RU-ARK", "RU-NEN", "RU-VLG", "RU-KGD", "RU-LEN", "RU-SPE", "RU-MUR",
+		"RU-NGR", "RU-PSK",
+	}},
+	{District{"southern", "Южный федеральный округ", "Southern Federal District"}, []string{
+		"RU-AD", "RU-KL", "RU-KDA", "RU-AST", "RU-VGG", "RU-ROS",
+	}},
+	{District{"north_caucasian", "Северо-Кавказский федеральный округ", "North Caucasian Federal District"}, []string{
+		"RU-DA", "RU-IN", "RU-KB", "RU-KC", "RU-SE", "RU-CE", "RU-STA",
+	}},
+	{District{"volga", "Приволжский федеральный округ", "Volga Federal District"}, []string{
+		"RU-BA", "RU-ME", "RU-MO", "RU-TA", "RU-UD", "RU-CU", "RU-PER", "RU-KIR", "RU-NIZ",
+		"RU-ORE", "RU-PNZ", "RU-SAM", "RU-SAR", "RU-ULY",
+	}},
+	{District{"ural", "Уральский федеральный округ", "Ural Federal District"}, []string{
+		"RU-KGN", "RU-SVE", "RU-TYU", "RU-KHM", "RU-YAN", "RU-CHE",
+	}},
+	{District{"siberian", "Сибирский федеральный округ", "Siberian Federal District"}, []string{
+		"RU-AL", "RU-TY", "RU-KK", "RU-ALT", "RU-KYA", "RU-IRK", "RU-KEM", "RU-NVS", "RU-OMS", "RU-TOM",
+	}},
+	{District{"far_eastern", "Дальневосточный федеральный округ", "Far Eastern Federal District"}, []string{
+		"RU-BU", "RU-ZAB", "RU-SA", "RU-KAM", "RU-PRI", "RU-KHA", "RU-AMU", "RU-MAG", "RU-SAK",
+		"RU-YEV", "RU-CHU",
+	}},
+}
+
+// regulations lists the regulation of the EEA, the United Kingdom and other
+// major jurisdictions; Country is filled in on lookup.
+var regulations = RegulationTable{
+	// EEA: GDPR, with the national age of digital consent (Article 8)
+	"AT": {Law: "GDPR", GDPR: true, ConsentAge: 14}, "BE": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"BG": {Law: "GDPR", GDPR: true, ConsentAge: 14}, "CY": {Law: "GDPR", GDPR: true, ConsentAge: 14},
+	"CZ": {Law: "GDPR", GDPR: true, ConsentAge: 15}, "DE": {Law: "GDPR", GDPR: true, ConsentAge: 16},
+	"DK": {Law: "GDPR", GDPR: true, ConsentAge: 13}, "EE": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"ES": {Law: "GDPR", GDPR: true, ConsentAge: 14}, "FI": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"FR": {Law: "GDPR", GDPR: true, ConsentAge: 15}, "GR": {Law: "GDPR", GDPR: true, ConsentAge: 15},
+	"HR": {Law: "GDPR", GDPR: true, ConsentAge: 16}, "HU": {Law: "GDPR", GDPR: true, ConsentAge: 16},
+	"IE": {Law: "GDPR", GDPR: true, ConsentAge: 16}, "IT": {Law: "GDPR", GDPR: true, ConsentAge: 14},
+	"LT": {Law: "GDPR", GDPR: true, ConsentAge: 14}, "LU": {Law: "GDPR", GDPR: true, ConsentAge: 16},
+	"LV": {Law: "GDPR", GDPR: true, ConsentAge: 13}, "MT": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"NL": {Law: "GDPR", GDPR: true, ConsentAge: 16}, "PL": {Law: "GDPR", GDPR: true, ConsentAge: 16},
+	"PT": {Law: "GDPR", GDPR: true, ConsentAge: 13}, "RO": {Law: "GDPR", GDPR: true, ConsentAge: 16},
+	"SE": {Law: "GDPR", GDPR: true, ConsentAge: 13}, "SI": {Law: "GDPR", GDPR: true, ConsentAge: 15},
+	"SK": {Law: "GDPR", GDPR: true, ConsentAge: 16}, "IS": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"LI": {Law: "GDPR", GDPR: true, ConsentAge: 16}, "NO": {Law: "GDPR", GDPR: true, ConsentAge: 13},
+	"GB": {Law: "UK GDPR", GDPR: true, ConsentAge: 13},
+
+	"US": {Law: "COPPA", ConsentAge: 13, Regional: true},
+	"BR": {Law: "LGPD", ConsentAge: 12},
+	"CN": {Law: "PIPL", ConsentAge: 14},
+	"KR": {Law: "PIPA", ConsentAge: 14},
+	"IN": {Law: "DPDP Act", ConsentAge: 18},
+	"ZA": {Law: "POPIA", ConsentAge: 18},
+	"CH": {Law: "FADP"},
+	"CA": {Law: "PIPEDA", Regional: true},
+	"JP": {Law: "APPI"},
+	"RU": {Law: "152-FZ"},
+}
@@ -0,0 +1,14 @@
+//go:build sxgo_minimal
+
+package sxgo
+
+// The sxgo_minimal build leaves out the enrichment tables: EUVATRates,
+// RussianFederalDistricts and DefaultRegulations return empty tables.
+var (
+	euVATRates  map[string]float64
+	regulations RegulationTable
+	ruDistricts []struct {
+		District
+		regions []string
+	}
+)
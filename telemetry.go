@@ -0,0 +1,65 @@
+package sxgo
+
+import "time"
+
+// Telemetry receives the instrumentation of an instance (see WithTelemetry).
+// It is all the core calls, so metrics libraries plug in through small
+// adapters instead of becoming dependencies of sxgo: the expvartelemetry
+// package publishes through expvar, and the prometheus and otel modules of
+// this repository adapt Prometheus and OpenTelemetry. Methods are called on
+// the lookup path and must be safe for concurrent use.
+type Telemetry interface {
+	// CounterAdd adds delta to the counter name.
+	CounterAdd(name string, delta uint64)
+	// ObserveDuration records one observation d of the distribution name.
+	ObserveDuration(name string, d time.Duration)
+}
+
+// Metric names reported to Telemetry.
+const (
+	MetricLookups           = "sxgo_lookups_total"             // Counter: address lookups reaching the database, as Stats.Lookups.
+	MetricLookupErrors      = "sxgo_lookup_errors_total"       // Counter: lookups failing to read the database.
+	MetricLookupDuration    = "sxgo_lookup_duration_seconds"   // Duration: resolving an address, as in the slow lookup log.
+	MetricFileReads         = "sxgo_file_reads_total"          // Counter: file reads after New, as IOStats.Reads.
+	MetricFileReadBytes     = "sxgo_file_read_bytes_total"     // Counter: bytes of those reads, as IOStats.Bytes.
+	MetricRecordCacheHits   = "sxgo_record_cache_hits_total"   // Counter: record reads answered by WithRecordCache.
+	MetricRecordCacheMisses = "sxgo_record_cache_misses_total" // Counter: record reads the cache could not answer.
+)
+
+// MetricInfo describes a metric reported to Telemetry.
+type MetricInfo struct {
+	Name     string // One of the Metric constants.
+	Help     string // One-line description.
+	Duration bool   // Reported through ObserveDuration rather than CounterAdd.
+}
+
+// Metrics lists the metrics reported to Telemetry, for adapters registering
+// them up front.
+func Metrics() []MetricInfo {
+	return []MetricInfo{
+		{Name: MetricLookups, Help: "Address lookups reaching the database."},
+		{Name: MetricLookupErrors, Help: "Lookups failing to read the database."},
+		{Name: MetricLookupDuration, Help: "Time spent resolving an address.", Duration: true},
+		{Name: MetricFileReads, Help: "Database file reads after opening."},
+		{Name: MetricFileReadBytes, Help: "Bytes of database file reads after opening."},
+		{Name: MetricRecordCacheHits, Help: "Record reads answered by the record cache."},
+		{Name: MetricRecordCacheMisses, Help: "Record reads the record cache could not answer."},
+	}
+}
+
+// WithTelemetry reports lookup counts and durations, file reads and record
+// cache use to t. Lookups resolved in a batch by a Planner count without a
+// duration.
+func WithTelemetry(t Telemetry) Option {
+	return func(s *SxGeo) {
+		s.telemetry = t
+	}
+}
+
+// count adds delta to the counter name of the telemetry, if any.
+// Internal function.
+func (s *SxGeo) count(name string, delta uint64) {
+	if s.telemetry != nil {
+		s.telemetry.CounterAdd(name, delta)
+	}
+}
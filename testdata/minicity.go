@@ -0,0 +1,109 @@
+//go:build ignore
+
+// Command minicity writes minicity.dat, the small City database the tests of
+// sxgo embed (see reference_test.go). Run it from the module root:
+//
+//	go run testdata/minicity.go
+//
+// The database has cp1251 names, a city without a region, a city with zero
+// coordinates, a country-level range, an unknown range, a first-byte window
+// starting above its first address, a window larger than one main index
+// partition, and blocks at both ends of the table.
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/idanyas/sxgo/dbwriter"
+)
+
+func main() {
+	ru := &dbwriter.Country{ID: 185, ISO: "RU", Lat: 60, Lon: 100, NameRU: cp1251("Россия"), NameEN: "Russia"}
+	ua := &dbwriter.Country{ID: 222, ISO: "UA", Lat: 49, Lon: 32, NameRU: cp1251("Украина"), NameEN: "Ukraine"}
+	de := &dbwriter.Country{ID: 56, ISO: "DE", Lat: 51.5, Lon: 10.5, NameRU: cp1251("Германия"), NameEN: "Germany"}
+	us := &dbwriter.Country{ID: 225, ISO: "US", Lat: 38, Lon: -97, NameRU: cp1251("США"), NameEN: "United States"}
+
+	mow := &dbwriter.Region{ID: 524894, Country: ru, ISO: "RU-MOW", NameRU: cp1251("Москва"), NameEN: "Moskva"}
+	ta := &dbwriter.Region{ID: 484048, Country: ru, ISO: "RU-TA", NameRU: cp1251("Татарстан"), NameEN: "Tatarstan"}
+	kv := &dbwriter.Region{ID: 703447, Country: ua, ISO: "UA-30", NameRU: cp1251("Киев"), NameEN: "Kyiv"}
+	by := &dbwriter.Region{ID: 2951839, Country: de, ISO: "DE-BY", NameRU: cp1251("Бавария"), NameEN: "Bavaria"}
+	ca := &dbwriter.Region{ID: 5332921, Country: us, ISO: "US-CA", NameRU: cp1251("Калифорния"), NameEN: "California"}
+
+	moscow := &dbwriter.City{ID: 524901, Region: mow, CountryID: 185, Lat: 55.75222, Lon: 37.61556, NameRU: cp1251("Москва"), NameEN: "Moscow"}
+	kazan := &dbwriter.City{ID: 551487, Region: ta, CountryID: 185, Lat: 55.78874, Lon: 49.12214, NameRU: cp1251("Казань"), NameEN: "Kazan"}
+	kyiv := &dbwriter.City{ID: 703448, Region: kv, CountryID: 222, Lat: 50.45466, Lon: 30.5238, NameRU: cp1251("Киев"), NameEN: "Kyiv"}
+	munich := &dbwriter.City{ID: 2867714, Region: by, CountryID: 56, Lat: 48.13743, Lon: 11.57549, NameRU: cp1251("Мюнхен"), NameEN: "Munich"}
+	la := &dbwriter.City{ID: 5368361, Region: ca, CountryID: 225, Lat: 34.05223, Lon: -118.24368, NameRU: cp1251("Лос-Анджелес"), NameEN: "Los Angeles"}
+	sochi := &dbwriter.City{ID: 491422, CountryID: 185, Lat: 43.60281, Lon: 39.73415, NameRU: cp1251("Сочи"), NameEN: "Sochi"}
+	zelenograd := &dbwriter.City{ID: 463343, Region: mow, CountryID: 185, NameRU: cp1251("Зеленоград"), NameEN: "Zelenograd"}
+
+	db := &dbwriter.Database{
+		Type:      dbwriter.TypeCity,
+		Charset:   dbwriter.CharsetCP1251,
+		Created:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Countries: []*dbwriter.Country{ru, ua, de, us},
+		Regions:   []*dbwriter.Region{mow, ta, kv, by, ca},
+		Cities:    []*dbwriter.City{moscow, kazan, kyiv, munich, la, sochi, zelenograd},
+	}
+	add := func(start string, city *dbwriter.City, country *dbwriter.Country) {
+		db.Blocks = append(db.Blocks, dbwriter.Block{Start: ip(start), City: city, Country: country})
+	}
+	add("1.0.0.0", la, nil) // First block of the table
+	add("1.0.128.0", nil, nil)
+	add("5.0.0.0", nil, ua) // Known to country level only
+	add("5.1.0.0", kyiv, nil)
+	add("46.0.16.0", sochi, nil) // Below it, 46/8 resolves to the block before the window; Sochi has no region
+	add("46.0.64.0", zelenograd, nil)
+	add("46.0.128.0", moscow, nil)
+	// More blocks than one main index partition holds
+	for i := 0; i < 20; i++ {
+		city := moscow
+		if i%2 == 1 {
+			city = kazan
+		}
+		db.Blocks = append(db.Blocks, dbwriter.Block{Start: ip("93.158.0.0") + uint32(i)<<8, City: city})
+	}
+	add("93.158.20.0", nil, nil)
+	add("100.64.0.0", moscow, nil) // CGNAT space the database locates
+	add("100.128.0.0", nil, nil)
+	add("185.0.0.0", nil, de)
+	add("223.255.255.0", munich, nil) // Last block of the table
+
+	data, err := db.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("testdata/minicity.dat", data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ip parses an IPv4 address.
+func ip(s string) uint32 {
+	a := netip.MustParseAddr(s).As4()
+	return binary.BigEndian.Uint32(a[:])
+}
+
+// cp1251 encodes the Cyrillic and ASCII string s in Windows-1251.
+func cp1251(s string) string {
+	var b []byte
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			b = append(b, byte(r))
+		case r >= 'А' && r <= 'я':
+			b = append(b, byte(r-'А'+0xC0))
+		case r == 'Ё':
+			b = append(b, 0xA8)
+		case r == 'ё':
+			b = append(b, 0xB8)
+		default:
+			log.Fatalf("no cp1251 code for %q", r)
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,124 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// errDirectIOUnsupported is returned by New when WithDirectIO is used on a
+// platform without O_DIRECT.
+var errDirectIOUnsupported = errors.New("direct I/O is not supported on this platform")
+
+// directIOAlignment is the read alignment used with WithDirectIO unless
+// WithReadAlignment sets another one. It matches the logical block size of
+// common storage devices.
+const directIOAlignment = 4096
+
+// fileTuning holds the ModeFile platform tuning options.
+// This struct is internal.
+type fileTuning struct {
+	fadviseRandom bool // posix_fadvise(POSIX_FADV_RANDOM) on the handle
+	directIO      bool // Reopen the database with O_DIRECT for lookups
+	alignment     int  // Align offset, length and buffer of every read (0: none)
+}
+
+// WithFadviseRandom tells the kernel that ModeFile reads are random
+// (posix_fadvise POSIX_FADV_RANDOM), disabling read-ahead. It is a hint and
+// has no effect on platforms without posix_fadvise.
+func WithFadviseRandom() Option {
+	return func(s *SxGeo) {
+		s.tuning.fadviseRandom = true
+	}
+}
+
+// WithDirectIO makes ModeFile lookups bypass the page cache by reading through
+// a handle opened with O_DIRECT (Linux only; New fails elsewhere). Reads are
+// aligned to 4096 bytes unless WithReadAlignment sets another alignment.
+// Only worth it on fast dedicated storage, as every lookup then hits the device.
+func WithDirectIO() Option {
+	return func(s *SxGeo) {
+		s.tuning.directIO = true
+	}
+}
+
+// WithReadAlignment aligns the offset, length and memory buffer of every
+// ModeFile read to n bytes, which must be a power of two. Reads are widened
+// to the enclosing aligned range.
+func WithReadAlignment(n int) Option {
+	return func(s *SxGeo) {
+		s.tuning.alignment = n
+	}
+}
+
+// applyFileTuning applies the tuning options to the ModeFile handle of db once New
+// has read the header and indexes through it.
+// Internal function.
+func (s *SxGeo) applyFileTuning(db *dbState, dbFile string) error {
+	t := &s.tuning
+	if t.alignment < 0 || t.alignment&(t.alignment-1) != 0 {
+		return fmt.Errorf("read alignment %d is not a power of two", t.alignment)
+	}
+	if t.directIO {
+		f, err := openDirect(dbFile)
+		if err != nil {
+			return err
+		}
+		db.f.Close()
+		db.f = f
+		if t.alignment == 0 {
+			t.alignment = directIOAlignment
+		}
+	}
+	if t.fadviseRandom {
+		if err := fadviseRandom(db.f); err != nil {
+			return fmt.Errorf("fadvise: %w", err)
+		}
+	}
+	return nil
+}
+
+// alignedReadAt is f.ReadAt issuing only reads whose offset, length and
+// buffer address are multiples of align (as O_DIRECT requires).
+// align <= 1 reads directly.
+// Internal function.
+func alignedReadAt(f *os.File, buf []byte, off int64, align int) (int, error) {
+	if align <= 1 || len(buf) == 0 {
+		return f.ReadAt(buf, off)
+	}
+
+	a := int64(align)
+	start := off &^ (a - 1)
+	end := (off + int64(len(buf)) + a - 1) &^ (a - 1)
+	tmp := alignedBuffer(int(end-start), align)
+	n, err := f.ReadAt(tmp, start)
+
+	skip := int(off - start)
+	if n <= skip {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	m := copy(buf, tmp[skip:n])
+	if m == len(buf) {
+		return m, nil // The widened read may hit EOF past the requested bytes
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	return m, err
+}
+
+// alignedBuffer returns a size-byte slice whose first byte is aligned to align.
+// Internal function.
+func alignedBuffer(size, align int) []byte {
+	b := make([]byte, size+align)
+	shift := 0
+	if r := int(uintptr(unsafe.Pointer(&b[0])) & uintptr(align-1)); r != 0 {
+		shift = align - r
+	}
+	return b[shift : shift+size : shift+size]
+}
@@ -0,0 +1,12 @@
+package sxgo
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens name read-only with O_DIRECT.
+// Internal function.
+func openDirect(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_RDONLY|syscall.O_DIRECT, 0)
+}
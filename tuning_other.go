@@ -0,0 +1,17 @@
+//go:build !linux
+
+package sxgo
+
+import "os"
+
+// openDirect is not available on this platform.
+// Internal function.
+func openDirect(name string) (*os.File, error) {
+	return nil, errDirectIOUnsupported
+}
+
+// fadviseRandom is a no-op on this platform.
+// Internal function.
+func fadviseRandom(f *os.File) error {
+	return nil
+}
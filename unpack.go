@@ -15,11 +15,27 @@ import (
 // Assumes LittleEndian for multi-byte fields within packed data based on observed PHP behavior.
 // Internal function.
 func unpack(format string, data []byte) (map[string]interface{}, error) {
+	result, _, err := unpackLen(format, data)
+	return result, err
+}
+
+// unpackLen is unpack that also returns the number of bytes consumed, so
+// consecutive records can be walked.
+// Internal function.
+func unpackLen(format string, data []byte) (map[string]interface{}, int, error) {
+	return unpackSelect(format, data, nil)
+}
+
+// unpackSelect is unpackLen decoding only the string fields for which keep
+// returns true (all of them if keep is nil); other strings are skipped
+// without allocating. Numeric fields are always decoded.
+// Internal function.
+func unpackSelect(format string, data []byte, keep func(name string) bool) (map[string]interface{}, int, error) {
 	if len(data) == 0 {
-		return make(map[string]interface{}), nil // Nothing to unpack
+		return make(map[string]interface{}), 0, nil // Nothing to unpack
 	}
 	if format == "" {
-		return nil, errors.New("unpack format string is empty")
+		return nil, 0, errors.New("unpack format string is empty")
 	}
 
 	result := make(map[string]interface{})
@@ -38,7 +54,7 @@ func unpack(format string, data []byte) (map[string]interface{}, error) {
 
 		spec := strings.SplitN(part, ":", 2)
 		if len(spec) != 2 {
-			return result, fmt.Errorf("invalid unpack format part: %q in format %q", part, format)
+			return result, offset, fmt.Errorf("invalid unpack format part: %q in format %q", part, format)
 		}
 		typeFormat, name := spec[0], spec[1]
 
@@ -164,7 +180,9 @@ func unpack(format string, data []byte) (map[string]interface{}, error) {
 				// err = io.ErrUnexpectedEOF // Keep track that we hit the end
 			}
 			// Trim trailing null bytes and potentially spaces based on observed data
-			value = strings.TrimRight(string(data[offset:offset+length]), "\x00 ")
+			if keep == nil || keep(name) {
+				value = strings.TrimRight(string(data[offset:offset+length]), "\x00 ")
+			}
 		case 'b': // null-terminated string
 			end := offset
 			for end < dataLen && data[end] != 0 {
@@ -172,14 +190,15 @@ func unpack(format string, data []byte) (map[string]interface{}, error) {
 			}
 			if end >= dataLen {
 				// No null terminator found within available data. Read rest as string.
-				value = string(data[offset:])
 				length = dataLen - offset
 				// err = errors.New("null terminator not found for 'b' type") // Informative error?
 			} else {
 				// Null terminator found at 'end'
-				value = string(data[offset:end])
 				length = (end - offset) + 1 // Consume the null terminator as well
 			}
+			if keep == nil || keep(name) {
+				value = string(data[offset:end])
+			}
 		default:
 			err = fmt.Errorf("unsupported format specifier: %q", typeCode)
 		} // end switch
@@ -192,15 +211,17 @@ func unpack(format string, data []byte) (map[string]interface{}, error) {
 			if errors.Is(err, io.ErrUnexpectedEOF) {
 				errContext = fmt.Errorf("field %q (format %q): unexpected end of data (offset %d, need %d, total %d)", name, typeFormat, offset, length, dataLen)
 			}
-			return result, errContext // Return partially unpacked data and the error
+			return result, offset, errContext // Return partially unpacked data and the error
 		}
 
-		result[name] = value
+		if value != nil { // Skipped strings are left out
+			result[name] = value
+		}
 		offset += length
 
 	} // end for loop over parts
 
-	return result, nil // Return fully unpacked data
+	return result, offset, nil // Return fully unpacked data
 }
 
 // --- Helper Getters for Unpacked Map ---
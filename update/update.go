@@ -0,0 +1,338 @@
+// Package update keeps a Sypex Geo database current by downloading releases
+// from a configurable URL and installing them in place of the database file
+// a service uses, optionally reloading it (see sxgo.SxGeo.Reload):
+//
+//	u := &update.Updater{Path: "/var/lib/geo/SxGeoCity.dat", Geo: geo}
+//	go u.Run(ctx, 24*time.Hour)
+//
+// Downloads may be a .dat file, a bundle (sxgo.BundleExt) or a .zip archive
+// holding the database, as sypexgeo.net ships them. They are checked against
+// the expected size and SHA-256 digest when given, must open with sxgo.New,
+// and replace the installed file atomically and only when newer (see
+// sxgo.ImportDatabase). Only the standard library is used.
+package update
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/idanyas/sxgo"
+)
+
+// DefaultURL is downloaded when Updater.URL is empty: the free City database
+// from sypexgeo.net.
+const DefaultURL = "https://sypexgeo.net/files/SxGeoCity_utf8.zip"
+
+// DefaultMaxSize bounds downloads when Updater.MaxSize is zero.
+const DefaultMaxSize = 1 << 30
+
+// Updater downloads and installs database updates. Its fields must not be
+// changed once it is in use; Update and Run are safe for concurrent use.
+type Updater struct {
+	URL  string // Download URL; DefaultURL if empty.
+	Path string // Installed database the download replaces; a bundle if it has the sxgo.BundleExt extension.
+
+	// Expected size in bytes and hex SHA-256 digest of the download (the
+	// archive, for .zip URLs), checked when set. ChecksumURL names a file
+	// holding the digest in sha256sum format instead, fetched with each
+	// download.
+	Size        int64
+	SHA256      string
+	ChecksumURL string
+
+	MaxSize int64        // Downloads larger than this fail; DefaultMaxSize if zero.
+	Client  *http.Client // HTTP client; http.DefaultClient if nil.
+	Geo     *sxgo.SxGeo  // Reloaded after an update if not nil; it must have been opened from Path.
+
+	// Report, if not nil, is called by Run with the outcome of each
+	// update attempt.
+	Report func(*Result, error)
+
+	mu       sync.Mutex // Serializes updates; guards the fields below
+	etag     string     // Of the last download, for conditional requests
+	modified string     // Last-Modified of the last download
+}
+
+// Result describes the outcome of an update.
+type Result struct {
+	URL         string    `json:"url"`
+	NotModified bool      `json:"not_modified"` // The server reported no change since the last download.
+	Size        int64     `json:"size"`         // Bytes downloaded.
+	SHA256      string    `json:"sha256"`       // Hex digest of the download.
+	Created     time.Time `json:"created"`      // Creation time of the downloaded database.
+	Installed   time.Time `json:"installed"`    // Creation time of the database installed before.
+	Updated     bool      `json:"updated"`      // Whether Path was replaced.
+	Reloaded    bool      `json:"reloaded"`     // Whether Geo was reloaded.
+}
+
+// Update downloads the database and installs it at Path if it is newer than
+// the installed one, then reloads Geo. The request is conditional on the
+// previous download (or the modification time of Path), so unchanged
+// releases are not downloaded again. A failed download or check leaves Path
+// untouched.
+func (u *Updater) Update(ctx context.Context) (*Result, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.Path == "" {
+		return nil, errors.New("update: Path is not set")
+	}
+	res := &Result{URL: u.url()}
+	resp, err := u.get(ctx, res.URL, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		res.NotModified = true
+		return res, nil
+	}
+
+	// Downloads are staged next to Path, so installing them is a rename
+	dir := filepath.Dir(u.Path)
+	download, err := os.CreateTemp(dir, "."+filepath.Base(u.Path)+".*.download")
+	if err != nil {
+		return nil, fmt.Errorf("update: failed to stage download: %w", err)
+	}
+	defer os.Remove(download.Name())
+	defer download.Close()
+
+	maxSize := u.maxSize()
+	h := sha256.New()
+	res.Size, err = io.Copy(io.MultiWriter(download, h), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("update: failed to download %s: %w", res.URL, err)
+	}
+	res.SHA256 = hex.EncodeToString(h.Sum(nil))
+	wantSum := strings.ToLower(u.SHA256)
+	if u.ChecksumURL != "" {
+		if wantSum, err = u.fetchChecksum(ctx); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case res.Size > maxSize:
+		return nil, fmt.Errorf("update: %s is larger than %d bytes", res.URL, maxSize)
+	case resp.ContentLength >= 0 && res.Size != resp.ContentLength:
+		return nil, fmt.Errorf("update: %s truncated: got %d of %d bytes", res.URL, res.Size, resp.ContentLength)
+	case u.Size > 0 && res.Size != u.Size:
+		return nil, fmt.Errorf("update: %s has %d bytes, want %d", res.URL, res.Size, u.Size)
+	case wantSum != "" && res.SHA256 != wantSum:
+		return nil, fmt.Errorf("update: %s has SHA-256 %s, want %s", res.URL, res.SHA256, wantSum)
+	}
+
+	staged, err := u.stage(download, res.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(staged)
+	imp, err := sxgo.ImportDatabase(staged, u.Path)
+	if err != nil {
+		return nil, fmt.Errorf("update: failed to install %s: %w", res.URL, err)
+	}
+	res.Created, res.Installed, res.Updated = imp.Created, imp.Installed, imp.Updated
+	u.etag, u.modified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+
+	if res.Updated && u.Geo != nil {
+		if err := u.Geo.Reload(); err != nil {
+			return res, fmt.Errorf("update: installed %s but failed to reload it: %w", res.URL, err)
+		}
+		res.Reloaded = true
+	}
+	return res, nil
+}
+
+// Run calls Update at once and then every interval until ctx is done,
+// passing each outcome to Report; it returns ctx.Err(). A failed attempt is
+// retried after a tenth of interval (at least a minute), so a transient
+// outage does not delay the update by a whole interval.
+func (u *Updater) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		res, err := u.Update(ctx)
+		if u.Report != nil && ctx.Err() == nil {
+			u.Report(res, err)
+		}
+		wait := interval
+		if err != nil {
+			wait = min(interval, max(interval/10, time.Minute))
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// url returns the download URL.
+// Internal function.
+func (u *Updater) url() string {
+	if u.URL == "" {
+		return DefaultURL
+	}
+	return u.URL
+}
+
+// maxSize returns the download size limit.
+// Internal function.
+func (u *Updater) maxSize() int64 {
+	if u.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return u.MaxSize
+}
+
+// get requests rawURL, conditionally on the last download if conditional is
+// set. Responses other than 200 and 304 are errors.
+// Internal function.
+func (u *Updater) get(ctx context.Context, rawURL string, conditional bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("update: invalid URL: %w", err)
+	}
+	if conditional {
+		if u.etag != "" {
+			req.Header.Set("If-None-Match", u.etag)
+		}
+		if u.modified != "" {
+			req.Header.Set("If-Modified-Since", u.modified)
+		} else if fi, err := os.Stat(u.Path); err == nil && u.etag == "" {
+			req.Header.Set("If-Modified-Since", fi.ModTime().UTC().Format(http.TimeFormat))
+		}
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("update: failed to download %s: %w", rawURL, err)
+	}
+	if resp.StatusCode != http.StatusOK && !(conditional && resp.StatusCode == http.StatusNotModified) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("update: failed to download %s: %s", rawURL, resp.Status)
+	}
+	return resp, nil
+}
+
+// fetchChecksum returns the digest ChecksumURL holds: the first field of its
+// first line, as sha256sum writes it.
+// Internal function.
+func (u *Updater) fetchChecksum(ctx context.Context) (string, error) {
+	resp, err := u.get(ctx, u.ChecksumURL, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(io.LimitReader(resp.Body, 4096)).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("update: failed to read checksum from %s: %w", u.ChecksumURL, err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields[0]) != 2*sha256.Size {
+		return "", fmt.Errorf("update: no SHA-256 digest in %s", u.ChecksumURL)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// stage returns the path of the downloaded database, named for sxgo.New to
+// open it as what it is: the download itself, or the database extracted
+// from it if it is a .zip archive.
+// Internal function.
+func (u *Updater) stage(download *os.File, size int64) (string, error) {
+	ext := strings.ToLower(path.Ext(u.urlPath()))
+	if ext == ".zip" {
+		return u.extract(download, size)
+	}
+	if ext != sxgo.BundleExt {
+		ext = ".dat"
+	}
+	f, err := os.CreateTemp(filepath.Dir(u.Path), "."+filepath.Base(u.Path)+".*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("update: failed to stage download: %w", err)
+	}
+	f.Close()
+	if err := os.Rename(download.Name(), f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("update: failed to stage download: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// extract writes the database in the zip archive download to a staging
+// file: the entry named like Path, or else the only .dat or bundle entry.
+// Internal function.
+func (u *Updater) extract(download *os.File, size int64) (string, error) {
+	zr, err := zip.NewReader(download, size)
+	if err != nil {
+		return "", fmt.Errorf("update: invalid archive %s: %w", u.url(), err)
+	}
+	var entry *zip.File
+	candidates := 0
+	for _, zf := range zr.File {
+		name := path.Base(zf.Name)
+		ext := strings.ToLower(path.Ext(name))
+		if zf.FileInfo().IsDir() || (ext != ".dat" && ext != sxgo.BundleExt) {
+			continue
+		}
+		if strings.EqualFold(name, filepath.Base(u.Path)) {
+			entry, candidates = zf, 1
+			break
+		}
+		entry = zf
+		candidates++
+	}
+	switch {
+	case candidates == 0:
+		return "", fmt.Errorf("update: no database in archive %s", u.url())
+	case candidates > 1:
+		return "", fmt.Errorf("update: archive %s holds several databases, none named %s", u.url(), filepath.Base(u.Path))
+	}
+
+	r, err := entry.Open()
+	if err != nil {
+		return "", fmt.Errorf("update: failed to extract %s: %w", entry.Name, err)
+	}
+	defer r.Close()
+	f, err := os.CreateTemp(filepath.Dir(u.Path), "."+filepath.Base(u.Path)+".*"+strings.ToLower(path.Ext(entry.Name)))
+	if err != nil {
+		return "", fmt.Errorf("update: failed to stage download: %w", err)
+	}
+	maxSize := u.maxSize()
+	n, err := io.Copy(f, io.LimitReader(r, maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("larger than %d bytes", maxSize)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("update: failed to extract %s: %w", entry.Name, err)
+	}
+	return f.Name(), nil
+}
+
+// urlPath returns the path of the download URL, for its extension.
+// Internal function.
+func (u *Updater) urlPath() string {
+	if p, err := url.Parse(u.url()); err == nil {
+		return p.Path
+	}
+	return u.url()
+}
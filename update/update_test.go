@@ -0,0 +1,190 @@
+package update
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/idanyas/sxgo"
+	"github.com/idanyas/sxgo/dbwriter"
+)
+
+// Creation times of the installed and the released database.
+var (
+	installed = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	released  = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// database returns a small City database created at created.
+func database(t *testing.T, created time.Time) []byte {
+	t.Helper()
+	ru := &dbwriter.Country{ID: 185, ISO: "RU", NameEN: "Russia"}
+	moscow := &dbwriter.City{ID: 524901, CountryID: 185, NameEN: "Moscow"}
+	db := &dbwriter.Database{
+		Type:      dbwriter.TypeCity,
+		Charset:   dbwriter.CharsetUTF8,
+		Created:   created,
+		Countries: []*dbwriter.Country{ru},
+		Cities:    []*dbwriter.City{moscow},
+		Blocks:    []dbwriter.Block{{Start: 93 << 24, City: moscow}},
+	}
+	data, err := db.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// install writes the installed database to a temporary directory and
+// returns its path.
+func install(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "SxGeoCity.dat")
+	if err := os.WriteFile(path, database(t, installed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// server serves body at every path with an ETag, answering 304 to requests
+// revalidating it, and counts the full responses.
+func server(t *testing.T, body []byte) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var full atomic.Int32
+	const etag = `"release-1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &full
+}
+
+// sum returns the hex SHA-256 digest of data.
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// created returns the creation time of the database at path.
+func created(t *testing.T, path string) time.Time {
+	t.Helper()
+	geo, err := sxgo.New(path, sxgo.ModeMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	return geo.Info().Created
+}
+
+func TestUpdate(t *testing.T) {
+	release := database(t, released)
+	srv, full := server(t, release)
+	path := install(t)
+	geo, err := sxgo.New(path, sxgo.ModeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geo.Close()
+	u := &Updater{URL: srv.URL + "/SxGeoCity.dat", Path: path, Geo: geo, SHA256: sum(release)}
+
+	res, err := u.Update(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Updated || !res.Reloaded || res.NotModified || res.Size != int64(len(release)) || res.SHA256 != sum(release) {
+		t.Errorf("first Update = %+v, want the release installed and reloaded", res)
+	}
+	if !res.Created.Equal(released) || !res.Installed.Equal(installed) {
+		t.Errorf("first Update created %v over %v, want %v over %v", res.Created, res.Installed, released, installed)
+	}
+	if got := geo.Info().Created; !got.Equal(released) {
+		t.Errorf("reloaded database created %v, want %v", got, released)
+	}
+
+	// The second request revalidates the download
+	res, err = u.Update(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.NotModified || res.Updated {
+		t.Errorf("second Update = %+v, want not modified", res)
+	}
+	if n := full.Load(); n != 1 {
+		t.Errorf("%d full downloads, want 1", n)
+	}
+}
+
+func TestUpdateZip(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, err := zw.Create("SxGeoCity_utf8/SxGeoCity.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(database(t, released))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := server(t, archive.Bytes())
+	path := install(t)
+	u := &Updater{URL: srv.URL + "/SxGeoCity_utf8.zip", Path: path}
+	res, err := u.Update(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Updated || res.Reloaded {
+		t.Errorf("Update = %+v, want the release installed without reloading", res)
+	}
+	if got := created(t, path); !got.Equal(released) {
+		t.Errorf("installed database created %v, want %v", got, released)
+	}
+}
+
+func TestUpdateChecksumMismatch(t *testing.T) {
+	release := database(t, released)
+	srv, _ := server(t, release)
+	sums := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sum([]byte("another release")) + "  SxGeoCity.dat\n"))
+	}))
+	defer sums.Close()
+
+	tests := []struct {
+		name string
+		set  func(u *Updater)
+	}{
+		{"SHA256", func(u *Updater) { u.SHA256 = sum([]byte("another release")) }},
+		{"ChecksumURL", func(u *Updater) { u.ChecksumURL = sums.URL + "/SxGeoCity.dat.sha256" }},
+		{"Size", func(u *Updater) { u.Size = int64(len(release)) + 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := install(t)
+			u := &Updater{URL: srv.URL + "/SxGeoCity.dat", Path: path}
+			tt.set(u)
+			if res, err := u.Update(context.Background()); err == nil {
+				t.Fatalf("Update = %+v, want an error", res)
+			}
+			if got := created(t, path); !got.Equal(installed) {
+				t.Errorf("installed database created %v after a failed check, want %v", got, installed)
+			}
+			if files, _ := os.ReadDir(filepath.Dir(path)); len(files) != 1 {
+				t.Errorf("%d files next to the database after a failed check, want none", len(files)-1)
+			}
+		})
+	}
+}
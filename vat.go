@@ -0,0 +1,70 @@
+package sxgo
+
+import "strings"
+
+// VATInfo describes the VAT treatment of digital services sold to consumers
+// in a country.
+type VATInfo struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code.
+	// EU reports whether the country is an EU member state, i.e. whether EU
+	// VAT (and the One-Stop Shop scheme) applies.
+	EU bool `json:"eu"`
+	// StandardRate is the standard VAT rate in percent; 0 outside the table.
+	StandardRate float64 `json:"standard_rate"`
+}
+
+// VATTable maps ISO country codes to their VAT information.
+type VATTable map[string]VATInfo
+
+// EUVATRates returns the standard VAT rates of the EU member states as of
+// 2025. Rates change: check them against the European Commission's
+// published rates and pass an amended table to WithVATTable as needed.
+// The table is a fresh copy that may be modified.
+func EUVATRates() VATTable {
+	t := make(VATTable, len(euVATRates))
+	for iso, rate := range euVATRates {
+		t[iso] = VATInfo{Country: iso, EU: true, StandardRate: rate}
+	}
+	return t
+}
+
+// WithVATTable replaces the table used by VATJurisdiction (EUVATRates by
+// default), e.g. to update rates or add non-EU countries where the seller is
+// registered. The map is not copied and must not be modified afterwards.
+func WithVATTable(t VATTable) Option {
+	return func(s *SxGeo) {
+		s.vatTable = t
+	}
+}
+
+// VATJurisdiction returns the VAT information for the country of ip. Countries
+// missing from the table yield a VATInfo with EU false and no rate.
+// Returns (nil, nil) if the country is unknown.
+// Country-level geolocation cannot tell special territories (e.g. the Canary
+// Islands or Åland, outside the EU VAT area) from the rest of their country;
+// OSS also requires a second, non-conflicting piece of location evidence.
+func (s *SxGeo) VATJurisdiction(ip string) (*VATInfo, error) {
+	iso, err := s.country(ip)
+	if err != nil || iso == "" {
+		return nil, err
+	}
+	return s.vatInfo(iso), nil
+}
+
+// vatInfo looks iso up in the configured VAT table.
+// Internal function.
+func (s *SxGeo) vatInfo(iso string) *VATInfo {
+	iso = strings.ToUpper(iso)
+	t := s.vatTable
+	if t == nil {
+		if rate, ok := euVATRates[iso]; ok {
+			return &VATInfo{Country: iso, EU: true, StandardRate: rate}
+		}
+		return &VATInfo{Country: iso}
+	}
+	if v, ok := t[iso]; ok {
+		v.Country = iso
+		return &v
+	}
+	return &VATInfo{Country: iso}
+}
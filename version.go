@@ -0,0 +1,101 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FormatVersion is the newest database format version the package reads, as
+// stored in the header (22 for 2.2).
+const FormatVersion = 22
+
+// errStrictVersion is the reason of a VersionError for databases refused by
+// WithStrictVersion.
+var errStrictVersion = errors.New("refused by WithStrictVersion")
+
+// VersionError describes a database in a format version newer than
+// FormatVersion. New reads such a database as version 2.2 when its layout
+// matches, i.e. the blocks its header describes account for the file exactly
+// and its pack formats are valid, and reports the VersionError through
+// VersionWarning; records may then lack fields the new version added.
+// Otherwise, or with WithStrictVersion, New fails with it (use errors.As),
+// rather than misreading the file.
+type VersionError struct {
+	Version   uint8 // Format version of the database.
+	Supported uint8 // FormatVersion.
+	Err       error // Why the database was not read; nil if it was read best-effort.
+}
+
+// Error describes the mismatch and what to do about it.
+func (e *VersionError) Error() string {
+	msg := fmt.Sprintf("sxgo: database format version %s is newer than %s, the newest this package reads",
+		formatVersion(e.Version), formatVersion(e.Supported))
+	if e.Err == nil {
+		return msg + "; it was read as " + formatVersion(e.Supported) +
+			", whose layout it matches, so new fields may be missing: update github.com/idanyas/sxgo for full support"
+	}
+	return fmt.Sprintf("%s, and was not read (%v): update github.com/idanyas/sxgo or use a database in format %s",
+		msg, e.Err, formatVersion(e.Supported))
+}
+
+// Unwrap returns the reason the database was not read.
+func (e *VersionError) Unwrap() error {
+	return e.Err
+}
+
+// WithStrictVersion makes New fail with a VersionError for databases in a
+// format version newer than FormatVersion instead of reading them
+// best-effort.
+func WithStrictVersion() Option {
+	return func(s *SxGeo) {
+		s.strictVersion = true
+	}
+}
+
+// VersionWarning returns the VersionError of a database read best-effort
+// because its format version is newer than FormatVersion, or nil. Services
+// may log it at startup, or fail their health checks on it.
+func (s *SxGeo) VersionWarning() error {
+	if v := s.db().header.version; v > FormatVersion {
+		return &VersionError{Version: v, Supported: FormatVersion}
+	}
+	return nil
+}
+
+// checkVersion returns the VersionError New fails with for db, if any,
+// once the block offsets are known.
+// Internal function.
+func (s *SxGeo) checkVersion(db *dbState) error {
+	if db.header.version <= FormatVersion {
+		return nil
+	}
+	verr := &VersionError{Version: db.header.version, Supported: FormatVersion}
+	if s.strictVersion {
+		verr.Err = errStrictVersion
+		return verr
+	}
+	end := db.citiesBegin + int64(db.header.citySize)
+	if db.separateCountries {
+		end = db.countriesBegin + int64(db.header.countrySize)
+	}
+	if end != db.end {
+		verr.Err = fmt.Errorf("header describes %d bytes, the database has %d", end-db.base, db.end-db.base)
+		return verr
+	}
+	for i, f := range db.packFormats {
+		if f == "" {
+			continue
+		}
+		if err := ValidatePackFormat(f); err != nil {
+			verr.Err = fmt.Errorf("pack format %d: %w", i, err)
+			return verr
+		}
+	}
+	return nil
+}
+
+// formatVersion returns a header version such as 22 as "2.2".
+// Internal function.
+func formatVersion(v uint8) string {
+	return fmt.Sprintf("%d.%d", v/10, v%10)
+}
@@ -0,0 +1,206 @@
+package sxgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoVersion means a VersionedResolver has no database created at or
+// before the requested time.
+var ErrNoVersion = errors.New("sxgo: no database version in effect at that time")
+
+// DatabaseVersion is a database file of a VersionedResolver.
+type DatabaseVersion struct {
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"` // Creation time from the database header (UTC).
+}
+
+// VersionedResolver answers lookups with the database as it was at a given
+// time, from a directory of archived releases (e.g. one .dat file per
+// month), to reproduce past decisions that depended on geo data. A version is
+// in effect from its header creation time until the next version's; file
+// names do not matter, and of two files created at the same time the last by
+// name is used. Versions are opened on first use, with the mode and options
+// given to NewVersionedResolver, and stay open until Close: prefer ModeFile
+// or ModeMMap for long archives. It is safe for concurrent use.
+type VersionedResolver struct {
+	dir  string
+	mode uint
+	opts []Option
+
+	mu       sync.RWMutex // Held for writing by Rescan and Close, for reading by lookups
+	versions []*archivedVersion
+	closed   bool
+}
+
+// archivedVersion is a file of a VersionedResolver and, once used, its
+// instance.
+// This struct is internal.
+type archivedVersion struct {
+	DatabaseVersion
+	file os.FileInfo // The file as scanned, to keep the instance across Rescan
+
+	mu  sync.Mutex // Held while opening
+	geo *SxGeo
+}
+
+// NewVersionedResolver returns a VersionedResolver for the .dat and bundle
+// files in dir. Each file must open with New. Options are applied to every
+// version, so options tied to one file (WithDiskCache, WithFileWatch,
+// WithAutoReload) must not be used.
+func NewVersionedResolver(dir string, mode uint, opts ...Option) (*VersionedResolver, error) {
+	r := &VersionedResolver{dir: dir, mode: mode, opts: opts}
+	if err := r.Rescan(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Rescan reads the directory again, e.g. after a new release was archived.
+// Instances of files that did not change are kept; the others are closed
+// once lookups in progress are done. On error the versions are unchanged.
+func (r *VersionedResolver) Rescan() error {
+	paths, err := databaseFiles(r.dir)
+	if err != nil {
+		return fmt.Errorf("sxgo: failed to read database directory: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("sxgo: no .dat or %s file in %q", BundleExt, r.dir)
+	}
+
+	r.mu.RLock()
+	known := make(map[string]*archivedVersion, len(r.versions))
+	for _, v := range r.versions {
+		known[v.Path] = v
+	}
+	r.mu.RUnlock()
+
+	versions := make([]*archivedVersion, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("sxgo: failed to read database version: %w", err)
+		}
+		if v := known[p]; v != nil && sameFileVersion(fi, v.file) {
+			versions = append(versions, v)
+			continue
+		}
+		geo, err := New(p, ModeFile)
+		if err != nil {
+			return fmt.Errorf("sxgo: database version rejected: %w", err)
+		}
+		created := geo.Info().Created
+		geo.Close()
+		versions = append(versions, &archivedVersion{DatabaseVersion: DatabaseVersion{Path: p, Created: created}, file: fi})
+	}
+	sort.SliceStable(versions, func(i, j int) bool { // Paths are sorted by name
+		return versions[i].Created.Before(versions[j].Created)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errors.New("sxgo: cannot rescan a closed resolver")
+	}
+	kept := make(map[*archivedVersion]bool, len(versions))
+	for _, v := range versions {
+		kept[v] = true
+	}
+	for _, v := range r.versions {
+		if !kept[v] && v.geo != nil {
+			v.geo.Close()
+		}
+	}
+	r.versions = versions
+	return nil
+}
+
+// Versions returns the database versions, oldest first.
+func (r *VersionedResolver) Versions() []DatabaseVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]DatabaseVersion, len(r.versions))
+	for i, v := range r.versions {
+		out[i] = v.DatabaseVersion
+	}
+	return out
+}
+
+// VersionAt returns the database version in effect at asOf, i.e. the newest
+// created at or before it, and false if there is none. Record it next to
+// decisions to show which data they were based on.
+func (r *VersionedResolver) VersionAt(asOf time.Time) (DatabaseVersion, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if v := r.versionAt(asOf); v != nil {
+		return v.DatabaseVersion, true
+	}
+	return DatabaseVersion{}, false
+}
+
+// LookupAt returns GetCityFull(ip, opts...) of the database version in
+// effect at asOf (see VersionAt), opening it if needed. It returns
+// ErrNoVersion (wrapped) if asOf precedes every version.
+func (r *VersionedResolver) LookupAt(ip string, asOf time.Time, opts ...CallOption) (*LocationInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, errors.New("sxgo: lookup on a closed resolver")
+	}
+	v := r.versionAt(asOf)
+	if v == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoVersion, asOf.UTC().Format(time.RFC3339))
+	}
+	geo, err := r.open(v)
+	if err != nil {
+		return nil, err
+	}
+	return geo.GetCityFull(ip, opts...)
+}
+
+// Close closes the instances of all versions opened so far.
+func (r *VersionedResolver) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	var errs []error
+	for _, v := range r.versions {
+		if v.geo != nil {
+			errs = append(errs, v.geo.Close())
+			v.geo = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// versionAt returns the version in effect at asOf, or nil. r.mu must be held.
+// Internal function.
+func (r *VersionedResolver) versionAt(asOf time.Time) *archivedVersion {
+	i := sort.Search(len(r.versions), func(i int) bool {
+		return r.versions[i].Created.After(asOf)
+	})
+	if i == 0 {
+		return nil
+	}
+	return r.versions[i-1]
+}
+
+// open returns the instance of v, opening it on first use. r.mu must be held
+// for reading.
+// Internal function.
+func (r *VersionedResolver) open(v *archivedVersion) (*SxGeo, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.geo == nil {
+		geo, err := New(v.Path, r.mode, r.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("sxgo: failed to open database version of %s: %w", v.Created.Format(time.RFC3339), err)
+		}
+		v.geo = geo
+	}
+	return v.geo, nil
+}
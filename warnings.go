@@ -0,0 +1,20 @@
+package sxgo
+
+// WithWarnings records the recoverable errors met while decoding a result
+// (a region or country record that cannot be read or is truncated, a missing
+// pack format) in LocationInfo.Warnings. Lookups return the partial result
+// either way; without this option the errors are dropped silently. Results
+// with warnings are not stored in the disk cache (see WithDiskCache).
+func WithWarnings() Option {
+	return func(s *SxGeo) {
+		s.warnings = true
+	}
+}
+
+// warn appends err to the warnings of info if WithWarnings is set.
+// Internal function.
+func (s *SxGeo) warn(info *LocationInfo, err error) {
+	if s.warnings {
+		info.Warnings = append(info.Warnings, err.Error())
+	}
+}
@@ -0,0 +1,159 @@
+package sxgo
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+)
+
+// ZeroIDCause tells why an address resolves to ID 0 (see ZeroIDError).
+type ZeroIDCause int
+
+const (
+	// ZeroIDRecord means the block the address resolves to stores ID 0: the
+	// database explicitly marks the range as unknown.
+	ZeroIDRecord ZeroIDCause = iota
+	// ZeroIDNoBlock means no block covers the address: its first-byte window
+	// has no blocks, or the address is below the first block of the table,
+	// so the search fell off the edge of the table.
+	ZeroIDNoBlock
+	// ZeroIDPatch means a range installed by ApplyPatch maps the address to
+	// ID 0.
+	ZeroIDPatch
+)
+
+// String returns the name of the cause.
+func (c ZeroIDCause) String() string {
+	switch c {
+	case ZeroIDRecord:
+		return "record"
+	case ZeroIDNoBlock:
+		return "no_block"
+	case ZeroIDPatch:
+		return "patch"
+	}
+	return fmt.Sprintf("ZeroIDCause(%d)", int(c))
+}
+
+// BlockRef describes a block of the block table next to a zero-ID lookup.
+type BlockRef struct {
+	Block uint32     `json:"block"` // Index of the block in the block table.
+	Start netip.Addr `json:"start"` // First address of the block; invalid if no first-byte window holds it.
+	ID    uint32     `json:"id"`    // ID the block stores (see LookupID).
+}
+
+// ZeroIDError explains why an address resolved to ID 0, which lookups
+// otherwise report as a plain miss (see WithZeroIDDiagnostics). It wraps
+// ErrNotFound.
+type ZeroIDError struct {
+	IP    string      `json:"ip"`    // Address looked up.
+	Cause ZeroIDCause `json:"cause"` // Why it resolved to ID 0.
+	Range IPRange     `json:"range"` // Addresses around IP resolving the same way (within its first-byte window, or the whole patch range).
+	// Block is the block IP resolves to, for ZeroIDRecord; -1 otherwise.
+	Block int64 `json:"block"`
+	// Prev and Next are the blocks before and after Block or, for
+	// ZeroIDNoBlock, around the position IP would take in the table; nil at
+	// the ends of the table and for ZeroIDPatch.
+	Prev *BlockRef `json:"prev,omitempty"`
+	Next *BlockRef `json:"next,omitempty"`
+}
+
+// Error describes the cause and the neighbouring blocks.
+func (e *ZeroIDError) Error() string {
+	msg := fmt.Sprintf("%v: %s resolves to ID 0", ErrNotFound, e.IP)
+	switch e.Cause {
+	case ZeroIDRecord:
+		msg += fmt.Sprintf(": block %d (%s) stores ID 0", e.Block, e.Range)
+	case ZeroIDNoBlock:
+		msg += fmt.Sprintf(": no block covers %s", e.Range)
+	case ZeroIDPatch:
+		msg += fmt.Sprintf(": patched range %s maps to ID 0", e.Range)
+	}
+	for _, b := range []struct {
+		name string
+		ref  *BlockRef
+	}{{"previous", e.Prev}, {"next", e.Next}} {
+		if b.ref != nil {
+			msg += fmt.Sprintf("; %s block %d starts at %s with ID %d", b.name, b.ref.Block, b.ref.Start, b.ref.ID)
+		}
+	}
+	return msg
+}
+
+// Unwrap returns ErrNotFound.
+func (e *ZeroIDError) Unwrap() error {
+	return ErrNotFound
+}
+
+// WithZeroIDDiagnostics makes lookups missing an address because it resolves
+// to ID 0 return a *ZeroIDError (use errors.As) telling an explicit unknown
+// record in the database from a search falling off the edge of the block
+// table, with the neighbouring blocks, to help debug database quality issues.
+// It implies WithNotFoundErrors; other misses return its errors. Diagnosing
+// a miss searches the address's first-byte window again.
+func WithZeroIDDiagnostics() Option {
+	return func(s *SxGeo) {
+		s.notFoundErrors = true
+		s.zeroIDErrors = true
+	}
+}
+
+// diagnoseZeroID returns the ZeroIDError of ip, or nil if it does not
+// resolve to ID 0 in db or cannot be diagnosed.
+// Internal function.
+func (s *SxGeo) diagnoseZeroID(db *dbState, ip string) *ZeroIDError {
+	ipNum, _, err := s.parseIP(ip)
+	if err != nil {
+		return nil
+	}
+	r, patched, err := s.rangeOf(db, ipNum)
+	if err != nil || r.id != 0 {
+		return nil
+	}
+	e := &ZeroIDError{
+		IP:    ip,
+		Range: IPRange{First: uint32ToAddr(r.first), Last: uint32ToAddr(r.last)},
+		Block: r.block,
+	}
+	var prev, next int64
+	switch {
+	case patched:
+		e.Cause = ZeroIDPatch
+		return e
+	case r.block >= 0:
+		e.Cause = ZeroIDRecord
+		prev, next = r.block-1, r.block+1
+	default:
+		// The blocks of the window (none, or all above ipNum) would follow ipNum
+		e.Cause = ZeroIDNoBlock
+		next = int64(min(db.byteIndexAt(ipNum>>24-1), db.header.dbItems))
+		prev = next - 1
+	}
+	e.Prev = s.blockRef(db, prev)
+	e.Next = s.blockRef(db, next)
+	return e
+}
+
+// blockRef describes block i, or returns nil if it does not exist or cannot
+// be read.
+// Internal function.
+func (s *SxGeo) blockRef(db *dbState, i int64) *BlockRef {
+	if i < 0 || i >= int64(db.header.dbItems) {
+		return nil
+	}
+	data, err := s.blockData(db, uint32(i), uint32(i)+1)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	id, err := db.blockID(data, 0)
+	if err != nil {
+		return nil
+	}
+	ref := &BlockRef{Block: uint32(i), ID: id}
+	// The window holding the block gives the first byte of its start
+	n := int(db.header.byteIndexLen)
+	if b := sort.Search(n, func(k int) bool { return int64(db.byteIndexAt(uint32(k))) > i }); b > 0 && b < n {
+		ref.Start = uint32ToAddr(uint32(b)<<24 | blockSuffix(data, 0, db.blockSize))
+	}
+	return ref
+}